@@ -3,94 +3,137 @@ package config
 import (
 	"database/sql"
 	"fmt"
-	"log"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Окружения приложения
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvProd = "prod"
+)
+
 type Config struct {
+	Env        string
+	ListenAddr string
+
+	Server   ServerConfig
+	Database DatabaseConfig
+	Session  SessionConfig
+	Mail     MailConfig
+	SMS      SMSConfig
+	Log      LogConfig
+	// OIDC провайдеры входа «Войти через …»; пустой список — вход только по паролю и SMS
+	OIDC []OIDCProviderConfig
+
+	// BaseURL адрес сайта для ссылок в письмах
+	BaseURL string
+	// AppSecret ключ для подписи CSRF-токенов
+	AppSecret Secret
+	// CORSOrigins источники, которым разрешены запросы с cookie из браузера.
+	// Пустой список — только свой источник.
+	CORSOrigins []string
+	// TrustedProxies адреса и подсети обратных прокси, которым доверяются
+	// X-Forwarded-For и X-Real-IP. Пустой список — IP клиента берется из соединения.
+	TrustedProxies []string
+
+	MigrationsDir   string
+	SeedsDir        string
+	BannedWordsFile string
+	// PrivacyPolicyFile файл политики конфиденциальности относительно корня сайта.
+	// При старте его хеш сверяется с последней версией в базе.
+	PrivacyPolicyFile string
+
 	DB *sql.DB
 }
 
-func NewConfig() (*Config, error) {
-	// Параметры подключения (лучше вынести в переменные окружения)
-	connStr := "user=postgres password=postgres dbname=beladonna sslmode=disable host=localhost port=5432"
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %v", err)
-	}
-
-	// Проверка подключения
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %v", err)
-	}
+// ServerConfig таймауты HTTP-сервера. ShutdownTimeout ограничивает ожидание
+// незавершенных запросов при остановке.
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+}
 
-	log.Println("Successfully connected to database")
-	return &Config{DB: db}, nil
+type DatabaseConfig struct {
+	DSN             Secret
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// QueryTimeout предельное время одного запроса к базе
+	QueryTimeout time.Duration
 }
 
-// Функция для выполнения миграций из файла
-func RunMigrations(db *sql.DB, migrationFilePath string) error {
-	// Получаем абсолютный путь к файлу
-	absPath, err := filepath.Abs(migrationFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to get absolute path for %s: %v", migrationFilePath, err)
-	}
+type SessionConfig struct {
+	CookieSecure       bool
+	Lifetime           time.Duration
+	RememberMeLifetime time.Duration
+}
 
-	// Проверяем существование файла
-	if _, err := os.Stat(absPath); os.IsNotExist(err) {
-		return fmt.Errorf("migration file does not exist: %s", absPath)
-	}
+// MailConfig настройки почты. Driver "log" пишет письма в лог, "smtp" отправляет через SMTP,
+// "outbox" складывает файлы .eml в OutboxDir. В письмах есть токены из ссылок, поэтому
+// OutboxDir по умолчанию во временном каталоге, вне файлов, которые раздает сервер.
+type MailConfig struct {
+	Driver    string
+	OutboxDir string
+	Host      string
+	Port      int
+	Username  string
+	Password  Secret
+	From      string
+}
 
-	// Читаем содержимое файла
-	content, err := os.ReadFile(absPath)
-	if err != nil {
-		return fmt.Errorf("failed to read migration file %s: %v", absPath, err)
-	}
+// SMSConfig отправка SMS. SMS-шлюза пока нет: Driver "disabled" отклоняет отправку,
+// заглушки для разработки "log" (пишет в лог, но без кода) и "file" (дописывает SMS в File)
+// в prod запрещены.
+type SMSConfig struct {
+	Driver string
+	File   string
+}
 
-	// Выполняем SQL
-	_, err = db.Exec(string(content))
-	if err != nil {
-		return fmt.Errorf("migration failed in file %s: %v", absPath, err)
-	}
+// OIDCProviderConfig клиент у провайдера OpenID Connect. Настройки читаются из ключей
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID и т.д., где NAME — имя из OIDC_PROVIDERS
+// в верхнем регистре с "_" вместо "-".
+type OIDCProviderConfig struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret Secret
+	Scopes       []string
+}
 
-	log.Printf("Migrations from %s completed successfully", filepath.Base(absPath))
-	return nil
+// LogConfig уровень и формат журнала. Format: json или text.
+type LogConfig struct {
+	Level  slog.Level
+	Format string
 }
 
-// Функция для выполнения всех миграций из папки
-func RunAllMigrations(db *sql.DB, migrationsDir string) error {
-	absPath, err := filepath.Abs(migrationsDir)
+// OpenDB открывает пул соединений с настройками из конфигурации
+func (c *Config) OpenDB() error {
+	db, err := sql.Open("postgres", string(c.Database.DSN))
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for directory %s: %v", migrationsDir, err)
+		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	// Читаем содержимое папки
-	entries, err := os.ReadDir(absPath)
-	if err != nil {
-		return fmt.Errorf("failed to read migrations directory %s: %v", absPath, err)
-	}
+	db.SetMaxOpenConns(c.Database.MaxOpenConns)
+	db.SetMaxIdleConns(c.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(c.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.Database.ConnMaxIdleTime)
 
-	// Выполняем каждый SQL файл
-	for _, entry := range entries {
-		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".sql" {
-			filePath := filepath.Join(absPath, entry.Name())
-			err := RunMigrations(db, filePath)
-			if err != nil {
-				return fmt.Errorf("failed to run migration %s: %v", entry.Name(), err)
-			}
-		}
+	// Проверка подключения
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Printf("All migrations from %s completed successfully", absPath)
+	slog.Info("Подключение к БД установлено")
+	c.DB = db
 	return nil
 }
-
-// Старая функция для обратной совместимости (можно удалить позже)
-func RunMigrationsOld(db *sql.DB) error {
-	// По умолчанию пытаемся выполнить миграции из папки migrations
-	return RunAllMigrations(db, "migrations")
-}
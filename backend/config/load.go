@@ -0,0 +1,441 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// devDSN подключение по умолчанию для локальной разработки
+const devDSN = "user=postgres password=postgres dbname=beladonna sslmode=disable host=localhost port=5432"
+
+// MinAppSecretLen наименьшая длина APP_SECRET
+const MinAppSecretLen = 32
+
+// Load собирает конфигурацию из значений по умолчанию, необязательного JSON-файла
+// (путь в APP_CONFIG_FILE) и переменных окружения. Переменные окружения важнее файла.
+// Файл содержит объект с теми же ключами, что и переменные окружения:
+//
+//	{"HTTP_ADDR": ":8080", "DATABASE_URL": "postgres://...", "CORS_ORIGINS": "https://belladonna.ru"}
+func Load() (*Config, error) {
+	values, err := readFile(os.Getenv("APP_CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range knownKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			values[key] = value
+		}
+	}
+	// Ключи провайдеров зависят от их имен, поэтому берутся по префиксу
+	for _, env := range os.Environ() {
+		if key, value, ok := strings.Cut(env, "="); ok && strings.HasPrefix(key, oidcPrefix) {
+			values[key] = value
+		}
+	}
+	return parse(values)
+}
+
+// oidcPrefix общий префикс ключей провайдеров OpenID Connect
+const oidcPrefix = "OIDC_"
+
+// knownKeys переменные, которые читает Load
+var knownKeys = []string{
+	"APP_ENV", "HTTP_ADDR",
+	"HTTP_READ_TIMEOUT", "HTTP_READ_HEADER_TIMEOUT", "HTTP_WRITE_TIMEOUT", "HTTP_IDLE_TIMEOUT", "SHUTDOWN_TIMEOUT",
+	"DATABASE_URL", "DB_MAX_OPEN_CONNS", "DB_MAX_IDLE_CONNS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME", "DB_QUERY_TIMEOUT",
+	"COOKIE_SECURE", "SESSION_LIFETIME", "SESSION_REMEMBER_LIFETIME",
+	"APP_BASE_URL", "APP_SECRET", "CORS_ORIGINS", "TRUSTED_PROXIES",
+	"MAIL_DRIVER", "SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD", "MAIL_FROM", "MAIL_OUTBOX_DIR",
+	"SMS_DRIVER", "SMS_FILE",
+	"OIDC_PROVIDERS",
+	"MIGRATIONS_DIR", "SEEDS_DIR", "BANNED_WORDS_FILE", "PRIVACY_POLICY_FILE",
+	"LOG_LEVEL", "LOG_FORMAT",
+}
+
+func readFile(path string) (map[string]string, error) {
+	values := map[string]string{}
+	if path == "" {
+		return values, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %v", path, err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, key := range knownKeys {
+		known[key] = true
+	}
+	for key, value := range raw {
+		if !known[key] && !strings.HasPrefix(key, oidcPrefix) {
+			return nil, fmt.Errorf("invalid config file %s: unknown key %s", path, key)
+		}
+		switch v := value.(type) {
+		case string:
+			values[key] = v
+		case []interface{}:
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(parts, ",")
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+// parser накапливает ошибки разбора, чтобы сообщить обо всех сразу
+type parser struct {
+	values map[string]string
+	errs   []error
+}
+
+func (p *parser) str(key, def string) string {
+	if value, ok := p.values[key]; ok && strings.TrimSpace(value) != "" {
+		return strings.TrimSpace(value)
+	}
+	return def
+}
+
+func (p *parser) int(key string, def int) int {
+	value := p.str(key, "")
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: expected integer, got %q", key, value))
+		return def
+	}
+	return n
+}
+
+func (p *parser) bool(key string, def bool) bool {
+	value := p.str(key, "")
+	if value == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: expected true or false, got %q", key, value))
+		return def
+	}
+	return b
+}
+
+func (p *parser) duration(key string, def time.Duration) time.Duration {
+	value := p.str(key, "")
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: expected duration like 30m or 24h, got %q", key, value))
+		return def
+	}
+	return d
+}
+
+func (p *parser) level(key string, def slog.Level) slog.Level {
+	value := p.str(key, "")
+	if value == "" {
+		return def
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		p.errs = append(p.errs, fmt.Errorf("%s: expected debug, info, warn or error, got %q", key, value))
+		return def
+	}
+	return level
+}
+
+func (p *parser) list(key string, def []string) []string {
+	value := p.str(key, "")
+	if value == "" {
+		return def
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// oidcProviders провайдеры из OIDC_PROVIDERS с их ключами OIDC_<NAME>_*
+func (p *parser) oidcProviders() []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	for _, name := range p.list("OIDC_PROVIDERS", nil) {
+		prefix := oidcKeyPrefix(name)
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  p.str(prefix+"DISPLAY_NAME", name),
+			Issuer:       p.str(prefix+"ISSUER", ""),
+			ClientID:     p.str(prefix+"CLIENT_ID", ""),
+			ClientSecret: Secret(p.str(prefix+"CLIENT_SECRET", "")),
+			Scopes:       p.list(prefix+"SCOPES", nil),
+		})
+	}
+	return providers
+}
+
+func oidcKeyPrefix(name string) string {
+	return oidcPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+}
+
+func parse(values map[string]string) (*Config, error) {
+	p := &parser{values: values}
+
+	cfg := &Config{
+		// Без явного APP_ENV действуют строгие проверки prod: послабления dev надо включать явно
+		Env:        p.str("APP_ENV", EnvProd),
+		ListenAddr: p.str("HTTP_ADDR", ":8080"),
+		Server: ServerConfig{
+			ReadTimeout:       p.duration("HTTP_READ_TIMEOUT", 15*time.Second),
+			ReadHeaderTimeout: p.duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+			WriteTimeout:      p.duration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:       p.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			ShutdownTimeout:   p.duration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		},
+		Database: DatabaseConfig{
+			DSN:             Secret(p.str("DATABASE_URL", "")),
+			MaxOpenConns:    p.int("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    p.int("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime: p.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: p.duration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+			QueryTimeout:    p.duration("DB_QUERY_TIMEOUT", 5*time.Second),
+		},
+		Session: SessionConfig{
+			CookieSecure:       p.bool("COOKIE_SECURE", false),
+			Lifetime:           p.duration("SESSION_LIFETIME", 7*24*time.Hour),
+			RememberMeLifetime: p.duration("SESSION_REMEMBER_LIFETIME", 30*24*time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:    p.str("MAIL_DRIVER", "log"),
+			OutboxDir: p.str("MAIL_OUTBOX_DIR", filepath.Join(os.TempDir(), "beladonna", "outbox")),
+			Host:      p.str("SMTP_HOST", ""),
+			Port:      p.int("SMTP_PORT", 587),
+			Username:  p.str("SMTP_USERNAME", ""),
+			Password:  Secret(p.str("SMTP_PASSWORD", "")),
+			From:      p.str("MAIL_FROM", "Belladonna <noreply@belladonna.ru>"),
+		},
+		SMS: SMSConfig{
+			Driver: p.str("SMS_DRIVER", ""),
+			File:   p.str("SMS_FILE", filepath.Join(os.TempDir(), "beladonna", "sms.log")),
+		},
+		Log: LogConfig{
+			Level:  p.level("LOG_LEVEL", slog.LevelInfo),
+			Format: p.str("LOG_FORMAT", "json"),
+		},
+		BaseURL:           p.str("APP_BASE_URL", "http://localhost:8080"),
+		AppSecret:         Secret(p.str("APP_SECRET", "")),
+		CORSOrigins:       p.list("CORS_ORIGINS", nil),
+		TrustedProxies:    p.list("TRUSTED_PROXIES", nil),
+		MigrationsDir:     p.str("MIGRATIONS_DIR", "backend/migrations"),
+		SeedsDir:          p.str("SEEDS_DIR", "backend/seeds"),
+		BannedWordsFile:   p.str("BANNED_WORDS_FILE", "backend/config/banned_words.txt"),
+		PrivacyPolicyFile: p.str("PRIVACY_POLICY_FILE", "privacy/Politics.pdf"),
+		OIDC:              p.oidcProviders(),
+	}
+
+	if cfg.Database.DSN == "" && cfg.Env != EnvProd {
+		cfg.Database.DSN = devDSN
+	}
+	// Вне prod секрет можно не задавать: случайный ключ живет до перезапуска,
+	// после чего выданные CSRF-токены перестают действовать
+	if cfg.AppSecret == "" && cfg.Env != EnvProd {
+		cfg.AppSecret = randomSecret()
+	}
+	// SMS-шлюза нет: в prod отправка по умолчанию выключена, вне prod коды пишутся в файл
+	if cfg.SMS.Driver == "" {
+		cfg.SMS.Driver = "file"
+		if cfg.Env == EnvProd {
+			cfg.SMS.Driver = "disabled"
+		}
+	}
+
+	errs := append(p.errs, cfg.Validate()...)
+	if len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		return nil, fmt.Errorf("invalid configuration:\n  %s", strings.Join(messages, "\n  "))
+	}
+	return cfg, nil
+}
+
+// Validate проверяет согласованность настроек
+func (c *Config) Validate() []error {
+	var errs []error
+
+	switch c.Env {
+	case EnvDev, EnvTest, EnvProd:
+	default:
+		errs = append(errs, fmt.Errorf("APP_ENV: must be dev, test or prod, got %q", c.Env))
+	}
+	if c.ListenAddr == "" {
+		errs = append(errs, errors.New("HTTP_ADDR: must not be empty"))
+	}
+
+	if c.Server.ReadTimeout <= 0 || c.Server.ReadHeaderTimeout <= 0 || c.Server.WriteTimeout <= 0 ||
+		c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("HTTP_*_TIMEOUT, SHUTDOWN_TIMEOUT: must be positive"))
+	}
+
+	if c.Database.DSN == "" {
+		errs = append(errs, errors.New("DATABASE_URL: required in prod"))
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS: must not be negative"))
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		errs = append(errs, errors.New("DB_MAX_IDLE_CONNS: must not exceed DB_MAX_OPEN_CONNS"))
+	}
+	if c.Database.QueryTimeout <= 0 {
+		errs = append(errs, errors.New("DB_QUERY_TIMEOUT: must be positive"))
+	}
+
+	if c.Session.Lifetime <= 0 || c.Session.RememberMeLifetime <= 0 {
+		errs = append(errs, errors.New("SESSION_LIFETIME, SESSION_REMEMBER_LIFETIME: must be positive"))
+	}
+	if c.Env == EnvProd && !c.Session.CookieSecure {
+		errs = append(errs, errors.New("COOKIE_SECURE: must be true in prod"))
+	}
+
+	if !validOrigin(c.BaseURL) {
+		errs = append(errs, fmt.Errorf("APP_BASE_URL: %q is not a site address like https://example.com", c.BaseURL))
+	}
+	if len(c.AppSecret) < MinAppSecretLen {
+		errs = append(errs, fmt.Errorf("APP_SECRET: must be at least %d characters", MinAppSecretLen))
+	}
+	for _, origin := range c.CORSOrigins {
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Errorf("CORS_ORIGINS: %q is not an origin like https://example.com; \"*\" is not allowed with cookies", origin))
+		}
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if !validProxy(proxy) {
+			errs = append(errs, fmt.Errorf("TRUSTED_PROXIES: %q is not an IP address or network like 10.0.0.0/8", proxy))
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "log":
+	case "outbox":
+		if c.Mail.OutboxDir == "" {
+			errs = append(errs, errors.New("MAIL_OUTBOX_DIR: required when MAIL_DRIVER=outbox"))
+		}
+	case "smtp":
+		if c.Mail.Host == "" {
+			errs = append(errs, errors.New("SMTP_HOST: required when MAIL_DRIVER=smtp"))
+		}
+		if c.Mail.Port <= 0 || c.Mail.Port > 65535 {
+			errs = append(errs, fmt.Errorf("SMTP_PORT: invalid port %d", c.Mail.Port))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("MAIL_DRIVER: must be log, outbox or smtp, got %q", c.Mail.Driver))
+	}
+	if c.Mail.From == "" {
+		errs = append(errs, errors.New("MAIL_FROM: must not be empty"))
+	}
+
+	switch c.SMS.Driver {
+	case "disabled":
+	case "log", "file":
+		// Заглушки сохраняют коды входа в открытом виде
+		if c.Env == EnvProd {
+			errs = append(errs, fmt.Errorf("SMS_DRIVER: %s is a development stub and is not allowed in prod, use disabled", c.SMS.Driver))
+		}
+		if c.SMS.Driver == "file" && c.SMS.File == "" {
+			errs = append(errs, errors.New("SMS_FILE: required when SMS_DRIVER=file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("SMS_DRIVER: must be disabled, log or file, got %q", c.SMS.Driver))
+	}
+
+	seen := map[string]bool{}
+	for _, provider := range c.OIDC {
+		prefix := oidcKeyPrefix(provider.Name)
+		if !oidcNamePattern.MatchString(provider.Name) {
+			errs = append(errs, fmt.Errorf("OIDC_PROVIDERS: %q must contain only a-z, 0-9 and -", provider.Name))
+			continue
+		}
+		if seen[provider.Name] {
+			errs = append(errs, fmt.Errorf("OIDC_PROVIDERS: duplicate provider %q", provider.Name))
+		}
+		seen[provider.Name] = true
+		if !validIssuer(provider.Issuer, c.Env == EnvProd) {
+			errs = append(errs, fmt.Errorf("%sISSUER: %q is not an issuer URL like https://accounts.example.com", prefix, provider.Issuer))
+		}
+		if provider.ClientID == "" {
+			errs = append(errs, fmt.Errorf("%sCLIENT_ID: required", prefix))
+		}
+	}
+
+	if c.Log.Format != "json" && c.Log.Format != "text" {
+		errs = append(errs, fmt.Errorf("LOG_FORMAT: must be json or text, got %q", c.Log.Format))
+	}
+
+	return errs
+}
+
+// validOrigin проверяет, что строка — источник вида scheme://host[:port] без пути
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// validProxy проверяет адрес или подсеть прокси
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// oidcNamePattern имя провайдера: оно попадает в адрес callback и в таблицу привязок
+var oidcNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// validIssuer проверяет адрес издателя; в prod допускается только https
+func validIssuer(issuer string, httpsOnly bool) bool {
+	u, err := url.Parse(issuer)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return false
+	}
+	return u.Scheme == "https" || (u.Scheme == "http" && !httpsOnly)
+}
+
+func randomSecret() Secret {
+	b := make([]byte, MinAppSecretLen)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return Secret(hex.EncodeToString(b))
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// prodValues минимальная корректная конфигурация prod
+func prodValues(extra map[string]string) map[string]string {
+	values := map[string]string{
+		"APP_ENV":       EnvProd,
+		"DATABASE_URL":  "postgres://beladonna@localhost/beladonna",
+		"APP_SECRET":    strings.Repeat("s", MinAppSecretLen),
+		"COOKIE_SECURE": "true",
+		"APP_BASE_URL":  "https://belladonna.ru",
+	}
+	for k, v := range extra {
+		values[k] = v
+	}
+	return values
+}
+
+func TestSMSDriver(t *testing.T) {
+	cfg, err := parse(prodValues(nil))
+	if err != nil {
+		t.Fatalf("prod config: %v", err)
+	}
+	if cfg.SMS.Driver != "disabled" {
+		t.Errorf("prod default SMS_DRIVER = %q, want disabled", cfg.SMS.Driver)
+	}
+
+	for _, driver := range []string{"log", "file"} {
+		_, err := parse(prodValues(map[string]string{"SMS_DRIVER": driver}))
+		if err == nil || !strings.Contains(err.Error(), "SMS_DRIVER") {
+			t.Errorf("prod with SMS_DRIVER=%s: err = %v, want rejected", driver, err)
+		}
+	}
+
+	cfg, err = parse(map[string]string{"APP_ENV": EnvDev})
+	if err != nil {
+		t.Fatalf("dev config: %v", err)
+	}
+	if cfg.SMS.Driver != "file" || cfg.SMS.File == "" {
+		t.Errorf("dev SMS = %+v, want file driver", cfg.SMS)
+	}
+}
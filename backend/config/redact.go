@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Secret строка, которая не выводится в логи: fmt и encoding/json печатают вместо нее "***"
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "***"
+}
+
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.String() + `"`), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+var dsnPasswordPattern = regexp.MustCompile(`(password=)(?:'[^']*'|\S+)`)
+
+// RedactDSN скрывает пароль в строке подключения формата URL или key=value
+func RedactDSN(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxx")
+			return strings.Replace(u.String(), ":xxx@", ":***@", 1)
+		}
+		return dsn
+	}
+	return dsnPasswordPattern.ReplaceAllString(dsn, "${1}***")
+}
+
+// String краткое описание конфигурации для лога; секреты скрыты
+func (c *Config) String() string {
+	return fmt.Sprintf(
+		"env=%s addr=%s db=%q pool=%d/%d cookie_secure=%t session=%s/%s cors=%v mail=%s sms=%s oidc=%v log=%s/%s",
+		c.Env,
+		c.ListenAddr,
+		RedactDSN(string(c.Database.DSN)),
+		c.Database.MaxOpenConns,
+		c.Database.MaxIdleConns,
+		c.Session.CookieSecure,
+		c.Session.Lifetime,
+		c.Session.RememberMeLifetime,
+		c.CORSOrigins,
+		c.mailSummary(),
+		c.smsSummary(),
+		c.oidcNames(),
+		c.Log.Level,
+		c.Log.Format,
+	)
+}
+
+func (c *Config) mailSummary() string {
+	switch c.Mail.Driver {
+	case "smtp":
+		return fmt.Sprintf("smtp://%s@%s:%d", c.Mail.Username, c.Mail.Host, c.Mail.Port)
+	case "outbox":
+		return "outbox:" + c.Mail.OutboxDir
+	}
+	return c.Mail.Driver
+}
+
+func (c *Config) smsSummary() string {
+	if c.SMS.Driver == "file" {
+		return "file:" + c.SMS.File
+	}
+	return c.SMS.Driver
+}
+
+func (c *Config) oidcNames() []string {
+	names := make([]string, len(c.OIDC))
+	for i, provider := range c.OIDC {
+		names[i] = provider.Name
+	}
+	return names
+}
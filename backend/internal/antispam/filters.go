@@ -0,0 +1,87 @@
+package antispam
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ContentFilter проверяет текст сообщения. Если текст не проходит проверку,
+// возвращается причина отказа, иначе пустая строка.
+type ContentFilter interface {
+	Check(text string) string
+}
+
+// Причины отказа, которые фильтры и проверки формы пишут в журнал
+const (
+	ReasonRateLimitUser = "rate_limit_user"
+	ReasonRateLimitIP   = "rate_limit_ip"
+	ReasonHoneypot      = "honeypot"
+	ReasonTooFast       = "too_fast"
+	ReasonFormToken     = "form_token_invalid"
+	ReasonDuplicate     = "duplicate"
+	ReasonBannedWord    = "banned_word"
+	ReasonTooManyLinks  = "too_many_links"
+)
+
+// BannedWordsFilter отклоняет тексты, содержащие слова из списка
+type BannedWordsFilter struct {
+	words []string
+}
+
+func NewBannedWordsFilter(words []string) *BannedWordsFilter {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" {
+			normalized = append(normalized, word)
+		}
+	}
+	return &BannedWordsFilter{words: normalized}
+}
+
+func (f *BannedWordsFilter) Check(text string) string {
+	lower := strings.ToLower(text)
+	for _, word := range f.words {
+		if strings.Contains(lower, word) {
+			return ReasonBannedWord
+		}
+	}
+	return ""
+}
+
+var linkPattern = regexp.MustCompile(`(?i)(https?://|www\.)\S+|\b[a-z0-9-]+\.(ru|com|net|org|info|biz|xyz|top)\b`)
+
+// LinkFilter отклоняет тексты, в которых больше MaxLinks ссылок
+type LinkFilter struct {
+	MaxLinks int
+}
+
+func (f *LinkFilter) Check(text string) string {
+	if len(linkPattern.FindAllString(text, -1)) > f.MaxLinks {
+		return ReasonTooManyLinks
+	}
+	return ""
+}
+
+// LoadWordList читает список слов из файла: одно слово или фраза на строку,
+// строки, начинающиеся с #, пропускаются
+func LoadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	return words, scanner.Err()
+}
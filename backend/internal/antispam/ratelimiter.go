@@ -0,0 +1,78 @@
+package antispam
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter ограничивает число событий на ключ в скользящем окне.
+// Состояние хранится в памяти процесса.
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	events map[string][]time.Time
+	now    func() time.Time
+}
+
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		window: window,
+		events: make(map[string][]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Allow регистрирует событие и сообщает, укладывается ли оно в лимит.
+// Отклоненные события не учитываются.
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	recent := l.prune(key, now)
+	if len(recent) >= l.limit {
+		return false
+	}
+
+	l.events[key] = append(recent, now)
+	return true
+}
+
+// Exceeded сообщает, исчерпан ли лимит для key, не регистрируя событие
+func (l *RateLimiter) Exceeded(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.prune(key, l.now())) >= l.limit
+}
+
+// Cleanup удаляет ключи без событий в текущем окне
+func (l *RateLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for key := range l.events {
+		l.prune(key, now)
+	}
+}
+
+func (l *RateLimiter) prune(key string, now time.Time) []time.Time {
+	events := l.events[key]
+	cutoff := now.Add(-l.window)
+
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+
+	if len(events) == 0 {
+		delete(l.events, key)
+		return nil
+	}
+	l.events[key] = events
+	return events
+}
@@ -0,0 +1,214 @@
+// Package apperr описывает типизированные ошибки предметной области.
+// Сервисы возвращают *Error, а обработчики HTTP превращают его в статус и JSON-ответ
+// в одном месте, не разбирая текст ошибки.
+package apperr
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"maps"
+	"net"
+	"strings"
+	"time"
+)
+
+// Kind класс ошибки, по которому выбирается HTTP-статус
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindTooManyRequests
+	// KindTimeout запрос к зависимости (базе данных) не уложился во время
+	KindTimeout
+	// KindUnavailable зависимость недоступна или запрос отменен
+	KindUnavailable
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindValidation:
+		return "validation"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindNotFound:
+		return "not_found"
+	case KindConflict:
+		return "conflict"
+	case KindTooManyRequests:
+		return "too_many_requests"
+	case KindTimeout:
+		return "timeout"
+	case KindUnavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+// Error ошибка с машиночитаемым кодом, сообщением для клиента и ошибками по полям.
+// Err — исходная причина; она попадает только в логи.
+// RetryAfter подсказывает клиенту, когда повторить запрос (заголовок Retry-After).
+type Error struct {
+	Kind       Kind
+	Code       string
+	Message    string
+	Fields     map[string]string
+	Err        error
+	RetryAfter time.Duration
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func TooManyRequests(code, message string) *Error {
+	return New(KindTooManyRequests, code, message)
+}
+
+// Ошибки, к которым From приводит ошибки базы: таймауты, обрывы соединения
+// и нарушение уникальности, которое сервис не перевел в свою ошибку
+var (
+	ErrTimeout     = New(KindTimeout, "timeout", "Сервер не успел обработать запрос, попробуйте позже")
+	ErrUnavailable = New(KindUnavailable, "service_unavailable", "Сервис временно недоступен, попробуйте позже")
+	ErrDuplicate   = Conflict("already_exists", "Такая запись уже существует")
+)
+
+// Internal оборачивает непредвиденную ошибку; клиент увидит только общий текст
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "Внутренняя ошибка сервера", Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Message + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is сравнивает ошибки по классу и коду, поэтому errors.Is работает
+// и для копий, полученных через WithField/Wrap
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	return e.Kind == t.Kind && e.Code == t.Code
+}
+
+// WithField возвращает копию ошибки с сообщением для поля name.
+// Исходная ошибка не меняется, поэтому метод безопасен для ошибок-переменных пакета.
+func (e *Error) WithField(name, message string) *Error {
+	c := *e
+	c.Fields = make(map[string]string, len(e.Fields)+1)
+	maps.Copy(c.Fields, e.Fields)
+	c.Fields[name] = message
+	return &c
+}
+
+// Wrap возвращает копию ошибки с причиной err
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// WithRetryAfter возвращает копию ошибки с подсказкой, через сколько повторить запрос
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	c := *e
+	c.RetryAfter = d
+	return &c
+}
+
+// From приводит любую ошибку к *Error. Таймауты и обрывы соединения с базой
+// становятся ErrTimeout и ErrUnavailable, нарушение уникальности (23505) — ErrDuplicate,
+// остальные неизвестные ошибки — внутренними.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	switch {
+	case isTimeout(err):
+		return ErrTimeout.Wrap(err)
+	case isUnavailable(err):
+		return ErrUnavailable.Wrap(err)
+	case stateOf(err) == "23505":
+		return ErrDuplicate.Wrap(err)
+	}
+	return Internal(err)
+}
+
+// sqlState код ошибки PostgreSQL; драйвер pq реализует этот метод у *pq.Error
+type sqlState interface {
+	SQLState() string
+}
+
+func stateOf(err error) string {
+	var s sqlState
+	if errors.As(err, &s) {
+		return s.SQLState()
+	}
+	return ""
+}
+
+// isTimeout истек дедлайн контекста или сервер отменил запрос по statement_timeout (57014)
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || stateOf(err) == "57014"
+}
+
+// isUnavailable соединение с базой потеряно, сервер перегружен или выключается,
+// либо клиент отменил запрос
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr *net.OpError
+	if errors.As(err, &netErr) {
+		return true
+	}
+	switch state := stateOf(err); {
+	case strings.HasPrefix(state, "08"): // connection exception
+		return true
+	case state == "53300", state == "57P01", state == "57P02", state == "57P03":
+		return true
+	}
+	return false
+}
+
+// IsKind сообщает, относится ли ошибка к классу kind
+func IsKind(err error, kind Kind) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == kind
+}
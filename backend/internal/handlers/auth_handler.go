@@ -1,204 +1,178 @@
 package handlers
 
 import (
+	"beladonna/backend/internal/logging"
 	"beladonna/backend/internal/models"
 	"beladonna/backend/internal/service"
 	"beladonna/backend/internal/utils"
 	"encoding/json"
-	"log"
 	"net/http"
 )
 
 type AuthHandler struct {
 	authService *service.AuthService
+	sessions    *Sessions
 }
 
-func NewAuthHandler(authService *service.AuthService) *AuthHandler {
-	return &AuthHandler{authService: authService}
+func NewAuthHandler(authService *service.AuthService, sessions *Sessions) *AuthHandler {
+	return &AuthHandler{authService: authService, sessions: sessions}
 }
 
 // Register обработчик регистрации
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	log.Println("=== НОВЫЙ ЗАПРОС РЕГИСТРАЦИИ ===")
-	log.Printf("Метод: %s", r.Method)
-	log.Printf("URL: %s", r.URL)
-
-	if r.Method != http.MethodPost {
-		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+	logger := logging.FromContext(r.Context())
 
 	var req models.RegisterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Ошибка декодирования JSON: %v", err)
-		sendErrorResponse(w, "Неверный формат данных", http.StatusBadRequest)
-		return
-	}
-
-	log.Printf("Получены данные: %+v", req)
-
-	// Валидация обязательных полей
-	if req.Email == "" || req.Password == "" || req.FirstName == "" || req.LastName == "" {
-		log.Printf("Не все обязательные поля заполнены: Email=%t, Password=%t, FirstName=%t, LastName=%t",
-			req.Email != "", req.Password != "", req.FirstName != "", req.LastName != "")
-		sendErrorResponse(w, "Все обязательные поля должны быть заполнены", http.StatusBadRequest)
+	if err := decodeJSON(r, &req); err != nil {
+		logger.Info("Некорректные данные регистрации", "error", err)
+		writeError(w, r, err)
 		return
 	}
 
-	if !req.AgreeTerms {
-		log.Printf("Пользователь %s не согласился с условиями", req.Email)
-		sendErrorResponse(w, "Необходимо согласие с условиями использования", http.StatusBadRequest)
-		return
-	}
+	logger.Debug("Получены данные регистрации", "request", req)
 
-	response, err := h.authService.Register(req)
+	response, err := h.authService.Register(r.Context(), req, clientMeta(r))
 	if err != nil {
-		log.Printf("Ошибка сервиса регистрации: %v", err)
-		sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	// Создаем сессию после успешной регистрации
-	fullName := req.FirstName + " " + req.LastName
-	log.Printf("Создание сессии для пользователя: ID=%d, Email=%s, Name=%s", response.UserID, req.Email, fullName)
-	utils.CreateSession(w, response.UserID, req.Email, fullName)
+	if err := h.sessions.Start(w, r, response.UserID, false, false); err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	log.Printf("Успешная регистрация: %+v", response)
 	json.NewEncoder(w).Encode(response)
 }
 
 // Login обработчик входа
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	log.Println("=== НОВЫЙ ЗАПРОС ВХОДА ===")
-	log.Printf("Метод: %s", r.Method)
-	log.Printf("URL: %s", r.URL)
+	logger := logging.FromContext(r.Context())
 
-	if r.Method != http.MethodPost {
-		sendErrorResponse(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var req models.LoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		logger.Info("Некорректные данные входа", "error", err)
+		writeError(w, r, err)
 		return
 	}
 
-	var req models.LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("Ошибка декодирования JSON: %v", err)
-		sendErrorResponse(w, "Неверный формат данных", http.StatusBadRequest)
+	logger.Debug("Получены данные для входа", "request", req)
+
+	response, err := h.authService.Login(r.Context(), req, utils.ClientIP(r))
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	log.Printf("Получены данные для входа: Email=%s, RememberMe=%t", req.Email, req.RememberMe)
+	// Создаем сессию после успешного входа; при 2FA — только после проверки кода
+	if !response.TwoFactorRequired {
+		if err := h.sessions.Start(w, r, response.UserID, false, req.RememberMe); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
 
-	if req.Email == "" || req.Password == "" {
-		log.Printf("Отсутствует email или пароль")
-		sendErrorResponse(w, "Email и пароль обязательны", http.StatusBadRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LoginTwoFactor второй шаг входа: код из приложения-аутентификатора или код восстановления
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	response, err := h.authService.Login(req)
+	response, err := h.authService.LoginTwoFactor(r.Context(), req, utils.ClientIP(r))
 	if err != nil {
-		log.Printf("Ошибка входа: %v", err)
-		sendErrorResponse(w, err.Error(), http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	// Создаем сессию после успешного входа
-	log.Printf("Создание сессии для пользователя: ID=%d, Email=%s, Name=%s", response.UserID, req.Email, response.Name)
-	utils.CreateSession(w, response.UserID, req.Email, response.Name)
+	if err := h.sessions.Start(w, r, response.UserID, true, req.RememberMe); err != nil {
+		writeError(w, r, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	log.Printf("Успешный вход: %+v", response)
 	json.NewEncoder(w).Encode(response)
 }
 
-// Logout обработчик выхода
-func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	log.Println("=== ЗАПРОС ВЫХОДА ===")
-
-	// Получаем информацию о пользователе перед выходом для логирования
-	session, err := utils.GetUserFromSession(r)
-	if err == nil {
-		log.Printf("Выход пользователя: ID=%d, Email=%s, Name=%s", session.UserID, session.Email, session.Name)
-	} else {
-		log.Printf("Выход: пользователь не авторизован")
+// RequestSmsCode отправляет код для входа по SMS. Ответ не зависит от того,
+// зарегистрирован ли номер.
+func (h *AuthHandler) RequestSmsCode(w http.ResponseWriter, r *http.Request) {
+	var req models.SmsCodeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
 	}
 
-	utils.ClearSession(w)
-
-	w.Header().Set("Content-Type", "application/json")
-	log.Println("Выход выполнен успешно")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "Выход выполнен успешно",
-	})
+	if err := h.authService.RequestSmsCode(r.Context(), req, utils.ClientIP(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Если номер подтвержден в аккаунте, на него отправлен код для входа")
 }
 
-// Profile получение данных пользователя
-func (h *AuthHandler) Profile(w http.ResponseWriter, r *http.Request) {
-	log.Println("=== ЗАПРОС ПРОФИЛЯ ===")
+// LoginSms вход по номеру телефона и коду из SMS; при 2FA сессия создается после второго шага
+func (h *AuthHandler) LoginSms(w http.ResponseWriter, r *http.Request) {
+	var req models.SmsLoginRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
 
-	session, err := utils.GetUserFromSession(r)
+	response, err := h.authService.LoginSms(r.Context(), req)
 	if err != nil {
-		log.Printf("Ошибка получения сессии: %v", err)
-		sendErrorResponse(w, "Неавторизован", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	log.Printf("Получение данных пользователя из сессии: ID=%d, Email=%s, Name=%s",
-		session.UserID, session.Email, session.Name)
-
-	w.Header().Set("Content-Type", "application/json")
-	log.Printf("Отправка данных пользователя: %+v", session)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"user": map[string]interface{}{
-			"id":    session.UserID,
-			"email": session.Email,
-			"name":  session.Name,
-		},
-	})
-}
-
-// Вспомогательная функция для отправки ошибок
-func sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
-	log.Printf("Отправка ошибки: %s (код: %d)", message, statusCode)
+	if !response.TwoFactorRequired {
+		if err := h.sessions.Start(w, r, response.UserID, false, req.RememberMe); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": false,
-		"message": message,
-		"error":   true,
-	})
+	json.NewEncoder(w).Encode(response)
 }
 
-// Добавьте этот метод в AuthHandler
-func (h *AuthHandler) GetUser(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// Unlock снимает блокировку входа по токену из письма
+func (h *AuthHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var req models.UnlockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	sessionData, err := utils.GetUserFromSession(r)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if err := h.authService.UnlockAccount(r.Context(), req.Token); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	user, err := h.authService.GetUserByID(sessionData.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Вход разблокирован, можно войти снова",
+	})
+}
 
-	response := map[string]interface{}{
-		"user": map[string]interface{}{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-		},
+// Logout обработчик выхода: сессия завершается на сервере, cookie удаляется
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessions.End(w, r); err != nil {
+		writeError(w, r, err)
+		return
 	}
+	logging.FromContext(r.Context()).Info("Выход пользователя")
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Выход выполнен успешно",
+	})
 }
@@ -1,10 +1,10 @@
 package handlers
 
 import (
+	"beladonna/backend/internal/models"
 	"beladonna/backend/internal/service"
 	"beladonna/backend/internal/utils"
 	"encoding/json"
-	"fmt"
 	"net/http"
 )
 
@@ -17,20 +17,15 @@ func NewCartHandler(cartService *service.CartService) *CartHandler {
 }
 
 func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID, err := h.getUserIDFromSession(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	items, err := h.cartService.GetCartItems(userID)
+	items, err := h.cartService.GetCartItems(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "Error fetching cart", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -39,34 +34,20 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID, err := h.getUserIDFromSession(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	var request struct {
-		ProductID int `json:"product_id"`
-		Quantity  int `json:"quantity"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if request.Quantity <= 0 {
-		http.Error(w, "Quantity must be positive", http.StatusBadRequest)
+	var request models.CartItemRequest
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.cartService.AddToCart(userID, request.ProductID, request.Quantity); err != nil {
-		http.Error(w, "Error adding to cart", http.StatusInternalServerError)
+	if err := h.cartService.AddToCart(r.Context(), userID, request.ProductID, request.Quantity); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -78,29 +59,26 @@ func (h *CartHandler) AddToCart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID, err := h.getUserIDFromSession(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	var request struct {
-		ItemID   int `json:"item_id"`
-		Quantity int `json:"quantity"`
+	itemID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var request models.CartQuantityRequest
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := h.cartService.UpdateCartItem(userID, request.ItemID, request.Quantity); err != nil {
-		http.Error(w, "Error updating cart", http.StatusInternalServerError)
+	if err := h.cartService.UpdateCartItem(r.Context(), userID, itemID, request.Quantity); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -112,28 +90,20 @@ func (h *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CartHandler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	userID, err := h.getUserIDFromSession(r)
 	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeError(w, r, err)
 		return
 	}
 
-	var request struct {
-		ItemID int `json:"item_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	itemID, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
 		return
 	}
 
-	if err := h.cartService.RemoveFromCart(userID, request.ItemID); err != nil {
-		http.Error(w, "Error removing from cart", http.StatusInternalServerError)
+	if err := h.cartService.RemoveFromCart(r.Context(), userID, itemID); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
@@ -145,9 +115,9 @@ func (h *CartHandler) RemoveFromCart(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *CartHandler) getUserIDFromSession(r *http.Request) (int, error) {
-	sessionData, err := utils.GetUserFromSession(r)
-	if err != nil {
-		return 0, fmt.Errorf("no valid session: %v", err)
+	sessionData, ok := utils.SessionFromContext(r.Context())
+	if !ok {
+		return 0, errUnauthorized
 	}
 	return sessionData.UserID, nil
 }
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// ConsentHandler согласия с политикой конфиденциальности и выбор в баннере cookie
+type ConsentHandler struct {
+	consents *service.ConsentService
+	sessions *Sessions
+}
+
+func NewConsentHandler(consents *service.ConsentService, sessions *Sessions) *ConsentHandler {
+	return &ConsentHandler{consents: consents, sessions: sessions}
+}
+
+// Status действующие документы и те, с которыми пользователь еще не согласился
+func (h *ConsentHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.consents.Status(r.Context(), currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeConsentStatus(w, status)
+}
+
+// Accept записывает согласие с новыми версиями документов
+func (h *ConsentHandler) Accept(w http.ResponseWriter, r *http.Request) {
+	var req models.ConsentAcceptRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	status, err := h.consents.Accept(r.Context(), currentSession(r).UserID, req, clientMeta(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeConsentStatus(w, status)
+}
+
+// Cookie сохраняет выбор в баннере cookie; доступен и гостям
+func (h *ConsentHandler) Cookie(w http.ResponseWriter, r *http.Request) {
+	var req models.CookieConsentRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	userID, err := h.sessions.OptionalUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	consent, err := h.consents.RecordCookieConsent(r.Context(), req, userID, clientMeta(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"consent_id": consent.ConsentID,
+		"status":     consent.Status,
+	})
+}
+
+func writeConsentStatus(w http.ResponseWriter, status *models.ConsentStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"documents": status.Documents,
+		"pending":   status.Pending,
+		"accepted":  status.Accepted,
+	})
+}
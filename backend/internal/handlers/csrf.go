@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	csrfCookieName = "csrf_token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+var (
+	errCSRFToken     = apperr.Forbidden("csrf_token_invalid", "Сессия формы устарела, обновите страницу и повторите попытку")
+	errOriginBlocked = apperr.Forbidden("origin_not_allowed", "Запрос с недоверенного сайта отклонен")
+)
+
+// CSRF защищает изменяющие запросы, которые авторизуются cookie.
+// Для POST, PUT, PATCH и DELETE проверяется, что Origin (или Referer, если Origin нет)
+// совпадает с адресом сайта или входит в список доверенных источников,
+// и что заголовок X-CSRF-Token равен cookie csrf_token (double submit).
+// Токен подписан HMAC, поэтому подставить в cookie произвольное значение нельзя.
+type CSRF struct {
+	secret  []byte
+	trusted []string
+	secure  bool
+}
+
+// NewCSRF создает защиту с ключом подписи secret. trustedOrigins — источники,
+// которым, кроме самого сайта, разрешено отправлять изменяющие запросы.
+func NewCSRF(secret string, trustedOrigins []string, secureCookie bool) *CSRF {
+	return &CSRF{secret: []byte(secret), trusted: trustedOrigins, secure: secureCookie}
+}
+
+// Protect middleware проверки источника и токена; безопасные методы пропускаются
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger := logging.FromContext(r.Context())
+		if !c.sameOrigin(r) {
+			logger.Warn("CSRF: недоверенный источник", "origin", r.Header.Get("Origin"), "referer", r.Referer())
+			writeError(w, r, errOriginBlocked)
+			return
+		}
+
+		cookie, err := r.Cookie(csrfCookieName)
+		header := r.Header.Get(csrfHeaderName)
+		if err != nil || header == "" || !hmac.Equal([]byte(cookie.Value), []byte(header)) || !c.valid(header) {
+			logger.Info("CSRF: токен отсутствует или не совпадает")
+			writeError(w, r, errCSRFToken)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Token выдает CSRF-токен: возвращает его в JSON и сохраняет в cookie.
+// Действующий токен из cookie переиспользуется, чтобы не ломать открытые вкладки.
+func (c *CSRF) Token(w http.ResponseWriter, r *http.Request) {
+	token := ""
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && c.valid(cookie.Value) {
+		token = cookie.Value
+	} else {
+		token = c.newToken()
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   c.secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"csrf_token": token,
+	})
+}
+
+// newToken случайное значение и его подпись: <nonce>.<hmac>
+func (c *CSRF) newToken() string {
+	nonce := make([]byte, 32)
+	rand.Read(nonce)
+	encoded := base64.RawURLEncoding.EncodeToString(nonce)
+	return encoded + "." + c.sign(encoded)
+}
+
+func (c *CSRF) valid(token string) bool {
+	nonce, mac, ok := strings.Cut(token, ".")
+	return ok && nonce != "" && hmac.Equal([]byte(mac), []byte(c.sign(nonce)))
+}
+
+func (c *CSRF) sign(nonce string) string {
+	h := hmac.New(sha256.New, c.secret)
+	h.Write([]byte("csrf:" + nonce))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin сверяет источник запроса с адресом сайта и доверенными источниками.
+// Запросы без Origin и Referer (не из браузера) пропускаются: их защищает проверка токена.
+func (c *CSRF) sameOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Referer()
+	}
+	if source == "" {
+		return true
+	}
+
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	return slices.Contains(c.trusted, u.Scheme+"://"+u.Host)
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/validation"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Ошибки уровня HTTP: разбор запроса и доступ
+var (
+	errInvalidJSON   = apperr.Validation("invalid_json", "Неверный формат данных")
+	errUnauthorized  = apperr.Unauthorized("unauthorized", "Требуется авторизация")
+	errForbidden     = apperr.Forbidden("forbidden", "Недостаточно прав")
+	errRouteNotFound = apperr.NotFound("route_not_found", "Маршрут не найден")
+	errMethodBlocked = apperr.New(apperr.KindValidation, "method_not_allowed", "Метод не поддерживается")
+
+	// errTwoFactorRequired роль требует 2FA, а сессия создана без второго фактора
+	errTwoFactorRequired = apperr.Forbidden("two_factor_required", "Подключите двухфакторную аутентификацию в личном кабинете и войдите с кодом")
+)
+
+// errorResponse единый формат ответа с ошибкой для всех эндпоинтов
+type errorResponse struct {
+	Success bool              `json:"success"`
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// statusFor HTTP-статус для класса ошибки
+func statusFor(kind apperr.Kind) int {
+	switch kind {
+	case apperr.KindValidation:
+		return http.StatusBadRequest
+	case apperr.KindUnauthorized:
+		return http.StatusUnauthorized
+	case apperr.KindForbidden:
+		return http.StatusForbidden
+	case apperr.KindNotFound:
+		return http.StatusNotFound
+	case apperr.KindConflict:
+		return http.StatusConflict
+	case apperr.KindTooManyRequests:
+		return http.StatusTooManyRequests
+	case apperr.KindTimeout:
+		return http.StatusGatewayTimeout
+	case apperr.KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError отправляет ошибку в едином JSON-формате.
+// Непредвиденные ошибки логируются, а клиент получает только общий текст.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	writeErrorStatus(w, r, apperr.From(err), 0)
+}
+
+func writeErrorStatus(w http.ResponseWriter, r *http.Request, e *apperr.Error, status int) {
+	if status == 0 {
+		status = statusFor(e.Kind)
+	}
+	switch e.Kind {
+	case apperr.KindInternal:
+		logging.FromContext(r.Context()).Error("Внутренняя ошибка", "error", e)
+	case apperr.KindTimeout, apperr.KindUnavailable:
+		logging.FromContext(r.Context()).Warn("База данных не ответила", "error", e)
+	}
+
+	if e.RetryAfter > 0 {
+		// Retry-After в целых секундах, с округлением вверх
+		w.Header().Set("Retry-After", strconv.Itoa(int((e.RetryAfter+time.Second-1)/time.Second)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{
+		Success: false,
+		Code:    e.Code,
+		Message: e.Message,
+		Fields:  e.Fields,
+	})
+}
+
+// decodeJSON разбирает тело запроса в dst и, если dst умеет себя проверять,
+// валидирует его с сообщениями на языке клиента
+func decodeJSON(r *http.Request, dst any) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return errInvalidJSON.Wrap(err)
+	}
+	if v, ok := dst.(validation.Validatable); ok {
+		return validation.Validate(requestLang(r), v)
+	}
+	return nil
+}
+
+// requestLang язык сообщений об ошибках по заголовку Accept-Language
+func requestLang(r *http.Request) string {
+	return validation.LanguageFromHeader(r.Header.Get("Accept-Language"))
+}
+
+// invalidID ошибка некорректного параметра пути
+func invalidID(name string) *apperr.Error {
+	return apperr.Validation("invalid_id", "Некорректный идентификатор").WithField(name, "Ожидается целое число")
+}
+
+// RouteError отвечает на запросы без подходящего маршрута (404) или с неподдерживаемым методом (405)
+func RouteError(w http.ResponseWriter, r *http.Request, status int) {
+	if status == http.StatusMethodNotAllowed {
+		writeErrorStatus(w, r, errMethodBlocked, status)
+		return
+	}
+	writeErrorStatus(w, r, errRouteNotFound, http.StatusNotFound)
+}
@@ -1,9 +1,11 @@
 package handlers
 
 import (
-	"fmt"
     "encoding/json"
+    "errors"
     "net/http"
+    "beladonna/backend/internal/antispam"
+    "beladonna/backend/internal/apperr"
     "beladonna/backend/internal/models"
     "beladonna/backend/internal/service"
     "beladonna/backend/internal/utils"
@@ -17,42 +19,49 @@ func NewFeedbackHandler(feedbackService *service.FeedbackService) *FeedbackHandl
     return &FeedbackHandler{feedbackService: feedbackService}
 }
 
-// Добавляем метод getSessionData
+// getSessionData данные сессии, положенные в контекст middleware RequireAuth
 func (h *FeedbackHandler) getSessionData(r *http.Request) (*utils.SessionData, error) {
-    return utils.GetUserFromSession(r)
+    sessionData, ok := utils.SessionFromContext(r.Context())
+    if !ok {
+        return nil, errUnauthorized
+    }
+    return sessionData, nil
+}
+
+// GetFormToken выдает метку открытия формы отзыва; ее нужно передать в form_token при отправке
+func (h *FeedbackHandler) GetFormToken(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Cache-Control", "no-store")
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "success":    true,
+        "form_token": h.feedbackService.FormToken(),
+    })
 }
 
 func (h *FeedbackHandler) CreateFeedback(w http.ResponseWriter, r *http.Request) {
     // Проверка авторизации
     sessionData, err := h.getSessionData(r)
     if err != nil {
-        http.Error(w, "Authentication required", http.StatusUnauthorized)
+        writeError(w, r, err)
         return
     }
 
-    // Можно также использовать данные сессии для автоматического заполнения
-    // имени и email пользователя, если нужно
-    fmt.Printf("User %s (ID: %d) is submitting feedback\n", sessionData.Name, sessionData.UserID)
-
-    if r.Method != http.MethodPost {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+    // Имя и email, если не указаны в форме, сервис возьмет из профиля пользователя
+    var submission models.FeedbackSubmission
+    if err := decodeJSON(r, &submission); err != nil {
+        writeError(w, r, err)
         return
     }
 
-    var feedback models.Feedback
-    if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
-        return
-    }
-
-    // Валидация
-    if feedback.Name == "" || feedback.Email == "" || feedback.Message == "" {
-        http.Error(w, "Name, email and message are required", http.StatusBadRequest)
-        return
-    }
-
-    if err := h.feedbackService.CreateFeedback(&feedback); err != nil {
-        http.Error(w, "Failed to create feedback", http.StatusInternalServerError)
+    meta := service.SubmissionMeta{UserID: sessionData.UserID, IP: utils.ClientIP(r)}
+    feedback, err := h.feedbackService.SubmitFeedback(r.Context(), submission, meta)
+    if err != nil {
+        var rejection *service.SpamRejection
+        if errors.As(err, &rejection) {
+            h.sendSpamRejection(w, r, rejection)
+            return
+        }
+        writeError(w, r, err)
         return
     }
 
@@ -60,19 +69,58 @@ func (h *FeedbackHandler) CreateFeedback(w http.ResponseWriter, r *http.Request)
     w.WriteHeader(http.StatusCreated)
     json.NewEncoder(w).Encode(map[string]interface{}{
         "success": true,
-        "message": "Feedback submitted successfully",
+        "message": "Feedback submitted for moderation",
+        "status":  feedback.ModerationStatus,
     })
 }
 
-func (h *FeedbackHandler) GetFeedbacks(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
-        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// sendSpamRejection отвечает на отклоненную антиспамом отправку.
+// Ботам, заполнившим honeypot, отвечаем как при успехе, чтобы не подсказывать им проверку.
+func (h *FeedbackHandler) sendSpamRejection(w http.ResponseWriter, r *http.Request, rejection *service.SpamRejection) {
+    switch rejection.Reason {
+    case antispam.ReasonHoneypot:
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "success": true,
+            "message": "Feedback submitted for moderation",
+            "status":  models.ModerationPending,
+        })
+    case antispam.ReasonRateLimitUser, antispam.ReasonRateLimitIP:
+        writeError(w, r, apperr.TooManyRequests("feedback_rate_limited", "Слишком много отзывов. Попробуйте позже"))
+    case antispam.ReasonDuplicate:
+        writeError(w, r, apperr.Conflict("feedback_duplicate", "Такой отзыв уже был отправлен"))
+    case antispam.ReasonTooFast:
+        writeError(w, r, apperr.Validation("feedback_too_fast", "Форма отправлена слишком быстро. Попробуйте еще раз"))
+    case antispam.ReasonFormToken:
+        writeError(w, r, apperr.Validation("feedback_form_expired", "Форма устарела, обновите страницу и повторите попытку"))
+    default:
+        writeError(w, r, apperr.Validation("feedback_spam", "Отзыв не прошел автоматическую проверку"))
+    }
+}
+
+// GetMyFeedbacks отзывы текущего пользователя
+func (h *FeedbackHandler) GetMyFeedbacks(w http.ResponseWriter, r *http.Request) {
+    sessionData, err := h.getSessionData(r)
+    if err != nil {
+        writeError(w, r, err)
+        return
+    }
+
+    feedbacks, err := h.feedbackService.GetUserFeedbacks(r.Context(), sessionData.UserID)
+    if err != nil {
+        writeError(w, r, err)
         return
     }
 
-    feedbacks, err := h.feedbackService.GetVisibleFeedbacks()
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(feedbacks)
+}
+
+func (h *FeedbackHandler) GetFeedbacks(w http.ResponseWriter, r *http.Request) {
+    feedbacks, err := h.feedbackService.GetVisibleFeedbacks(r.Context(), r.URL.Query().Get("theme"))
     if err != nil {
-        http.Error(w, "Failed to get feedbacks", http.StatusInternalServerError)
+        writeError(w, r, err)
         return
     }
 
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"encoding/json"
+	"net/http"
+)
+
+// Обработчики модерации отзывов. Доступ ограничивается группой маршрутов /api/moderation в main.go.
+
+func (h *FeedbackHandler) GetPendingFeedbacks(w http.ResponseWriter, r *http.Request) {
+	feedbacks, err := h.feedbackService.GetPendingFeedbacks(r.Context(), r.URL.Query().Get("theme"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedbacks)
+}
+
+func (h *FeedbackHandler) ApproveFeedback(w http.ResponseWriter, r *http.Request) {
+	sessionData := currentSession(r)
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	if err := h.feedbackService.ApproveFeedback(r.Context(), sessionData.UserID, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Отзыв одобрен", "feedback_id", id, "moderator_id", sessionData.UserID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Feedback approved",
+	})
+}
+
+func (h *FeedbackHandler) RejectFeedback(w http.ResponseWriter, r *http.Request) {
+	sessionData := currentSession(r)
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	if err := h.feedbackService.RejectFeedback(r.Context(), sessionData.UserID, id, request.Reason); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("Отзыв отклонен", "feedback_id", id, "moderator_id", sessionData.UserID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Feedback rejected",
+	})
+}
+
+func (h *FeedbackHandler) EditFeedback(w http.ResponseWriter, r *http.Request) {
+	sessionData := currentSession(r)
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	var request models.FeedbackEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+	request.ID = id
+
+	feedback, err := h.feedbackService.EditFeedback(r.Context(), sessionData.UserID, request)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
+
+func (h *FeedbackHandler) GetModerationHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	entries, err := h.feedbackService.GetModerationHistory(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ReplyToFeedback ответ сотрудника на отзыв
+func (h *FeedbackHandler) ReplyToFeedback(w http.ResponseWriter, r *http.Request) {
+	sessionData := currentSession(r)
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	var request models.FeedbackReplyRequest
+	if err := decodeJSON(r, &request); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	request.FeedbackID = id
+
+	reply, err := h.feedbackService.ReplyToFeedback(r.Context(), sessionData.UserID, request)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"reply":      reply,
+		"email_sent": reply.EmailedAt != nil,
+	})
+}
+
+// SetFeedbackStatus смена статуса переписки: open, answered, closed
+func (h *FeedbackHandler) SetFeedbackStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	var request struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	if err := h.feedbackService.SetFeedbackStatus(r.Context(), id, request.Status); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Feedback status updated",
+	})
+}
+
+// GetFeedbackThread отзыв со всеми ответами, включая приватные
+func (h *FeedbackHandler) GetFeedbackThread(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	feedback, err := h.feedbackService.GetFeedbackThread(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(feedback)
+}
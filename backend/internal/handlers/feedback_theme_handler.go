@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+type FeedbackThemeHandler struct {
+	themeService *service.FeedbackThemeService
+}
+
+func NewFeedbackThemeHandler(themeService *service.FeedbackThemeService) *FeedbackThemeHandler {
+	return &FeedbackThemeHandler{themeService: themeService}
+}
+
+// GetThemes активные темы для формы отзыва
+func (h *FeedbackThemeHandler) GetThemes(w http.ResponseWriter, r *http.Request) {
+	h.writeThemes(w, r, true)
+}
+
+// GetAllThemes все темы вместе с правилами маршрутизации (admin)
+func (h *FeedbackThemeHandler) GetAllThemes(w http.ResponseWriter, r *http.Request) {
+	h.writeThemes(w, r, false)
+}
+
+// CreateTheme добавление темы (admin)
+func (h *FeedbackThemeHandler) CreateTheme(w http.ResponseWriter, r *http.Request) {
+	var theme models.FeedbackTheme
+	if err := json.NewDecoder(r.Body).Decode(&theme); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+
+	if err := h.themeService.CreateTheme(r.Context(), &theme); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(theme)
+}
+
+// UpdateTheme изменение темы и ее правила маршрутизации (admin)
+func (h *FeedbackThemeHandler) UpdateTheme(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	var theme models.FeedbackTheme
+	if err := json.NewDecoder(r.Body).Decode(&theme); err != nil {
+		writeError(w, r, errInvalidJSON)
+		return
+	}
+	theme.ID = id
+
+	updated, err := h.themeService.UpdateTheme(r.Context(), &theme)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *FeedbackThemeHandler) writeThemes(w http.ResponseWriter, r *http.Request, activeOnly bool) {
+	themes, err := h.themeService.GetThemes(r.Context(), activeOnly)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(themes)
+}
@@ -0,0 +1,1292 @@
+package handlers_test
+
+import (
+	"archive/zip"
+	"beladonna/backend/internal/handlers"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/oidc"
+	"beladonna/backend/internal/oidc/oidctest"
+	"beladonna/backend/internal/repository/memory"
+	"beladonna/backend/internal/router"
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/sms"
+	"beladonna/backend/internal/totp"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProvider поддельный провайдер OpenID Connect, общий для тестов: ключ RSA создается долго
+var testProvider *oidctest.Server
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	testProvider = oidctest.NewServer("belladonna", "provider-secret")
+	code := m.Run()
+	testProvider.Close()
+	os.Exit(code)
+}
+
+const (
+	testSecret    = "test-secret-test-secret-test-secret"
+	trustedOrigin = "https://admin.belladonna.test"
+)
+
+// testApp API поверх хранилищ в памяти с теми же маршрутами, что и в main.go
+type testApp struct {
+	server     *httptest.Server
+	users      *memory.UserStore
+	products   *memory.ProductStore
+	feedbacks  *memory.FeedbackStore
+	deletions  *memory.AccountDeletionStore
+	consents   *service.ConsentService
+	cookies    *memory.ConsentStore
+	newsletter *service.NewsletterService
+	mail       *recordingMailer
+	sms        *recordingSms
+}
+
+// recordingMailer запоминает отправленные письма
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// last последнее письмо на адрес to
+func (m *recordingMailer) last(to string) (mailer.Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.sent) - 1; i >= 0; i-- {
+		if m.sent[i].To == to {
+			return m.sent[i], true
+		}
+	}
+	return mailer.Message{}, false
+}
+
+// recordingSms запоминает отправленные SMS
+type recordingSms struct {
+	mu   sync.Mutex
+	sent []sms.Message
+}
+
+func (s *recordingSms) Send(msg sms.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// code код из последнего SMS на номер to или пустая строка
+func (s *recordingSms) code(to string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := len(s.sent) - 1; i >= 0; i-- {
+		if s.sent[i].To == to {
+			return regexp.MustCompile(`\d{6}`).FindString(s.sent[i].Text)
+		}
+	}
+	return ""
+}
+
+func (s *recordingSms) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	users := memory.NewUserStore()
+	products := memory.NewProductStore()
+	themes := memory.NewFeedbackThemeStore()
+	feedbacks := memory.NewFeedbackStore(users, themes)
+	tx := memory.NewUnitOfWork()
+	mail := &recordingMailer{}
+
+	attempts := memory.NewLoginAttemptStore()
+	carts := memory.NewCartStore(products)
+	deletions := memory.NewAccountDeletionStore()
+	loginGuard := service.NewLoginGuard(service.DefaultLoginGuardConfig(), attempts, tx, mailer.NewLogMailer(), "http://localhost")
+	twoFactorService := service.NewTwoFactorService(memory.NewTwoFactorStore(), users, tx, service.DefaultTwoFactorPolicy(), testSecret)
+	consentStore := memory.NewConsentStore(users)
+	consentService := service.NewConsentService(consentStore, tx)
+	newsletterStore := memory.NewNewsletterStore(users)
+	newsletterService := service.NewNewsletterService(newsletterStore, users, products, tx, mail,
+		service.DefaultNewsletterOptions(), testSecret, "http://localhost")
+	smsSender := &recordingSms{}
+	phoneService := service.NewPhoneService(memory.NewSmsCodeStore(users), users, smsSender, service.DefaultPhoneOptions(), testSecret)
+	authService := service.NewAuthService(users, tx, loginGuard, twoFactorService, consentService, newsletterService, phoneService)
+	sessionService := service.NewSessionService(memory.NewSessionStore(users), service.DefaultSessionOptions())
+	sessions := handlers.NewSessions(sessionService, false)
+	profileService := service.NewProfileService(users, memory.NewEmailChangeStore(), sessionService, newsletterService, tx, mail, "http://localhost")
+	identities := memory.NewIdentityStore(users)
+	oidcService := service.NewOIDCService([]oidc.Provider{oidc.NewClient(testProvider.Config("test"), nil)},
+		identities, users, tx, "http://localhost")
+	privacyService := service.NewPrivacyService(users, carts, feedbacks, attempts, deletions, consentStore, newsletterStore, identities, twoFactorService, sessionService, tx, mail)
+	themeService := service.NewFeedbackThemeService(themes, users, tx, nil)
+	guard := service.NewFeedbackGuard(service.DefaultFeedbackGuardConfig(), "test-secret", feedbacks)
+	feedbackService := service.NewFeedbackService(feedbacks, users, themeService, guard, tx, nil)
+
+	authHandler := handlers.NewAuthHandler(authService, sessions)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, sessions)
+	profileHandler := handlers.NewProfileHandler(profileService, sessions)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, sessions)
+	consentHandler := handlers.NewConsentHandler(consentService, sessions)
+	phoneHandler := handlers.NewPhoneHandler(phoneService)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, authService, sessions)
+	newsletterHandler := handlers.NewNewsletterHandler(newsletterService)
+	productHandler := handlers.NewProductHandler(service.NewProductService(products))
+	cartHandler := handlers.NewCartHandler(service.NewCartService(carts, tx))
+	feedbackHandler := handlers.NewFeedbackHandler(feedbackService)
+	roleMiddleware := handlers.NewRoleMiddleware(authService, service.DefaultTwoFactorPolicy())
+	csrf := handlers.NewCSRF(testSecret, []string{trustedOrigin}, false)
+
+	r := router.New()
+	r.SetErrorHandler(handlers.RouteError)
+	api := r.Group("/api", csrf.Protect)
+	api.Get("/csrf-token", csrf.Token)
+	api.Post("/register", authHandler.Register)
+	api.Post("/login", authHandler.Login)
+	api.Post("/login/2fa", authHandler.LoginTwoFactor)
+	api.Post("/login/sms/code", authHandler.RequestSmsCode)
+	api.Post("/login/sms", authHandler.LoginSms)
+	api.Post("/logout", authHandler.Logout)
+	api.Get("/auth/providers", oidcHandler.Providers)
+	api.Get("/auth/oidc/{provider}", oidcHandler.Start)
+	api.Get("/auth/oidc/{provider}/callback", oidcHandler.Callback)
+	api.Post("/account/unlock", authHandler.Unlock)
+	api.Post("/profile/email/confirm", profileHandler.ConfirmEmailChange)
+	api.Post("/cookie-consent", consentHandler.Cookie)
+	api.Post("/newsletter/subscribe", newsletterHandler.Subscribe)
+	api.Post("/newsletter/confirm", newsletterHandler.Confirm)
+	api.Post("/newsletter/unsubscribe", newsletterHandler.Unsubscribe)
+	r.Post("/api/newsletter/unsubscribe/one-click", newsletterHandler.OneClickUnsubscribe)
+	api.Get("/products/{id}", productHandler.GetProduct)
+
+	authed := api.Group("", sessions.RequireAuth)
+	authed.Get("/profile", profileHandler.Get)
+	authed.Get("/user", profileHandler.Get)
+	authed.Put("/profile", profileHandler.Update)
+	authed.Delete("/profile", privacyHandler.Delete)
+	authed.Get("/profile/export", privacyHandler.Export)
+	authed.Post("/profile/email", profileHandler.RequestEmailChange)
+	authed.Post("/profile/password", profileHandler.ChangePassword)
+	authed.Post("/profile/phone/code", phoneHandler.RequestVerification)
+	authed.Post("/profile/phone/confirm", phoneHandler.ConfirmVerification)
+	authed.Get("/consents", consentHandler.Status)
+	authed.Post("/consents", consentHandler.Accept)
+	authed.Get("/account/2fa", twoFactorHandler.Status)
+	authed.Post("/account/2fa/setup", twoFactorHandler.Setup)
+	authed.Post("/account/2fa/confirm", twoFactorHandler.Confirm)
+	authed.Get("/account/identities", oidcHandler.Identities)
+	authed.Delete("/account/identities/{id}", oidcHandler.Unlink)
+	authed.Get("/cart", cartHandler.GetCart)
+	authed.Post("/cart/items", cartHandler.AddToCart)
+	authed.Put("/cart/items/{id}", cartHandler.UpdateCart)
+	authed.Delete("/cart/items/{id}", cartHandler.RemoveFromCart)
+
+	moderation := api.Group("/moderation", sessions.RequireAuth, roleMiddleware.Require(models.RoleManager, models.RoleAdmin))
+	moderation.Get("/feedbacks", feedbackHandler.GetPendingFeedbacks)
+	campaigns := api.Group("/newsletter/campaigns", sessions.RequireAuth, roleMiddleware.Require(models.RoleManager, models.RoleAdmin))
+	campaigns.Get("", newsletterHandler.GetCampaigns)
+	campaigns.Post("", newsletterHandler.CreateCampaign)
+	campaigns.Get("/{id}/preview", newsletterHandler.PreviewCampaign)
+	campaigns.Post("/{id}/send", newsletterHandler.SendCampaign)
+
+	server := httptest.NewServer(handlers.RequestLogger(handlers.CORS([]string{trustedOrigin})(r)))
+	t.Cleanup(server.Close)
+	return &testApp{server: server, users: users, products: products, feedbacks: feedbacks, deletions: deletions,
+		consents: consentService, cookies: consentStore, newsletter: newsletterService, mail: mail, sms: smsSender}
+}
+
+// client клиент со своей cookie-сессией
+func (a *testApp) client(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar}
+}
+
+// register регистрирует пользователя и возвращает клиента с его сессией
+func (a *testApp) register(t *testing.T, email string) (*http.Client, int) {
+	t.Helper()
+	c := a.client(t)
+	var resp models.AuthResponse
+	status := a.do(t, c, http.MethodPost, "/api/register", map[string]any{
+		"email":      email,
+		"password":   "secret123",
+		"firstName":  "Анна",
+		"lastName":   "Иванова",
+		"agreeTerms": true,
+	}, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("register %s: status %d", email, status)
+	}
+	return c, resp.UserID
+}
+
+// csrfToken получает CSRF-токен; cookie с ним сохраняется в клиенте
+func (a *testApp) csrfToken(t *testing.T, c *http.Client) string {
+	t.Helper()
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	if status := a.send(t, c, a.request(t, http.MethodGet, "/api/csrf-token", nil), &body); status != http.StatusOK || body.Token == "" {
+		t.Fatalf("csrf-token = %d %+v", status, body)
+	}
+	return body.Token
+}
+
+// do отправляет JSON-запрос как браузер: изменяющие запросы несут CSRF-токен.
+// Ответ разбирается в out, если он задан.
+func (a *testApp) do(t *testing.T, c *http.Client, method, path string, body, out any) int {
+	t.Helper()
+	req := a.request(t, method, path, body)
+	if method != http.MethodGet {
+		req.Header.Set("X-CSRF-Token", a.csrfToken(t, c))
+	}
+	return a.send(t, c, req, out)
+}
+
+func (a *testApp) request(t *testing.T, method, path string, body any) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func (a *testApp) send(t *testing.T, c *http.Client, req *http.Request, out any) int {
+	t.Helper()
+	method, path := req.Method, req.URL.Path
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type errorBody struct {
+	Success bool              `json:"success"`
+	Code    string            `json:"code"`
+	Fields  map[string]string `json:"fields"`
+}
+
+func TestRegisterAndLogin(t *testing.T) {
+	app := newTestApp(t)
+	c, userID := app.register(t, "anna@example.com")
+
+	var profile struct {
+		User struct {
+			ID    int    `json:"id"`
+			Email string `json:"email"`
+		} `json:"user"`
+	}
+	if status := app.do(t, c, http.MethodGet, "/api/profile", nil, &profile); status != http.StatusOK {
+		t.Fatalf("profile status = %d, want 200", status)
+	}
+	if profile.User.ID != userID || profile.User.Email != "anna@example.com" {
+		t.Errorf("profile = %+v, want registered user", profile.User)
+	}
+
+	var dup errorBody
+	status := app.do(t, app.client(t), http.MethodPost, "/api/register", map[string]any{
+		"email": "anna@example.com", "password": "secret123",
+		"firstName": "Анна", "lastName": "Иванова", "agreeTerms": true,
+	}, &dup)
+	if status != http.StatusConflict || dup.Code != "email_taken" {
+		t.Errorf("duplicate register = %d %+v, want 409 email_taken", status, dup)
+	}
+
+	var bad errorBody
+	status = app.do(t, app.client(t), http.MethodPost, "/api/login", map[string]any{
+		"email": "anna@example.com", "password": "wrong-password1",
+	}, &bad)
+	if status != http.StatusUnauthorized || bad.Code != "invalid_credentials" {
+		t.Errorf("login with wrong password = %d %+v, want 401 invalid_credentials", status, bad)
+	}
+
+	status = app.do(t, app.client(t), http.MethodPost, "/api/login", map[string]any{
+		"email": "anna@example.com", "password": "secret123",
+	}, nil)
+	if status != http.StatusOK {
+		t.Errorf("login status = %d, want 200", status)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	app := newTestApp(t)
+
+	var body errorBody
+	status := app.do(t, app.client(t), http.MethodPost, "/api/register", map[string]any{
+		"email": "not-an-email", "password": "short", "firstName": "Анна",
+	}, &body)
+	if status != http.StatusBadRequest || body.Code != "validation_failed" {
+		t.Fatalf("register = %d %+v, want 400 validation_failed", status, body)
+	}
+	for _, field := range []string{"email", "password", "lastName", "agreeTerms"} {
+		if body.Fields[field] == "" {
+			t.Errorf("no error for field %q in %v", field, body.Fields)
+		}
+	}
+}
+
+func TestProtectedRoutesRequireSession(t *testing.T) {
+	app := newTestApp(t)
+
+	for _, path := range []string{"/api/profile", "/api/cart", "/api/moderation/feedbacks"} {
+		var body errorBody
+		if status := app.do(t, app.client(t), http.MethodGet, path, nil, &body); status != http.StatusUnauthorized || body.Code != "unauthorized" {
+			t.Errorf("GET %s = %d %+v, want 401 unauthorized", path, status, body)
+		}
+	}
+}
+
+func TestCartOwnership(t *testing.T) {
+	app := newTestApp(t)
+	product := app.products.AddProduct(models.Product{Name: "Штора блэкаут", Price: 2500, InStock: true})
+	owner, _ := app.register(t, "owner@example.com")
+	stranger, _ := app.register(t, "stranger@example.com")
+
+	if status := app.do(t, owner, http.MethodPost, "/api/cart/items", map[string]any{
+		"product_id": product.ID, "quantity": 2,
+	}, nil); status != http.StatusOK {
+		t.Fatalf("add to cart status = %d, want 200", status)
+	}
+
+	var cart []models.CartItem
+	app.do(t, owner, http.MethodGet, "/api/cart", nil, &cart)
+	if len(cart) != 1 {
+		t.Fatalf("owner cart = %+v, want one item", cart)
+	}
+	itemPath := fmt.Sprintf("/api/cart/items/%d", cart[0].ID)
+
+	var body errorBody
+	if status := app.do(t, stranger, http.MethodPut, itemPath, map[string]any{"quantity": 5}, &body); status != http.StatusNotFound || body.Code != "cart_item_not_found" {
+		t.Errorf("stranger PUT = %d %+v, want 404 cart_item_not_found", status, body)
+	}
+	if status := app.do(t, stranger, http.MethodDelete, itemPath, nil, &body); status != http.StatusNotFound {
+		t.Errorf("stranger DELETE status = %d, want 404", status)
+	}
+
+	app.do(t, stranger, http.MethodGet, "/api/cart", nil, &cart)
+	if len(cart) != 0 {
+		t.Errorf("stranger cart = %+v, want empty", cart)
+	}
+
+	if status := app.do(t, owner, http.MethodPut, itemPath, map[string]any{"quantity": 5}, nil); status != http.StatusOK {
+		t.Errorf("owner PUT status = %d, want 200", status)
+	}
+	app.do(t, owner, http.MethodGet, "/api/cart", nil, &cart)
+	if len(cart) != 1 || cart[0].Quantity != 5 {
+		t.Errorf("owner cart = %+v, want quantity 5", cart)
+	}
+
+	if status := app.do(t, owner, http.MethodPut, "/api/cart/items/abc", map[string]any{"quantity": 5}, &body); status != http.StatusBadRequest {
+		t.Errorf("PUT with invalid id = %d, want 400", status)
+	}
+}
+
+func TestModerationRequiresStaffRole(t *testing.T) {
+	app := newTestApp(t)
+	customer, _ := app.register(t, "customer@example.com")
+	manager, managerID := app.register(t, "manager@example.com")
+	if err := app.users.SetRole(managerID, models.RoleManager); err != nil {
+		t.Fatal(err)
+	}
+
+	var body errorBody
+	if status := app.do(t, customer, http.MethodGet, "/api/moderation/feedbacks", nil, &body); status != http.StatusForbidden || body.Code != "forbidden" {
+		t.Errorf("customer = %d %+v, want 403 forbidden", status, body)
+	}
+
+	// Для менеджера 2FA обязательна: без нее модерация закрыта
+	if status := app.do(t, manager, http.MethodGet, "/api/moderation/feedbacks", nil, &body); status != http.StatusForbidden || body.Code != "two_factor_required" {
+		t.Errorf("manager without 2FA = %d %+v, want 403 two_factor_required", status, body)
+	}
+	app.enableTwoFactor(t, manager)
+	if status := app.do(t, manager, http.MethodGet, "/api/moderation/feedbacks", nil, nil); status != http.StatusOK {
+		t.Errorf("manager with 2FA status = %d, want 200", status)
+	}
+}
+
+// enableTwoFactor подключает 2FA клиенту c и возвращает коды восстановления
+func (a *testApp) enableTwoFactor(t *testing.T, c *http.Client) []string {
+	t.Helper()
+	var setup models.TwoFactorSetup
+	if status := a.do(t, c, http.MethodPost, "/api/account/2fa/setup", nil, &setup); status != http.StatusOK {
+		t.Fatalf("2FA setup status = %d, want 200", status)
+	}
+	code, err := totp.Code(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var confirmed struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	if status := a.do(t, c, http.MethodPost, "/api/account/2fa/confirm", map[string]any{"code": code}, &confirmed); status != http.StatusOK {
+		t.Fatalf("2FA confirm status = %d, want 200", status)
+	}
+	return confirmed.RecoveryCodes
+}
+
+func TestTwoStepLogin(t *testing.T) {
+	app := newTestApp(t)
+	c, userID := app.register(t, "anna@example.com")
+	recoveryCodes := app.enableTwoFactor(t, c)
+	if len(recoveryCodes) != service.RecoveryCodeCount {
+		t.Fatalf("recovery codes = %v, want %d", recoveryCodes, service.RecoveryCodeCount)
+	}
+
+	fresh := app.client(t)
+	var first models.AuthResponse
+	status := app.do(t, fresh, http.MethodPost, "/api/login", map[string]any{
+		"email": "anna@example.com", "password": "secret123",
+	}, &first)
+	if status != http.StatusOK || !first.TwoFactorRequired || first.Challenge == "" {
+		t.Fatalf("login = %d %+v, want challenge for the second step", status, first)
+	}
+	if status := app.do(t, fresh, http.MethodGet, "/api/profile", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("profile before second step = %d, want 401", status)
+	}
+
+	var bad errorBody
+	status = app.do(t, fresh, http.MethodPost, "/api/login/2fa", map[string]any{"challenge": first.Challenge, "code": "000000"}, &bad)
+	if status != http.StatusBadRequest || bad.Code != "invalid_two_factor_code" {
+		t.Errorf("second step with wrong code = %d %+v, want 400 invalid_two_factor_code", status, bad)
+	}
+	status = app.do(t, fresh, http.MethodPost, "/api/login/2fa", map[string]any{"challenge": first.Challenge + "x", "code": recoveryCodes[0]}, &bad)
+	if status != http.StatusUnauthorized || bad.Code != "two_factor_challenge_invalid" {
+		t.Errorf("second step with forged challenge = %d %+v, want 401", status, bad)
+	}
+
+	var second models.AuthResponse
+	status = app.do(t, fresh, http.MethodPost, "/api/login/2fa", map[string]any{"challenge": first.Challenge, "code": recoveryCodes[0]}, &second)
+	if status != http.StatusOK || second.UserID != userID {
+		t.Fatalf("second step = %d %+v, want user %d", status, second, userID)
+	}
+	if status := app.do(t, fresh, http.MethodGet, "/api/profile", nil, nil); status != http.StatusOK {
+		t.Errorf("profile after second step = %d, want 200", status)
+	}
+
+	// Код восстановления одноразовый
+	status = app.do(t, app.client(t), http.MethodPost, "/api/login/2fa", map[string]any{"challenge": first.Challenge, "code": recoveryCodes[0]}, &bad)
+	if status != http.StatusBadRequest || bad.Code != "invalid_two_factor_code" {
+		t.Errorf("reused recovery code = %d %+v, want 400 invalid_two_factor_code", status, bad)
+	}
+}
+
+// login входит новым клиентом и возвращает его
+func (a *testApp) login(t *testing.T, email, password string) *http.Client {
+	t.Helper()
+	c := a.client(t)
+	if status := a.do(t, c, http.MethodPost, "/api/login", map[string]any{"email": email, "password": password}, nil); status != http.StatusOK {
+		t.Fatalf("login %s: status %d", email, status)
+	}
+	return c
+}
+
+type profileBody struct {
+	User struct {
+		ID            int    `json:"id"`
+		Email         string `json:"email"`
+		Name          string `json:"name"`
+		FirstName     string `json:"first_name"`
+		LastName      string `json:"last_name"`
+		Phone         string `json:"phone"`
+		PhoneVerified bool   `json:"phone_verified"`
+		Newsletter    bool   `json:"newsletter"`
+	} `json:"user"`
+}
+
+func TestProfileUpdate(t *testing.T) {
+	app := newTestApp(t)
+	c, userID := app.register(t, "anna@example.com")
+
+	var body errorBody
+	status := app.do(t, c, http.MethodPut, "/api/profile", map[string]any{"firstName": "", "lastName": "Петрова", "phone": "12345"}, &body)
+	if status != http.StatusBadRequest || body.Fields["firstName"] == "" || body.Fields["phone"] == "" {
+		t.Errorf("invalid update = %d %+v, want 400 with firstName and phone errors", status, body)
+	}
+
+	var profile profileBody
+	status = app.do(t, c, http.MethodPut, "/api/profile", map[string]any{
+		"firstName": "Анна", "lastName": "Петрова", "phone": "8 (999) 123-45-67", "newsletter": true,
+	}, &profile)
+	if status != http.StatusOK {
+		t.Fatalf("update status = %d, want 200", status)
+	}
+
+	// Рассылка включится только после подтверждения по ссылке из письма
+	if profile.User.Newsletter {
+		t.Error("newsletter enabled before confirmation")
+	}
+	app.confirmNewsletter(t, "anna@example.com")
+
+	// /api/user читает профиль из базы, а не из cookie
+	profile = profileBody{}
+	app.do(t, c, http.MethodGet, "/api/user", nil, &profile)
+	if profile.User.ID != userID || profile.User.Name != "Анна Петрова" || profile.User.Phone != "+79991234567" || !profile.User.Newsletter {
+		t.Errorf("profile = %+v, want updated fields", profile.User)
+	}
+}
+
+// linkToken значение параметра param из ссылки в последнем письме на адрес to
+func (a *testApp) linkToken(t *testing.T, to, param string) string {
+	t.Helper()
+	msg, ok := a.mail.last(to)
+	if !ok {
+		t.Fatalf("no mail to %s", to)
+	}
+	m := regexp.MustCompile(param + `=(\S+)`).FindStringSubmatch(msg.Body)
+	if m == nil {
+		t.Fatalf("mail to %s has no %s link: %q", to, param, msg.Body)
+	}
+	token, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// confirmNewsletter переходит по ссылке подтверждения подписки из письма
+func (a *testApp) confirmNewsletter(t *testing.T, email string) {
+	t.Helper()
+	token := a.linkToken(t, email, "newsletter_confirm")
+	if status := a.do(t, a.client(t), http.MethodPost, "/api/newsletter/confirm", map[string]any{"token": token}, nil); status != http.StatusOK {
+		t.Fatalf("newsletter confirm status = %d, want 200", status)
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	app := newTestApp(t)
+	c, _ := app.register(t, "anna@example.com")
+	other := app.login(t, "anna@example.com", "secret123")
+
+	var body errorBody
+	status := app.do(t, c, http.MethodPost, "/api/profile/password", map[string]any{"currentPassword": "wrong-password1", "newPassword": "newsecret456"}, &body)
+	if status != http.StatusBadRequest || body.Code != "wrong_password" {
+		t.Errorf("change with wrong current password = %d %+v, want 400 wrong_password", status, body)
+	}
+	if status := app.do(t, c, http.MethodPost, "/api/profile/password", map[string]any{"currentPassword": "secret123", "newPassword": "newsecret456"}, nil); status != http.StatusOK {
+		t.Fatalf("change password status = %d, want 200", status)
+	}
+
+	if status := app.do(t, c, http.MethodGet, "/api/profile", nil, nil); status != http.StatusOK {
+		t.Errorf("current session after password change = %d, want 200", status)
+	}
+	if status := app.do(t, other, http.MethodGet, "/api/profile", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("other session after password change = %d, want 401", status)
+	}
+	app.login(t, "anna@example.com", "newsecret456")
+
+	// После выхода cookie сессии больше не действует
+	app.do(t, c, http.MethodPost, "/api/logout", nil, nil)
+	if status := app.do(t, c, http.MethodGet, "/api/profile", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("profile after logout = %d, want 401", status)
+	}
+}
+
+var confirmEmailLink = regexp.MustCompile(`confirm_email=(\S+)`)
+
+func TestEmailChange(t *testing.T) {
+	app := newTestApp(t)
+	c, _ := app.register(t, "anna@example.com")
+	app.register(t, "taken@example.com")
+
+	var body errorBody
+	status := app.do(t, c, http.MethodPost, "/api/profile/email", map[string]any{"newEmail": "taken@example.com", "currentPassword": "secret123"}, &body)
+	if status != http.StatusConflict || body.Fields["newEmail"] == "" {
+		t.Errorf("change to taken email = %d %+v, want 409 with newEmail error", status, body)
+	}
+	status = app.do(t, c, http.MethodPost, "/api/profile/email", map[string]any{"newEmail": "new@example.com", "currentPassword": "wrong-password1"}, &body)
+	if status != http.StatusBadRequest || body.Code != "wrong_password" {
+		t.Errorf("change with wrong password = %d %+v, want 400 wrong_password", status, body)
+	}
+
+	if status := app.do(t, c, http.MethodPost, "/api/profile/email", map[string]any{"newEmail": "new@example.com", "currentPassword": "secret123"}, nil); status != http.StatusOK {
+		t.Fatalf("request email change status = %d, want 200", status)
+	}
+	if _, ok := app.mail.last("anna@example.com"); !ok {
+		t.Error("old address was not notified")
+	}
+	msg, ok := app.mail.last("new@example.com")
+	match := confirmEmailLink.FindStringSubmatch(msg.Body)
+	if !ok || match == nil {
+		t.Fatalf("no confirmation link sent to the new address: %+v", msg)
+	}
+	token, _ := url.QueryUnescape(match[1])
+
+	// Пока ссылка не подтверждена, email прежний
+	var profile profileBody
+	app.do(t, c, http.MethodGet, "/api/profile", nil, &profile)
+	if profile.User.Email != "anna@example.com" {
+		t.Errorf("email before confirmation = %q, want the old one", profile.User.Email)
+	}
+
+	if status := app.do(t, app.client(t), http.MethodPost, "/api/profile/email/confirm", map[string]any{"token": token}, nil); status != http.StatusOK {
+		t.Fatalf("confirm status = %d, want 200", status)
+	}
+	app.do(t, c, http.MethodGet, "/api/profile", nil, &profile)
+	if profile.User.Email != "new@example.com" {
+		t.Errorf("email after confirmation = %q, want new@example.com", profile.User.Email)
+	}
+	app.login(t, "new@example.com", "secret123")
+
+	status = app.do(t, app.client(t), http.MethodPost, "/api/profile/email/confirm", map[string]any{"token": token}, &body)
+	if status != http.StatusBadRequest || body.Code != "invalid_email_token" {
+		t.Errorf("reused confirmation link = %d %+v, want 400 invalid_email_token", status, body)
+	}
+}
+
+func TestDataExportAndAccountDeletion(t *testing.T) {
+	app := newTestApp(t)
+	product := app.products.AddProduct(models.Product{Name: "Штора блэкаут", Price: 2500, InStock: true})
+	c, userID := app.register(t, "anna@example.com")
+	app.do(t, c, http.MethodPost, "/api/cart/items", map[string]any{"product_id": product.ID, "quantity": 1}, nil)
+	feedback := &models.Feedback{UserID: userID, Name: "Анна Иванова", Email: "anna@example.com", Message: "Отличные шторы"}
+	if err := app.feedbacks.CreateFeedback(context.Background(), feedback); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Get(app.server.URL + "/api/profile/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("export = %d %s, want 200 application/zip", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("export is not a zip: %v", err)
+	}
+	contents := map[string]string{}
+	for _, f := range archive.File {
+		r, _ := f.Open()
+		b, _ := io.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(b)
+	}
+	for name, want := range map[string]string{"profile.json": "anna@example.com", "cart.json": "Штора блэкаут", "feedbacks.json": "Отличные шторы", "consents.json": "[]"} {
+		if !strings.Contains(contents[name], want) {
+			t.Errorf("%s = %q, want it to contain %q", name, contents[name], want)
+		}
+	}
+
+	var body errorBody
+	status := app.do(t, c, http.MethodDelete, "/api/profile", map[string]any{"currentPassword": "secret123"}, &body)
+	if status != http.StatusBadRequest || body.Fields["confirm"] == "" {
+		t.Errorf("delete without confirmation = %d %+v, want 400 with confirm error", status, body)
+	}
+	status = app.do(t, c, http.MethodDelete, "/api/profile", map[string]any{"currentPassword": "wrong-password1", "confirm": true}, &body)
+	if status != http.StatusBadRequest || body.Code != "wrong_password" {
+		t.Errorf("delete with wrong password = %d %+v, want 400 wrong_password", status, body)
+	}
+	if status := app.do(t, c, http.MethodDelete, "/api/profile", map[string]any{"currentPassword": "secret123", "confirm": true}, nil); status != http.StatusOK {
+		t.Fatalf("delete status = %d, want 200", status)
+	}
+
+	if status := app.do(t, c, http.MethodGet, "/api/profile", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("profile after deletion = %d, want 401", status)
+	}
+	status = app.do(t, app.client(t), http.MethodPost, "/api/login", map[string]any{"email": "anna@example.com", "password": "secret123"}, &body)
+	if status != http.StatusUnauthorized {
+		t.Errorf("login after deletion = %d, want 401", status)
+	}
+
+	// Отзыв остается, но без имени и email
+	kept, _ := app.feedbacks.GetFeedbackByID(context.Background(), feedback.ID)
+	if kept == nil || kept.Name != models.DeletedUserName || kept.Email != "" || kept.UserID != 0 {
+		t.Errorf("feedback after deletion = %+v, want anonymized", kept)
+	}
+	deletions := app.deletions.Deletions()
+	if len(deletions) != 1 || deletions[0].UserID != userID || deletions[0].FeedbacksAnonymized != 1 ||
+		strings.Contains(deletions[0].EmailHash, "anna") {
+		t.Errorf("deletion log = %+v, want one entry with hashed email", deletions)
+	}
+}
+
+func TestPolicyConsents(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	v1, err := app.consents.Publish(ctx, models.PolicyPrivacy, "/privacy/Politics.pdf", []byte("редакция 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := app.register(t, "anna@example.com")
+
+	type consentBody struct {
+		Pending  []models.PolicyDocument `json:"pending"`
+		Accepted []models.Consent        `json:"accepted"`
+	}
+	var status consentBody
+	app.do(t, c, http.MethodGet, "/api/consents", nil, &status)
+	if len(status.Pending) != 0 || len(status.Accepted) != 1 || status.Accepted[0].DocumentID != v1.ID {
+		t.Fatalf("consents after register = %+v, want accepted v1", status)
+	}
+
+	// Тот же файл не создает новую версию
+	if same, _ := app.consents.Publish(ctx, models.PolicyPrivacy, "/privacy/Politics.pdf", []byte("редакция 1")); same.ID != v1.ID {
+		t.Errorf("republish unchanged = version %d, want %d", same.Version, v1.Version)
+	}
+	v2, _ := app.consents.Publish(ctx, models.PolicyPrivacy, "/privacy/Politics.pdf", []byte("редакция 2"))
+	app.do(t, c, http.MethodGet, "/api/consents", nil, &status)
+	if v2.Version != 2 || len(status.Pending) != 1 || status.Pending[0].ID != v2.ID {
+		t.Fatalf("consents after new version = %+v, want v2 pending", status)
+	}
+
+	var body errorBody
+	if code := app.do(t, c, http.MethodPost, "/api/consents", map[string]any{"document_ids": []int{v1.ID}}, &body); code != http.StatusConflict || body.Code != "policy_outdated" {
+		t.Errorf("accept outdated = %d %+v, want 409 policy_outdated", code, body)
+	}
+	app.do(t, c, http.MethodPost, "/api/consents", map[string]any{"document_ids": []int{v2.ID}}, &status)
+	if len(status.Pending) != 0 || len(status.Accepted) != 2 {
+		t.Errorf("consents after accept = %+v, want nothing pending", status)
+	}
+}
+
+func TestCookieConsent(t *testing.T) {
+	app := newTestApp(t)
+	guest := app.client(t)
+
+	var first struct {
+		ConsentID string `json:"consent_id"`
+	}
+	if code := app.do(t, guest, http.MethodPost, "/api/cookie-consent", map[string]any{"status": "rejected"}, &first); code != http.StatusOK || first.ConsentID == "" {
+		t.Fatalf("guest cookie consent = %d %+v, want 200 with consent_id", code, first)
+	}
+	if code := app.do(t, guest, http.MethodPost, "/api/cookie-consent", map[string]any{"status": "maybe"}, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown status = %d, want 400", code)
+	}
+
+	c, userID := app.register(t, "anna@example.com")
+	var second struct {
+		ConsentID string `json:"consent_id"`
+	}
+	app.do(t, c, http.MethodPost, "/api/cookie-consent", map[string]any{"status": "accepted", "consent_id": first.ConsentID}, &second)
+	if second.ConsentID != first.ConsentID {
+		t.Errorf("consent_id = %q, want existing %q kept", second.ConsentID, first.ConsentID)
+	}
+	var forged struct {
+		ConsentID string `json:"consent_id"`
+	}
+	app.do(t, guest, http.MethodPost, "/api/cookie-consent", map[string]any{"status": "accepted", "consent_id": "'; DROP"}, &forged)
+	if forged.ConsentID == "'; DROP" {
+		t.Error("forged consent_id was stored as is")
+	}
+
+	log := app.cookies.CookieConsents()
+	if len(log) != 3 || log[0].UserID != nil || log[1].UserID == nil || *log[1].UserID != userID || log[1].Status != models.CookieConsentAccepted {
+		t.Errorf("cookie consent log = %+v, want guest then user %d", log, userID)
+	}
+}
+
+func TestNewsletter(t *testing.T) {
+	app := newTestApp(t)
+	ctx := context.Background()
+	product := app.products.AddProduct(models.Product{Name: "Тюль вуаль", Price: 1800, InStock: true})
+
+	// Гость подписывается из формы, подписка действует после подтверждения
+	guest := app.client(t)
+	if status := app.do(t, guest, http.MethodPost, "/api/newsletter/subscribe", map[string]any{"email": "Guest@Example.com"}, nil); status != http.StatusOK {
+		t.Fatalf("subscribe status = %d, want 200", status)
+	}
+	var body errorBody
+	if status := app.do(t, guest, http.MethodPost, "/api/newsletter/confirm", map[string]any{"token": "forged"}, &body); status != http.StatusBadRequest || body.Code != "invalid_newsletter_token" {
+		t.Errorf("confirm with forged token = %d %+v, want 400", status, body)
+	}
+	app.confirmNewsletter(t, "guest@example.com")
+
+	// Пользователь отмечает рассылку при регистрации
+	c := app.client(t)
+	var registered models.AuthResponse
+	app.do(t, c, http.MethodPost, "/api/register", map[string]any{
+		"email": "anna@example.com", "password": "secret123", "firstName": "Анна", "lastName": "Иванова",
+		"agreeTerms": true, "newsletter": true,
+	}, &registered)
+	app.confirmNewsletter(t, "anna@example.com")
+	// Неподтвержденная подписка писем не получает
+	app.do(t, app.client(t), http.MethodPost, "/api/newsletter/subscribe", map[string]any{"email": "pending@example.com"}, nil)
+
+	manager, managerID := app.register(t, "manager@example.com")
+	if err := app.users.SetRole(managerID, models.RoleManager); err != nil {
+		t.Fatal(err)
+	}
+	app.enableTwoFactor(t, manager)
+
+	var campaign models.NewsletterCampaign
+	status := app.do(t, manager, http.MethodPost, "/api/newsletter/campaigns", map[string]any{
+		"subject": "Новая коллекция", "intro": "Встречайте весеннюю коллекцию!", "product_ids": []int{product.ID},
+	}, &campaign)
+	if status != http.StatusCreated || campaign.Status != models.CampaignDraft {
+		t.Fatalf("create campaign = %d %+v, want 201 draft", status, campaign)
+	}
+	if status := app.do(t, c, http.MethodPost, "/api/newsletter/campaigns", map[string]any{"subject": "x", "intro": "x"}, nil); status != http.StatusForbidden {
+		t.Errorf("customer creates campaign = %d, want 403", status)
+	}
+	if status := app.do(t, manager, http.MethodPost, fmt.Sprintf("/api/newsletter/campaigns/%d/send", campaign.ID), nil, &campaign); status != http.StatusAccepted {
+		t.Fatalf("send campaign status = %d, want 202", status)
+	}
+	if status := app.do(t, manager, http.MethodPost, fmt.Sprintf("/api/newsletter/campaigns/%d/send", campaign.ID), nil, &body); status != http.StatusConflict {
+		t.Errorf("second send = %d, want 409", status)
+	}
+
+	// Первый проход отправляет партию, второй отмечает кампанию отправленной
+	for i := 0; i < 2; i++ {
+		if err := app.newsletter.SendPending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var campaigns []models.NewsletterCampaign
+	app.do(t, manager, http.MethodGet, "/api/newsletter/campaigns", nil, &campaigns)
+	if len(campaigns) != 1 || campaigns[0].Status != models.CampaignSent || campaigns[0].Stats[models.DeliverySent] != 2 {
+		t.Errorf("campaigns = %+v, want one sent to 2 subscribers", campaigns)
+	}
+
+	msg, _ := app.mail.last("anna@example.com")
+	if msg.Subject != "Новая коллекция" || !strings.Contains(msg.Body, "Тюль вуаль") || msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Fatalf("campaign mail = %+v, want product block and one-click headers", msg)
+	}
+	if last, _ := app.mail.last("pending@example.com"); last.Subject == "Новая коллекция" {
+		t.Error("unconfirmed subscriber received the campaign")
+	}
+
+	// Отписка кнопкой почтового клиента: POST без cookie и CSRF-токена
+	oneClick := strings.Trim(msg.Headers["List-Unsubscribe"], "<>")
+	oneClick = strings.Replace(oneClick, "http://localhost", app.server.URL, 1)
+	resp, err := http.Post(oneClick, "application/x-www-form-urlencoded", strings.NewReader("List-Unsubscribe=One-Click"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("one-click unsubscribe status = %d, want 200", resp.StatusCode)
+	}
+	var profile profileBody
+	app.do(t, c, http.MethodGet, "/api/profile", nil, &profile)
+	if profile.User.Newsletter {
+		t.Error("newsletter flag still set after unsubscribe")
+	}
+	forged := strings.Replace(oneClick, "token=", "token=9", 1)
+	if resp, _ := http.Post(forged, "", nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("forged unsubscribe token = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestSmsLogin(t *testing.T) {
+	app := newTestApp(t)
+	c, userID := app.register(t, "anna@example.com")
+	const number = "+79991234567"
+	profile := map[string]any{"firstName": "Анна", "lastName": "Иванова", "phone": "8 999 123-45-67"}
+	app.do(t, c, http.MethodPut, "/api/profile", profile, nil)
+
+	// По номеру, который никто не подтвердил, код не отправляется, но ответ тот же
+	guest := app.client(t)
+	if status := app.do(t, guest, http.MethodPost, "/api/login/sms/code", map[string]any{"phone": "+79990001122"}, nil); status != http.StatusOK {
+		t.Fatalf("code for unverified phone = %d, want 200", status)
+	}
+	if app.sms.count() != 0 {
+		t.Fatal("sms sent to unverified phone")
+	}
+
+	if status := app.do(t, c, http.MethodPost, "/api/profile/phone/code", nil, nil); status != http.StatusOK {
+		t.Fatalf("verification code status = %d, want 200", status)
+	}
+	var body errorBody
+	if status := app.do(t, c, http.MethodPost, "/api/profile/phone/code", nil, &body); status != http.StatusTooManyRequests || body.Code != "sms_throttled" {
+		t.Errorf("resend = %d %+v, want 429 sms_throttled", status, body)
+	}
+	body = errorBody{}
+	if status := app.do(t, c, http.MethodPost, "/api/profile/phone/confirm", map[string]any{"code": "000000x"}, &body); status != http.StatusBadRequest || body.Code != "invalid_sms_code" {
+		t.Errorf("wrong code = %d %+v, want 400 invalid_sms_code", status, body)
+	}
+	var verified profileBody
+	if status := app.do(t, c, http.MethodPost, "/api/profile/phone/confirm", map[string]any{"code": app.sms.code(number)}, &verified); status != http.StatusOK || !verified.User.PhoneVerified {
+		t.Fatalf("confirm = %d %+v, want verified phone", status, verified.User)
+	}
+
+	// Подтвержденный номер принадлежит одному аккаунту
+	other, _ := app.register(t, "boris@example.com")
+	profile["phone"] = "+7 (999) 123 45 67"
+	app.do(t, other, http.MethodPut, "/api/profile", profile, nil)
+	body = errorBody{}
+	if status := app.do(t, other, http.MethodPost, "/api/profile/phone/code", nil, &body); status != http.StatusConflict || body.Code != "phone_taken" {
+		t.Errorf("verify taken phone = %d %+v, want 409 phone_taken", status, body)
+	}
+
+	if status := app.do(t, guest, http.MethodPost, "/api/login/sms/code", map[string]any{"phone": "8 (999) 123-45-67"}, nil); status != http.StatusOK {
+		t.Fatalf("login code status = %d, want 200", status)
+	}
+	code := app.sms.code(number)
+	body = errorBody{}
+	if status := app.do(t, guest, http.MethodPost, "/api/login/sms", map[string]any{"phone": number, "code": "123"}, &body); status != http.StatusBadRequest || body.Code != "invalid_sms_code" {
+		t.Errorf("login with wrong code = %d %+v, want 400 invalid_sms_code", status, body)
+	}
+	var resp models.AuthResponse
+	if status := app.do(t, guest, http.MethodPost, "/api/login/sms", map[string]any{"phone": number, "code": code}, &resp); status != http.StatusOK || resp.UserID != userID {
+		t.Fatalf("sms login = %d %+v, want user %d", status, resp, userID)
+	}
+	if status := app.do(t, guest, http.MethodGet, "/api/profile", nil, nil); status != http.StatusOK {
+		t.Errorf("profile after sms login = %d, want 200", status)
+	}
+
+	// Код одноразовый
+	if status := app.do(t, app.client(t), http.MethodPost, "/api/login/sms", map[string]any{"phone": number, "code": code}, nil); status != http.StatusBadRequest {
+		t.Errorf("reused code status = %d, want 400", status)
+	}
+
+	// Смена номера снимает подтверждение
+	profile["phone"] = "+79990001122"
+	var changed profileBody
+	app.do(t, c, http.MethodPut, "/api/profile", profile, &changed)
+	if changed.User.Phone != "+79990001122" || changed.User.PhoneVerified {
+		t.Errorf("changed phone = %+v, want unverified +79990001122", changed.User)
+	}
+}
+
+// oidcLogin проходит вход через поддельного провайдера как браузер: адрес входа,
+// страница провайдера, возврат на callback. Возвращает адрес последнего перенаправления.
+func (a *testApp) oidcLogin(t *testing.T, c *http.Client, path string) *url.URL {
+	t.Helper()
+	nc := *c
+	nc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	redirect := func(rawURL string) *url.URL {
+		t.Helper()
+		resp, err := nc.Get(rawURL)
+		if err != nil {
+			t.Fatalf("GET %s: %v", rawURL, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("GET %s: status %d, want 302", rawURL, resp.StatusCode)
+		}
+		loc, err := url.Parse(resp.Header.Get("Location"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loc
+	}
+
+	authURL := redirect(a.server.URL + path)
+	if authURL.Query().Get("code_challenge_method") != "S256" || authURL.Query().Get("nonce") == "" {
+		return authURL
+	}
+	callback := redirect(authURL.String())
+	// redirect_uri указывает на APP_BASE_URL; в тесте приложение слушает другой адрес
+	return redirect(a.server.URL + callback.RequestURI())
+}
+
+func TestOIDCLogin(t *testing.T) {
+	app := newTestApp(t)
+
+	var providers struct {
+		Providers []models.AuthProvider `json:"providers"`
+	}
+	if app.do(t, app.client(t), http.MethodGet, "/api/auth/providers", nil, &providers); len(providers.Providers) != 1 || providers.Providers[0].Name != "test" {
+		t.Fatalf("providers = %+v, want test", providers)
+	}
+
+	// Первый вход регистрирует пользователя по подтвержденному email
+	testProvider.SetUser(oidctest.User{Subject: "sub-anna", Email: "anna@example.com", EmailVerified: true, GivenName: "Анна", FamilyName: "Иванова"})
+	c := app.client(t)
+	if loc := app.oidcLogin(t, c, "/api/auth/oidc/test"); loc.Path != "/index.html" || loc.RawQuery != "" {
+		t.Fatalf("first login redirect = %s, want /index.html", loc)
+	}
+	var profile profileBody
+	if status := app.do(t, c, http.MethodGet, "/api/profile", nil, &profile); status != http.StatusOK || profile.User.Email != "anna@example.com" {
+		t.Fatalf("profile after oidc signup = %d %+v", status, profile.User)
+	}
+
+	// Повторный вход — тот же пользователь
+	again := app.client(t)
+	app.oidcLogin(t, again, "/api/auth/oidc/test")
+	var second profileBody
+	if app.do(t, again, http.MethodGet, "/api/profile", nil, &second); second.User.ID != profile.User.ID {
+		t.Errorf("second login user = %d, want %d", second.User.ID, profile.User.ID)
+	}
+
+	// Неподтвержденный email не годится для регистрации
+	testProvider.SetUser(oidctest.User{Subject: "sub-x", Email: "x@example.com"})
+	if loc := app.oidcLogin(t, app.client(t), "/api/auth/oidc/test"); loc.Query().Get("oidc_error") != "oidc_email_required" {
+		t.Errorf("unverified email redirect = %s, want oidc_email_required", loc)
+	}
+
+	// Аккаунт с таким email уже есть: автоматически не привязывается
+	boris, borisID := app.register(t, "boris@example.com")
+	testProvider.SetUser(oidctest.User{Subject: "sub-boris", Email: "boris@example.com", EmailVerified: true})
+	if loc := app.oidcLogin(t, app.client(t), "/api/auth/oidc/test"); loc.Query().Get("oidc_error") != "oidc_email_taken" {
+		t.Errorf("taken email redirect = %s, want oidc_email_taken", loc)
+	}
+
+	// Привязка из профиля, после чего вход через провайдера попадает в этот аккаунт
+	if loc := app.oidcLogin(t, boris, "/api/auth/oidc/test?link=1"); loc.Query().Get("oidc_linked") != "1" {
+		t.Fatalf("link redirect = %s, want oidc_linked=1", loc)
+	}
+	viaProvider := app.client(t)
+	app.oidcLogin(t, viaProvider, "/api/auth/oidc/test")
+	var linked profileBody
+	if app.do(t, viaProvider, http.MethodGet, "/api/profile", nil, &linked); linked.User.ID != borisID {
+		t.Errorf("login after link user = %d, want %d", linked.User.ID, borisID)
+	}
+
+	// Аккаунт провайдера, уже привязанный к Анне, к Борису не привязывается
+	testProvider.SetUser(oidctest.User{Subject: "sub-anna", Email: "anna@example.com", EmailVerified: true})
+	if loc := app.oidcLogin(t, boris, "/api/auth/oidc/test?link=1"); loc.Query().Get("oidc_error") != "oidc_identity_linked" {
+		t.Errorf("foreign identity link redirect = %s, want oidc_identity_linked", loc)
+	}
+
+	var list struct {
+		Identities []models.UserIdentity `json:"identities"`
+	}
+	if app.do(t, boris, http.MethodGet, "/api/account/identities", nil, &list); len(list.Identities) != 1 || list.Identities[0].Provider != "test" {
+		t.Fatalf("identities = %+v, want one test identity", list.Identities)
+	}
+	path := fmt.Sprintf("/api/account/identities/%d", list.Identities[0].ID)
+	var body errorBody
+	if status := app.do(t, boris, http.MethodDelete, path, map[string]any{"currentPassword": "wrong-password1"}, &body); status != http.StatusBadRequest || body.Code != "wrong_password" {
+		t.Errorf("unlink with wrong password = %d %+v, want 400 wrong_password", status, body)
+	}
+	if status := app.do(t, boris, http.MethodDelete, path, map[string]any{"currentPassword": "secret123"}, nil); status != http.StatusOK {
+		t.Errorf("unlink = %d, want 200", status)
+	}
+	if status := app.do(t, boris, http.MethodDelete, path, map[string]any{"currentPassword": "secret123"}, nil); status != http.StatusNotFound {
+		t.Errorf("second unlink = %d, want 404", status)
+	}
+}
+
+// Регистрация через провайдера не записывает согласие: пользователь документы не видел
+func TestOIDCSignupLeavesConsentsPending(t *testing.T) {
+	app := newTestApp(t)
+	policy, err := app.consents.Publish(context.Background(), models.PolicyPrivacy, "/privacy/Politics.pdf", []byte("редакция 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testProvider.SetUser(oidctest.User{Subject: "sub-vera", Email: "vera@example.com", EmailVerified: true, GivenName: "Вера"})
+	c := app.client(t)
+	if loc := app.oidcLogin(t, c, "/api/auth/oidc/test"); loc.Path != "/index.html" {
+		t.Fatalf("oidc signup redirect = %s, want /index.html", loc)
+	}
+
+	var status struct {
+		Pending  []models.PolicyDocument `json:"pending"`
+		Accepted []models.Consent        `json:"accepted"`
+	}
+	if code := app.do(t, c, http.MethodGet, "/api/consents", nil, &status); code != http.StatusOK {
+		t.Fatalf("consents = %d, want 200", code)
+	}
+	if len(status.Accepted) != 0 || len(status.Pending) != 1 || status.Pending[0].ID != policy.ID {
+		t.Errorf("consents after oidc signup = %+v, want policy pending and nothing accepted", status)
+	}
+}
+
+func TestOIDCCallbackRejectsForeignState(t *testing.T) {
+	app := newTestApp(t)
+	testProvider.SetUser(oidctest.User{Subject: "sub-anna", Email: "anna@example.com", EmailVerified: true})
+
+	// Злоумышленник начинает вход и подсовывает жертве ссылку на callback со своим кодом
+	attacker := app.client(t)
+	nc := *attacker
+	nc.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := nc.Get(app.server.URL + "/api/auth/oidc/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	resp, err = nc.Get(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	victim := app.client(t)
+	vc := *victim
+	vc.CheckRedirect = nc.CheckRedirect
+	resp, err = vc.Get(app.server.URL + callback.RequestURI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	if loc.Query().Get("oidc_error") != "oidc_state" {
+		t.Fatalf("callback without state cookie = %s, want oidc_error=oidc_state", loc)
+	}
+	if status := app.do(t, victim, http.MethodGet, "/api/profile", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("victim profile = %d, want 401", status)
+	}
+
+	// Подделанный ID-токен отклоняется, даже если state верный
+	testProvider.TokenHook = func(claims map[string]any) { claims["aud"] = "someone-else" }
+	defer func() { testProvider.TokenHook = nil }()
+	if loc := app.oidcLogin(t, app.client(t), "/api/auth/oidc/test"); loc.Query().Get("oidc_error") != "oidc_failed" {
+		t.Errorf("bad audience redirect = %s, want oidc_failed", loc)
+	}
+}
+
+func TestUnknownProduct(t *testing.T) {
+	app := newTestApp(t)
+
+	var body errorBody
+	if status := app.do(t, app.client(t), http.MethodGet, "/api/products/42", nil, &body); status != http.StatusNotFound || body.Code != "product_not_found" {
+		t.Errorf("GET unknown product = %d %+v, want 404 product_not_found", status, body)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	app := newTestApp(t)
+	product := app.products.AddProduct(models.Product{Name: "Штора блэкаут", Price: 2500, InStock: true})
+	c, _ := app.register(t, "anna@example.com")
+	token := app.csrfToken(t, c)
+	addItem := map[string]any{"product_id": product.ID, "quantity": 1}
+
+	tests := []struct {
+		name     string
+		token    string
+		origin   string
+		referer  string
+		wantCode string
+	}{
+		{"no token", "", "", "", "csrf_token_invalid"},
+		{"forged token", "forged.token", "", "", "csrf_token_invalid"},
+		{"foreign origin", token, "https://evil.example", "", "origin_not_allowed"},
+		{"foreign referer", token, "", "https://evil.example/page", "origin_not_allowed"},
+		{"same origin", token, app.server.URL, "", ""},
+		{"trusted origin", token, trustedOrigin, "", ""},
+		{"no origin", token, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := app.request(t, http.MethodPost, "/api/cart/items", addItem)
+			if tt.token != "" {
+				req.Header.Set("X-CSRF-Token", tt.token)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+
+			var body errorBody
+			status := app.send(t, c, req, &body)
+			if tt.wantCode == "" {
+				if status != http.StatusOK {
+					t.Errorf("status = %d %+v, want 200", status, body)
+				}
+				return
+			}
+			if status != http.StatusForbidden || body.Code != tt.wantCode {
+				t.Errorf("got %d %+v, want 403 %s", status, body, tt.wantCode)
+			}
+		})
+	}
+
+	// Токен другого клиента не подходит к чужой cookie
+	other := app.client(t)
+	app.csrfToken(t, other)
+	req := app.request(t, http.MethodPost, "/api/login", map[string]any{"email": "anna@example.com", "password": "secret123"})
+	req.Header.Set("X-CSRF-Token", token)
+	var body errorBody
+	if status := app.send(t, other, req, &body); status != http.StatusForbidden || body.Code != "csrf_token_invalid" {
+		t.Errorf("token from another client = %d %+v, want 403 csrf_token_invalid", status, body)
+	}
+}
+
+func TestCORSAllowList(t *testing.T) {
+	app := newTestApp(t)
+
+	for origin, allowed := range map[string]bool{trustedOrigin: true, "https://evil.example": false} {
+		req := app.request(t, http.MethodOptions, "/api/cart/items", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		gotOrigin := resp.Header.Get("Access-Control-Allow-Origin")
+		gotCredentials := resp.Header.Get("Access-Control-Allow-Credentials")
+		if allowed && (gotOrigin != origin || gotCredentials != "true") {
+			t.Errorf("%s: Allow-Origin %q, Allow-Credentials %q; want the origin with credentials", origin, gotOrigin, gotCredentials)
+		}
+		if !allowed && gotOrigin != "" {
+			t.Errorf("%s: Allow-Origin %q, want none", origin, gotOrigin)
+		}
+	}
+}
+
+func TestLoginThrottled(t *testing.T) {
+	app := newTestApp(t)
+	app.register(t, "anna@example.com")
+	c := app.client(t)
+	wrong := map[string]any{"email": "anna@example.com", "password": "wrong-password1"}
+
+	backoffAfter := service.DefaultLoginGuardConfig().BackoffAfter
+	for i := 0; i < backoffAfter; i++ {
+		if status := app.do(t, c, http.MethodPost, "/api/login", wrong, nil); status != http.StatusUnauthorized {
+			t.Fatalf("attempt %d status = %d, want 401", i+1, status)
+		}
+	}
+
+	req := app.request(t, http.MethodPost, "/api/login", map[string]any{"email": "anna@example.com", "password": "secret123"})
+	req.Header.Set("X-CSRF-Token", app.csrfToken(t, c))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body errorBody
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusTooManyRequests || body.Code != "login_throttled" {
+		t.Errorf("login after %d failures = %d %+v, want 429 login_throttled", backoffAfter, resp.StatusCode, body)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("no Retry-After header")
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/utils"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// RealIP определяет IP клиента с учетом доверенных прокси и кладет его в контекст
+// для utils.ClientIP. Подключается снаружи RequestLogger.
+func RealIP(proxies utils.TrustedProxies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(utils.WithClientIP(r.Context(), proxies.Resolve(r))))
+		})
+	}
+}
+
+// RequestLogger присваивает запросу ID (берет корректный X-Request-ID клиента или создает новый),
+// возвращает его в заголовке ответа, кладет в контекст логгер с этим ID и пишет строку журнала доступа
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := logging.WithRequestID(r.Context(), id)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "HTTP-запрос",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Int("bytes", rec.bytes),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("ip", utils.ClientIP(r)),
+		)
+	})
+}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// statusRecorder запоминает статус и размер ответа для журнала доступа
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if !s.wroteHeader {
+		s.status = status
+		s.wroteHeader = true
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+// Unwrap дает http.ResponseController доступ к исходному ResponseWriter
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// RoleMiddleware пропускает запрос только пользователям с нужной ролью.
+// Роль читается из базы, а не из cookie, чтобы изменения прав применялись сразу.
+// Для ролей из политики 2FA сессия должна быть создана с проверкой второго фактора.
+type RoleMiddleware struct {
+	authService *service.AuthService
+	policy      service.TwoFactorPolicy
+}
+
+func NewRoleMiddleware(authService *service.AuthService, policy service.TwoFactorPolicy) *RoleMiddleware {
+	return &RoleMiddleware{authService: authService, policy: policy}
+}
+
+// Require возвращает middleware, проверяющее роль. Ставится после RequireAuth.
+func (m *RoleMiddleware) Require(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sessionData, ok := utils.SessionFromContext(r.Context())
+			if !ok {
+				writeError(w, r, errUnauthorized)
+				return
+			}
+
+			user, err := m.authService.GetUserByID(r.Context(), sessionData.UserID)
+			if apperr.IsKind(err, apperr.KindNotFound) {
+				writeError(w, r, errUnauthorized)
+				return
+			}
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+
+			if slices.Contains(roles, user.Role) {
+				if m.policy.Required(user.Role) && !sessionData.TwoFactor {
+					logging.FromContext(r.Context()).Warn("Доступ запрещен: вход без второго фактора",
+						"user_id", user.ID, "role", user.Role)
+					writeError(w, r, errTwoFactorRequired)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			logging.FromContext(r.Context()).Warn("Доступ запрещен",
+				"user_id", user.ID, "role", user.Role, "required_roles", roles)
+			writeError(w, r, errForbidden)
+		})
+	}
+}
+
+// CORS разрешает браузеру запросы с cookie только из источников allowed.
+// Источники сравниваются точно, "*" не поддерживается: он несовместим с credentials.
+func CORS(allowed []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			if origin != "" && slices.Contains(allowed, origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+				w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Language, "+csrfHeaderName)
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// pathID читает числовой параметр пути, например {id} в /api/products/{id}
+func pathID(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(r.PathValue(name))
+}
+
+// currentSession данные сессии, положенные в контекст RequireAuth
+func currentSession(r *http.Request) *utils.SessionData {
+	sessionData, _ := utils.SessionFromContext(r.Context())
+	return sessionData
+}
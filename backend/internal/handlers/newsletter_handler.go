@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// NewsletterHandler подписка на рассылку и управление кампаниями (manager, admin)
+type NewsletterHandler struct {
+	newsletter *service.NewsletterService
+}
+
+func NewNewsletterHandler(newsletter *service.NewsletterService) *NewsletterHandler {
+	return &NewsletterHandler{newsletter: newsletter}
+}
+
+// Subscribe подписка из формы на сайте; подписка начнет действовать после подтверждения
+func (h *NewsletterHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterSubscribeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.newsletter.Subscribe(r.Context(), req, clientMeta(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Проверьте почту: мы отправили ссылку для подтверждения подписки")
+}
+
+// Confirm подтверждение подписки по ссылке из письма
+func (h *NewsletterHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.newsletter.Confirm(r.Context(), req.Token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Подписка на рассылку подтверждена")
+}
+
+// Unsubscribe отписка со страницы сайта по ссылке из письма рассылки
+func (h *NewsletterHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterTokenRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.newsletter.Unsubscribe(r.Context(), req.Token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Вы отписались от рассылки")
+}
+
+// OneClickUnsubscribe отписка кнопкой почтового клиента по заголовку List-Unsubscribe (RFC 8058).
+// Почтовый сервис отправляет POST без cookie и CSRF-токена, поэтому маршрут
+// регистрируется вне защиты CSRF: запрос авторизует подписанный токен в адресе.
+func (h *NewsletterHandler) OneClickUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	if err := h.newsletter.Unsubscribe(r.Context(), r.URL.Query().Get("token")); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Вы отписались от рассылки")
+}
+
+// GetCampaigns список кампаний со статистикой отправки
+func (h *NewsletterHandler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.newsletter.GetCampaigns(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaigns)
+}
+
+// CreateCampaign создание черновика кампании
+func (h *NewsletterHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req models.NewsletterCampaignRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	campaign, err := h.newsletter.CreateCampaign(r.Context(), req, currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// UpdateCampaign изменение черновика
+func (h *NewsletterHandler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+	var req models.NewsletterCampaignRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	campaign, err := h.newsletter.UpdateCampaign(r.Context(), id, req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(campaign)
+}
+
+// PreviewCampaign тема и текст письма кампании
+func (h *NewsletterHandler) PreviewCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	msg, err := h.newsletter.Preview(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"subject": msg.Subject,
+		"body":    msg.Body,
+		"headers": msg.Headers,
+	})
+}
+
+// SendCampaign запуск рассылки: письма уходят в фоне партиями
+func (h *NewsletterHandler) SendCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, invalidID("id"))
+		return
+	}
+
+	campaign, err := h.newsletter.Launch(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(campaign)
+}
+
+func writeMessage(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": message,
+	})
+}
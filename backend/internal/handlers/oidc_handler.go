@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/utils"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	oidcStateCookie = "oidc_state"
+	// oidcCookiePath cookie с state нужна только адресам входа через провайдеров
+	oidcCookiePath = "/api/auth/oidc/"
+
+	// Страницы, на которые браузер возвращается после входа через провайдера.
+	// Ошибка передается кодом в параметре oidc_error.
+	oidcLoginPage   = "/pages/login.html"
+	oidcDonePage    = "/index.html"
+	oidcProfilePage = "/index.html"
+)
+
+// OIDCHandler вход через внешних провайдеров OpenID Connect. Адреса входа и возврата
+// открываются браузером, а не fetch, поэтому отвечают перенаправлениями, а не JSON.
+type OIDCHandler struct {
+	oidc     *service.OIDCService
+	auth     *service.AuthService
+	sessions *Sessions
+}
+
+func NewOIDCHandler(oidc *service.OIDCService, auth *service.AuthService, sessions *Sessions) *OIDCHandler {
+	return &OIDCHandler{oidc: oidc, auth: auth, sessions: sessions}
+}
+
+// Providers список провайдеров для кнопок «Войти через …»
+func (h *OIDCHandler) Providers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":   true,
+		"providers": h.oidc.Providers(),
+	})
+}
+
+// Start перенаправляет на страницу входа провайдера. С ?link=1 вошедший пользователь
+// привязывает аккаунт провайдера к своему.
+func (h *OIDCHandler) Start(w http.ResponseWriter, r *http.Request) {
+	failPage := oidcLoginPage
+	userID := 0
+	if r.URL.Query().Get("link") == "1" {
+		failPage = oidcProfilePage
+		id, err := h.sessions.OptionalUserID(r)
+		if err != nil {
+			h.redirectError(w, r, failPage, err)
+			return
+		}
+		if id == 0 {
+			h.redirectError(w, r, oidcLoginPage, errUnauthorized)
+			return
+		}
+		userID = id
+	}
+
+	authURL, state, err := h.oidc.Start(r.Context(), r.PathValue("provider"), userID, utils.ClientIP(r))
+	if err != nil {
+		h.redirectError(w, r, failPage, err)
+		return
+	}
+
+	// SameSite=Lax: cookie должна прийти с переходом верхнего уровня от провайдера
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     oidcCookiePath,
+		MaxAge:   int((10 * time.Minute).Seconds()),
+		HttpOnly: true,
+		Secure:   h.sessions.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// Callback возврат с провайдера: вход, регистрация или привязка аккаунта
+func (h *OIDCHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	cookieState := ""
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		cookieState = cookie.Value
+	}
+	// state одноразовый, cookie больше не нужна при любом исходе
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     oidcCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.sessions.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := r.URL.Query()
+	result, err := h.oidc.Callback(r.Context(), service.OIDCCallback{
+		Provider:    r.PathValue("provider"),
+		State:       q.Get("state"),
+		Code:        q.Get("code"),
+		Error:       q.Get("error"),
+		CookieState: cookieState,
+	})
+	if err != nil {
+		h.redirectError(w, r, oidcLoginPage, err)
+		return
+	}
+	if result.Linked {
+		http.Redirect(w, r, oidcProfilePage+"?oidc_linked=1", http.StatusFound)
+		return
+	}
+
+	response, err := h.auth.LoginExternal(r.Context(), result.User)
+	if err != nil {
+		h.redirectError(w, r, oidcLoginPage, err)
+		return
+	}
+	if response.TwoFactorRequired {
+		// challenge во фрагменте: он не уходит на сервер и не попадает в журналы
+		http.Redirect(w, r, oidcLoginPage+"#challenge="+url.QueryEscape(response.Challenge), http.StatusFound)
+		return
+	}
+	if err := h.sessions.Start(w, r, response.UserID, false, false); err != nil {
+		h.redirectError(w, r, oidcLoginPage, err)
+		return
+	}
+	http.Redirect(w, r, oidcDonePage, http.StatusFound)
+}
+
+// Identities привязанные к аккаунту провайдеры
+func (h *OIDCHandler) Identities(w http.ResponseWriter, r *http.Request) {
+	identities, err := h.oidc.Identities(r.Context(), currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":    true,
+		"identities": identities,
+		"providers":  h.oidc.Providers(),
+	})
+}
+
+// Unlink отвязывает аккаунт провайдера; нужен текущий пароль
+func (h *OIDCHandler) Unlink(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		writeError(w, r, service.ErrIdentityNotFound)
+		return
+	}
+	var req models.IdentityUnlinkRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.oidc.Unlink(r.Context(), currentSession(r).UserID, id, req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Аккаунт отвязан")
+}
+
+// redirectError возвращает браузер на страницу page с кодом ошибки
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, page string, err error) {
+	e := apperr.From(err)
+	logger := logging.FromContext(r.Context())
+	switch e.Kind {
+	case apperr.KindInternal, apperr.KindTimeout, apperr.KindUnavailable:
+		logger.Error("Ошибка входа через провайдера", "error", e)
+	default:
+		logger.Info("Вход через провайдера не выполнен", "code", e.Code)
+	}
+	http.Redirect(w, r, page+"?oidc_error="+url.QueryEscape(e.Code), http.StatusFound)
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/utils"
+	"net/http"
+)
+
+// PhoneHandler подтверждение номера телефона из профиля кодом из SMS
+type PhoneHandler struct {
+	phones *service.PhoneService
+}
+
+func NewPhoneHandler(phones *service.PhoneService) *PhoneHandler {
+	return &PhoneHandler{phones: phones}
+}
+
+// RequestVerification отправляет код на номер из профиля
+func (h *PhoneHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.phones.RequestVerification(r.Context(), currentSession(r).UserID, utils.ClientIP(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeMessage(w, "Код подтверждения отправлен по SMS")
+}
+
+// ConfirmVerification подтверждает номер кодом и возвращает обновленный профиль
+func (h *PhoneHandler) ConfirmVerification(w http.ResponseWriter, r *http.Request) {
+	var req models.PhoneConfirmRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.phones.ConfirmVerification(r.Context(), currentSession(r).UserID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeProfile(w, user)
+}
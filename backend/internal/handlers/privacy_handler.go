@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"archive/zip"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PrivacyHandler выгрузка персональных данных и удаление аккаунта
+type PrivacyHandler struct {
+	privacy  *service.PrivacyService
+	sessions *Sessions
+}
+
+func NewPrivacyHandler(privacy *service.PrivacyService, sessions *Sessions) *PrivacyHandler {
+	return &PrivacyHandler{privacy: privacy, sessions: sessions}
+}
+
+// Export отдает данные пользователя ZIP-архивом с JSON-файлами; ?format=json — одним JSON
+func (h *PrivacyHandler) Export(w http.ResponseWriter, r *http.Request) {
+	userID := currentSession(r).UserID
+	export, err := h.privacy.Export(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="belladonna-data.json"`)
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(export)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="belladonna-data.zip"`)
+	if err := writeExportZip(w, export); err != nil {
+		// Заголовки уже отправлены, остается только записать ошибку в журнал
+		logging.FromContext(r.Context()).Error("Ошибка записи архива с данными", "user_id", userID, "error", err)
+	}
+}
+
+// Delete удаляет аккаунт текущего пользователя и завершает сессию
+func (h *PrivacyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	var req models.AccountDeleteRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.privacy.DeleteAccount(r.Context(), currentSession(r).UserID, req, utils.ClientIP(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// Сессии удалены вместе с аккаунтом, осталось убрать cookie
+	h.sessions.End(w, r)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Аккаунт удален",
+	})
+}
+
+// writeExportZip пишет выгрузку архивом: по файлу на раздел и README с описанием
+func writeExportZip(w io.Writer, export *models.DataExport) error {
+	zw := zip.NewWriter(w)
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", map[string]any{
+			"profile":            export.Profile,
+			"two_factor_enabled": export.TwoFactorEnabled,
+			"newsletter":         export.Newsletter,
+		}},
+		{"cart.json", export.Cart},
+		{"feedbacks.json", export.Feedbacks},
+		{"consents.json", export.Consents},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	readme, err := zw.Create("README.txt")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(readme, "Выгрузка персональных данных Belladonna от %s (UTC).\n\n"+
+		"profile.json   — данные профиля, настройки входа и подписка на рассылку\n"+
+		"cart.json      — товары в корзине\n"+
+		"feedbacks.json — ваши отзывы и ответы на них\n"+
+		"consents.json  — версии документов, с которыми вы согласились\n",
+		export.GeneratedAt.Format("02.01.2006 15:04"))
+
+	return zw.Close()
+}
@@ -4,7 +4,6 @@ import (
 	"beladonna/backend/internal/models"
 	"beladonna/backend/internal/service"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strconv"
 )
@@ -18,11 +17,6 @@ func NewProductHandler(productService *service.ProductService) *ProductHandler {
 }
 
 func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
 	filters := models.ProductFilters{
 		Search: r.URL.Query().Get("search"),
 	}
@@ -33,10 +27,9 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	products, err := h.productService.GetProducts(filters)
+	products, err := h.productService.GetProducts(r.Context(), filters)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Error fetching products", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -45,28 +38,15 @@ func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		http.Error(w, "Product ID is required", http.StatusBadRequest)
-		return
-	}
-
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		writeError(w, r, invalidID("id"))
 		return
 	}
 
-	product, err := h.productService.GetProductByID(id)
+	product, err := h.productService.GetProductByID(r.Context(), id)
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Product not found", http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
@@ -75,15 +55,9 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *ProductHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	categories, err := h.productService.GetCategories()
+	categories, err := h.productService.GetCategories(r.Context())
 	if err != nil {
-		log.Println(err)
-		http.Error(w, "Error fetching categories", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
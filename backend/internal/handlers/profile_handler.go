@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// ProfileHandler личный кабинет: данные профиля, смена email и пароля
+type ProfileHandler struct {
+	profile  *service.ProfileService
+	sessions *Sessions
+}
+
+func NewProfileHandler(profile *service.ProfileService, sessions *Sessions) *ProfileHandler {
+	return &ProfileHandler{profile: profile, sessions: sessions}
+}
+
+// Get данные текущего пользователя из базы
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, err := h.profile.GetProfile(r.Context(), currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeProfile(w, user)
+}
+
+// Update сохраняет имя, фамилию, телефон и подписку на рассылку
+func (h *ProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req models.ProfileUpdateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	user, err := h.profile.UpdateProfile(r.Context(), currentSession(r).UserID, req, clientMeta(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeProfile(w, user)
+}
+
+// RequestEmailChange отправляет ссылку подтверждения на новый email
+func (h *ProfileHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailChangeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.profile.RequestEmailChange(r.Context(), currentSession(r).UserID, req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Мы отправили ссылку для подтверждения на новый email",
+	})
+}
+
+// ConfirmEmailChange применяет новый email по токену из письма. Доступен без входа:
+// ссылку могут открыть в другом браузере.
+func (h *ProfileHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailConfirmRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.profile.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Email подтвержден, теперь используйте его для входа",
+	})
+}
+
+// ChangePassword меняет пароль; все сессии, кроме текущей, завершаются
+func (h *ProfileHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordChangeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.profile.ChangePassword(r.Context(), currentSession(r).UserID, req, sessionToken(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Пароль изменен, вход на других устройствах завершен",
+	})
+}
+
+func writeProfile(w http.ResponseWriter, user *models.User) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"user": map[string]interface{}{
+			"id":             user.ID,
+			"email":          user.Email,
+			"name":           user.FirstName + " " + user.LastName,
+			"first_name":     user.FirstName,
+			"last_name":      user.LastName,
+			"phone":          user.Phone,
+			"phone_verified": user.PhoneVerifiedAt != nil,
+			"newsletter":     user.Newsletter,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/utils"
+	"net/http"
+	"time"
+)
+
+const sessionCookieName = "user_session"
+
+// Sessions cookie серверной сессии и проверка авторизации запросов.
+// В cookie лежит только случайный токен; данные сессии хранятся в базе.
+type Sessions struct {
+	service *service.SessionService
+	secure  bool
+}
+
+func NewSessions(sessionService *service.SessionService, secureCookie bool) *Sessions {
+	return &Sessions{service: sessionService, secure: secureCookie}
+}
+
+// Start открывает сессию пользователя и ставит cookie.
+// remember продлевает жизнь сессии до RememberMeLifetime.
+func (s *Sessions) Start(w http.ResponseWriter, r *http.Request, userID int, twoFactor, remember bool) error {
+	token, expires, err := s.service.Start(r.Context(), userID, twoFactor, remember, clientMeta(r))
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// End завершает текущую сессию и удаляет cookie
+func (s *Sessions) End(w http.ResponseWriter, r *http.Request) error {
+	err := s.service.End(r.Context(), sessionToken(r))
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return err
+}
+
+// RequireAuth пропускает только запросы с действующей сессией и кладет данные сессии в контекст запроса
+func (s *Sessions) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := s.service.Resolve(r.Context(), sessionToken(r))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if session == nil {
+			writeError(w, r, errUnauthorized)
+			return
+		}
+
+		sessionData := &utils.SessionData{
+			UserID:    session.UserID,
+			Email:     session.Email,
+			Name:      session.Name,
+			TwoFactor: session.TwoFactor,
+		}
+		next.ServeHTTP(w, r.WithContext(utils.WithSession(r.Context(), sessionData)))
+	})
+}
+
+// OptionalUserID ID пользователя с действующей сессией или 0 для гостя.
+// Для публичных маршрутов, которым пользователь полезен, но не обязателен.
+func (s *Sessions) OptionalUserID(r *http.Request) (int, error) {
+	session, err := s.service.Resolve(r.Context(), sessionToken(r))
+	if err != nil || session == nil {
+		return 0, err
+	}
+	return session.UserID, nil
+}
+
+// clientMeta адрес и браузер клиента для журналов
+func clientMeta(r *http.Request) service.ClientMeta {
+	return service.ClientMeta{IP: utils.ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// sessionToken токен сессии из cookie или пустая строка
+func sessionToken(r *http.Request) string {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
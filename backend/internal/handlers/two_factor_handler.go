@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// TwoFactorHandler подключение и отключение двухфакторной аутентификации в личном кабинете
+type TwoFactorHandler struct {
+	twoFactor *service.TwoFactorService
+	sessions  *Sessions
+}
+
+func NewTwoFactorHandler(twoFactor *service.TwoFactorService, sessions *Sessions) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor, sessions: sessions}
+}
+
+// Status подключена ли 2FA и сколько осталось кодов восстановления
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status, err := h.twoFactor.Status(r.Context(), currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// Setup выдает ключ и otpauth-URI для приложения-аутентификатора
+func (h *TwoFactorHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	setup, err := h.twoFactor.Setup(r.Context(), currentSession(r).UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(setup)
+}
+
+// Confirm включает 2FA по коду из приложения и возвращает коды восстановления.
+// Текущая сессия заменяется новой, прошедшей второй фактор.
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	session := currentSession(r)
+	codes, err := h.twoFactor.Confirm(r.Context(), session.UserID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.sessions.End(w, r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.sessions.Start(w, r, session.UserID, true, false); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeRecoveryCodes(w, codes)
+}
+
+// Disable отключает 2FA после проверки текущего кода
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.twoFactor.Disable(r.Context(), currentSession(r).UserID, req.Code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "Двухфакторная аутентификация отключена",
+	})
+}
+
+// RegenerateRecoveryCodes выдает новые коды восстановления взамен прежних
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(r.Context(), currentSession(r).UserID, req.Code)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeRecoveryCodes(w, codes)
+}
+
+func writeRecoveryCodes(w http.ResponseWriter, codes []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"recovery_codes": codes,
+	})
+}
@@ -0,0 +1,78 @@
+// Package logging настраивает структурированный журнал (log/slog) и передает
+// логгер с ID запроса через context.Context от обработчиков к сервисам.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Форматы вывода
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Options struct {
+	Level  slog.Leveler
+	Format string
+	// Output по умолчанию os.Stderr, как у стандартного пакета log
+	Output io.Writer
+}
+
+// New создает логгер; чувствительные поля скрываются функцией Redact
+func New(opts Options) *slog.Logger {
+	out := opts.Output
+	if out == nil {
+		out = os.Stderr
+	}
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level, ReplaceAttr: Redact}
+
+	var handler slog.Handler
+	if opts.Format == FormatText {
+		handler = slog.NewTextHandler(out, handlerOpts)
+	} else {
+		handler = slog.NewJSONHandler(out, handlerOpts)
+	}
+	return slog.New(handler)
+}
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// WithLogger кладет логгер в контекст
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext логгер из контекста или slog.Default(), если его там нет
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestID сохраняет ID запроса в контексте и добавляет его ко всем записям логгера из контекста
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return WithLogger(ctx, FromContext(ctx).With(slog.String("request_id", id)))
+}
+
+// RequestID ID текущего запроса или ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID случайный ID из 16 шестнадцатеричных символов
+func NewRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+const redacted = "***"
+
+// secretKeys имена полей, значения которых не пишутся в журнал никогда.
+// Имена сравниваются без учета регистра, '_' и '-'.
+var secretKeys = []string{"password", "passwordhash", "secret", "token", "authorization", "cookie", "dsn", "apikey"}
+
+// Redact функция ReplaceAttr для slog: скрывает секреты, маскирует email и телефоны
+// и раскрывает структуры в группы, чтобы эти правила действовали и на их поля
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	key := normalizeKey(a.Key)
+
+	if isSecretKey(key) {
+		return slog.String(a.Key, redacted)
+	}
+	if a.Value.Kind() == slog.KindString {
+		switch {
+		case strings.HasSuffix(key, "email"):
+			return slog.String(a.Key, MaskEmail(a.Value.String()))
+		case strings.HasSuffix(key, "phone"):
+			return slog.String(a.Key, MaskPhone(a.Value.String()))
+		}
+	}
+	if a.Value.Kind() == slog.KindAny {
+		if group, ok := structGroup(a.Value.Any()); ok {
+			return slog.Attr{Key: a.Key, Value: group}
+		}
+	}
+	return a
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	return strings.NewReplacer("_", "", "-", "").Replace(key)
+}
+
+func isSecretKey(key string) bool {
+	for _, s := range secretKeys {
+		if key == s || strings.HasSuffix(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskEmail оставляет первую букву имени и домен: i***@example.com
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		if email == "" {
+			return ""
+		}
+		return redacted
+	}
+	return email[:1] + redacted + email[at:]
+}
+
+// MaskPhone оставляет код страны и две последние цифры: +7*******67
+func MaskPhone(phone string) string {
+	if len(phone) <= 4 {
+		if phone == "" {
+			return ""
+		}
+		return redacted
+	}
+	return phone[:2] + strings.Repeat("*", len(phone)-4) + phone[len(phone)-2:]
+}
+
+// structGroup превращает структуру (или указатель на нее) в группу атрибутов с именами
+// из json-тегов. Поля с тегом json:"-" пропускаются. Типы, которые сами умеют
+// себя выводить (ошибки, Stringer, json.Marshaler и т.п.), не раскрываются.
+func structGroup(v any) (slog.Value, bool) {
+	switch v.(type) {
+	case error, fmt.Stringer, json.Marshaler, encoding.TextMarshaler:
+		return slog.Value{}, false
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return slog.Value{}, false
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return slog.Value{}, false
+	}
+
+	rt := rv.Type()
+	attrs := make([]slog.Attr, 0, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				attrs = append(attrs, slog.Any(name, nil))
+				continue
+			}
+			fv = fv.Elem()
+		}
+		attrs = append(attrs, slog.Any(name, fv.Interface()))
+	}
+	return slog.GroupValue(attrs...), true
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		key   string
+		value slog.Value
+		want  string
+	}{
+		{"password", slog.StringValue("hunter22"), redacted},
+		{"new_password", slog.StringValue("hunter22"), redacted},
+		{"Password-Hash", slog.StringValue("$2a$10$abc"), redacted},
+		{"APP_SECRET", slog.StringValue("s3cr3t"), redacted},
+		{"unlock_token", slog.StringValue("abc"), redacted},
+		{"Authorization", slog.StringValue("Bearer abc"), redacted},
+		{"X-Api-Key", slog.StringValue("abc"), redacted},
+		{"dsn", slog.StringValue("postgres://user:pass@db/app"), redacted},
+		{"attempts", slog.IntValue(3), "3"},
+		{"token_count", slog.IntValue(3), "3"},
+
+		{"email", slog.StringValue("anna@example.com"), "a***@example.com"},
+		{"new_email", slog.StringValue("ivan.petrov@mail.ru"), "i***@mail.ru"},
+		{"NotifyEmail", slog.StringValue("support@belladonna.ru"), "s***@belladonna.ru"},
+		{"email", slog.StringValue("not-an-email"), redacted},
+		{"email", slog.StringValue(""), ""},
+		{"email_count", slog.IntValue(2), "2"},
+
+		{"phone", slog.StringValue("+79991234567"), "+7********67"},
+		{"user_phone", slog.StringValue("+79991234567"), "+7********67"},
+		{"phone", slog.StringValue("123"), redacted},
+		{"phone_verified", slog.BoolValue(true), "true"},
+
+		{"ip", slog.StringValue("203.0.113.7"), "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		got := Redact(nil, slog.Attr{Key: tt.key, Value: tt.value})
+		if got.Key != tt.key || got.Value.String() != tt.want {
+			t.Errorf("Redact(%s=%v) = %s=%v, want %s", tt.key, tt.value, got.Key, got.Value, tt.want)
+		}
+	}
+}
+
+type testProfile struct {
+	ID       int          `json:"id"`
+	Email    string       `json:"email"`
+	Password string       `json:"password"`
+	Hash     string       `json:"-"`
+	Contact  *testContact `json:"contact"`
+	Backup   *testContact `json:"backup"`
+	Note     string
+}
+
+type testContact struct {
+	Phone string `json:"phone"`
+	Token string `json:"token"`
+}
+
+func TestRedactNestedStruct(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(Options{Level: slog.LevelInfo, Format: FormatJSON, Output: &buf})
+	logger.Info("profile",
+		"user", &testProfile{
+			ID:       7,
+			Email:    "anna@example.com",
+			Password: "hunter22",
+			Hash:     "$2a$10$abc",
+			Contact:  &testContact{Phone: "+79991234567", Token: "abc"},
+			Note:     "vip",
+		},
+		"error", errors.New("email anna@example.com not found"),
+	)
+
+	var entry struct {
+		User  map[string]any `json:"user"`
+		Error string         `json:"error"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	user := entry.User
+	contact, _ := user["contact"].(map[string]any)
+	checks := map[string]any{
+		"id":       float64(7),
+		"email":    "a***@example.com",
+		"password": redacted,
+		"Note":     "vip",
+		"backup":   nil,
+	}
+	for key, want := range checks {
+		if got, ok := user[key]; !ok || got != want {
+			t.Errorf("user.%s = %v, want %v", key, got, want)
+		}
+	}
+	if _, ok := user["Hash"]; ok {
+		t.Error("field tagged json:\"-\" was logged")
+	}
+	if contact["phone"] != "+7********67" || contact["token"] != redacted {
+		t.Errorf("user.contact = %v, want masked phone and hidden token", contact)
+	}
+	// Ошибки не раскрываются в группы и выводятся как есть
+	if entry.Error != "email anna@example.com not found" {
+		t.Errorf("error = %q, want the message unchanged", entry.Error)
+	}
+}
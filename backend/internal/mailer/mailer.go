@@ -0,0 +1,28 @@
+package mailer
+
+import "log/slog"
+
+// Message письмо для отправки
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	Headers map[string]string
+}
+
+// Mailer отправляет письма. Реализации: LogMailer для разработки, SMTPMailer для продакшена.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// LogMailer не отправляет письма, а пишет их в лог
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	slog.Info("Письмо не отправлено (LogMailer)", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
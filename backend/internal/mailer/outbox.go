@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OutboxMailer складывает письма файлами .eml в каталог вместо отправки.
+// Подходит для локальной проверки рассылок: файлы открываются любым почтовым клиентом.
+type OutboxMailer struct {
+	Dir  string
+	From string
+
+	mu  sync.Mutex
+	seq int
+}
+
+func NewOutboxMailer(dir, from string) *OutboxMailer {
+	return &OutboxMailer{Dir: dir, From: from}
+}
+
+func (m *OutboxMailer) Send(msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("outbox: %w", err)
+	}
+
+	m.mu.Lock()
+	m.seq++
+	name := fmt.Sprintf("%s-%06d.eml", time.Now().Format("20060102-150405"), m.seq)
+	m.mu.Unlock()
+
+	if err := os.WriteFile(filepath.Join(m.Dir, name), BuildMessage(m.From, msg), 0o644); err != nil {
+		return fmt.Errorf("outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// lockKey ключ pg_advisory_lock, под которым выполняются миграции.
+// Пока блокировка занята, другие экземпляры приложения ждут.
+const lockKey int64 = 7_104_031
+
+var ErrNoDownMigration = errors.New("migration has no down file")
+
+// Status состояние одной версии схемы
+type Status struct {
+	Version          int64
+	Name             string
+	Applied          bool
+	AppliedAt        time.Time
+	ChecksumMismatch bool
+	Missing          bool // версия применена, но файла миграции больше нет
+}
+
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator применяет и откатывает версии схемы, записывая их в schema_migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// NewFromDir создает Migrator по файлам из папки
+func NewFromDir(db *sql.DB, dir string) (*Migrator, error) {
+	migrations, err := LoadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return New(db, migrations), nil
+}
+
+// Up применяет все неприменённые миграции. Каждая выполняется в своей транзакции.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.verify(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down откатывает последние steps примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		for ; count < steps; count++ {
+			rolledBack, err := m.rollbackLast(ctx, conn)
+			if err != nil {
+				return err
+			}
+			if !rolledBack {
+				break
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Redo откатывает и заново применяет последнюю миграцию
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		last, ok := lastApplied(applied)
+		if !ok {
+			return errors.New("no applied migrations to redo")
+		}
+		migration, ok := m.find(last.version)
+		if !ok {
+			return fmt.Errorf("migration %d is applied but its file is missing", last.version)
+		}
+
+		if _, err := m.rollbackLast(ctx, conn); err != nil {
+			return err
+		}
+		return m.apply(ctx, conn, migration)
+	})
+}
+
+// Status возвращает состояние всех известных версий: из файлов и из schema_migrations
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if a, ok := applied[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = a.appliedAt
+				status.ChecksumMismatch = a.checksum != migration.Checksum
+				delete(applied, migration.Version)
+			}
+			statuses = append(statuses, status)
+		}
+		for _, a := range applied {
+			statuses = append(statuses, Status{
+				Version:   a.version,
+				Name:      a.name,
+				Applied:   true,
+				AppliedAt: a.appliedAt,
+				Missing:   true,
+			})
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %v", err)
+	}
+	defer func() {
+		// Контекст запроса мог быть отменен: снимаем блокировку независимо от него
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			slog.Error("Ошибка снятия блокировки миграций", "error", err)
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version BIGINT PRIMARY KEY,
+            name VARCHAR(255) NOT NULL,
+            checksum VARCHAR(64) NOT NULL,
+            applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+        )`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// verify проверяет, что примененные миграции не изменились после применения
+func (m *Migrator) verify(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	applied, err := m.loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.migrations {
+		if a, ok := applied[migration.Version]; ok && a.checksum != migration.Checksum {
+			return nil, fmt.Errorf("checksum mismatch for applied migration %d_%s: file was changed after it was applied",
+				migration.Version, migration.Name)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[a.version] = a
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Миграция применена", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// rollbackLast откатывает последнюю примененную миграцию; false, если откатывать нечего
+func (m *Migrator) rollbackLast(ctx context.Context, conn *sql.Conn) (bool, error) {
+	applied, err := m.loadApplied(ctx, conn)
+	if err != nil {
+		return false, err
+	}
+	last, ok := lastApplied(applied)
+	if !ok {
+		return false, nil
+	}
+
+	migration, ok := m.find(last.version)
+	if !ok {
+		return false, fmt.Errorf("migration %d is applied but its file is missing", last.version)
+	}
+	if migration.Down == "" {
+		return false, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+		return false, fmt.Errorf("rollback of %d_%s failed: %v", migration.Version, migration.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	slog.InfoContext(ctx, "Миграция откачена", "version", migration.Version, "name", migration.Name)
+	return true, nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+func lastApplied(applied map[int64]appliedMigration) (appliedMigration, bool) {
+	var last appliedMigration
+	found := false
+	for _, a := range applied {
+		if !found || a.version > last.version {
+			last = a
+			found = true
+		}
+	}
+	return last, found
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// PolicyPrivacy политика конфиденциальности, с которой соглашаются при регистрации
+const PolicyPrivacy = "privacy"
+
+// RequiredPolicies документы, согласие с которыми нужно для работы с аккаунтом
+var RequiredPolicies = []string{PolicyPrivacy}
+
+// PolicyDocument опубликованная версия документа; SHA256 — хеш файла на момент публикации
+type PolicyDocument struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	Version     int       `json:"version"`
+	URL         string    `json:"url"`
+	SHA256      string    `json:"sha256"`
+	PublishedAt time.Time `json:"published_at"`
+}
+
+// Consent согласие пользователя с версией документа. Kind и Version берутся из документа при чтении.
+type Consent struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	DocumentID int       `json:"document_id"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	Kind       string    `json:"kind"`
+	Version    int       `json:"version"`
+}
+
+// ConsentStatus действующие документы и те из них, которые пользователь еще не принял
+type ConsentStatus struct {
+	Documents []PolicyDocument `json:"documents"`
+	Pending   []PolicyDocument `json:"pending"`
+	Accepted  []Consent        `json:"accepted"`
+}
+
+type ConsentAcceptRequest struct {
+	DocumentIDs []int `json:"document_ids"`
+}
+
+func (r ConsentAcceptRequest) Validate(v *validation.Validator) {
+	v.Check(len(r.DocumentIDs) > 0, "document_ids", "required")
+}
+
+const (
+	CookieConsentAccepted = "accepted"
+	CookieConsentRejected = "rejected"
+)
+
+// CookieConsent решение посетителя в баннере cookie
+type CookieConsent struct {
+	ID         int       `json:"id"`
+	ConsentID  string    `json:"consent_id"`
+	UserID     *int      `json:"user_id,omitempty"`
+	Status     string    `json:"status"`
+	DocumentID *int      `json:"document_id,omitempty"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CookieConsentRequest выбор в баннере; ConsentID пуст при первом решении
+type CookieConsentRequest struct {
+	ConsentID string `json:"consent_id"`
+	Status    string `json:"status"`
+}
+
+func (r CookieConsentRequest) Validate(v *validation.Validator) {
+	v.Check(r.Status == CookieConsentAccepted || r.Status == CookieConsentRejected, "status", "required")
+	v.MaxLen("consent_id", r.ConsentID, 64)
+}
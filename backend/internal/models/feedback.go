@@ -1,15 +1,129 @@
 package models
 
 import (
+    "beladonna/backend/internal/validation"
     "time"
 )
 
+// Ограничения на поля отзыва; name, email и theme совпадают с размерами колонок
+const (
+    MaxFeedbackMessageLen = 5000
+    MaxFeedbackEmailLen   = 150
+    MaxFeedbackThemeLen   = 50
+)
+
+// Статусы модерации отзыва
+const (
+    ModerationPending  = "pending"
+    ModerationApproved = "approved"
+    ModerationRejected = "rejected"
+)
+
+// Статусы переписки по отзыву
+const (
+    FeedbackOpen     = "open"
+    FeedbackAnswered = "answered"
+    FeedbackClosed   = "closed"
+)
+
+// Действия модератора, которые попадают в журнал
+const (
+    ModerationActionApprove = "approve"
+    ModerationActionReject  = "reject"
+    ModerationActionEdit    = "edit"
+)
+
 type Feedback struct {
+    ID               int        `json:"id"`
+    UserID           int        `json:"user_id,omitempty"`
+    Name             string     `json:"name"`
+    Email            string     `json:"email,omitempty"`
+    Theme            string     `json:"theme"`
+    ThemeTitle       string     `json:"theme_title,omitempty"`
+    Message          string     `json:"message"`
+    CreatedAt        time.Time  `json:"created_at"`
+    IsVisible        bool       `json:"is_visible"`
+    ModerationStatus string     `json:"moderation_status,omitempty"`
+    RejectionReason  string     `json:"rejection_reason,omitempty"`
+    ModeratedBy      *int       `json:"moderated_by,omitempty"`
+    ModeratedAt      *time.Time `json:"moderated_at,omitempty"`
+    Status           string     `json:"status,omitempty"`
+    Replies          []FeedbackReply `json:"replies,omitempty"`
+}
+
+// FeedbackReply ответ сотрудника на отзыв. Публичные ответы показываются под отзывом,
+// приватные отправляются автору на email.
+type FeedbackReply struct {
+    ID         int        `json:"id"`
+    FeedbackID int        `json:"feedback_id"`
+    AuthorID   int        `json:"author_id,omitempty"`
+    AuthorName string     `json:"author_name"`
+    Message    string     `json:"message"`
+    IsPublic   bool       `json:"is_public"`
+    EmailedAt  *time.Time `json:"emailed_at,omitempty"`
+    CreatedAt  time.Time  `json:"created_at"`
+}
+
+// FeedbackReplyRequest запрос сотрудника на ответ
+type FeedbackReplyRequest struct {
+    FeedbackID int    `json:"feedback_id"`
+    Message    string `json:"message"`
+    IsPublic   bool   `json:"is_public"`
+}
+
+func (r FeedbackReplyRequest) Validate(v *validation.Validator) {
+    if v.Required("message", r.Message) {
+        v.MaxLen("message", r.Message, MaxFeedbackMessageLen)
+    }
+}
+
+// FeedbackModerationEntry запись журнала модерации
+type FeedbackModerationEntry struct {
+    ID          int       `json:"id"`
+    FeedbackID  int       `json:"feedback_id"`
+    ModeratorID int       `json:"moderator_id"`
+    Action      string    `json:"action"`
+    Reason      string    `json:"reason,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
+}
+
+// FeedbackEditRequest правка отзыва модератором
+type FeedbackEditRequest struct {
+    ID      int    `json:"id"`
+    Name    string `json:"name"`
+    Theme   string `json:"theme"`
+    Message string `json:"message"`
+}
+
+// FeedbackSubmission данные формы отзыва вместе с антиспам-полями.
+// Пустые Name и Email заполняются из профиля пользователя.
+type FeedbackSubmission struct {
+    Name      string `json:"name"`
+    Email     string `json:"email"`
+    Theme     string `json:"theme"`
+    Message   string `json:"message"`
+    Website   string `json:"website"`    // honeypot: скрытое поле, которое заполняют только боты
+    FormToken string `json:"form_token"` // подписанная сервером метка времени открытия формы
+}
+
+func (s FeedbackSubmission) Validate(v *validation.Validator) {
+    v.MaxLen("name", s.Name, validation.MaxNameLen)
+    if s.Email != "" && v.MaxLen("email", s.Email, MaxFeedbackEmailLen) {
+        v.Email("email", s.Email)
+    }
+    v.MaxLen("theme", s.Theme, MaxFeedbackThemeLen)
+    if v.Required("message", s.Message) {
+        v.MaxLen("message", s.Message, MaxFeedbackMessageLen)
+    }
+}
+
+// FeedbackRejection отклоненная антиспамом отправка; хранится для настройки порогов
+type FeedbackRejection struct {
     ID        int       `json:"id"`
-    Name      string    `json:"name"`
+    UserID    int       `json:"user_id"`
+    IP        string    `json:"ip"`
     Email     string    `json:"email"`
-    Theme     string    `json:"theme"`
+    Reason    string    `json:"reason"`
     Message   string    `json:"message"`
     CreatedAt time.Time `json:"created_at"`
-    IsVisible bool      `json:"is_visible"`
-}
\ No newline at end of file
+}
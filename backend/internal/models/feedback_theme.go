@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// FeedbackTheme тема отзыва с правилом маршрутизации уведомлений
+type FeedbackTheme struct {
+	ID          int       `json:"id"`
+	Code        string    `json:"code"`
+	Title       string    `json:"title"`
+	NotifyEmail string    `json:"notify_email,omitempty"`
+	NotifyRole  string    `json:"notify_role,omitempty"`
+	IsActive    bool      `json:"is_active"`
+	SortOrder   int       `json:"sort_order"`
+	CreatedAt   time.Time `json:"created_at"`
+}
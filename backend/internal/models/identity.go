@@ -0,0 +1,46 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// UserIdentity аккаунт внешнего провайдера OpenID Connect, привязанный к пользователю.
+// Subject — неизменный идентификатор пользователя у провайдера (утверждение sub).
+type UserIdentity struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"-"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"-"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState незавершенный вход через провайдера. StateHash — SHA-256 от state;
+// UserID задан, если вошедший пользователь привязывает новый аккаунт.
+type OIDCLoginState struct {
+	ID           int       `json:"id"`
+	StateHash    string    `json:"-"`
+	Provider     string    `json:"provider"`
+	Nonce        string    `json:"-"`
+	CodeVerifier string    `json:"-"`
+	UserID       int       `json:"user_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// AuthProvider провайдер для кнопки «Войти через …»
+type AuthProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// IdentityUnlinkRequest отвязка внешнего аккаунта подтверждается паролем
+type IdentityUnlinkRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+}
+
+func (r IdentityUnlinkRequest) Validate(v *validation.Validator) {
+	v.Required("currentPassword", r.CurrentPassword)
+}
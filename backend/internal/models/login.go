@@ -0,0 +1,37 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// LoginThrottle счетчик неудачных входов по email
+type LoginThrottle struct {
+	Email         string     `json:"email"`
+	Failures      int        `json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty"`
+}
+
+// AccountLockout запись о блокировке входа после серии неудачных попыток.
+// UnlockTokenHash пуст, если email не принадлежит пользователю и письмо не отправлялось.
+type AccountLockout struct {
+	ID              int        `json:"id"`
+	UserID          int        `json:"user_id,omitempty"`
+	Email           string     `json:"email"`
+	IP              string     `json:"ip"`
+	Failures        int        `json:"failures"`
+	LockedUntil     time.Time  `json:"locked_until"`
+	UnlockTokenHash string     `json:"-"`
+	UnlockedAt      *time.Time `json:"unlocked_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// UnlockRequest разблокировка входа по ссылке из письма
+type UnlockRequest struct {
+	Token string `json:"token"`
+}
+
+func (r UnlockRequest) Validate(v *validation.Validator) {
+	v.Required("token", r.Token)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// Статусы подписки на рассылку
+const (
+	SubscriptionPending      = "pending"
+	SubscriptionActive       = "active"
+	SubscriptionUnsubscribed = "unsubscribed"
+)
+
+// Откуда пришла подписка
+const (
+	SubscriptionSourceForm         = "form"
+	SubscriptionSourceRegistration = "registration"
+	SubscriptionSourceProfile      = "profile"
+)
+
+// NewsletterSubscription подписка на рассылку. У гостя UserID пуст;
+// письма зарегистрированному пользователю уходят на его текущий email.
+type NewsletterSubscription struct {
+	ID               int        `json:"id"`
+	Email            string     `json:"email"`
+	UserID           *int       `json:"user_id,omitempty"`
+	Status           string     `json:"status"`
+	Source           string     `json:"source"`
+	ConfirmTokenHash string     `json:"-"`
+	ConfirmSentAt    *time.Time `json:"-"`
+	IP               string     `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+	UnsubscribedAt   *time.Time `json:"unsubscribed_at,omitempty"`
+}
+
+type NewsletterSubscribeRequest struct {
+	Email string `json:"email"`
+}
+
+func (r NewsletterSubscribeRequest) Validate(v *validation.Validator) {
+	if v.Required("email", r.Email) {
+		v.Email("email", r.Email)
+	}
+	v.MaxLen("email", r.Email, 255)
+}
+
+// NewsletterTokenRequest токен из письма: подтверждение подписки или отписка
+type NewsletterTokenRequest struct {
+	Token string `json:"token"`
+}
+
+func (r NewsletterTokenRequest) Validate(v *validation.Validator) {
+	v.Required("token", r.Token)
+}
+
+// Статусы кампании и отдельных писем
+const (
+	CampaignDraft   = "draft"
+	CampaignSending = "sending"
+	CampaignSent    = "sent"
+
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed"
+	DeliverySkipped = "skipped"
+)
+
+// MaxCampaignProducts сколько товаров можно добавить в одну кампанию
+const MaxCampaignProducts = 12
+
+// NewsletterCampaign письмо рассылки: вступление и блоки товаров из каталога
+type NewsletterCampaign struct {
+	ID         int            `json:"id"`
+	Subject    string         `json:"subject"`
+	Intro      string         `json:"intro"`
+	ProductIDs []int          `json:"product_ids"`
+	Status     string         `json:"status"`
+	CreatedBy  *int           `json:"created_by,omitempty"`
+	CreatedAt  time.Time      `json:"created_at"`
+	SentAt     *time.Time     `json:"sent_at,omitempty"`
+	Stats      map[string]int `json:"stats,omitempty"`
+}
+
+type NewsletterCampaignRequest struct {
+	Subject    string `json:"subject"`
+	Intro      string `json:"intro"`
+	ProductIDs []int  `json:"product_ids"`
+}
+
+func (r NewsletterCampaignRequest) Validate(v *validation.Validator) {
+	v.Required("subject", r.Subject)
+	v.MaxLen("subject", r.Subject, 200)
+	v.Required("intro", r.Intro)
+	v.MaxLen("intro", r.Intro, 5000)
+	v.Check(len(r.ProductIDs) <= MaxCampaignProducts, "product_ids", "out_of_range", 0, MaxCampaignProducts)
+}
+
+// NewsletterDelivery письмо кампании одному подписчику. Email и SubscriptionStatus
+// берутся из подписки при чтении.
+type NewsletterDelivery struct {
+	ID                 int        `json:"id"`
+	CampaignID         int        `json:"campaign_id"`
+	SubscriptionID     int        `json:"subscription_id"`
+	Status             string     `json:"status"`
+	Error              string     `json:"error,omitempty"`
+	SentAt             *time.Time `json:"sent_at,omitempty"`
+	Email              string     `json:"-"`
+	SubscriptionStatus string     `json:"-"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// Назначение кодов из SMS
+const (
+	SmsCodeLogin  = "login"
+	SmsCodeVerify = "verify"
+)
+
+// SmsCode одноразовый код, отправленный на номер Phone. UserID равен 0 для кода
+// на номер, которого нет среди подтвержденных: такой код не отправляется.
+type SmsCode struct {
+	ID        int        `json:"id"`
+	Phone     string     `json:"phone"`
+	Purpose   string     `json:"purpose"`
+	UserID    int        `json:"user_id,omitempty"`
+	CodeHash  string     `json:"-"`
+	Attempts  int        `json:"attempts"`
+	IP        string     `json:"ip,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// SmsCodeRequest запрос кода для входа по номеру телефона
+type SmsCodeRequest struct {
+	Phone string `json:"phone"`
+}
+
+func (r SmsCodeRequest) Validate(v *validation.Validator) {
+	if v.Required("phone", r.Phone) {
+		v.Phone("phone", r.Phone)
+	}
+}
+
+// SmsLoginRequest вход по номеру телефона и коду из SMS
+type SmsLoginRequest struct {
+	Phone      string `json:"phone"`
+	Code       string `json:"code"`
+	RememberMe bool   `json:"rememberMe"`
+}
+
+func (r SmsLoginRequest) Validate(v *validation.Validator) {
+	if v.Required("phone", r.Phone) {
+		v.Phone("phone", r.Phone)
+	}
+	if v.Required("code", r.Code) {
+		v.MaxLen("code", r.Code, 32)
+	}
+}
+
+// PhoneConfirmRequest подтверждение номера из профиля кодом из SMS
+type PhoneConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+func (r PhoneConfirmRequest) Validate(v *validation.Validator) {
+	if v.Required("code", r.Code) {
+		v.MaxLen("code", r.Code, 32)
+	}
+}
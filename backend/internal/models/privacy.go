@@ -0,0 +1,43 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// DeletedUserName имя автора в отзывах удаленного пользователя
+const DeletedUserName = "Удаленный пользователь"
+
+// DataExport персональные данные пользователя для выгрузки по запросу (152-ФЗ, GDPR)
+type DataExport struct {
+	GeneratedAt      time.Time               `json:"generated_at"`
+	Profile          *User                   `json:"profile"`
+	TwoFactorEnabled bool                    `json:"two_factor_enabled"`
+	Cart             []CartItem              `json:"cart"`
+	Feedbacks        []Feedback              `json:"feedbacks"`
+	Consents         []Consent               `json:"consents"`
+	Newsletter       *NewsletterSubscription `json:"newsletter,omitempty"`
+	Identities       []UserIdentity          `json:"identities"`
+}
+
+// AccountDeletion запись журнала удаленных аккаунтов; email хранится только как хеш
+type AccountDeletion struct {
+	ID                  int       `json:"id"`
+	UserID              int       `json:"user_id"`
+	EmailHash           string    `json:"email_hash"`
+	Role                string    `json:"role"`
+	FeedbacksAnonymized int       `json:"feedbacks_anonymized"`
+	IP                  string    `json:"ip,omitempty"`
+	DeletedAt           time.Time `json:"deleted_at"`
+}
+
+// AccountDeleteRequest удаление аккаунта подтверждается паролем и явным согласием
+type AccountDeleteRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	Confirm         bool   `json:"confirm"`
+}
+
+func (r AccountDeleteRequest) Validate(v *validation.Validator) {
+	v.Required("currentPassword", r.CurrentPassword)
+	v.Accepted("confirm", r.Confirm)
+}
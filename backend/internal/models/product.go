@@ -1,9 +1,13 @@
 package models
 
 import (
+	"beladonna/backend/internal/validation"
 	"time"
 )
 
+// MaxCartQuantity наибольшее количество одного товара в корзине
+const MaxCartQuantity = 99
+
 type Category struct {
 	ID          int       `json:"id"`
 	Name        string    `json:"name"`
@@ -35,6 +39,26 @@ type CartItem struct {
 	AddedAt     time.Time `json:"added_at"`
 }
 
+// CartItemRequest добавление товара в корзину
+type CartItemRequest struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+func (r CartItemRequest) Validate(v *validation.Validator) {
+	v.Check(r.ProductID > 0, "product_id", "required")
+	v.IntRange("quantity", r.Quantity, 1, MaxCartQuantity)
+}
+
+// CartQuantityRequest изменение количества товара в корзине
+type CartQuantityRequest struct {
+	Quantity int `json:"quantity"`
+}
+
+func (r CartQuantityRequest) Validate(v *validation.Validator) {
+	v.IntRange("quantity", r.Quantity, 1, MaxCartQuantity)
+}
+
 type ProductFilters struct {
 	CategoryID int     `json:"category_id"`
 	Search     string  `json:"search"`
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// EmailChange запрос на смену email, ожидающий подтверждения по ссылке
+type EmailChange struct {
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	NewEmail    string     `json:"new_email"`
+	TokenHash   string     `json:"-"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ProfileUpdateRequest изменяемые поля профиля
+type ProfileUpdateRequest struct {
+	FirstName  string `json:"firstName"`
+	LastName   string `json:"lastName"`
+	Phone      string `json:"phone"`
+	Newsletter bool   `json:"newsletter"`
+}
+
+func (r ProfileUpdateRequest) Validate(v *validation.Validator) {
+	if v.Required("firstName", r.FirstName) {
+		v.MaxLen("firstName", r.FirstName, validation.MaxNameLen)
+	}
+	if v.Required("lastName", r.LastName) {
+		v.MaxLen("lastName", r.LastName, validation.MaxNameLen)
+	}
+	if r.Phone != "" {
+		v.Phone("phone", r.Phone)
+	}
+}
+
+// EmailChangeRequest смена email подтверждается текущим паролем
+type EmailChangeRequest struct {
+	NewEmail        string `json:"newEmail"`
+	CurrentPassword string `json:"currentPassword"`
+}
+
+func (r EmailChangeRequest) Validate(v *validation.Validator) {
+	if v.Required("newEmail", r.NewEmail) && v.MaxLen("newEmail", r.NewEmail, validation.MaxEmailLen) {
+		v.Email("newEmail", r.NewEmail)
+	}
+	v.Required("currentPassword", r.CurrentPassword)
+}
+
+// EmailConfirmRequest подтверждение нового email по токену из письма
+type EmailConfirmRequest struct {
+	Token string `json:"token"`
+}
+
+func (r EmailConfirmRequest) Validate(v *validation.Validator) {
+	v.Required("token", r.Token)
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+func (r PasswordChangeRequest) Validate(v *validation.Validator) {
+	v.Required("currentPassword", r.CurrentPassword)
+	if v.Required("newPassword", r.NewPassword) {
+		v.Password("newPassword", r.NewPassword)
+	}
+}
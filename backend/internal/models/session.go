@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session серверная сессия. Email и Name берутся из профиля пользователя при чтении.
+type Session struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	TokenHash string    `json:"-"`
+	TwoFactor bool      `json:"two_factor"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Email     string    `json:"-"`
+	Name      string    `json:"-"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
+
+// TwoFactor ключ TOTP пользователя. EnabledAt пуст, пока подключение не подтверждено кодом.
+type TwoFactor struct {
+	UserID       int        `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at,omitempty"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.EnabledAt != nil
+}
+
+// TwoFactorStatus состояние двухфакторной аутентификации для личного кабинета
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup ключ для приложения-аутентификатора: вручную или через QR-код с URI
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorCodeRequest код из приложения или код восстановления
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r TwoFactorCodeRequest) Validate(v *validation.Validator) {
+	if v.Required("code", r.Code) {
+		v.MaxLen("code", r.Code, 32)
+	}
+}
+
+// TwoFactorLoginRequest второй шаг входа: challenge из ответа на /api/login и код
+type TwoFactorLoginRequest struct {
+	Challenge  string `json:"challenge"`
+	Code       string `json:"code"`
+	RememberMe bool   `json:"rememberMe"`
+}
+
+func (r TwoFactorLoginRequest) Validate(v *validation.Validator) {
+	v.Required("challenge", r.Challenge)
+	if v.Required("code", r.Code) {
+		v.MaxLen("code", r.Code, 32)
+	}
+}
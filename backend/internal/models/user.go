@@ -1,16 +1,30 @@
 package models
 
-import "time"
+import (
+	"beladonna/backend/internal/validation"
+	"time"
+)
 
+// Роли пользователей
+const (
+	RoleCustomer = "customer"
+	RoleManager  = "manager"
+	RoleAdmin    = "admin"
+)
+
+// User пользователь. Phone хранится в E.164; PhoneVerifiedAt задан, если номер
+// подтвержден кодом из SMS и по нему можно входить.
 type User struct {
-	ID           int       `json:"id"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	Phone        string    `json:"phone,omitempty"`
-	Newsletter   bool      `json:"newsletter"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              int        `json:"id"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	Phone           string     `json:"phone,omitempty"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty"`
+	Newsletter      bool       `json:"newsletter"`
+	Role            string     `json:"role"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
 type RegisterRequest struct {
@@ -23,15 +37,52 @@ type RegisterRequest struct {
 	AgreeTerms bool   `json:"agreeTerms"`
 }
 
+func (r RegisterRequest) Validate(v *validation.Validator) {
+	if v.Required("email", r.Email) && v.MaxLen("email", r.Email, validation.MaxEmailLen) {
+		v.Email("email", r.Email)
+	}
+	if v.Required("password", r.Password) {
+		v.Password("password", r.Password)
+	}
+	if v.Required("firstName", r.FirstName) {
+		v.MaxLen("firstName", r.FirstName, validation.MaxNameLen)
+	}
+	if v.Required("lastName", r.LastName) {
+		v.MaxLen("lastName", r.LastName, validation.MaxNameLen)
+	}
+	if r.Phone != "" {
+		v.Phone("phone", r.Phone)
+	}
+	v.Accepted("agreeTerms", r.AgreeTerms)
+}
+
 type LoginRequest struct {
 	Email      string `json:"email"`
 	Password   string `json:"password"`
 	RememberMe bool   `json:"rememberMe"`
 }
 
+// Validate проверяет только заполненность и размер: формат пароля при входе
+// не раскрываем, чтобы не подсказывать политику перебору
+func (r LoginRequest) Validate(v *validation.Validator) {
+	if v.Required("email", r.Email) {
+		v.MaxLen("email", r.Email, validation.MaxEmailLen)
+	}
+	if v.Required("password", r.Password) {
+		v.Check(len(r.Password) <= validation.MaxPasswordBytes, "password", "password_too_long", validation.MaxPasswordBytes)
+	}
+}
+
+// AuthResponse результат входа. Если TwoFactorRequired, сессия еще не создана:
+// клиент отправляет Challenge с кодом на /api/login/2fa.
+// TwoFactorSetupRequired — для роли пользователя 2FA обязательна, но не подключена.
 type AuthResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	UserID  int    `json:"user_id,omitempty"`
-	Name    string `json:"name,omitempty"`
+	Success                bool   `json:"success"`
+	Message                string `json:"message"`
+	UserID                 int    `json:"user_id,omitempty"`
+	Email                  string `json:"email,omitempty"`
+	Name                   string `json:"name,omitempty"`
+	TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`
+	Challenge              string `json:"challenge,omitempty"`
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"`
 }
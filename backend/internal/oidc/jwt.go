@@ -0,0 +1,257 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken ID-токен не прошел проверку
+var ErrInvalidToken = errors.New("oidc: invalid id token")
+
+const (
+	// clockSkew допустимое расхождение часов с провайдером
+	clockSkew = time.Minute
+	// minRefetchInterval как часто можно перезагружать JWKS из-за незнакомого kid
+	minRefetchInterval = time.Minute
+)
+
+// JSONWebKey открытый ключ RSA из JWKS (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func (k JSONWebKey) rsaKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("unsupported exponent")
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	if pub.N.BitLen() < 2048 {
+		return nil, errors.New("key is shorter than 2048 bits")
+	}
+	return pub, nil
+}
+
+// Verifier проверяет ID-токены одного издателя для одного client_id.
+// Принимается только RS256: токены без подписи и с HMAC отклоняются.
+type Verifier struct {
+	issuer   string
+	clientID string
+	fetch    func(ctx context.Context) ([]JSONWebKey, error)
+	now      func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier создает проверку; fetch загружает актуальные ключи провайдера
+func NewVerifier(issuer, clientID string, fetch func(ctx context.Context) ([]JSONWebKey, error)) *Verifier {
+	return &Verifier{issuer: issuer, clientID: clientID, fetch: fetch, now: time.Now}
+}
+
+// claims утверждения ID-токена, которые нужны для входа
+type claims struct {
+	Issuer        string      `json:"iss"`
+	Subject       string      `json:"sub"`
+	Audience      audience    `json:"aud"`
+	AuthorizedBy  string      `json:"azp"`
+	Expiry        json.Number `json:"exp"`
+	IssuedAt      json.Number `json:"iat"`
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified flag        `json:"email_verified"`
+	Name          string      `json:"name"`
+	GivenName     string      `json:"given_name"`
+	FamilyName    string      `json:"family_name"`
+}
+
+// audience aud бывает строкой или массивом строк
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// flag email_verified: некоторые провайдеры передают его строкой "true"
+type flag bool
+
+func (f *flag) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "true", `"true"`:
+		*f = true
+	default:
+		*f = false
+	}
+	return nil
+}
+
+// Verify проверяет подпись, издателя, получателя и срок действия токена
+func (v *Verifier) Verify(ctx context.Context, raw string) (*IDToken, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	return v.check(c)
+}
+
+// check проверяет утверждения по OpenID Connect Core, 3.1.3.7
+func (v *Verifier) check(c claims) (*IDToken, error) {
+	if c.Issuer != v.issuer {
+		return nil, fmt.Errorf("%w: issuer %q", ErrInvalidToken, c.Issuer)
+	}
+	if c.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	if !slices.Contains(c.Audience, v.clientID) {
+		return nil, fmt.Errorf("%w: audience %v", ErrInvalidToken, []string(c.Audience))
+	}
+	if len(c.Audience) > 1 && c.AuthorizedBy != v.clientID {
+		return nil, fmt.Errorf("%w: azp %q", ErrInvalidToken, c.AuthorizedBy)
+	}
+
+	exp, err := c.Expiry.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%w: no exp", ErrInvalidToken)
+	}
+	iat, _ := c.IssuedAt.Float64()
+	now := v.now()
+	expiry, issuedAt := time.Unix(int64(exp), 0), time.Unix(int64(iat), 0)
+	if !now.Before(expiry.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: expired at %s", ErrInvalidToken, expiry.UTC().Format(time.RFC3339))
+	}
+	if issuedAt.After(now.Add(clockSkew)) {
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	}
+
+	return &IDToken{
+		Issuer:        c.Issuer,
+		Subject:       c.Subject,
+		Audience:      c.Audience,
+		Expiry:        expiry,
+		IssuedAt:      issuedAt,
+		Nonce:         c.Nonce,
+		Email:         c.Email,
+		EmailVerified: bool(c.EmailVerified),
+		Name:          c.Name,
+		GivenName:     c.GivenName,
+		FamilyName:    c.FamilyName,
+	}, nil
+}
+
+// key открытый ключ по kid. Незнакомый kid означает, что провайдер сменил ключи:
+// JWKS перезагружается, но не чаще minRefetchInterval.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key := v.lookup(kid); key != nil {
+		return key, nil
+	}
+	if v.keys != nil && v.now().Sub(v.fetchedAt) < minRefetchInterval {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+	}
+
+	jwks, err := v.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = make(map[string]*rsa.PublicKey, len(jwks))
+	v.fetchedAt = v.now()
+	for _, k := range jwks {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		if pub, err := k.rsaKey(); err == nil {
+			v.keys[k.Kid] = pub
+		}
+	}
+
+	if key := v.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// lookup ключ по kid; токен без kid подходит, только если ключ у провайдера один
+func (v *Verifier) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+	return v.keys[kid]
+}
+
+func decodeSegment(seg string, dst any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	return dec.Decode(dst)
+}
@@ -0,0 +1,255 @@
+// Package oidc вход через внешних провайдеров OpenID Connect: поток authorization code
+// с PKCE (S256) и проверкой подписи ID-токена RS256 по ключам JWKS провайдера.
+// Адреса провайдера берутся из документа /.well-known/openid-configuration.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrProvider провайдер вернул ошибку или некорректный ответ
+var ErrProvider = errors.New("oidc: provider error")
+
+// maxResponseSize ограничение на размер ответов провайдера
+const maxResponseSize = 1 << 20
+
+// Provider внешний провайдер входа. Name используется в адресах и в таблице привязок.
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// AuthCodeURL адрес страницы входа у провайдера
+	AuthCodeURL(ctx context.Context, req AuthRequest) (string, error)
+	// Exchange обменивает код на токены и возвращает проверенный ID-токен.
+	// Nonce сверяет вызывающая сторона.
+	Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*IDToken, error)
+}
+
+// AuthRequest параметры перехода на страницу входа провайдера
+type AuthRequest struct {
+	State         string
+	Nonce         string
+	CodeChallenge string
+	RedirectURI   string
+}
+
+// IDToken проверенные утверждения ID-токена
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Expiry        time.Time
+	IssuedAt      time.Time
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+}
+
+// Config настройки клиента у провайдера
+type Config struct {
+	Name         string
+	DisplayName  string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// Client провайдер OpenID Connect по стандарту: discovery, код авторизации и JWKS
+type Client struct {
+	cfg      Config
+	http     *http.Client
+	verifier *Verifier
+
+	mu   sync.Mutex
+	meta *metadata
+}
+
+// metadata нужная часть документа discovery
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewClient создает клиента; httpClient nil — клиент с таймаутом 10 секунд
+func NewClient(cfg Config, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	c := &Client{cfg: cfg, http: httpClient}
+	c.verifier = NewVerifier(cfg.Issuer, cfg.ClientID, c.fetchKeys)
+	return c
+}
+
+func (c *Client) Name() string { return c.cfg.Name }
+
+func (c *Client) DisplayName() string {
+	if c.cfg.DisplayName == "" {
+		return c.cfg.Name
+	}
+	return c.cfg.DisplayName
+}
+
+func (c *Client) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {req.RedirectURI},
+		"scope":                 {strings.Join(c.cfg.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {req.CodeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (*IDToken, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {c.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		// client_secret_basic: идентификатор и секрет кодируются как form-urlencoded (RFC 6749, 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := c.doJSON(req, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("%w: token endpoint %d %s %s", ErrProvider, status, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in token response", ErrProvider)
+	}
+	return c.verifier.Verify(ctx, body.IDToken)
+}
+
+// discover загружает документ discovery один раз; при ошибке попытка повторится со следующим входом
+func (c *Client) discover(ctx context.Context) (*metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.meta != nil {
+		return c.meta, nil
+	}
+
+	wellKnown := strings.TrimSuffix(c.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	var meta metadata
+	status, err := c.doJSON(req, &meta)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: discovery %d", ErrProvider, status)
+	}
+	// Документ должен описывать того же издателя, что и в настройках (OpenID Connect Discovery, 4.3)
+	if meta.Issuer != c.cfg.Issuer {
+		return nil, fmt.Errorf("%w: discovery issuer %q, want %q", ErrProvider, meta.Issuer, c.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: incomplete discovery document", ErrProvider)
+	}
+	c.meta = &meta
+	return c.meta, nil
+}
+
+// fetchKeys загружает открытые ключи провайдера
+func (c *Client) fetchKeys(ctx context.Context) ([]JSONWebKey, error) {
+	meta, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, meta.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	status, err := c.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("%w: jwks %d", ErrProvider, status)
+	}
+	return set.Keys, nil
+}
+
+// doJSON выполняет запрос и разбирает JSON-ответ независимо от статуса
+func (c *Client) doJSON(req *http.Request, dst any) (int, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrProvider, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(dst); err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: %s: %v", ErrProvider, req.URL.Path, err)
+	}
+	return resp.StatusCode, nil
+}
+
+// NewCodeVerifier случайный code_verifier для PKCE (RFC 7636): 43 символа base64url
+func NewCodeVerifier() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CodeChallenge code_challenge метода S256 для verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
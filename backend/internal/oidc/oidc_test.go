@@ -0,0 +1,168 @@
+package oidc_test
+
+import (
+	"beladonna/backend/internal/oidc"
+	"beladonna/backend/internal/oidc/oidctest"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const redirectURI = "http://app.test/api/auth/oidc/test/callback"
+
+// authorize проходит страницу входа поддельного провайдера и возвращает код
+func authorize(t *testing.T, c *oidc.Client, verifier, nonce string) string {
+	t.Helper()
+	authURL, err := c.AuthCodeURL(context.Background(), oidc.AuthRequest{
+		State:         "state-1",
+		Nonce:         nonce,
+		CodeChallenge: oidc.CodeChallenge(verifier),
+		RedirectURI:   redirectURI,
+	})
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize status %d", resp.StatusCode)
+	}
+	loc, _ := url.Parse(resp.Header.Get("Location"))
+	if loc.Query().Get("state") != "state-1" {
+		t.Fatalf("state not echoed: %s", loc)
+	}
+	return loc.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	srv := oidctest.NewServer("app", "s3cret/+=")
+	defer srv.Close()
+	srv.SetUser(oidctest.User{Subject: "u-1", Email: "anna@example.com", EmailVerified: true, GivenName: "Анна"})
+
+	c := oidc.NewClient(srv.Config("test"), nil)
+	verifier := oidc.NewCodeVerifier()
+	code := authorize(t, c, verifier, "nonce-1")
+
+	tok, err := c.Exchange(context.Background(), code, verifier, redirectURI)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if tok.Subject != "u-1" || tok.Nonce != "nonce-1" || tok.Email != "anna@example.com" || !tok.EmailVerified || tok.GivenName != "Анна" {
+		t.Fatalf("token = %+v", tok)
+	}
+
+	// Код одноразовый
+	if _, err := c.Exchange(context.Background(), code, verifier, redirectURI); !errors.Is(err, oidc.ErrProvider) {
+		t.Fatalf("code reuse: %v", err)
+	}
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	srv := oidctest.NewServer("app", "secret")
+	defer srv.Close()
+	srv.SetUser(oidctest.User{Subject: "u-1"})
+
+	c := oidc.NewClient(srv.Config("test"), nil)
+	code := authorize(t, c, oidc.NewCodeVerifier(), "n")
+	if _, err := c.Exchange(context.Background(), code, oidc.NewCodeVerifier(), redirectURI); !errors.Is(err, oidc.ErrProvider) {
+		t.Fatalf("wrong verifier: %v", err)
+	}
+}
+
+func TestExchangeRejectsBadTokens(t *testing.T) {
+	srv := oidctest.NewServer("app", "secret")
+	defer srv.Close()
+	srv.SetUser(oidctest.User{Subject: "u-1"})
+
+	tests := []struct {
+		name string
+		hook func(claims map[string]any)
+	}{
+		{"wrong audience", func(c map[string]any) { c["aud"] = "other-app" }},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example" }},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-5 * time.Minute).Unix() }},
+		{"issued in future", func(c map[string]any) { c["iat"] = time.Now().Add(time.Hour).Unix() }},
+		{"no subject", func(c map[string]any) { delete(c, "sub") }},
+		{"foreign azp", func(c map[string]any) { c["aud"] = []string{"app", "other"}; c["azp"] = "other" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.TokenHook = tt.hook
+			c := oidc.NewClient(srv.Config("test"), nil)
+			verifier := oidc.NewCodeVerifier()
+			code := authorize(t, c, verifier, "n")
+			if _, err := c.Exchange(context.Background(), code, verifier, redirectURI); !errors.Is(err, oidc.ErrInvalidToken) {
+				t.Fatalf("err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsForgedTokens(t *testing.T) {
+	srv := oidctest.NewServer("app", "secret")
+	defer srv.Close()
+	other := oidctest.NewServer("app", "secret")
+	defer other.Close()
+
+	fetch := func(ctx context.Context) ([]oidc.JSONWebKey, error) {
+		return jwks(t, srv.Issuer)
+	}
+	v := oidc.NewVerifier(srv.Issuer, "app", fetch)
+	claims := srv.Claims(oidctest.User{Subject: "u-1"}, "n")
+
+	if _, err := v.Verify(context.Background(), srv.Sign(claims)); err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+
+	valid := strings.Split(srv.Sign(claims), ".")
+	forged := map[string]string{
+		// Подпись чужим ключом с тем же kid
+		"foreign key": other.Sign(claims),
+		// Заголовок alg none без подписи
+		"alg none": "eyJhbGciOiJub25lIiwia2lkIjoidGVzdC1rZXkifQ." + valid[1] + ".",
+		// HS256 с открытым ключом в роли секрета
+		"alg HS256": "eyJhbGciOiJIUzI1NiIsImtpZCI6InRlc3Qta2V5In0." + valid[1] + "." + valid[2],
+		// Подмененные утверждения при старой подписи
+		"tampered payload": valid[0] + "." + strings.Split(srv.Sign(srv.Claims(oidctest.User{Subject: "admin"}, "n")), ".")[1] + "." + valid[2],
+		"malformed":        "abc.def",
+	}
+	for name, raw := range forged {
+		if _, err := v.Verify(context.Background(), raw); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidToken", name, err)
+		}
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	srv := oidctest.NewServer("app", "secret")
+	defer srv.Close()
+
+	cfg := srv.Config("test")
+	cfg.Issuer = strings.Replace(srv.Issuer, "127.0.0.1", "localhost", 1)
+	c := oidc.NewClient(cfg, nil)
+	if _, err := c.AuthCodeURL(context.Background(), oidc.AuthRequest{State: "s"}); !errors.Is(err, oidc.ErrProvider) {
+		t.Fatalf("err = %v, want ErrProvider", err)
+	}
+}
+
+func jwks(t *testing.T, issuer string) ([]oidc.JSONWebKey, error) {
+	t.Helper()
+	resp, err := http.Get(issuer + "/jwks")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var set struct {
+		Keys []oidc.JSONWebKey `json:"keys"`
+	}
+	return set.Keys, json.NewDecoder(resp.Body).Decode(&set)
+}
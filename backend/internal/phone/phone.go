@@ -0,0 +1,73 @@
+// Package phone приводит телефонные номера к формату E.164 (+79991234567).
+// Понимает привычные российские записи: 8 (999) 123-45-67, +7 999 123 45 67,
+// 9991234567, а также международный выход через 810.
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrInvalid номер не удалось разобрать
+var ErrInvalid = errors.New("phone: invalid number")
+
+// Длины по E.164: код страны и номер вместе — не больше 15 цифр
+const (
+	minDigits = 8
+	maxDigits = 15
+	// MaxLen длина нормализованного номера вместе с «+»
+	MaxLen = maxDigits + 1
+)
+
+// Normalize возвращает номер в формате E.164. Пробелы, дефисы, точки и скобки
+// игнорируются. Номер без «+» считается российским.
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	plus := strings.HasPrefix(raw, "+")
+	if plus {
+		raw = raw[1:]
+	}
+
+	digits := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c >= '0' && c <= '9':
+			digits = append(digits, c)
+		case c == ' ' || c == '-' || c == '(' || c == ')' || c == '.':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	number := string(digits)
+
+	if !plus {
+		switch {
+		// 810 — выход на международную линию с российского телефона
+		case strings.HasPrefix(number, "810") && len(number) > 11:
+			number = number[3:]
+		// 8 или 7 — код выхода на междугороднюю линию или код страны
+		case len(number) == 11 && (number[0] == '8' || number[0] == '7'):
+			number = "7" + number[1:]
+		// Номер без кода страны: 999 123-45-67
+		case len(number) == 10:
+			number = "7" + number
+		default:
+			return "", ErrInvalid
+		}
+	}
+
+	if len(number) < minDigits || len(number) > maxDigits || number[0] == '0' {
+		return "", ErrInvalid
+	}
+	// У номеров зоны +7 ровно 10 цифр после кода страны, и они не начинаются с 0
+	if number[0] == '7' && (len(number) != 11 || number[1] == '0') {
+		return "", ErrInvalid
+	}
+	return "+" + number, nil
+}
+
+// Valid сообщает, можно ли нормализовать номер
+func Valid(raw string) bool {
+	_, err := Normalize(raw)
+	return err == nil
+}
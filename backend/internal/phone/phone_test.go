@@ -0,0 +1,48 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"+79991234567", "+79991234567"},
+		{"+7 (999) 123-45-67", "+79991234567"},
+		{"8 999 123 45 67", "+79991234567"},
+		{"89991234567", "+79991234567"},
+		{"79991234567", "+79991234567"},
+		{"(999) 123-45-67", "+79991234567"},
+		{"8 (495) 123-45-67", "+74951234567"},
+		{"8-10-49-30-1234567", "+49301234567"},
+		{"+375 29 123-45-67", "+375291234567"},
+		{" +7.999.123.45.67 ", "+79991234567"},
+	}
+	for _, tt := range tests {
+		got, err := Normalize(tt.raw)
+		if err != nil || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", tt.raw, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	for _, raw := range []string{
+		"",
+		"+",
+		"12345",
+		"999-12-34",
+		"+7 999 123 45 6",
+		"+7 999 123 45 678",
+		"+7 099 123 45 67",
+		"+0 999 123 45 67",
+		"+1234567890123456",
+		"8 999 123 45 67 доб. 12",
+		"+7 999 123 45 67+",
+		"99912345678",
+	} {
+		if got, err := Normalize(raw); err == nil {
+			t.Errorf("Normalize(%q) = %q, want error", raw, got)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+)
+
+// AccountDeletionRepository журнал удаленных аккаунтов
+type AccountDeletionRepository struct {
+	db *DB
+}
+
+func NewAccountDeletionRepository(db *DB) *AccountDeletionRepository {
+	return &AccountDeletionRepository{db: db}
+}
+
+func (r *AccountDeletionRepository) CreateAccountDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO account_deletions (user_id, email_hash, role, feedbacks_anonymized, ip)
+              VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+              RETURNING id, deleted_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		deletion.UserID,
+		deletion.EmailHash,
+		deletion.Role,
+		deletion.FeedbacksAnonymized,
+		deletion.IP,
+	).Scan(&deletion.ID, &deletion.DeletedAt)
+}
@@ -2,18 +2,22 @@ package repository
 
 import (
 	"beladonna/backend/internal/models"
+	"context"
 	"database/sql"
 )
 
 type CartRepository struct {
-	db *sql.DB
+	db *DB
 }
 
-func NewCartRepository(db *sql.DB) *CartRepository {
+func NewCartRepository(db *DB) *CartRepository {
 	return &CartRepository{db: db}
 }
 
-func (r *CartRepository) GetCartItems(userID int) ([]models.CartItem, error) {
+func (r *CartRepository) GetCartItems(ctx context.Context, userID int) ([]models.CartItem, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         SELECT ci.id, ci.user_id, ci.product_id, ci.quantity, 
                p.name as product_name, p.price, p.image_url, ci.added_at
@@ -23,7 +27,7 @@ func (r *CartRepository) GetCartItems(userID int) ([]models.CartItem, error) {
         ORDER BY ci.added_at DESC
     `
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,51 +49,71 @@ func (r *CartRepository) GetCartItems(userID int) ([]models.CartItem, error) {
 	return items, nil
 }
 
-func (r *CartRepository) AddToCart(userID, productID, quantity int) error {
+// AddToCart добавляет товар или увеличивает его количество, но не выше maxQuantity
+func (r *CartRepository) AddToCart(ctx context.Context, userID, productID, quantity, maxQuantity int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         INSERT INTO cart_items (user_id, product_id, quantity) 
         VALUES ($1, $2, $3)
         ON CONFLICT (user_id, product_id) 
-        DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity
+        DO UPDATE SET quantity = LEAST(cart_items.quantity + EXCLUDED.quantity, $4)
     `
 
-	_, err := r.db.Exec(query, userID, productID, quantity)
+	_, err := r.db.ExecContext(ctx, query, userID, productID, quantity, maxQuantity)
 	return err
 }
 
-func (r *CartRepository) UpdateCartItem(itemID, quantity int) error {
+func (r *CartRepository) UpdateCartItem(ctx context.Context, itemID, quantity int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	if quantity <= 0 {
-		return r.RemoveFromCart(itemID)
+		return r.RemoveFromCart(ctx, itemID)
 	}
 
 	query := `UPDATE cart_items SET quantity = $1 WHERE id = $2`
-	_, err := r.db.Exec(query, quantity, itemID)
+	_, err := r.db.ExecContext(ctx, query, quantity, itemID)
 	return err
 }
 
-func (r *CartRepository) RemoveFromCart(itemID int) error {
+func (r *CartRepository) RemoveFromCart(ctx context.Context, itemID int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM cart_items WHERE id = $1`
-	_, err := r.db.Exec(query, itemID)
+	_, err := r.db.ExecContext(ctx, query, itemID)
 	return err
 }
 
-func (r *CartRepository) ClearUserCart(userID int) error {
+func (r *CartRepository) ClearUserCart(ctx context.Context, userID int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `DELETE FROM cart_items WHERE user_id = $1`
-	_, err := r.db.Exec(query, userID)
+	_, err := r.db.ExecContext(ctx, query, userID)
 	return err
 }
 
-func (r *CartRepository) GetCartItemByID(itemID int) (*models.CartItem, error) {
+// GetCartItemByID возвращает позицию корзины по ID или nil, если такой нет
+func (r *CartRepository) GetCartItemByID(ctx context.Context, itemID int) (*models.CartItem, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         SELECT id, user_id, product_id, quantity, added_at
         FROM cart_items 
         WHERE id = $1
     `
 
-	row := r.db.QueryRow(query, itemID)
+	row := r.db.QueryRowContext(ctx, query, itemID)
 
 	var item models.CartItem
 	err := row.Scan(&item.ID, &item.UserID, &item.ProductID, &item.Quantity, &item.AddedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+// ConsentRepository версии документов и согласия пользователей
+type ConsentRepository struct {
+	db *DB
+}
+
+func NewConsentRepository(db *DB) *ConsentRepository {
+	return &ConsentRepository{db: db}
+}
+
+func (r *ConsentRepository) CreatePolicyDocument(ctx context.Context, doc *models.PolicyDocument) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO policy_documents (kind, version, url, sha256)
+              VALUES ($1, $2, $3, $4)
+              RETURNING id, published_at`
+	return r.db.QueryRowContext(ctx, query, doc.Kind, doc.Version, doc.URL, doc.SHA256).
+		Scan(&doc.ID, &doc.PublishedAt)
+}
+
+// GetLatestPolicyDocument последняя версия документа или nil, если он не публиковался
+func (r *ConsentRepository) GetLatestPolicyDocument(ctx context.Context, kind string) (*models.PolicyDocument, error) {
+	return r.getPolicyDocument(ctx, `WHERE kind = $1 ORDER BY version DESC LIMIT 1`, kind)
+}
+
+func (r *ConsentRepository) GetPolicyDocumentByID(ctx context.Context, id int) (*models.PolicyDocument, error) {
+	return r.getPolicyDocument(ctx, `WHERE id = $1`, id)
+}
+
+func (r *ConsentRepository) getPolicyDocument(ctx context.Context, where string, arg any) (*models.PolicyDocument, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var d models.PolicyDocument
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, kind, version, url, sha256, published_at FROM policy_documents `+where, arg,
+	).Scan(&d.ID, &d.Kind, &d.Version, &d.URL, &d.SHA256, &d.PublishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CreateConsent записывает согласие; повторное согласие с той же версией не меняет
+// исходную запись, а consent получает ее ID и время
+func (r *ConsentRepository) CreateConsent(ctx context.Context, consent *models.Consent) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `WITH inserted AS (
+                  INSERT INTO user_consents (user_id, document_id, ip, user_agent)
+                  VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+                  ON CONFLICT (user_id, document_id) DO NOTHING
+                  RETURNING id, accepted_at
+              )
+              SELECT id, accepted_at FROM inserted
+              UNION ALL
+              SELECT id, accepted_at FROM user_consents WHERE user_id = $1 AND document_id = $2
+              LIMIT 1`
+	return r.db.QueryRowContext(ctx,
+		query,
+		consent.UserID,
+		consent.DocumentID,
+		consent.IP,
+		consent.UserAgent,
+	).Scan(&consent.ID, &consent.AcceptedAt)
+}
+
+// GetUserConsents согласия пользователя от новых к старым
+func (r *ConsentRepository) GetUserConsents(ctx context.Context, userID int) ([]models.Consent, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT c.id, c.user_id, c.document_id, COALESCE(c.ip, ''), COALESCE(c.user_agent, ''),
+                     c.accepted_at, d.kind, d.version
+              FROM user_consents c
+              JOIN policy_documents d ON d.id = c.document_id
+              WHERE c.user_id = $1
+              ORDER BY c.accepted_at DESC, c.id DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []models.Consent{}
+	for rows.Next() {
+		var c models.Consent
+		if err := rows.Scan(&c.ID, &c.UserID, &c.DocumentID, &c.IP, &c.UserAgent, &c.AcceptedAt, &c.Kind, &c.Version); err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+func (r *ConsentRepository) CreateCookieConsent(ctx context.Context, consent *models.CookieConsent) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO cookie_consents (consent_id, user_id, status, document_id, ip, user_agent)
+              VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		consent.ConsentID,
+		consent.UserID,
+		consent.Status,
+		consent.DocumentID,
+		consent.IP,
+		consent.UserAgent,
+	).Scan(&consent.ID, &consent.CreatedAt)
+}
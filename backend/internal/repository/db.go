@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DB пул соединений, общий для репозиториев, с ограничением времени одного запроса
+type DB struct {
+	*sql.DB
+	queryTimeout time.Duration
+}
+
+// NewDB оборачивает пул; queryTimeout <= 0 отключает ограничение
+func NewDB(db *sql.DB, queryTimeout time.Duration) *DB {
+	return &DB{DB: db, queryTimeout: queryTimeout}
+}
+
+// withTimeout ограничивает контекст временем одного запроса.
+// Если у ctx уже есть более ранний дедлайн (например, клиент ждет меньше), действует он.
+func (d *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.queryTimeout)
+}
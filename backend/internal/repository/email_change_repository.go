@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// EmailChangeRepository запросы на смену email, ожидающие подтверждения
+type EmailChangeRepository struct {
+	db *DB
+}
+
+func NewEmailChangeRepository(db *DB) *EmailChangeRepository {
+	return &EmailChangeRepository{db: db}
+}
+
+func (r *EmailChangeRepository) CreateEmailChange(ctx context.Context, change *models.EmailChange) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+              VALUES ($1, $2, $3, $4)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		change.UserID,
+		change.NewEmail,
+		change.TokenHash,
+		change.ExpiresAt.UTC(),
+	).Scan(&change.ID, &change.CreatedAt)
+}
+
+// GetEmailChangeByTokenHash возвращает запрос по хешу токена из письма или nil
+func (r *EmailChangeRepository) GetEmailChangeByTokenHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, new_email, token_hash, expires_at, confirmed_at, created_at
+              FROM email_changes WHERE token_hash = $1`
+
+	var c models.EmailChange
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.NewEmail,
+		&c.TokenHash,
+		&c.ExpiresAt,
+		&c.ConfirmedAt,
+		&c.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *EmailChangeRepository) MarkEmailChangeConfirmed(ctx context.Context, id int, at time.Time) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE email_changes SET confirmed_at = $2 WHERE id = $1`, id, at.UTC())
+	return err
+}
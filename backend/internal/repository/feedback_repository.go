@@ -1,43 +1,63 @@
 package repository
 
 import (
+    "context"
     "database/sql"
     "time"
     "beladonna/backend/internal/models"
+
+    "github.com/lib/pq"
 )
 
 type FeedbackRepository struct {
-    DB *sql.DB
+    DB *DB
 }
 
-func NewFeedbackRepository(db *sql.DB) *FeedbackRepository {
+func NewFeedbackRepository(db *DB) *FeedbackRepository {
     return &FeedbackRepository{DB: db}
 }
 
-func (r *FeedbackRepository) CreateFeedback(feedback *models.Feedback) error {
-    query := `INSERT INTO feedbacks (name, email, theme, message, created_at, is_visible) 
-              VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+// CreateFeedback сохраняет отзыв в статусе ожидания модерации
+func (r *FeedbackRepository) CreateFeedback(ctx context.Context, feedback *models.Feedback) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := `INSERT INTO feedbacks (user_id, name, email, theme, message, created_at, is_visible, moderation_status) 
+              VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, status`
     
-    err := r.DB.QueryRow(
+    err := r.DB.QueryRowContext(ctx,
         query,
+        feedback.UserID,
         feedback.Name,
         feedback.Email,
         feedback.Theme,
         feedback.Message,
         time.Now(),
-        true,
-    ).Scan(&feedback.ID)
-    
-    return err
+        false,
+        models.ModerationPending,
+    ).Scan(&feedback.ID, &feedback.CreatedAt, &feedback.Status)
+    if err != nil {
+        return err
+    }
+
+    feedback.IsVisible = false
+    feedback.ModerationStatus = models.ModerationPending
+    return nil
 }
 
-func (r *FeedbackRepository) GetVisibleFeedbacks() ([]models.Feedback, error) {
-    query := `SELECT id, name, email, theme, message, created_at 
-              FROM feedbacks 
-              WHERE is_visible = true 
-              ORDER BY created_at DESC`
+// GetVisibleFeedbacks возвращает опубликованные отзывы, непустой theme ограничивает выборку темой.
+// Email автора не выбирается, чтобы не попасть в публичную выдачу.
+func (r *FeedbackRepository) GetVisibleFeedbacks(ctx context.Context, theme string) ([]models.Feedback, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := `SELECT f.id, f.name, COALESCE(f.theme, ''), COALESCE(t.title, ''), f.message, f.created_at, f.status 
+              FROM feedbacks f
+              LEFT JOIN feedback_themes t ON t.code = f.theme
+              WHERE f.is_visible = true AND ($1 = '' OR f.theme = $1)
+              ORDER BY f.created_at DESC`
     
-    rows, err := r.DB.Query(query)
+    rows, err := r.DB.QueryContext(ctx, query, theme)
     if err != nil {
         return nil, err
     }
@@ -49,10 +69,11 @@ func (r *FeedbackRepository) GetVisibleFeedbacks() ([]models.Feedback, error) {
         err := rows.Scan(
             &feedback.ID,
             &feedback.Name,
-            &feedback.Email,
             &feedback.Theme,
+            &feedback.ThemeTitle,
             &feedback.Message,
             &feedback.CreatedAt,
+            &feedback.Status,
         )
         if err != nil {
             return nil, err
@@ -60,5 +81,331 @@ func (r *FeedbackRepository) GetVisibleFeedbacks() ([]models.Feedback, error) {
         feedbacks = append(feedbacks, feedback)
     }
     
-    return feedbacks, nil
-}
\ No newline at end of file
+    return feedbacks, rows.Err()
+}
+
+// CreateReply сохраняет ответ сотрудника и переводит переписку в статус answered
+func (r *FeedbackRepository) CreateReply(ctx context.Context, reply *models.FeedbackReply) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    return r.DB.inTx(ctx, func(ctx context.Context) error {
+        query := `INSERT INTO feedback_replies (feedback_id, author_id, message, is_public) 
+                  VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+        err := r.DB.QueryRowContext(ctx, query, reply.FeedbackID, reply.AuthorID, reply.Message, reply.IsPublic).
+            Scan(&reply.ID, &reply.CreatedAt)
+        if err != nil {
+            return err
+        }
+
+        _, err = r.DB.ExecContext(ctx, `UPDATE feedbacks SET status = $1 WHERE id = $2`, models.FeedbackAnswered, reply.FeedbackID)
+        return err
+    })
+}
+
+// MarkReplyEmailed отмечает, что приватный ответ отправлен автору отзыва
+func (r *FeedbackRepository) MarkReplyEmailed(ctx context.Context, replyID int) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    _, err := r.DB.ExecContext(ctx, `UPDATE feedback_replies SET emailed_at = NOW() WHERE id = $1`, replyID)
+    return err
+}
+
+// SetStatus меняет статус переписки
+func (r *FeedbackRepository) SetStatus(ctx context.Context, feedbackID int, status string) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    _, err := r.DB.ExecContext(ctx, `UPDATE feedbacks SET status = $1 WHERE id = $2`, status, feedbackID)
+    return err
+}
+
+// GetReplies возвращает ответы на отзывы. Если publicOnly, только публичные.
+// Результат сгруппирован по feedback_id.
+func (r *FeedbackRepository) GetReplies(ctx context.Context, feedbackIDs []int, publicOnly bool) (map[int][]models.FeedbackReply, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    replies := make(map[int][]models.FeedbackReply)
+    if len(feedbackIDs) == 0 {
+        return replies, nil
+    }
+
+    query := `SELECT fr.id, fr.feedback_id, COALESCE(fr.author_id, 0), COALESCE(u.first_name, ''),
+                     fr.message, fr.is_public, fr.emailed_at, fr.created_at
+              FROM feedback_replies fr
+              LEFT JOIN users u ON fr.author_id = u.id
+              WHERE fr.feedback_id = ANY($1) AND (fr.is_public OR NOT $2)
+              ORDER BY fr.created_at ASC`
+
+    rows, err := r.DB.QueryContext(ctx, query, pq.Array(feedbackIDs), publicOnly)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var reply models.FeedbackReply
+        var emailedAt sql.NullTime
+        err := rows.Scan(
+            &reply.ID,
+            &reply.FeedbackID,
+            &reply.AuthorID,
+            &reply.AuthorName,
+            &reply.Message,
+            &reply.IsPublic,
+            &emailedAt,
+            &reply.CreatedAt,
+        )
+        if err != nil {
+            return nil, err
+        }
+        if emailedAt.Valid {
+            reply.EmailedAt = &emailedAt.Time
+        }
+        replies[reply.FeedbackID] = append(replies[reply.FeedbackID], reply)
+    }
+
+    return replies, rows.Err()
+}
+
+// GetFeedbacksByStatus возвращает отзывы с указанным статусом модерации.
+// Непустой theme ограничивает выборку темой.
+func (r *FeedbackRepository) GetFeedbacksByStatus(ctx context.Context, status, theme string) ([]models.Feedback, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := moderatedFeedbackSelect + `
+              WHERE f.moderation_status = $1 AND ($2 = '' OR f.theme = $2)
+              ORDER BY f.created_at ASC`
+
+    rows, err := r.DB.QueryContext(ctx, query, status, theme)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var feedbacks []models.Feedback
+    for rows.Next() {
+        feedback, err := scanModeratedFeedback(rows)
+        if err != nil {
+            return nil, err
+        }
+        feedbacks = append(feedbacks, *feedback)
+    }
+
+    return feedbacks, rows.Err()
+}
+
+// GetFeedbacksByUser возвращает все отзывы пользователя, включая ожидающие модерации
+func (r *FeedbackRepository) GetFeedbacksByUser(ctx context.Context, userID int) ([]models.Feedback, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := moderatedFeedbackSelect + `
+              WHERE f.user_id = $1 
+              ORDER BY f.created_at DESC`
+
+    rows, err := r.DB.QueryContext(ctx, query, userID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var feedbacks []models.Feedback
+    for rows.Next() {
+        feedback, err := scanModeratedFeedback(rows)
+        if err != nil {
+            return nil, err
+        }
+        feedbacks = append(feedbacks, *feedback)
+    }
+
+    return feedbacks, rows.Err()
+}
+
+// GetFeedbackByID возвращает отзыв вместе с данными модерации
+func (r *FeedbackRepository) GetFeedbackByID(ctx context.Context, id int) (*models.Feedback, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := moderatedFeedbackSelect + `
+              WHERE f.id = $1`
+
+    feedback, err := scanModeratedFeedback(r.DB.QueryRowContext(ctx, query, id))
+    if err == sql.ErrNoRows {
+        return nil, nil
+    }
+    return feedback, err
+}
+
+// SetModerationStatus меняет статус отзыва и записывает решение в журнал
+func (r *FeedbackRepository) SetModerationStatus(ctx context.Context, id, moderatorID int, status, action, reason string) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    return r.DB.inTx(ctx, func(ctx context.Context) error {
+        query := `UPDATE feedbacks 
+                  SET moderation_status = $1, is_visible = $2, rejection_reason = NULLIF($3, ''),
+                      moderated_by = $4, moderated_at = NOW()
+                  WHERE id = $5`
+        if _, err := r.DB.ExecContext(ctx, query, status, status == models.ModerationApproved, reason, moderatorID, id); err != nil {
+            return err
+        }
+        return r.insertModerationEntry(ctx, id, moderatorID, action, reason)
+    })
+}
+
+// UpdateFeedbackContent сохраняет правки модератора и записывает их в журнал
+func (r *FeedbackRepository) UpdateFeedbackContent(ctx context.Context, feedback *models.Feedback, moderatorID int, note string) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    return r.DB.inTx(ctx, func(ctx context.Context) error {
+        query := `UPDATE feedbacks 
+                  SET name = $1, theme = $2, message = $3, moderated_by = $4, moderated_at = NOW()
+                  WHERE id = $5`
+        if _, err := r.DB.ExecContext(ctx, query, feedback.Name, feedback.Theme, feedback.Message, moderatorID, feedback.ID); err != nil {
+            return err
+        }
+        return r.insertModerationEntry(ctx, feedback.ID, moderatorID, models.ModerationActionEdit, note)
+    })
+}
+
+// GetModerationHistory возвращает журнал решений по отзыву
+func (r *FeedbackRepository) GetModerationHistory(ctx context.Context, feedbackID int) ([]models.FeedbackModerationEntry, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := `SELECT id, feedback_id, COALESCE(moderator_id, 0), action, COALESCE(reason, ''), created_at
+              FROM feedback_moderation_log
+              WHERE feedback_id = $1
+              ORDER BY created_at ASC`
+
+    rows, err := r.DB.QueryContext(ctx, query, feedbackID)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var entries []models.FeedbackModerationEntry
+    for rows.Next() {
+        var entry models.FeedbackModerationEntry
+        err := rows.Scan(&entry.ID, &entry.FeedbackID, &entry.ModeratorID, &entry.Action, &entry.Reason, &entry.CreatedAt)
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, entry)
+    }
+
+    return entries, rows.Err()
+}
+
+// HasRecentDuplicate проверяет, отправлялся ли такой же текст с этого email начиная с since
+func (r *FeedbackRepository) HasRecentDuplicate(ctx context.Context, email, message string, since time.Time) (bool, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    var exists bool
+    query := `SELECT EXISTS(
+                  SELECT 1 FROM feedbacks 
+                  WHERE lower(email) = lower($1) 
+                    AND lower(btrim(message)) = lower(btrim($2)) 
+                    AND created_at >= $3)`
+    err := r.DB.QueryRowContext(ctx, query, email, message, since).Scan(&exists)
+    return exists, err
+}
+
+// LogRejection записывает отклоненную антиспамом отправку
+func (r *FeedbackRepository) LogRejection(ctx context.Context, rejection *models.FeedbackRejection) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := `INSERT INTO feedback_rejections (user_id, ip, email, reason, message) 
+              VALUES (NULLIF($1, 0), $2, $3, $4, $5) RETURNING id, created_at`
+    return r.DB.QueryRowContext(ctx,
+        query,
+        rejection.UserID,
+        rejection.IP,
+        rejection.Email,
+        rejection.Reason,
+        rejection.Message,
+    ).Scan(&rejection.ID, &rejection.CreatedAt)
+}
+
+// AnonymizeUserFeedbacks отвязывает отзывы от удаляемого пользователя: имя заменяется на name,
+// email стирается. Отзывы без user_id, оставленные до привязки к аккаунтам, ищутся по email.
+func (r *FeedbackRepository) AnonymizeUserFeedbacks(ctx context.Context, userID int, email, name string) (int64, error) {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    query := `UPDATE feedbacks SET user_id = NULL, name = $3, email = ''
+              WHERE user_id = $1 OR (user_id IS NULL AND email = $2)`
+    result, err := r.DB.ExecContext(ctx, query, userID, email, name)
+    if err != nil {
+        return 0, err
+    }
+    return result.RowsAffected()
+}
+
+// DeleteUserRejections удаляет отклоненные антиспамом отправки пользователя
+func (r *FeedbackRepository) DeleteUserRejections(ctx context.Context, userID int, email string) error {
+    ctx, cancel := r.DB.withTimeout(ctx)
+    defer cancel()
+
+    _, err := r.DB.ExecContext(ctx, `DELETE FROM feedback_rejections WHERE user_id = $1 OR email = $2`, userID, email)
+    return err
+}
+
+// insertModerationEntry пишет в журнал модерации; вызывается в транзакции вместе с изменением отзыва
+func (r *FeedbackRepository) insertModerationEntry(ctx context.Context, feedbackID, moderatorID int, action, reason string) error {
+    query := `INSERT INTO feedback_moderation_log (feedback_id, moderator_id, action, reason) 
+              VALUES ($1, $2, $3, NULLIF($4, ''))`
+    _, err := r.DB.ExecContext(ctx, query, feedbackID, moderatorID, action, reason)
+    return err
+}
+
+const moderatedFeedbackSelect = `SELECT f.id, COALESCE(f.user_id, 0), f.name, f.email, COALESCE(f.theme, ''),
+                     COALESCE(t.title, ''), f.message, f.created_at, f.is_visible, f.moderation_status,
+                     COALESCE(f.rejection_reason, ''), f.moderated_by, f.moderated_at, f.status
+              FROM feedbacks f
+              LEFT JOIN feedback_themes t ON t.code = f.theme`
+
+type rowScanner interface {
+    Scan(dest ...interface{}) error
+}
+
+func scanModeratedFeedback(row rowScanner) (*models.Feedback, error) {
+    var feedback models.Feedback
+    var moderatedBy sql.NullInt64
+    var moderatedAt sql.NullTime
+    err := row.Scan(
+        &feedback.ID,
+        &feedback.UserID,
+        &feedback.Name,
+        &feedback.Email,
+        &feedback.Theme,
+        &feedback.ThemeTitle,
+        &feedback.Message,
+        &feedback.CreatedAt,
+        &feedback.IsVisible,
+        &feedback.ModerationStatus,
+        &feedback.RejectionReason,
+        &moderatedBy,
+        &moderatedAt,
+        &feedback.Status,
+    )
+    if err != nil {
+        return nil, err
+    }
+
+    if moderatedBy.Valid {
+        id := int(moderatedBy.Int64)
+        feedback.ModeratedBy = &id
+    }
+    if moderatedAt.Valid {
+        feedback.ModeratedAt = &moderatedAt.Time
+    }
+    return &feedback, nil
+}
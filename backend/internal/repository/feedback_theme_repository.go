@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type FeedbackThemeRepository struct {
+	db *DB
+}
+
+func NewFeedbackThemeRepository(db *DB) *FeedbackThemeRepository {
+	return &FeedbackThemeRepository{db: db}
+}
+
+const feedbackThemeColumns = `id, code, title, COALESCE(notify_email, ''), COALESCE(notify_role, ''),
+               is_active, sort_order, created_at`
+
+// GetThemes возвращает темы; если activeOnly, только доступные в форме
+func (r *FeedbackThemeRepository) GetThemes(ctx context.Context, activeOnly bool) ([]models.FeedbackTheme, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feedbackThemeColumns + `
+        FROM feedback_themes
+        WHERE is_active OR NOT $1
+        ORDER BY sort_order, title`
+
+	rows, err := r.db.QueryContext(ctx, query, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var themes []models.FeedbackTheme
+	for rows.Next() {
+		theme, err := scanFeedbackTheme(rows)
+		if err != nil {
+			return nil, err
+		}
+		themes = append(themes, *theme)
+	}
+
+	return themes, rows.Err()
+}
+
+// GetThemeByCode возвращает тему по коду или nil, если такой нет
+func (r *FeedbackThemeRepository) GetThemeByCode(ctx context.Context, code string) (*models.FeedbackTheme, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feedbackThemeColumns + ` FROM feedback_themes WHERE code = $1`
+
+	theme, err := scanFeedbackTheme(r.db.QueryRowContext(ctx, query, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return theme, err
+}
+
+// GetThemeByID возвращает тему по ID или nil, если такой нет
+func (r *FeedbackThemeRepository) GetThemeByID(ctx context.Context, id int) (*models.FeedbackTheme, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + feedbackThemeColumns + ` FROM feedback_themes WHERE id = $1`
+
+	theme, err := scanFeedbackTheme(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return theme, err
+}
+
+func (r *FeedbackThemeRepository) CreateTheme(ctx context.Context, theme *models.FeedbackTheme) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO feedback_themes (code, title, notify_email, notify_role, is_active, sort_order)
+              VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		theme.Code,
+		theme.Title,
+		theme.NotifyEmail,
+		theme.NotifyRole,
+		theme.IsActive,
+		theme.SortOrder,
+	).Scan(&theme.ID, &theme.CreatedAt)
+}
+
+func (r *FeedbackThemeRepository) UpdateTheme(ctx context.Context, theme *models.FeedbackTheme) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE feedback_themes
+              SET title = $1, notify_email = NULLIF($2, ''), notify_role = NULLIF($3, ''),
+                  is_active = $4, sort_order = $5
+              WHERE id = $6`
+	_, err := r.db.ExecContext(ctx,
+		query,
+		theme.Title,
+		theme.NotifyEmail,
+		theme.NotifyRole,
+		theme.IsActive,
+		theme.SortOrder,
+		theme.ID,
+	)
+	return err
+}
+
+func scanFeedbackTheme(row rowScanner) (*models.FeedbackTheme, error) {
+	var theme models.FeedbackTheme
+	err := row.Scan(
+		&theme.ID,
+		&theme.Code,
+		&theme.Title,
+		&theme.NotifyEmail,
+		&theme.NotifyRole,
+		&theme.IsActive,
+		&theme.SortOrder,
+		&theme.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &theme, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// IdentityRepository аккаунты OpenID Connect, привязанные к пользователям
+type IdentityRepository struct {
+	db *DB
+}
+
+func NewIdentityRepository(db *DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+func (r *IdentityRepository) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO user_identities (user_id, provider, subject, email, last_login_at)
+              VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		identity.UserID,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.LastLoginAt,
+	).Scan(&identity.ID, &identity.CreatedAt)
+}
+
+// GetIdentity привязка аккаунта subject у провайдера provider или nil
+func (r *IdentityRepository) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at
+              FROM user_identities WHERE provider = $1 AND subject = $2`
+
+	var i models.UserIdentity
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Provider,
+		&i.Subject,
+		&i.Email,
+		&i.CreatedAt,
+		&i.LastLoginAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (r *IdentityRepository) GetUserIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at, last_login_at
+              FROM user_identities WHERE user_id = $1 ORDER BY created_at, id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var i models.UserIdentity
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &i.Email, &i.CreatedAt, &i.LastLoginAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity отвязывает аккаунт; false, если у пользователя нет привязки с таким id
+func (r *IdentityRepository) DeleteIdentity(ctx context.Context, userID, id int) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM user_identities WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchIdentity отмечает вход и обновляет email из ID-токена
+func (r *IdentityRepository) TouchIdentity(ctx context.Context, id int, email string, at time.Time) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE user_identities SET last_login_at = $2, email = COALESCE(NULLIF($3, ''), email) WHERE id = $1`,
+		id, at.UTC(), email)
+	return err
+}
+
+func (r *IdentityRepository) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, user_id, expires_at)
+              VALUES ($1, $2, $3, $4, NULLIF($5, 0), $6)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		state.StateHash,
+		state.Provider,
+		state.Nonce,
+		state.CodeVerifier,
+		state.UserID,
+		state.ExpiresAt.UTC(),
+	).Scan(&state.ID, &state.CreatedAt)
+}
+
+// TakeOIDCState удаляет и возвращает незавершенный вход по хешу state или nil.
+// Истекшие записи тоже возвращаются: срок проверяет сервис.
+func (r *IdentityRepository) TakeOIDCState(ctx context.Context, hash string) (*models.OIDCLoginState, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM oidc_login_states WHERE state_hash = $1
+              RETURNING id, state_hash, provider, nonce, code_verifier, COALESCE(user_id, 0), expires_at, created_at`
+
+	var s models.OIDCLoginState
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&s.ID,
+		&s.StateHash,
+		&s.Provider,
+		&s.Nonce,
+		&s.CodeVerifier,
+		&s.UserID,
+		&s.ExpiresAt,
+		&s.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteExpiredOIDCStates удаляет входы, не завершенные до before
+func (r *IdentityRepository) DeleteExpiredOIDCStates(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM oidc_login_states WHERE expires_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
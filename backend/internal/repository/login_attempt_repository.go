@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LoginAttemptRepository счетчики неудачных входов и журнал блокировок
+type LoginAttemptRepository struct {
+	db *DB
+}
+
+func NewLoginAttemptRepository(db *DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// GetThrottle возвращает счетчик по email или nil, если неудачных входов не было
+func (r *LoginAttemptRepository) GetThrottle(ctx context.Context, email string) (*models.LoginThrottle, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT email, failures, last_failure_at, locked_until FROM login_throttle WHERE email = $1`
+
+	var t models.LoginThrottle
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&t.Email, &t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecordFailure увеличивает счетчик неудач. Если предыдущая неудача была раньше since,
+// счет начинается заново.
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, email string, at, since time.Time) (*models.LoginThrottle, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO login_throttle (email, failures, last_failure_at)
+              VALUES ($1, 1, $2)
+              ON CONFLICT (email) DO UPDATE
+              SET failures = CASE WHEN login_throttle.last_failure_at < $3 THEN 1
+                                  ELSE login_throttle.failures + 1 END,
+                  last_failure_at = $2
+              RETURNING email, failures, last_failure_at, locked_until`
+
+	var t models.LoginThrottle
+	err := r.db.QueryRowContext(ctx, query, email, at.UTC(), since.UTC()).
+		Scan(&t.Email, &t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// LockUntil запрещает вход до until и обнуляет счетчик неудач
+func (r *LoginAttemptRepository) LockUntil(ctx context.Context, email string, until time.Time) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE login_throttle SET failures = 0, locked_until = $2 WHERE email = $1`
+	_, err := r.db.ExecContext(ctx, query, email, until.UTC())
+	return err
+}
+
+// ResetThrottle удаляет счетчик после успешного входа или разблокировки
+func (r *LoginAttemptRepository) ResetThrottle(ctx context.Context, email string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM login_throttle WHERE email = $1`, email)
+	return err
+}
+
+// DeleteStaleThrottles удаляет счетчики без неудач и блокировок после before
+func (r *LoginAttemptRepository) DeleteStaleThrottles(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM login_throttle
+              WHERE last_failure_at < $1 AND (locked_until IS NULL OR locked_until < $1)`
+	result, err := r.db.ExecContext(ctx, query, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CreateLockout записывает блокировку в журнал
+func (r *LoginAttemptRepository) CreateLockout(ctx context.Context, lockout *models.AccountLockout) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO account_lockouts (user_id, email, ip, failures, locked_until, unlock_token_hash)
+              VALUES (NULLIF($1, 0), $2, $3, $4, $5, NULLIF($6, ''))
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		lockout.UserID,
+		lockout.Email,
+		lockout.IP,
+		lockout.Failures,
+		lockout.LockedUntil.UTC(),
+		lockout.UnlockTokenHash,
+	).Scan(&lockout.ID, &lockout.CreatedAt)
+}
+
+// GetLockoutByTokenHash возвращает блокировку по хешу токена разблокировки или nil
+func (r *LoginAttemptRepository) GetLockoutByTokenHash(ctx context.Context, hash string) (*models.AccountLockout, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, COALESCE(user_id, 0), email, COALESCE(ip, ''), failures, locked_until,
+                     unlock_token_hash, unlocked_at, created_at
+              FROM account_lockouts WHERE unlock_token_hash = $1`
+
+	var l models.AccountLockout
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&l.ID,
+		&l.UserID,
+		&l.Email,
+		&l.IP,
+		&l.Failures,
+		&l.LockedUntil,
+		&l.UnlockTokenHash,
+		&l.UnlockedAt,
+		&l.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// MarkUnlocked отмечает, что блокировка снята по ссылке из письма
+func (r *LoginAttemptRepository) MarkUnlocked(ctx context.Context, id int, at time.Time) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE account_lockouts SET unlocked_at = $2 WHERE id = $1`, id, at.UTC())
+	return err
+}
+
+// DeleteUserLockouts удаляет журнал блокировок пользователя вместе с его аккаунтом
+func (r *LoginAttemptRepository) DeleteUserLockouts(ctx context.Context, userID int, email string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM account_lockouts WHERE user_id = $1 OR email = $2`, userID, email)
+	return err
+}
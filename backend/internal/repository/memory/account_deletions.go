@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.AccountDeletionStore = (*AccountDeletionStore)(nil)
+
+type AccountDeletionStore struct {
+	mu        sync.Mutex
+	nextID    int
+	deletions []models.AccountDeletion
+}
+
+func NewAccountDeletionStore() *AccountDeletionStore {
+	return &AccountDeletionStore{}
+}
+
+func (s *AccountDeletionStore) CreateAccountDeletion(ctx context.Context, deletion *models.AccountDeletion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	deletion.ID = s.nextID
+	deletion.DeletedAt = time.Now()
+	s.deletions = append(s.deletions, *deletion)
+	return nil
+}
+
+// Deletions возвращает журнал удалений; в Postgres его читают напрямую из таблицы
+func (s *AccountDeletionStore) Deletions() []models.AccountDeletion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.deletions)
+}
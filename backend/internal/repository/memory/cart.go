@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ repository.CartStore = (*CartStore)(nil)
+
+// CartStore корзины пользователей. Название, цена и картинка товара
+// берутся из каталога при чтении, как JOIN products в CartRepository.
+type CartStore struct {
+	mu       sync.RWMutex
+	products *ProductStore
+	nextID   int
+	items    []models.CartItem
+}
+
+func NewCartStore(products *ProductStore) *CartStore {
+	return &CartStore{products: products}
+}
+
+func (s *CartStore) GetCartItems(ctx context.Context, userID int) ([]models.CartItem, error) {
+	s.mu.RLock()
+	var own []models.CartItem
+	for _, item := range s.items {
+		if item.UserID == userID {
+			own = append(own, item)
+		}
+	}
+	s.mu.RUnlock()
+
+	var items []models.CartItem
+	for _, item := range own {
+		product, err := s.products.GetProductByID(ctx, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		if product == nil {
+			continue
+		}
+		item.ProductName = product.Name
+		item.Price = product.Price
+		item.ImageURL = product.ImageURL
+		items = append(items, item)
+	}
+
+	sortByTime(items, func(i models.CartItem) (time.Time, int) { return i.AddedAt, i.ID }, true)
+	return items, nil
+}
+
+// AddToCart добавляет товар или увеличивает его количество, но не выше maxQuantity,
+// как INSERT ... ON CONFLICT (user_id, product_id) в CartRepository
+func (s *CartStore) AddToCart(ctx context.Context, userID, productID, quantity, maxQuantity int) error {
+	product, err := s.products.GetProductByID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return fmt.Errorf("cart_items.product_id %d: %w", productID, ErrForeignKeyViolation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.items {
+		if s.items[i].UserID == userID && s.items[i].ProductID == productID {
+			s.items[i].Quantity = min(s.items[i].Quantity+quantity, maxQuantity)
+			return nil
+		}
+	}
+
+	s.nextID++
+	s.items = append(s.items, models.CartItem{
+		ID:        s.nextID,
+		UserID:    userID,
+		ProductID: productID,
+		Quantity:  quantity,
+		AddedAt:   time.Now(),
+	})
+	return nil
+}
+
+func (s *CartStore) UpdateCartItem(ctx context.Context, itemID, quantity int) error {
+	if quantity <= 0 {
+		return s.RemoveFromCart(ctx, itemID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.items {
+		if s.items[i].ID == itemID {
+			s.items[i].Quantity = quantity
+		}
+	}
+	return nil
+}
+
+func (s *CartStore) RemoveFromCart(ctx context.Context, itemID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeWhere(func(item models.CartItem) bool { return item.ID == itemID })
+	return nil
+}
+
+func (s *CartStore) ClearUserCart(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeWhere(func(item models.CartItem) bool { return item.UserID == userID })
+	return nil
+}
+
+// GetCartItemByID возвращает позицию без данных товара, как и CartRepository
+func (s *CartStore) GetCartItemByID(ctx context.Context, itemID int) (*models.CartItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, item := range s.items {
+		if item.ID == itemID {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *CartStore) removeWhere(match func(models.CartItem) bool) {
+	kept := s.items[:0]
+	for _, item := range s.items {
+		if !match(item) {
+			kept = append(kept, item)
+		}
+	}
+	s.items = kept
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.ConsentStore = (*ConsentStore)(nil)
+
+type ConsentStore struct {
+	mu        sync.Mutex
+	users     *UserStore
+	nextID    int
+	documents []models.PolicyDocument
+	consents  []models.Consent
+	cookies   []models.CookieConsent
+}
+
+// NewConsentStore создает хранилище; users нужен для проверки ссылок на пользователя
+func NewConsentStore(users *UserStore) *ConsentStore {
+	return &ConsentStore{users: users}
+}
+
+func (s *ConsentStore) CreatePolicyDocument(ctx context.Context, doc *models.PolicyDocument) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.documents {
+		if d.Kind == doc.Kind && d.Version == doc.Version {
+			return fmt.Errorf("policy_documents (kind, version): %w", repository.ErrDuplicate)
+		}
+	}
+	s.nextID++
+	doc.ID = s.nextID
+	doc.PublishedAt = time.Now()
+	s.documents = append(s.documents, *doc)
+	return nil
+}
+
+func (s *ConsentStore) GetLatestPolicyDocument(ctx context.Context, kind string) (*models.PolicyDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *models.PolicyDocument
+	for i, d := range s.documents {
+		if d.Kind == kind && (latest == nil || d.Version > latest.Version) {
+			latest = &s.documents[i]
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	doc := *latest
+	return &doc, nil
+}
+
+func (s *ConsentStore) GetPolicyDocumentByID(ctx context.Context, id int) (*models.PolicyDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.document(id), nil
+}
+
+func (s *ConsentStore) CreateConsent(ctx context.Context, consent *models.Consent) error {
+	if user, _ := s.users.lookup(consent.UserID); user == nil {
+		return fmt.Errorf("user_consents.user_id %d: %w", consent.UserID, ErrForeignKeyViolation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.document(consent.DocumentID) == nil {
+		return fmt.Errorf("user_consents.document_id %d: %w", consent.DocumentID, ErrForeignKeyViolation)
+	}
+	for _, c := range s.consents {
+		if c.UserID == consent.UserID && c.DocumentID == consent.DocumentID {
+			consent.ID = c.ID
+			consent.AcceptedAt = c.AcceptedAt
+			return nil
+		}
+	}
+	s.nextID++
+	consent.ID = s.nextID
+	consent.AcceptedAt = time.Now()
+	s.consents = append(s.consents, *consent)
+	return nil
+}
+
+func (s *ConsentStore) GetUserConsents(ctx context.Context, userID int) ([]models.Consent, error) {
+	// Согласия удаленного пользователя исчезают вместе с ним (ON DELETE CASCADE)
+	if user, err := s.users.lookup(userID); err != nil || user == nil {
+		return []models.Consent{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	consents := []models.Consent{}
+	for _, c := range s.consents {
+		if c.UserID != userID {
+			continue
+		}
+		doc := s.document(c.DocumentID)
+		c.Kind, c.Version = doc.Kind, doc.Version
+		consents = append(consents, c)
+	}
+	sortByTime(consents, func(c models.Consent) (time.Time, int) { return c.AcceptedAt, c.ID }, true)
+	return consents, nil
+}
+
+func (s *ConsentStore) CreateCookieConsent(ctx context.Context, consent *models.CookieConsent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	consent.ID = s.nextID
+	consent.CreatedAt = time.Now()
+	s.cookies = append(s.cookies, *consent)
+	return nil
+}
+
+// CookieConsents возвращает журнал решений в баннере cookie
+func (s *ConsentStore) CookieConsents() []models.CookieConsent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.cookies)
+}
+
+// document ищет версию документа по ID; вызывается под s.mu
+func (s *ConsentStore) document(id int) *models.PolicyDocument {
+	for _, d := range s.documents {
+		if d.ID == id {
+			return &d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ repository.EmailChangeStore = (*EmailChangeStore)(nil)
+
+type EmailChangeStore struct {
+	mu      sync.Mutex
+	nextID  int
+	changes []models.EmailChange
+}
+
+func NewEmailChangeStore() *EmailChangeStore {
+	return &EmailChangeStore{}
+}
+
+func (s *EmailChangeStore) CreateEmailChange(ctx context.Context, change *models.EmailChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.changes {
+		if c.TokenHash == change.TokenHash {
+			return fmt.Errorf("email_changes.token_hash: %w", repository.ErrDuplicate)
+		}
+	}
+	s.nextID++
+	change.ID = s.nextID
+	change.CreatedAt = time.Now()
+	s.changes = append(s.changes, *change)
+	return nil
+}
+
+func (s *EmailChangeStore) GetEmailChangeByTokenHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.changes {
+		if c.TokenHash == hash {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *EmailChangeStore) MarkEmailChangeConfirmed(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.changes {
+		if s.changes[i].ID == id {
+			s.changes[i].ConfirmedAt = &at
+		}
+	}
+	return nil
+}
@@ -0,0 +1,322 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ repository.FeedbackStore = (*FeedbackStore)(nil)
+
+// FeedbackStore отзывы, ответы на них, журнал модерации и отклоненные антиспамом отправки.
+// Название темы и имя автора ответа берутся из themes и users при чтении;
+// любое из этих хранилищ может быть nil.
+type FeedbackStore struct {
+	mu     sync.RWMutex
+	users  *UserStore
+	themes *FeedbackThemeStore
+
+	nextFeedbackID  int
+	nextReplyID     int
+	nextEntryID     int
+	nextRejectionID int
+
+	feedbacks  []models.Feedback
+	replies    []models.FeedbackReply
+	history    []models.FeedbackModerationEntry
+	rejections []models.FeedbackRejection
+}
+
+func NewFeedbackStore(users *UserStore, themes *FeedbackThemeStore) *FeedbackStore {
+	return &FeedbackStore{users: users, themes: themes}
+}
+
+// CreateFeedback сохраняет отзыв в статусе ожидания модерации
+func (s *FeedbackStore) CreateFeedback(ctx context.Context, feedback *models.Feedback) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextFeedbackID++
+	feedback.ID = s.nextFeedbackID
+	feedback.CreatedAt = time.Now()
+	feedback.Status = models.FeedbackOpen
+	feedback.IsVisible = false
+	feedback.ModerationStatus = models.ModerationPending
+
+	stored := *feedback
+	stored.ThemeTitle = ""
+	stored.Replies = nil
+	stored.RejectionReason = ""
+	stored.ModeratedBy = nil
+	stored.ModeratedAt = nil
+	s.feedbacks = append(s.feedbacks, stored)
+	return nil
+}
+
+// GetVisibleFeedbacks возвращает опубликованные отзывы без email и данных модерации
+func (s *FeedbackStore) GetVisibleFeedbacks(ctx context.Context, theme string) ([]models.Feedback, error) {
+	found := s.filter(func(f models.Feedback) bool {
+		return f.IsVisible && (theme == "" || f.Theme == theme)
+	}, true)
+
+	feedbacks := make([]models.Feedback, 0, len(found))
+	for _, f := range found {
+		feedbacks = append(feedbacks, models.Feedback{
+			ID:         f.ID,
+			Name:       f.Name,
+			Theme:      f.Theme,
+			ThemeTitle: f.ThemeTitle,
+			Message:    f.Message,
+			CreatedAt:  f.CreatedAt,
+			Status:     f.Status,
+		})
+	}
+	return feedbacks, nil
+}
+
+// CreateReply сохраняет ответ сотрудника и переводит переписку в статус answered
+func (s *FeedbackStore) CreateReply(ctx context.Context, reply *models.FeedbackReply) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feedback := s.find(reply.FeedbackID)
+	if feedback == nil {
+		return fmt.Errorf("feedback_replies.feedback_id %d: %w", reply.FeedbackID, ErrForeignKeyViolation)
+	}
+
+	s.nextReplyID++
+	reply.ID = s.nextReplyID
+	reply.CreatedAt = time.Now()
+	stored := *reply
+	stored.AuthorName = ""
+	stored.EmailedAt = nil
+	s.replies = append(s.replies, stored)
+
+	feedback.Status = models.FeedbackAnswered
+	return nil
+}
+
+func (s *FeedbackStore) MarkReplyEmailed(ctx context.Context, replyID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.replies {
+		if s.replies[i].ID == replyID {
+			now := time.Now()
+			s.replies[i].EmailedAt = &now
+		}
+	}
+	return nil
+}
+
+func (s *FeedbackStore) SetStatus(ctx context.Context, feedbackID int, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feedback := s.find(feedbackID); feedback != nil {
+		feedback.Status = status
+	}
+	return nil
+}
+
+// GetReplies возвращает ответы, сгруппированные по feedback_id, в порядке создания
+func (s *FeedbackStore) GetReplies(ctx context.Context, feedbackIDs []int, publicOnly bool) (map[int][]models.FeedbackReply, error) {
+	s.mu.RLock()
+	var found []models.FeedbackReply
+	for _, r := range s.replies {
+		if slices.Contains(feedbackIDs, r.FeedbackID) && (r.IsPublic || !publicOnly) {
+			found = append(found, r)
+		}
+	}
+	s.mu.RUnlock()
+
+	sortByTime(found, func(r models.FeedbackReply) (time.Time, int) { return r.CreatedAt, r.ID }, false)
+
+	replies := make(map[int][]models.FeedbackReply)
+	for _, r := range found {
+		if author, _ := s.users.lookup(r.AuthorID); author != nil {
+			r.AuthorName = author.FirstName
+		}
+		replies[r.FeedbackID] = append(replies[r.FeedbackID], r)
+	}
+	return replies, nil
+}
+
+// GetFeedbacksByStatus возвращает отзывы со статусом модерации status, старые первыми
+func (s *FeedbackStore) GetFeedbacksByStatus(ctx context.Context, status, theme string) ([]models.Feedback, error) {
+	return s.filter(func(f models.Feedback) bool {
+		return f.ModerationStatus == status && (theme == "" || f.Theme == theme)
+	}, false), nil
+}
+
+// GetFeedbacksByUser возвращает все отзывы пользователя, новые первыми
+func (s *FeedbackStore) GetFeedbacksByUser(ctx context.Context, userID int) ([]models.Feedback, error) {
+	return s.filter(func(f models.Feedback) bool { return f.UserID == userID }, true), nil
+}
+
+func (s *FeedbackStore) GetFeedbackByID(ctx context.Context, id int) (*models.Feedback, error) {
+	found := s.filter(func(f models.Feedback) bool { return f.ID == id }, false)
+	if len(found) == 0 {
+		return nil, nil
+	}
+	return &found[0], nil
+}
+
+// SetModerationStatus меняет статус отзыва и записывает решение в журнал
+func (s *FeedbackStore) SetModerationStatus(ctx context.Context, id, moderatorID int, status, action, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feedback := s.find(id)
+	if feedback == nil {
+		return fmt.Errorf("feedback_moderation_log.feedback_id %d: %w", id, ErrForeignKeyViolation)
+	}
+
+	now := time.Now()
+	feedback.ModerationStatus = status
+	feedback.IsVisible = status == models.ModerationApproved
+	feedback.RejectionReason = reason
+	feedback.ModeratedBy = &moderatorID
+	feedback.ModeratedAt = &now
+	s.logModeration(id, moderatorID, action, reason)
+	return nil
+}
+
+// UpdateFeedbackContent сохраняет правки модератора и записывает их в журнал
+func (s *FeedbackStore) UpdateFeedbackContent(ctx context.Context, feedback *models.Feedback, moderatorID int, note string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.find(feedback.ID)
+	if stored == nil {
+		return fmt.Errorf("feedback_moderation_log.feedback_id %d: %w", feedback.ID, ErrForeignKeyViolation)
+	}
+
+	now := time.Now()
+	stored.Name = feedback.Name
+	stored.Theme = feedback.Theme
+	stored.Message = feedback.Message
+	stored.ModeratedBy = &moderatorID
+	stored.ModeratedAt = &now
+	s.logModeration(feedback.ID, moderatorID, models.ModerationActionEdit, note)
+	return nil
+}
+
+func (s *FeedbackStore) GetModerationHistory(ctx context.Context, feedbackID int) ([]models.FeedbackModerationEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []models.FeedbackModerationEntry
+	for _, e := range s.history {
+		if e.FeedbackID == feedbackID {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// HasRecentDuplicate сравнивает email и текст без учета регистра и крайних пробелов
+func (s *FeedbackStore) HasRecentDuplicate(ctx context.Context, email, message string, since time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	message = strings.Trim(message, " ")
+	for _, f := range s.feedbacks {
+		if strings.EqualFold(f.Email, email) && strings.EqualFold(strings.Trim(f.Message, " "), message) &&
+			!f.CreatedAt.Before(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *FeedbackStore) LogRejection(ctx context.Context, rejection *models.FeedbackRejection) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextRejectionID++
+	rejection.ID = s.nextRejectionID
+	rejection.CreatedAt = time.Now()
+	s.rejections = append(s.rejections, *rejection)
+	return nil
+}
+
+func (s *FeedbackStore) AnonymizeUserFeedbacks(ctx context.Context, userID int, email, name string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for i, f := range s.feedbacks {
+		if f.UserID == userID || (f.UserID == 0 && f.Email == email) {
+			s.feedbacks[i].UserID = 0
+			s.feedbacks[i].Name = name
+			s.feedbacks[i].Email = ""
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *FeedbackStore) DeleteUserRejections(ctx context.Context, userID int, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rejections = slices.DeleteFunc(s.rejections, func(r models.FeedbackRejection) bool {
+		return r.UserID == userID || r.Email == email
+	})
+	return nil
+}
+
+// Rejections возвращает отклоненные антиспамом отправки; в Postgres их читают напрямую из таблицы
+func (s *FeedbackStore) Rejections() []models.FeedbackRejection {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return slices.Clone(s.rejections)
+}
+
+// filter возвращает копии подходящих отзывов с названием темы, отсортированные по времени создания
+func (s *FeedbackStore) filter(match func(models.Feedback) bool, newestFirst bool) []models.Feedback {
+	s.mu.RLock()
+	var feedbacks []models.Feedback
+	for _, f := range s.feedbacks {
+		if match(f) {
+			feedbacks = append(feedbacks, f)
+		}
+	}
+	s.mu.RUnlock()
+
+	for i := range feedbacks {
+		feedbacks[i].ThemeTitle = s.themes.title(feedbacks[i].Theme)
+	}
+	sortByTime(feedbacks, func(f models.Feedback) (time.Time, int) { return f.CreatedAt, f.ID }, newestFirst)
+	return feedbacks
+}
+
+// find возвращает указатель на хранимый отзыв; вызывается под s.mu
+func (s *FeedbackStore) find(id int) *models.Feedback {
+	for i := range s.feedbacks {
+		if s.feedbacks[i].ID == id {
+			return &s.feedbacks[i]
+		}
+	}
+	return nil
+}
+
+// logModeration добавляет запись в журнал; вызывается под s.mu
+func (s *FeedbackStore) logModeration(feedbackID, moderatorID int, action, reason string) {
+	s.nextEntryID++
+	s.history = append(s.history, models.FeedbackModerationEntry{
+		ID:          s.nextEntryID,
+		FeedbackID:  feedbackID,
+		ModeratorID: moderatorID,
+		Action:      action,
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+	})
+}
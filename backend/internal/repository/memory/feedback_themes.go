@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ repository.FeedbackThemeStore = (*FeedbackThemeStore)(nil)
+
+type FeedbackThemeStore struct {
+	mu     sync.RWMutex
+	nextID int
+	themes []models.FeedbackTheme
+}
+
+func NewFeedbackThemeStore() *FeedbackThemeStore {
+	return &FeedbackThemeStore{}
+}
+
+// GetThemes возвращает темы по sort_order и названию; если activeOnly, только доступные в форме
+func (s *FeedbackThemeStore) GetThemes(ctx context.Context, activeOnly bool) ([]models.FeedbackTheme, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var themes []models.FeedbackTheme
+	for _, t := range s.themes {
+		if t.IsActive || !activeOnly {
+			themes = append(themes, t)
+		}
+	}
+	sort.SliceStable(themes, func(i, j int) bool {
+		if themes[i].SortOrder != themes[j].SortOrder {
+			return themes[i].SortOrder < themes[j].SortOrder
+		}
+		return themes[i].Title < themes[j].Title
+	})
+	return themes, nil
+}
+
+func (s *FeedbackThemeStore) GetThemeByCode(ctx context.Context, code string) (*models.FeedbackTheme, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.themes {
+		if t.Code == code {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FeedbackThemeStore) GetThemeByID(ctx context.Context, id int) (*models.FeedbackTheme, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.themes {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FeedbackThemeStore) CreateTheme(ctx context.Context, theme *models.FeedbackTheme) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.themes {
+		if t.Code == theme.Code {
+			return fmt.Errorf("feedback_themes.code %q: %w", theme.Code, repository.ErrDuplicate)
+		}
+	}
+
+	s.nextID++
+	theme.ID = s.nextID
+	theme.CreatedAt = time.Now()
+	s.themes = append(s.themes, *theme)
+	return nil
+}
+
+// UpdateTheme меняет все поля, кроме кода
+func (s *FeedbackThemeStore) UpdateTheme(ctx context.Context, theme *models.FeedbackTheme) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.themes {
+		if s.themes[i].ID == theme.ID {
+			t := &s.themes[i]
+			t.Title = theme.Title
+			t.NotifyEmail = theme.NotifyEmail
+			t.NotifyRole = theme.NotifyRole
+			t.IsActive = theme.IsActive
+			t.SortOrder = theme.SortOrder
+		}
+	}
+	return nil
+}
+
+// title название темы по коду или пустая строка, как LEFT JOIN feedback_themes
+func (s *FeedbackThemeStore) title(code string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.themes {
+		if t.Code == code {
+			return t.Title
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.IdentityStore = (*IdentityStore)(nil)
+
+type IdentityStore struct {
+	mu          sync.Mutex
+	nextID      int
+	nextStateID int
+	identities  []models.UserIdentity
+	states      []models.OIDCLoginState
+	users       *UserStore
+}
+
+// NewIdentityStore создает хранилище; users проверяет ссылку на пользователя, nil отключает проверку
+func NewIdentityStore(users *UserStore) *IdentityStore {
+	return &IdentityStore{users: users}
+}
+
+// CreateIdentity соблюдает оба ограничения уникальности таблицы user_identities
+func (s *IdentityStore) CreateIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	if s.users != nil {
+		if user, _ := s.users.lookup(identity.UserID); user == nil {
+			return fmt.Errorf("user_identities.user_id %d: %w", identity.UserID, ErrForeignKeyViolation)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range s.identities {
+		if i.Provider == identity.Provider && (i.Subject == identity.Subject || i.UserID == identity.UserID) {
+			return fmt.Errorf("user_identities %s/%s: %w", identity.Provider, identity.Subject, repository.ErrDuplicate)
+		}
+	}
+
+	s.nextID++
+	identity.ID = s.nextID
+	identity.CreatedAt = time.Now()
+	s.identities = append(s.identities, *identity)
+	return nil
+}
+
+func (s *IdentityStore) GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range s.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *IdentityStore) GetUserIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identities := []models.UserIdentity{}
+	for _, i := range s.identities {
+		if i.UserID == userID {
+			identities = append(identities, i)
+		}
+	}
+	sortByTime(identities, func(i models.UserIdentity) (time.Time, int) { return i.CreatedAt, i.ID }, false)
+	return identities, nil
+}
+
+func (s *IdentityStore) DeleteIdentity(ctx context.Context, userID, id int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.identities)
+	s.identities = slices.DeleteFunc(s.identities, func(i models.UserIdentity) bool { return i.ID == id && i.UserID == userID })
+	return len(s.identities) < n, nil
+}
+
+func (s *IdentityStore) TouchIdentity(ctx context.Context, id int, email string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.identities {
+		if s.identities[i].ID == id {
+			s.identities[i].LastLoginAt = &at
+			if email != "" {
+				s.identities[i].Email = email
+			}
+		}
+	}
+	return nil
+}
+
+func (s *IdentityStore) CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error {
+	if state.UserID != 0 && s.users != nil {
+		if user, _ := s.users.lookup(state.UserID); user == nil {
+			return fmt.Errorf("oidc_login_states.user_id %d: %w", state.UserID, ErrForeignKeyViolation)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, st := range s.states {
+		if st.StateHash == state.StateHash {
+			return fmt.Errorf("oidc_login_states.state_hash: %w", repository.ErrDuplicate)
+		}
+	}
+
+	s.nextStateID++
+	state.ID = s.nextStateID
+	state.CreatedAt = time.Now()
+	s.states = append(s.states, *state)
+	return nil
+}
+
+func (s *IdentityStore) TakeOIDCState(ctx context.Context, hash string) (*models.OIDCLoginState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, st := range s.states {
+		if st.StateHash == hash {
+			s.states = slices.Delete(s.states, i, i+1)
+			return &st, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *IdentityStore) DeleteExpiredOIDCStates(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.states)
+	s.states = slices.DeleteFunc(s.states, func(st models.OIDCLoginState) bool { return st.ExpiresAt.Before(before) })
+	return int64(n - len(s.states)), nil
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.LoginAttemptStore = (*LoginAttemptStore)(nil)
+
+type LoginAttemptStore struct {
+	mu        sync.Mutex
+	throttles map[string]models.LoginThrottle
+	nextID    int
+	lockouts  []models.AccountLockout
+}
+
+func NewLoginAttemptStore() *LoginAttemptStore {
+	return &LoginAttemptStore{throttles: make(map[string]models.LoginThrottle)}
+}
+
+func (s *LoginAttemptStore) GetThrottle(ctx context.Context, email string) (*models.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[email]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (s *LoginAttemptStore) RecordFailure(ctx context.Context, email string, at, since time.Time) (*models.LoginThrottle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.throttles[email]
+	t.Email = email
+	if t.LastFailureAt != nil && t.LastFailureAt.Before(since) {
+		t.Failures = 0
+	}
+	t.Failures++
+	t.LastFailureAt = &at
+	s.throttles[email] = t
+	return &t, nil
+}
+
+func (s *LoginAttemptStore) LockUntil(ctx context.Context, email string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.throttles[email]
+	if !ok {
+		return nil
+	}
+	t.Failures = 0
+	t.LockedUntil = &until
+	s.throttles[email] = t
+	return nil
+}
+
+func (s *LoginAttemptStore) ResetThrottle(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.throttles, email)
+	return nil
+}
+
+func (s *LoginAttemptStore) DeleteStaleThrottles(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for email, t := range s.throttles {
+		if t.LastFailureAt != nil && t.LastFailureAt.Before(before) &&
+			(t.LockedUntil == nil || t.LockedUntil.Before(before)) {
+			delete(s.throttles, email)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CreateLockout сохраняет блокировку; CreatedAt заполняется текущим временем, если не задан
+func (s *LoginAttemptStore) CreateLockout(ctx context.Context, lockout *models.AccountLockout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lockout.UnlockTokenHash != "" {
+		for _, l := range s.lockouts {
+			if l.UnlockTokenHash == lockout.UnlockTokenHash {
+				return fmt.Errorf("account_lockouts.unlock_token_hash: %w", repository.ErrDuplicate)
+			}
+		}
+	}
+
+	s.nextID++
+	lockout.ID = s.nextID
+	if lockout.CreatedAt.IsZero() {
+		lockout.CreatedAt = time.Now()
+	}
+	s.lockouts = append(s.lockouts, *lockout)
+	return nil
+}
+
+func (s *LoginAttemptStore) GetLockoutByTokenHash(ctx context.Context, hash string) (*models.AccountLockout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range s.lockouts {
+		if hash != "" && l.UnlockTokenHash == hash {
+			return &l, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *LoginAttemptStore) MarkUnlocked(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.lockouts {
+		if s.lockouts[i].ID == id {
+			s.lockouts[i].UnlockedAt = &at
+		}
+	}
+	return nil
+}
+
+// Lockouts возвращает журнал блокировок для проверок в тестах
+func (s *LoginAttemptStore) Lockouts() []models.AccountLockout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.AccountLockout(nil), s.lockouts...)
+}
+
+func (s *LoginAttemptStore) DeleteUserLockouts(ctx context.Context, userID int, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lockouts = slices.DeleteFunc(s.lockouts, func(l models.AccountLockout) bool {
+		return l.UserID == userID || l.Email == email
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package memory реализует хранилища repository в памяти процесса.
+// Поведение повторяет репозитории Postgres, включая ограничения схемы,
+// поэтому сервисы и обработчики можно тестировать без живой базы.
+// Все хранилища безопасны для конкурентного использования.
+package memory
+
+import (
+	"beladonna/backend/internal/repository"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrForeignKeyViolation ссылка на несуществующую запись (23503 в Postgres).
+// Нарушение уникальности возвращается как repository.ErrDuplicate.
+var ErrForeignKeyViolation = errors.New("memory: ссылка на несуществующую запись")
+
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
+
+// UnitOfWork выполняет единицы работы строго по очереди, что дает ту же изоляцию,
+// что и SERIALIZABLE в Postgres. Изменения при ошибке не откатываются.
+type UnitOfWork struct {
+	mu sync.Mutex
+}
+
+func NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{}
+}
+
+type unitKey struct{}
+
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(unitKey{}) == u {
+		return fn(ctx)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return fn(context.WithValue(ctx, unitKey{}, u))
+}
+
+// sortByTime упорядочивает записи по времени, при равном времени — по ID,
+// чтобы порядок не зависел от того, как быстро они были созданы
+func sortByTime[T any](items []T, key func(T) (time.Time, int), desc bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		ti, idi := key(items[i])
+		tj, idj := key(items[j])
+		if !ti.Equal(tj) {
+			return ti.Before(tj) != desc
+		}
+		return (idi < idj) != desc
+	})
+}
@@ -0,0 +1,259 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.NewsletterStore = (*NewsletterStore)(nil)
+
+type NewsletterStore struct {
+	mu            sync.Mutex
+	users         *UserStore
+	nextID        int
+	subscriptions []models.NewsletterSubscription
+	campaigns     []models.NewsletterCampaign
+	deliveries    []models.NewsletterDelivery
+}
+
+// NewNewsletterStore создает хранилище; users нужен, чтобы брать адрес из аккаунта
+// и скрывать подписки удаленных пользователей, как ON DELETE CASCADE
+func NewNewsletterStore(users *UserStore) *NewsletterStore {
+	return &NewsletterStore{users: users}
+}
+
+func (s *NewsletterStore) CreateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnique(sub); err != nil {
+		return err
+	}
+	s.nextID++
+	sub.ID = s.nextID
+	sub.CreatedAt = time.Now()
+	s.subscriptions = append(s.subscriptions, *sub)
+	return nil
+}
+
+func (s *NewsletterStore) UpdateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnique(sub); err != nil {
+		return err
+	}
+	for i := range s.subscriptions {
+		if s.subscriptions[i].ID == sub.ID {
+			sub.Email = s.subscriptions[i].Email
+			sub.CreatedAt = s.subscriptions[i].CreatedAt
+			s.subscriptions[i] = *sub
+		}
+	}
+	return nil
+}
+
+// checkUnique повторяет уникальные индексы таблицы; вызывается под s.mu
+func (s *NewsletterStore) checkUnique(sub *models.NewsletterSubscription) error {
+	for _, other := range s.live() {
+		if other.ID == sub.ID {
+			continue
+		}
+		switch {
+		case sub.ID == 0 && other.Email == sub.Email:
+			return fmt.Errorf("newsletter_subscriptions.email: %w", repository.ErrDuplicate)
+		case sub.UserID != nil && other.UserID != nil && *other.UserID == *sub.UserID:
+			return fmt.Errorf("newsletter_subscriptions.user_id: %w", repository.ErrDuplicate)
+		case sub.ConfirmTokenHash != "" && other.ConfirmTokenHash == sub.ConfirmTokenHash:
+			return fmt.Errorf("newsletter_subscriptions.confirm_token_hash: %w", repository.ErrDuplicate)
+		}
+	}
+	return nil
+}
+
+func (s *NewsletterStore) GetSubscriptionByID(ctx context.Context, id int) (*models.NewsletterSubscription, error) {
+	return s.findSubscription(func(sub models.NewsletterSubscription) bool { return sub.ID == id })
+}
+
+func (s *NewsletterStore) GetSubscriptionByEmail(ctx context.Context, email string) (*models.NewsletterSubscription, error) {
+	return s.findSubscription(func(sub models.NewsletterSubscription) bool { return sub.Email == email })
+}
+
+func (s *NewsletterStore) GetSubscriptionByUser(ctx context.Context, userID int) (*models.NewsletterSubscription, error) {
+	return s.findSubscription(func(sub models.NewsletterSubscription) bool { return sub.UserID != nil && *sub.UserID == userID })
+}
+
+func (s *NewsletterStore) GetSubscriptionByTokenHash(ctx context.Context, hash string) (*models.NewsletterSubscription, error) {
+	return s.findSubscription(func(sub models.NewsletterSubscription) bool { return sub.ConfirmTokenHash == hash })
+}
+
+func (s *NewsletterStore) findSubscription(match func(models.NewsletterSubscription) bool) (*models.NewsletterSubscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sub := range s.live() {
+		if match(sub) {
+			return &sub, nil
+		}
+	}
+	return nil, nil
+}
+
+// live подписки без удаленных вместе с пользователем; вызывается под s.mu
+func (s *NewsletterStore) live() []models.NewsletterSubscription {
+	subs := make([]models.NewsletterSubscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		if sub.UserID != nil {
+			if user, _ := s.users.lookup(*sub.UserID); user == nil {
+				continue
+			}
+		}
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (s *NewsletterStore) CreateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	campaign.ID = s.nextID
+	campaign.CreatedAt = time.Now()
+	c := *campaign
+	c.ProductIDs = slices.Clone(campaign.ProductIDs)
+	s.campaigns = append(s.campaigns, c)
+	return nil
+}
+
+func (s *NewsletterStore) UpdateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.campaigns {
+		if s.campaigns[i].ID == campaign.ID {
+			c := &s.campaigns[i]
+			c.Subject = campaign.Subject
+			c.Intro = campaign.Intro
+			c.ProductIDs = slices.Clone(campaign.ProductIDs)
+			c.Status = campaign.Status
+			c.SentAt = campaign.SentAt
+		}
+	}
+	return nil
+}
+
+func (s *NewsletterStore) GetCampaignByID(ctx context.Context, id int) (*models.NewsletterCampaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.campaigns {
+		if c.ID == id {
+			c.ProductIDs = slices.Clone(c.ProductIDs)
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *NewsletterStore) GetCampaigns(ctx context.Context, status string) ([]models.NewsletterCampaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaigns := []models.NewsletterCampaign{}
+	for _, c := range s.campaigns {
+		if status == "" || c.Status == status {
+			c.ProductIDs = slices.Clone(c.ProductIDs)
+			campaigns = append(campaigns, c)
+		}
+	}
+	sortByTime(campaigns, func(c models.NewsletterCampaign) (time.Time, int) { return c.CreatedAt, c.ID }, true)
+	return campaigns, nil
+}
+
+func (s *NewsletterStore) EnqueueDeliveries(ctx context.Context, campaignID int) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := map[int]bool{}
+	for _, d := range s.deliveries {
+		if d.CampaignID == campaignID {
+			queued[d.SubscriptionID] = true
+		}
+	}
+	var added int64
+	for _, sub := range s.live() {
+		if sub.Status != models.SubscriptionActive || queued[sub.ID] {
+			continue
+		}
+		s.nextID++
+		s.deliveries = append(s.deliveries, models.NewsletterDelivery{
+			ID:             s.nextID,
+			CampaignID:     campaignID,
+			SubscriptionID: sub.ID,
+			Status:         models.DeliveryPending,
+		})
+		added++
+	}
+	return added, nil
+}
+
+func (s *NewsletterStore) GetPendingDeliveries(ctx context.Context, campaignID, limit int) ([]models.NewsletterDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := map[int]models.NewsletterSubscription{}
+	for _, sub := range s.live() {
+		subs[sub.ID] = sub
+	}
+	var deliveries []models.NewsletterDelivery
+	for _, d := range s.deliveries {
+		sub, ok := subs[d.SubscriptionID]
+		if d.CampaignID != campaignID || d.Status != models.DeliveryPending || !ok {
+			continue
+		}
+		d.Email, d.SubscriptionStatus = sub.Email, sub.Status
+		if sub.UserID != nil {
+			if user, _ := s.users.lookup(*sub.UserID); user != nil {
+				d.Email = user.Email
+			}
+		}
+		deliveries = append(deliveries, d)
+		if len(deliveries) == limit {
+			break
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *NewsletterStore) MarkDelivery(ctx context.Context, id int, status, errText string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deliveries {
+		if s.deliveries[i].ID == id {
+			s.deliveries[i].Status = status
+			s.deliveries[i].Error = errText
+			s.deliveries[i].SentAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *NewsletterStore) CountDeliveries(ctx context.Context, campaignID int) (map[string]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := map[string]int{}
+	for _, d := range s.deliveries {
+		if d.CampaignID == campaignID {
+			counts[d.Status]++
+		}
+	}
+	return counts, nil
+}
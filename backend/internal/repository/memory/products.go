@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ repository.ProductStore = (*ProductStore)(nil)
+
+// ProductStore каталог товаров. Приложение каталог не меняет,
+// поэтому данные добавляются методами AddCategory и AddProduct.
+type ProductStore struct {
+	mu             sync.RWMutex
+	nextCategoryID int
+	nextProductID  int
+	categories     []models.Category
+	products       []models.Product
+}
+
+func NewProductStore() *ProductStore {
+	return &ProductStore{}
+}
+
+// AddCategory добавляет категорию и возвращает ее с присвоенным ID
+func (s *ProductStore) AddCategory(category models.Category) models.Category {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextCategoryID++
+	category.ID = s.nextCategoryID
+	if category.CreatedAt.IsZero() {
+		category.CreatedAt = time.Now()
+	}
+	s.categories = append(s.categories, category)
+	return category
+}
+
+// AddProduct добавляет товар и возвращает его с присвоенным ID
+func (s *ProductStore) AddProduct(product models.Product) models.Product {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextProductID++
+	product.ID = s.nextProductID
+	if product.CreatedAt.IsZero() {
+		product.CreatedAt = time.Now()
+	}
+	product.CategoryName = ""
+	s.products = append(s.products, product)
+	return s.withCategory(product)
+}
+
+// GetProducts повторяет фильтры ProductRepository: в выдачу попадают только товары в наличии
+func (s *ProductStore) GetProducts(ctx context.Context, filters models.ProductFilters) ([]models.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	search := strings.ToLower(filters.Search)
+	var products []models.Product
+	for _, p := range s.products {
+		switch {
+		case !p.InStock:
+			continue
+		case filters.CategoryID > 0 && p.CategoryID != filters.CategoryID:
+			continue
+		case search != "" && !strings.Contains(strings.ToLower(p.Name), search) &&
+			!strings.Contains(strings.ToLower(p.Description), search):
+			continue
+		case filters.MinPrice > 0 && p.Price < filters.MinPrice:
+			continue
+		case filters.MaxPrice > 0 && p.Price > filters.MaxPrice:
+			continue
+		}
+		products = append(products, s.withCategory(p))
+	}
+
+	sortByTime(products, func(p models.Product) (time.Time, int) { return p.CreatedAt, p.ID }, true)
+	return products, nil
+}
+
+func (s *ProductStore) GetProductByID(ctx context.Context, id int) (*models.Product, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, p := range s.products {
+		if p.ID == id {
+			p = s.withCategory(p)
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *ProductStore) GetCategories(ctx context.Context) ([]models.Category, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	categories := append([]models.Category(nil), s.categories...)
+	sort.SliceStable(categories, func(i, j int) bool { return categories[i].Name < categories[j].Name })
+	return categories, nil
+}
+
+// withCategory заполняет название категории, как LEFT JOIN categories
+func (s *ProductStore) withCategory(p models.Product) models.Product {
+	for _, c := range s.categories {
+		if c.ID == p.CategoryID {
+			p.CategoryName = c.Name
+			break
+		}
+	}
+	return p
+}
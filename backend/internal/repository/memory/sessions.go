@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ repository.SessionStore = (*SessionStore)(nil)
+
+type SessionStore struct {
+	mu       sync.Mutex
+	users    *UserStore
+	nextID   int
+	sessions map[string]models.Session
+}
+
+// NewSessionStore создает хранилище; users нужен, чтобы заполнять email и имя, как JOIN в Postgres
+func NewSessionStore(users *UserStore) *SessionStore {
+	return &SessionStore{users: users, sessions: make(map[string]models.Session)}
+}
+
+func (s *SessionStore) CreateSession(ctx context.Context, session *models.Session) error {
+	if user, _ := s.users.lookup(session.UserID); user == nil {
+		return fmt.Errorf("user_sessions.user_id %d: %w", session.UserID, ErrForeignKeyViolation)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.TokenHash]; ok {
+		return fmt.Errorf("user_sessions.token_hash: %w", repository.ErrDuplicate)
+	}
+	s.nextID++
+	session.ID = s.nextID
+	session.CreatedAt = time.Now()
+	s.sessions[session.TokenHash] = *session
+	return nil
+}
+
+func (s *SessionStore) GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	s.mu.Lock()
+	session, ok := s.sessions[hash]
+	s.mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	// Сессии удаленного пользователя исчезают вместе с ним (ON DELETE CASCADE)
+	user, err := s.users.lookup(session.UserID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	session.Email = user.Email
+	session.Name = user.FirstName + " " + user.LastName
+	return &session, nil
+}
+
+func (s *SessionStore) DeleteSession(ctx context.Context, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, hash)
+	return nil
+}
+
+func (s *SessionStore) DeleteUserSessions(ctx context.Context, userID int, exceptHash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for hash, session := range s.sessions {
+		if session.UserID == userID && hash != exceptHash {
+			delete(s.sessions, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *SessionStore) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var deleted int64
+	for hash, session := range s.sessions {
+		if session.ExpiresAt.Before(before) {
+			delete(s.sessions, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.SmsCodeStore = (*SmsCodeStore)(nil)
+
+type SmsCodeStore struct {
+	mu     sync.Mutex
+	nextID int
+	codes  []models.SmsCode
+	users  *UserStore
+}
+
+// NewSmsCodeStore создает хранилище; users проверяет ссылку на пользователя, nil отключает проверку
+func NewSmsCodeStore(users *UserStore) *SmsCodeStore {
+	return &SmsCodeStore{users: users}
+}
+
+func (s *SmsCodeStore) CreateSmsCode(ctx context.Context, code *models.SmsCode) error {
+	if code.UserID != 0 && s.users != nil {
+		if user, _ := s.users.lookup(code.UserID); user == nil {
+			return fmt.Errorf("sms_codes.user_id %d: %w", code.UserID, ErrForeignKeyViolation)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	code.ID = s.nextID
+	code.CreatedAt = time.Now()
+	s.codes = append(s.codes, *code)
+	return nil
+}
+
+func (s *SmsCodeStore) GetLatestSmsCode(ctx context.Context, phone, purpose string) (*models.SmsCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Коды добавляются по порядку, последний подходящий — самый новый
+	for i := len(s.codes) - 1; i >= 0; i-- {
+		if c := s.codes[i]; c.Phone == phone && c.Purpose == purpose {
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *SmsCodeStore) CountSmsCodes(ctx context.Context, phone string, since time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, c := range s.codes {
+		if c.Phone == phone && c.CreatedAt.After(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *SmsCodeStore) AddSmsCodeAttempt(ctx context.Context, id int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.codes {
+		if s.codes[i].ID == id {
+			s.codes[i].Attempts++
+			return s.codes[i].Attempts, nil
+		}
+	}
+	return 0, fmt.Errorf("memory: код %d не найден", id)
+}
+
+func (s *SmsCodeStore) MarkSmsCodeUsed(ctx context.Context, id int, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.codes {
+		if s.codes[i].ID == id && s.codes[i].UsedAt == nil {
+			s.codes[i].UsedAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *SmsCodeStore) DeleteSmsCodesBefore(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.codes)
+	s.codes = slices.DeleteFunc(s.codes, func(c models.SmsCode) bool { return c.CreatedAt.Before(before) })
+	return int64(n - len(s.codes)), nil
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+var _ repository.TwoFactorStore = (*TwoFactorStore)(nil)
+
+type recoveryCode struct {
+	hash string
+	used bool
+}
+
+type TwoFactorStore struct {
+	mu    sync.Mutex
+	keys  map[int]models.TwoFactor
+	codes map[int][]recoveryCode
+}
+
+func NewTwoFactorStore() *TwoFactorStore {
+	return &TwoFactorStore{keys: make(map[int]models.TwoFactor), codes: make(map[int][]recoveryCode)}
+}
+
+func (s *TwoFactorStore) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.keys[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (s *TwoFactorStore) SavePendingSecret(ctx context.Context, userID int, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[userID] = models.TwoFactor{UserID: userID, Secret: secret, CreatedAt: time.Now()}
+	return nil
+}
+
+func (s *TwoFactorStore) EnableTwoFactor(ctx context.Context, userID int, at time.Time, step int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.keys[userID]; ok {
+		t.EnabledAt = &at
+		t.LastUsedStep = step
+		s.keys[userID] = t
+	}
+	return nil
+}
+
+func (s *TwoFactorStore) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.keys[userID]
+	if !ok || t.LastUsedStep >= step {
+		return false, nil
+	}
+	t.LastUsedStep = step
+	s.keys[userID] = t
+	return true, nil
+}
+
+func (s *TwoFactorStore) DeleteTwoFactor(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, userID)
+	delete(s.codes, userID)
+	return nil
+}
+
+func (s *TwoFactorStore) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make([]recoveryCode, len(hashes))
+	for i, hash := range hashes {
+		codes[i] = recoveryCode{hash: hash}
+	}
+	s.codes[userID] = codes
+	return nil
+}
+
+func (s *TwoFactorStore) UseRecoveryCode(ctx context.Context, userID int, hash string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, c := range s.codes[userID] {
+		if c.hash == hash && !c.used {
+			s.codes[userID][i].used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *TwoFactorStore) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, c := range s.codes[userID] {
+		if !c.used {
+			count++
+		}
+	}
+	return count, nil
+}
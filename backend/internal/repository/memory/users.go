@@ -0,0 +1,206 @@
+package memory
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ repository.UserStore = (*UserStore)(nil)
+
+type UserStore struct {
+	mu     sync.RWMutex
+	nextID int
+	users  []models.User
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{}
+}
+
+// CreateUser сохраняет пользователя с ролью customer, как и колонка role по умолчанию
+func (s *UserStore) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == user.Email {
+			return fmt.Errorf("users.email %q: %w", user.Email, repository.ErrDuplicate)
+		}
+	}
+
+	s.nextID++
+	user.ID = s.nextID
+	user.Role = models.RoleCustomer
+	user.CreatedAt = time.Now()
+	s.users = append(s.users, *user)
+	return nil
+}
+
+func (s *UserStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *UserStore) UserExists(ctx context.Context, email string) (bool, error) {
+	user, err := s.GetUserByEmail(ctx, email)
+	return user != nil, err
+}
+
+// GetUserByID возвращает пользователя без хеша пароля, как и запрос в UserRepository
+func (s *UserStore) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.ID == userID {
+			u.PasswordHash = ""
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *UserStore) GetUsersByRole(ctx context.Context, role string) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var users []models.User
+	for _, u := range s.users {
+		if u.Role == role {
+			u.PasswordHash = ""
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (s *UserStore) UpdateProfile(ctx context.Context, user *models.User) error {
+	return s.update(user.ID, func(u *models.User) error {
+		u.FirstName = user.FirstName
+		u.LastName = user.LastName
+		if u.Phone != user.Phone {
+			u.PhoneVerifiedAt = nil
+		}
+		u.Phone = user.Phone
+		return nil
+	})
+}
+
+func (s *UserStore) GetUserByVerifiedPhone(ctx context.Context, phone string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, u := range s.users {
+		if u.Phone == phone && u.PhoneVerifiedAt != nil {
+			u.PasswordHash = ""
+			return &u, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetPhoneVerified повторяет частичный уникальный индекс по подтвержденным номерам
+func (s *UserStore) SetPhoneVerified(ctx context.Context, userID int, phone string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var user *models.User
+	for i := range s.users {
+		u := &s.users[i]
+		if u.ID == userID {
+			user = u
+		} else if u.Phone == phone && u.PhoneVerifiedAt != nil {
+			return false, fmt.Errorf("users.phone %q: %w", phone, repository.ErrDuplicate)
+		}
+	}
+	if user == nil || user.Phone != phone {
+		return false, nil
+	}
+	user.PhoneVerifiedAt = &at
+	return true, nil
+}
+
+func (s *UserStore) SetNewsletter(ctx context.Context, userID int, subscribed bool) error {
+	return s.update(userID, func(u *models.User) error {
+		u.Newsletter = subscribed
+		return nil
+	})
+}
+
+func (s *UserStore) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	return s.update(userID, func(u *models.User) error {
+		u.PasswordHash = passwordHash
+		return nil
+	})
+}
+
+func (s *UserStore) UpdateEmail(ctx context.Context, userID int, email string) error {
+	s.mu.RLock()
+	for _, u := range s.users {
+		if u.Email == email && u.ID != userID {
+			s.mu.RUnlock()
+			return fmt.Errorf("users.email %q: %w", email, repository.ErrDuplicate)
+		}
+	}
+	s.mu.RUnlock()
+
+	return s.update(userID, func(u *models.User) error {
+		u.Email = email
+		return nil
+	})
+}
+
+func (s *UserStore) DeleteUser(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = slices.DeleteFunc(s.users, func(u models.User) bool { return u.ID == userID })
+	return nil
+}
+
+// update применяет fn к пользователю; как и UPDATE в Postgres, отсутствие записи не ошибка
+func (s *UserStore) update(userID int, fn func(u *models.User) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID == userID {
+			return fn(&s.users[i])
+		}
+	}
+	return nil
+}
+
+// SetRole меняет роль пользователя; в Postgres роли назначаются вне приложения
+func (s *UserStore) SetRole(userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].ID == userID {
+			s.users[i].Role = role
+			return nil
+		}
+	}
+	return fmt.Errorf("memory: пользователь %d не найден", userID)
+}
+
+// lookup ищет пользователя по ID; nil-хранилище ничего не находит
+func (s *UserStore) lookup(userID int) (*models.User, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return s.GetUserByID(context.Background(), userID)
+}
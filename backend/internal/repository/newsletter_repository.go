@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// NewsletterRepository подписки на рассылку, кампании и очередь писем
+type NewsletterRepository struct {
+	db *DB
+}
+
+func NewNewsletterRepository(db *DB) *NewsletterRepository {
+	return &NewsletterRepository{db: db}
+}
+
+func (r *NewsletterRepository) CreateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO newsletter_subscriptions (email, user_id, status, source, confirm_token_hash, confirm_sent_at, ip)
+              VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, NULLIF($7, ''))
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		sub.Email,
+		sub.UserID,
+		sub.Status,
+		sub.Source,
+		sub.ConfirmTokenHash,
+		sub.ConfirmSentAt,
+		sub.IP,
+	).Scan(&sub.ID, &sub.CreatedAt)
+}
+
+// UpdateSubscription сохраняет статус, привязку к пользователю и токен подтверждения
+func (r *NewsletterRepository) UpdateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE newsletter_subscriptions
+              SET user_id = $2, status = $3, source = $4, confirm_token_hash = NULLIF($5, ''),
+                  confirm_sent_at = $6, ip = NULLIF($7, ''), confirmed_at = $8, unsubscribed_at = $9
+              WHERE id = $1`
+	_, err := r.db.ExecContext(ctx,
+		query,
+		sub.ID,
+		sub.UserID,
+		sub.Status,
+		sub.Source,
+		sub.ConfirmTokenHash,
+		sub.ConfirmSentAt,
+		sub.IP,
+		sub.ConfirmedAt,
+		sub.UnsubscribedAt,
+	)
+	return err
+}
+
+func (r *NewsletterRepository) GetSubscriptionByID(ctx context.Context, id int) (*models.NewsletterSubscription, error) {
+	return r.getSubscription(ctx, `id = $1`, id)
+}
+
+// GetSubscriptionByEmail ищет подписку по адресу; адреса хранятся в нижнем регистре
+func (r *NewsletterRepository) GetSubscriptionByEmail(ctx context.Context, email string) (*models.NewsletterSubscription, error) {
+	return r.getSubscription(ctx, `email = $1`, email)
+}
+
+func (r *NewsletterRepository) GetSubscriptionByUser(ctx context.Context, userID int) (*models.NewsletterSubscription, error) {
+	return r.getSubscription(ctx, `user_id = $1`, userID)
+}
+
+func (r *NewsletterRepository) GetSubscriptionByTokenHash(ctx context.Context, hash string) (*models.NewsletterSubscription, error) {
+	return r.getSubscription(ctx, `confirm_token_hash = $1`, hash)
+}
+
+func (r *NewsletterRepository) getSubscription(ctx context.Context, where string, arg any) (*models.NewsletterSubscription, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, email, user_id, status, source, COALESCE(confirm_token_hash, ''), confirm_sent_at,
+                     COALESCE(ip, ''), created_at, confirmed_at, unsubscribed_at
+              FROM newsletter_subscriptions WHERE ` + where
+
+	var s models.NewsletterSubscription
+	var userID sql.NullInt64
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(
+		&s.ID,
+		&s.Email,
+		&userID,
+		&s.Status,
+		&s.Source,
+		&s.ConfirmTokenHash,
+		&s.ConfirmSentAt,
+		&s.IP,
+		&s.CreatedAt,
+		&s.ConfirmedAt,
+		&s.UnsubscribedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		s.UserID = &id
+	}
+	return &s, nil
+}
+
+func (r *NewsletterRepository) CreateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO newsletter_campaigns (subject, intro, product_ids, status, created_by)
+              VALUES ($1, $2, $3, $4, $5)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		campaign.Subject,
+		campaign.Intro,
+		pq.Array(campaign.ProductIDs),
+		campaign.Status,
+		campaign.CreatedBy,
+	).Scan(&campaign.ID, &campaign.CreatedAt)
+}
+
+func (r *NewsletterRepository) UpdateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE newsletter_campaigns
+              SET subject = $2, intro = $3, product_ids = $4, status = $5, sent_at = $6
+              WHERE id = $1`
+	_, err := r.db.ExecContext(ctx,
+		query,
+		campaign.ID,
+		campaign.Subject,
+		campaign.Intro,
+		pq.Array(campaign.ProductIDs),
+		campaign.Status,
+		campaign.SentAt,
+	)
+	return err
+}
+
+const campaignColumns = `id, subject, intro, product_ids, status, created_by, created_at, sent_at`
+
+func (r *NewsletterRepository) GetCampaignByID(ctx context.Context, id int) (*models.NewsletterCampaign, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	c, err := scanCampaign(r.db.QueryRowContext(ctx, `SELECT `+campaignColumns+` FROM newsletter_campaigns WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// GetCampaigns кампании от новых к старым; пустой status — все
+func (r *NewsletterRepository) GetCampaigns(ctx context.Context, status string) ([]models.NewsletterCampaign, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT ` + campaignColumns + ` FROM newsletter_campaigns
+              WHERE $1 = '' OR status = $1
+              ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	campaigns := []models.NewsletterCampaign{}
+	for rows.Next() {
+		c, err := scanCampaign(rows)
+		if err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, *c)
+	}
+	return campaigns, rows.Err()
+}
+
+func scanCampaign(row interface{ Scan(...any) error }) (*models.NewsletterCampaign, error) {
+	var c models.NewsletterCampaign
+	var productIDs pq.Int64Array
+	var createdBy sql.NullInt64
+	err := row.Scan(&c.ID, &c.Subject, &c.Intro, &productIDs, &c.Status, &createdBy, &c.CreatedAt, &c.SentAt)
+	if err != nil {
+		return nil, err
+	}
+	c.ProductIDs = make([]int, len(productIDs))
+	for i, id := range productIDs {
+		c.ProductIDs[i] = int(id)
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		c.CreatedBy = &id
+	}
+	return &c, nil
+}
+
+// EnqueueDeliveries ставит в очередь письма всем активным подписчикам.
+// Подписчики, которым письмо кампании уже поставлено, пропускаются.
+func (r *NewsletterRepository) EnqueueDeliveries(ctx context.Context, campaignID int) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO newsletter_deliveries (campaign_id, subscription_id)
+              SELECT $1, id FROM newsletter_subscriptions WHERE status = 'active'
+              ON CONFLICT (campaign_id, subscription_id) DO NOTHING`
+	result, err := r.db.ExecContext(ctx, query, campaignID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetPendingDeliveries очередная партия неотправленных писем с адресом и статусом подписки
+func (r *NewsletterRepository) GetPendingDeliveries(ctx context.Context, campaignID, limit int) ([]models.NewsletterDelivery, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT d.id, d.campaign_id, d.subscription_id, d.status, COALESCE(u.email, s.email), s.status
+              FROM newsletter_deliveries d
+              JOIN newsletter_subscriptions s ON s.id = d.subscription_id
+              LEFT JOIN users u ON u.id = s.user_id
+              WHERE d.campaign_id = $1 AND d.status = 'pending'
+              ORDER BY d.id
+              LIMIT $2`
+	rows, err := r.db.QueryContext(ctx, query, campaignID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.NewsletterDelivery
+	for rows.Next() {
+		var d models.NewsletterDelivery
+		if err := rows.Scan(&d.ID, &d.CampaignID, &d.SubscriptionID, &d.Status, &d.Email, &d.SubscriptionStatus); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *NewsletterRepository) MarkDelivery(ctx context.Context, id int, status, errText string, at time.Time) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE newsletter_deliveries SET status = $2, error = NULLIF($3, ''), sent_at = $4 WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, id, status, errText, at.UTC())
+	return err
+}
+
+// CountDeliveries число писем кампании по статусам
+func (r *NewsletterRepository) CountDeliveries(ctx context.Context, campaignID int) (map[string]int, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT status, COUNT(*) FROM newsletter_deliveries WHERE campaign_id = $1 GROUP BY status`, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
@@ -2,19 +2,23 @@ package repository
 
 import (
 	"beladonna/backend/internal/models"
+	"context"
 	"database/sql"
 	"fmt"
 )
 
 type ProductRepository struct {
-	db *sql.DB
+	db *DB
 }
 
-func NewProductRepository(db *sql.DB) *ProductRepository {
+func NewProductRepository(db *DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (r *ProductRepository) GetProducts(filters models.ProductFilters) ([]models.Product, error) {
+func (r *ProductRepository) GetProducts(ctx context.Context, filters models.ProductFilters) ([]models.Product, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         SELECT p.id, p.name, p.description, p.price, p.category_id, 
                c.name as category_name, p.image_url, p.in_stock, p.material, p.created_at
@@ -52,7 +56,7 @@ func (r *ProductRepository) GetProducts(filters models.ProductFilters) ([]models
 
 	query += " AND p.in_stock = true ORDER BY p.created_at DESC"
 
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +78,11 @@ func (r *ProductRepository) GetProducts(filters models.ProductFilters) ([]models
 	return products, nil
 }
 
-func (r *ProductRepository) GetProductByID(id int) (*models.Product, error) {
+// GetProductByID возвращает товар по ID или nil, если такого нет
+func (r *ProductRepository) GetProductByID(ctx context.Context, id int) (*models.Product, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `
         SELECT p.id, p.name, p.description, p.price, p.category_id, 
                c.name as category_name, p.image_url, p.in_stock, p.material, p.created_at
@@ -83,13 +91,16 @@ func (r *ProductRepository) GetProductByID(id int) (*models.Product, error) {
         WHERE p.id = $1
     `
 
-	row := r.db.QueryRow(query, id)
+	row := r.db.QueryRowContext(ctx, query, id)
 
 	var p models.Product
 	err := row.Scan(
 		&p.ID, &p.Name, &p.Description, &p.Price, &p.CategoryID,
 		&p.CategoryName, &p.ImageURL, &p.InStock, &p.Material, &p.CreatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -97,10 +108,13 @@ func (r *ProductRepository) GetProductByID(id int) (*models.Product, error) {
 	return &p, nil
 }
 
-func (r *ProductRepository) GetCategories() ([]models.Category, error) {
+func (r *ProductRepository) GetCategories(ctx context.Context) ([]models.Category, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `SELECT id, name, description, created_at FROM categories ORDER BY name`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
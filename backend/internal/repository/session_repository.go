@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SessionRepository серверные сессии пользователей
+type SessionRepository struct {
+	db *DB
+}
+
+func NewSessionRepository(db *DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// CreateSession сохраняет сессию; в базе хранится только хеш токена
+func (r *SessionRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO user_sessions (user_id, token_hash, two_factor, ip, user_agent, expires_at)
+              VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		session.UserID,
+		session.TokenHash,
+		session.TwoFactor,
+		session.IP,
+		session.UserAgent,
+		session.ExpiresAt.UTC(),
+	).Scan(&session.ID, &session.CreatedAt)
+}
+
+// GetSessionByTokenHash возвращает сессию вместе с email и именем пользователя или nil
+func (r *SessionRepository) GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT s.id, s.user_id, s.token_hash, s.two_factor, COALESCE(s.ip, ''), COALESCE(s.user_agent, ''),
+                     s.expires_at, s.created_at, u.email, COALESCE(u.first_name, '') || ' ' || COALESCE(u.last_name, '')
+              FROM user_sessions s
+              JOIN users u ON u.id = s.user_id
+              WHERE s.token_hash = $1`
+
+	var s models.Session
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.TokenHash,
+		&s.TwoFactor,
+		&s.IP,
+		&s.UserAgent,
+		&s.ExpiresAt,
+		&s.CreatedAt,
+		&s.Email,
+		&s.Name,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteSession завершает сессию по хешу токена
+func (r *SessionRepository) DeleteSession(ctx context.Context, hash string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE token_hash = $1`, hash)
+	return err
+}
+
+// DeleteUserSessions завершает все сессии пользователя, кроме сессии с хешем exceptHash
+func (r *SessionRepository) DeleteUserSessions(ctx context.Context, userID int, exceptHash string) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `DELETE FROM user_sessions WHERE user_id = $1 AND token_hash <> $2`
+	result, err := r.db.ExecContext(ctx, query, userID, exceptHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// DeleteExpiredSessions удаляет сессии, истекшие до before
+func (r *SessionRepository) DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_sessions WHERE expires_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
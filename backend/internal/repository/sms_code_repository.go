@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SmsCodeRepository одноразовые коды из SMS для входа и подтверждения номера
+type SmsCodeRepository struct {
+	db *DB
+}
+
+func NewSmsCodeRepository(db *DB) *SmsCodeRepository {
+	return &SmsCodeRepository{db: db}
+}
+
+func (r *SmsCodeRepository) CreateSmsCode(ctx context.Context, code *models.SmsCode) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO sms_codes (phone, purpose, user_id, code_hash, ip, expires_at)
+              VALUES ($1, $2, NULLIF($3, 0), $4, NULLIF($5, ''), $6)
+              RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx,
+		query,
+		code.Phone,
+		code.Purpose,
+		code.UserID,
+		code.CodeHash,
+		code.IP,
+		code.ExpiresAt.UTC(),
+	).Scan(&code.ID, &code.CreatedAt)
+}
+
+// GetLatestSmsCode последний код на номер с назначением purpose или nil
+func (r *SmsCodeRepository) GetLatestSmsCode(ctx context.Context, phone, purpose string) (*models.SmsCode, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, phone, purpose, COALESCE(user_id, 0), code_hash, attempts, COALESCE(ip, ''),
+                     expires_at, used_at, created_at
+              FROM sms_codes WHERE phone = $1 AND purpose = $2
+              ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	var c models.SmsCode
+	err := r.db.QueryRowContext(ctx, query, phone, purpose).Scan(
+		&c.ID,
+		&c.Phone,
+		&c.Purpose,
+		&c.UserID,
+		&c.CodeHash,
+		&c.Attempts,
+		&c.IP,
+		&c.ExpiresAt,
+		&c.UsedAt,
+		&c.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CountSmsCodes число кодов на номер, выданных после since, с любым назначением
+func (r *SmsCodeRepository) CountSmsCodes(ctx context.Context, phone string, since time.Time) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM sms_codes WHERE phone = $1 AND created_at > $2`, phone, since.UTC()).Scan(&n)
+	return n, err
+}
+
+// AddSmsCodeAttempt учитывает попытку ввода кода и возвращает их число с учетом этой
+func (r *SmsCodeRepository) AddSmsCodeAttempt(ctx context.Context, id int) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var attempts int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE sms_codes SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts`, id).Scan(&attempts)
+	return attempts, err
+}
+
+// MarkSmsCodeUsed гасит код; false, если его уже использовал параллельный запрос
+func (r *SmsCodeRepository) MarkSmsCodeUsed(ctx context.Context, id int, at time.Time) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `UPDATE sms_codes SET used_at = $2 WHERE id = $1 AND used_at IS NULL`, id, at.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteSmsCodesBefore удаляет коды, выданные раньше before
+func (r *SmsCodeRepository) DeleteSmsCodesBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sms_codes WHERE created_at < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
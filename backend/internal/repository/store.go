@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"time"
+)
+
+// Интерфейсы хранилищ, от которых зависят сервисы. Репозитории этого пакета работают
+// с Postgres, пакет repository/memory хранит данные в памяти для тестов.
+// Методы поиска по ID возвращают nil без ошибки, если запись не найдена.
+
+type UserStore interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UserExists(ctx context.Context, email string) (bool, error)
+	GetUserByID(ctx context.Context, userID int) (*models.User, error)
+	GetUsersByRole(ctx context.Context, role string) ([]models.User, error)
+	UpdateProfile(ctx context.Context, user *models.User) error
+	GetUserByVerifiedPhone(ctx context.Context, phone string) (*models.User, error)
+	SetPhoneVerified(ctx context.Context, userID int, phone string, at time.Time) (bool, error)
+	SetNewsletter(ctx context.Context, userID int, subscribed bool) error
+	UpdatePassword(ctx context.Context, userID int, passwordHash string) error
+	UpdateEmail(ctx context.Context, userID int, email string) error
+	DeleteUser(ctx context.Context, userID int) error
+}
+
+type ProductStore interface {
+	GetProducts(ctx context.Context, filters models.ProductFilters) ([]models.Product, error)
+	GetProductByID(ctx context.Context, id int) (*models.Product, error)
+	GetCategories(ctx context.Context) ([]models.Category, error)
+}
+
+// CartStore корзины пользователей. AddToCart при повторном добавлении товара
+// увеличивает количество, но не выше maxQuantity.
+type CartStore interface {
+	GetCartItems(ctx context.Context, userID int) ([]models.CartItem, error)
+	AddToCart(ctx context.Context, userID, productID, quantity, maxQuantity int) error
+	UpdateCartItem(ctx context.Context, itemID, quantity int) error
+	RemoveFromCart(ctx context.Context, itemID int) error
+	ClearUserCart(ctx context.Context, userID int) error
+	GetCartItemByID(ctx context.Context, itemID int) (*models.CartItem, error)
+}
+
+type FeedbackStore interface {
+	CreateFeedback(ctx context.Context, feedback *models.Feedback) error
+	GetVisibleFeedbacks(ctx context.Context, theme string) ([]models.Feedback, error)
+	CreateReply(ctx context.Context, reply *models.FeedbackReply) error
+	MarkReplyEmailed(ctx context.Context, replyID int) error
+	SetStatus(ctx context.Context, feedbackID int, status string) error
+	GetReplies(ctx context.Context, feedbackIDs []int, publicOnly bool) (map[int][]models.FeedbackReply, error)
+	GetFeedbacksByStatus(ctx context.Context, status, theme string) ([]models.Feedback, error)
+	GetFeedbacksByUser(ctx context.Context, userID int) ([]models.Feedback, error)
+	GetFeedbackByID(ctx context.Context, id int) (*models.Feedback, error)
+	SetModerationStatus(ctx context.Context, id, moderatorID int, status, action, reason string) error
+	UpdateFeedbackContent(ctx context.Context, feedback *models.Feedback, moderatorID int, note string) error
+	GetModerationHistory(ctx context.Context, feedbackID int) ([]models.FeedbackModerationEntry, error)
+	HasRecentDuplicate(ctx context.Context, email, message string, since time.Time) (bool, error)
+	LogRejection(ctx context.Context, rejection *models.FeedbackRejection) error
+	AnonymizeUserFeedbacks(ctx context.Context, userID int, email, name string) (int64, error)
+	DeleteUserRejections(ctx context.Context, userID int, email string) error
+}
+
+type FeedbackThemeStore interface {
+	GetThemes(ctx context.Context, activeOnly bool) ([]models.FeedbackTheme, error)
+	GetThemeByCode(ctx context.Context, code string) (*models.FeedbackTheme, error)
+	GetThemeByID(ctx context.Context, id int) (*models.FeedbackTheme, error)
+	CreateTheme(ctx context.Context, theme *models.FeedbackTheme) error
+	UpdateTheme(ctx context.Context, theme *models.FeedbackTheme) error
+}
+
+// LoginAttemptStore счетчики неудачных входов по email и журнал блокировок.
+// Методы поиска возвращают nil без ошибки, если записи нет.
+type LoginAttemptStore interface {
+	GetThrottle(ctx context.Context, email string) (*models.LoginThrottle, error)
+	RecordFailure(ctx context.Context, email string, at, since time.Time) (*models.LoginThrottle, error)
+	LockUntil(ctx context.Context, email string, until time.Time) error
+	ResetThrottle(ctx context.Context, email string) error
+	DeleteStaleThrottles(ctx context.Context, before time.Time) (int64, error)
+	CreateLockout(ctx context.Context, lockout *models.AccountLockout) error
+	GetLockoutByTokenHash(ctx context.Context, hash string) (*models.AccountLockout, error)
+	MarkUnlocked(ctx context.Context, id int, at time.Time) error
+	DeleteUserLockouts(ctx context.Context, userID int, email string) error
+}
+
+// TwoFactorStore ключи TOTP и хеши кодов восстановления.
+// UseStep и UseRecoveryCode возвращают false, если код уже использован.
+type TwoFactorStore interface {
+	GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error)
+	SavePendingSecret(ctx context.Context, userID int, secret string) error
+	EnableTwoFactor(ctx context.Context, userID int, at time.Time, step int64) error
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	DeleteTwoFactor(ctx context.Context, userID int) error
+	ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, hash string, at time.Time) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID int) (int, error)
+}
+
+// SessionStore серверные сессии. Сессии ищутся по SHA-256 токена из cookie;
+// GetSessionByTokenHash заполняет Email и Name из профиля пользователя.
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSessionByTokenHash(ctx context.Context, hash string) (*models.Session, error)
+	DeleteSession(ctx context.Context, hash string) error
+	DeleteUserSessions(ctx context.Context, userID int, exceptHash string) (int64, error)
+	DeleteExpiredSessions(ctx context.Context, before time.Time) (int64, error)
+}
+
+type EmailChangeStore interface {
+	CreateEmailChange(ctx context.Context, change *models.EmailChange) error
+	GetEmailChangeByTokenHash(ctx context.Context, hash string) (*models.EmailChange, error)
+	MarkEmailChangeConfirmed(ctx context.Context, id int, at time.Time) error
+}
+
+// AccountDeletionStore журнал удаленных аккаунтов
+type AccountDeletionStore interface {
+	CreateAccountDeletion(ctx context.Context, deletion *models.AccountDeletion) error
+}
+
+// ConsentStore версии юридических документов и журнал согласий с ними.
+// CreateConsent не дублирует согласие с уже принятой версией.
+type ConsentStore interface {
+	CreatePolicyDocument(ctx context.Context, doc *models.PolicyDocument) error
+	GetLatestPolicyDocument(ctx context.Context, kind string) (*models.PolicyDocument, error)
+	GetPolicyDocumentByID(ctx context.Context, id int) (*models.PolicyDocument, error)
+	CreateConsent(ctx context.Context, consent *models.Consent) error
+	GetUserConsents(ctx context.Context, userID int) ([]models.Consent, error)
+	CreateCookieConsent(ctx context.Context, consent *models.CookieConsent) error
+}
+
+// NewsletterStore подписки на рассылку, кампании и очередь их писем.
+// Письма подписчику с аккаунтом уходят на текущий email аккаунта.
+type NewsletterStore interface {
+	CreateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error
+	UpdateSubscription(ctx context.Context, sub *models.NewsletterSubscription) error
+	GetSubscriptionByID(ctx context.Context, id int) (*models.NewsletterSubscription, error)
+	GetSubscriptionByEmail(ctx context.Context, email string) (*models.NewsletterSubscription, error)
+	GetSubscriptionByUser(ctx context.Context, userID int) (*models.NewsletterSubscription, error)
+	GetSubscriptionByTokenHash(ctx context.Context, hash string) (*models.NewsletterSubscription, error)
+	CreateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error
+	UpdateCampaign(ctx context.Context, campaign *models.NewsletterCampaign) error
+	GetCampaignByID(ctx context.Context, id int) (*models.NewsletterCampaign, error)
+	GetCampaigns(ctx context.Context, status string) ([]models.NewsletterCampaign, error)
+	EnqueueDeliveries(ctx context.Context, campaignID int) (int64, error)
+	GetPendingDeliveries(ctx context.Context, campaignID, limit int) ([]models.NewsletterDelivery, error)
+	MarkDelivery(ctx context.Context, id int, status, errText string, at time.Time) error
+	CountDeliveries(ctx context.Context, campaignID int) (map[string]int, error)
+}
+
+// SmsCodeStore одноразовые коды из SMS. GetLatestSmsCode возвращает последний
+// выданный код на номер, даже если он использован или истек.
+type SmsCodeStore interface {
+	CreateSmsCode(ctx context.Context, code *models.SmsCode) error
+	GetLatestSmsCode(ctx context.Context, phone, purpose string) (*models.SmsCode, error)
+	CountSmsCodes(ctx context.Context, phone string, since time.Time) (int, error)
+	AddSmsCodeAttempt(ctx context.Context, id int) (int, error)
+	MarkSmsCodeUsed(ctx context.Context, id int, at time.Time) (bool, error)
+	DeleteSmsCodesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// IdentityStore привязанные аккаунты OpenID Connect и незавершенные входы через провайдеров.
+// TakeOIDCState удаляет найденную запись: state годится только для одного возврата.
+type IdentityStore interface {
+	CreateIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	GetUserIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error)
+	DeleteIdentity(ctx context.Context, userID, id int) (bool, error)
+	TouchIdentity(ctx context.Context, id int, email string, at time.Time) error
+	CreateOIDCState(ctx context.Context, state *models.OIDCLoginState) error
+	TakeOIDCState(ctx context.Context, hash string) (*models.OIDCLoginState, error)
+	DeleteExpiredOIDCStates(ctx context.Context, before time.Time) (int64, error)
+}
+
+var (
+	_ UserStore            = (*UserRepository)(nil)
+	_ ProductStore         = (*ProductRepository)(nil)
+	_ CartStore            = (*CartRepository)(nil)
+	_ FeedbackStore        = (*FeedbackRepository)(nil)
+	_ FeedbackThemeStore   = (*FeedbackThemeRepository)(nil)
+	_ LoginAttemptStore    = (*LoginAttemptRepository)(nil)
+	_ TwoFactorStore       = (*TwoFactorRepository)(nil)
+	_ SessionStore         = (*SessionRepository)(nil)
+	_ EmailChangeStore     = (*EmailChangeRepository)(nil)
+	_ AccountDeletionStore = (*AccountDeletionRepository)(nil)
+	_ ConsentStore         = (*ConsentRepository)(nil)
+	_ NewsletterStore      = (*NewsletterRepository)(nil)
+	_ SmsCodeStore         = (*SmsCodeRepository)(nil)
+	_ IdentityStore        = (*IdentityRepository)(nil)
+)
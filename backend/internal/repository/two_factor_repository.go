@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"beladonna/backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// TwoFactorRepository ключи TOTP и коды восстановления пользователей
+type TwoFactorRepository struct {
+	db *DB
+}
+
+func NewTwoFactorRepository(db *DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// GetTwoFactor возвращает ключ пользователя или nil, если 2FA не подключалась
+func (r *TwoFactorRepository) GetTwoFactor(ctx context.Context, userID int) (*models.TwoFactor, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT user_id, secret, enabled_at, last_used_step, created_at
+              FROM user_two_factor WHERE user_id = $1`
+
+	var t models.TwoFactor
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&t.UserID, &t.Secret, &t.EnabledAt, &t.LastUsedStep, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SavePendingSecret сохраняет новый ключ, ожидающий подтверждения, вместо прежнего
+func (r *TwoFactorRepository) SavePendingSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `INSERT INTO user_two_factor (user_id, secret) VALUES ($1, $2)
+              ON CONFLICT (user_id) DO UPDATE
+              SET secret = EXCLUDED.secret, enabled_at = NULL, last_used_step = 0, created_at = NOW()`
+	_, err := r.db.ExecContext(ctx, query, userID, secret)
+	return err
+}
+
+// EnableTwoFactor подтверждает подключение; step — шаг кода, которым оно подтверждено
+func (r *TwoFactorRepository) EnableTwoFactor(ctx context.Context, userID int, at time.Time, step int64) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_two_factor SET enabled_at = $2, last_used_step = $3 WHERE user_id = $1`
+	_, err := r.db.ExecContext(ctx, query, userID, at.UTC(), step)
+	return err
+}
+
+// UseStep запоминает шаг принятого кода. Возвращает false, если этот или более поздний
+// шаг уже использован: так один код не пройдет дважды даже при параллельных запросах.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_two_factor SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteTwoFactor отключает 2FA и удаляет коды восстановления
+func (r *TwoFactorRepository) DeleteTwoFactor(ctx context.Context, userID int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return r.db.inTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		_, err := r.db.ExecContext(ctx, `DELETE FROM user_two_factor WHERE user_id = $1`, userID)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodes заменяет все коды восстановления пользователя новыми (по их хешам)
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, hashes []string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	return r.db.inTx(ctx, func(ctx context.Context) error {
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, hash := range hashes {
+			query := `INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+			if _, err := r.db.ExecContext(ctx, query, userID, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode погашает неиспользованный код с хешем hash. Возвращает false, если такого нет.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID int, hash string, at time.Time) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE user_recovery_codes SET used_at = $3
+              WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	result, err := r.db.ExecContext(ctx, query, userID, hash, at.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// CountRecoveryCodes число неиспользованных кодов восстановления
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	var count int
+	query := `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UnitOfWork выполняет несколько вызовов репозиториев как одно целое.
+// Все вызовы с контекстом, переданным в fn, идут в одной транзакции;
+// если fn возвращает ошибку, изменения откатываются.
+// Вложенный Do выполняется в уже открытой транзакции.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+var _ UnitOfWork = (*DB)(nil)
+
+// ErrDuplicate нарушено ограничение уникальности. Хранилища в памяти возвращают его,
+// Postgres — ошибку 23505; IsDuplicate распознает оба варианта.
+var ErrDuplicate = errors.New("repository: запись уже существует")
+
+// IsDuplicate сообщает, нарушено ли ограничение уникальности
+func IsDuplicate(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return errors.Is(err, ErrDuplicate)
+}
+
+// Повторы транзакции при конфликте сериализации
+const (
+	maxTxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+type txKey struct{}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// Do выполняет fn в транзакции с уровнем изоляции SERIALIZABLE.
+// При конфликте сериализации (40001) или взаимоблокировке (40P01) fn повторяется
+// целиком, поэтому она не должна иметь побочных эффектов вне базы.
+func (d *DB) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	for attempt := 1; ; attempt++ {
+		err := d.runTx(ctx, opts, fn)
+		if err == nil || attempt == maxTxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(time.Duration(attempt) * txRetryDelay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// inTx выполняет несколько запросов одного репозитория атомарно:
+// в транзакции из ctx, если она есть, иначе в собственной
+func (d *DB) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return d.runTx(ctx, nil, fn)
+}
+
+func (d *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context) error) error {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// QueryContext, QueryRowContext и ExecContext выполняют запрос в транзакции из ctx,
+// если она открыта через Do, иначе на пуле соединений
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryContext(ctx, query, args...)
+	}
+	return d.DB.QueryContext(ctx, query, args...)
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return d.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	return d.DB.ExecContext(ctx, query, args...)
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		duplicate bool
+		retryable bool
+	}{
+		{"unique violation", &pq.Error{Code: "23505"}, true, false},
+		{"wrapped unique violation", fmt.Errorf("create user: %w", &pq.Error{Code: "23505"}), true, false},
+		{"in-memory duplicate", fmt.Errorf("users.email: %w", ErrDuplicate), true, false},
+		{"serialization failure", &pq.Error{Code: "40001"}, false, true},
+		{"deadlock", &pq.Error{Code: "40P01"}, false, true},
+		{"foreign key violation", &pq.Error{Code: "23503"}, false, false},
+		{"other error", errors.New("boom"), false, false},
+		{"nil", nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDuplicate(tt.err); got != tt.duplicate {
+				t.Errorf("IsDuplicate = %v, want %v", got, tt.duplicate)
+			}
+			if got := isRetryable(tt.err); got != tt.retryable {
+				t.Errorf("isRetryable = %v, want %v", got, tt.retryable)
+			}
+		})
+	}
+}
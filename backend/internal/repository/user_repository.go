@@ -2,22 +2,27 @@ package repository
 
 import (
 	"beladonna/backend/internal/models"
+	"context"
 	"database/sql"
+	"time"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db *DB
 }
 
-func NewUserRepository(db *sql.DB) *UserRepository {
+func NewUserRepository(db *DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(user *models.User) error {
+func (r *UserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	query := `INSERT INTO users (email, password_hash, first_name, last_name, phone, newsletter) 
               VALUES ($1, $2, $3, $4, $5, $6) 
-              RETURNING id, created_at`
-	err := r.db.QueryRow(
+              RETURNING id, role, created_at`
+	err := r.db.QueryRowContext(ctx,
 		query,
 		user.Email,
 		user.PasswordHash,
@@ -25,22 +30,27 @@ func (r *UserRepository) CreateUser(user *models.User) error {
 		user.LastName,
 		user.Phone,
 		user.Newsletter,
-	).Scan(&user.ID, &user.CreatedAt)
+	).Scan(&user.ID, &user.Role, &user.CreatedAt)
 	return err
 }
 
-func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
+func (r *UserRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	user := &models.User{}
-	query := `SELECT id, email, password_hash, first_name, last_name, phone, newsletter, created_at 
+	query := `SELECT id, email, password_hash, first_name, last_name, phone, phone_verified_at, newsletter, role, created_at 
               FROM users WHERE email = $1`
-	err := r.db.QueryRow(query, email).Scan(
+	err := r.db.QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Email,
 		&user.PasswordHash,
 		&user.FirstName,
 		&user.LastName,
 		&user.Phone,
+		&user.PhoneVerifiedAt,
 		&user.Newsletter,
+		&user.Role,
 		&user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -49,29 +59,156 @@ func (r *UserRepository) GetUserByEmail(email string) (*models.User, error) {
 	return user, err
 }
 
-func (r *UserRepository) UserExists(email string) (bool, error) {
+func (r *UserRepository) UserExists(ctx context.Context, email string) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
-	err := r.db.QueryRow(query, email).Scan(&exists)
+	err := r.db.QueryRowContext(ctx, query, email).Scan(&exists)
 	return exists, err
 }
 
-// GetUserByID получает пользователя по ID
-func (r *UserRepository) GetUserByID(userID int) (*models.User, error) {
+// GetUserByID получает пользователя по ID или nil, если такого нет
+func (r *UserRepository) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
 	user := &models.User{}
-	query := `SELECT id, email, first_name, last_name, phone, newsletter, created_at 
+	query := `SELECT id, email, first_name, last_name, phone, phone_verified_at, newsletter, role, created_at 
               FROM users WHERE id = $1`
-	err := r.db.QueryRow(query, userID).Scan(
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID,
 		&user.Email,
 		&user.FirstName,
 		&user.LastName,
 		&user.Phone,
+		&user.PhoneVerifiedAt,
 		&user.Newsletter,
+		&user.Role,
 		&user.CreatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
+
+// GetUsersByRole возвращает пользователей с указанной ролью
+func (r *UserRepository) GetUsersByRole(ctx context.Context, role string) ([]models.User, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT id, email, first_name, last_name, role, created_at 
+              FROM users WHERE role = $1 ORDER BY id`
+	rows, err := r.db.QueryContext(ctx, query, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.Email, &user.FirstName, &user.LastName, &user.Role, &user.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateProfile сохраняет имя, фамилию и телефон; новый номер требует повторного подтверждения
+func (r *UserRepository) UpdateProfile(ctx context.Context, user *models.User) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	query := `UPDATE users SET first_name = $2, last_name = $3, phone = $4,
+                  phone_verified_at = CASE WHEN phone = $4 THEN phone_verified_at END
+              WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, query, user.ID, user.FirstName, user.LastName, user.Phone)
+	return err
+}
+
+// GetUserByVerifiedPhone возвращает пользователя, подтвердившего номер phone, или nil
+func (r *UserRepository) GetUserByVerifiedPhone(ctx context.Context, phone string) (*models.User, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	user := &models.User{}
+	query := `SELECT id, email, first_name, last_name, phone, phone_verified_at, newsletter, role, created_at
+              FROM users WHERE phone = $1 AND phone_verified_at IS NOT NULL`
+	err := r.db.QueryRowContext(ctx, query, phone).Scan(
+		&user.ID,
+		&user.Email,
+		&user.FirstName,
+		&user.LastName,
+		&user.Phone,
+		&user.PhoneVerifiedAt,
+		&user.Newsletter,
+		&user.Role,
+		&user.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// SetPhoneVerified отмечает номер подтвержденным, если у пользователя все еще номер phone.
+// Возвращает false, если номер успели сменить; номер, подтвержденный другим
+// пользователем, остановит уникальный индекс.
+func (r *UserRepository) SetPhoneVerified(ctx context.Context, userID int, phone string, at time.Time) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET phone_verified_at = $3 WHERE id = $1 AND phone = $2`, userID, phone, at.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// SetNewsletter отмечает, что пользователь подписан на рассылку; флаг ведет NewsletterService
+func (r *UserRepository) SetNewsletter(ctx context.Context, userID int, subscribed bool) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET newsletter = $2 WHERE id = $1`, userID, subscribed)
+	return err
+}
+
+// UpdatePassword заменяет хеш пароля
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID int, passwordHash string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, userID, passwordHash)
+	return err
+}
+
+// UpdateEmail меняет email; занятый адрес остановит уникальный индекс
+func (r *UserRepository) UpdateEmail(ctx context.Context, userID int, email string) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET email = $2 WHERE id = $1`, userID, email)
+	return err
+}
+
+// DeleteUser удаляет пользователя; корзина, сессии и ключи 2FA удаляются каскадом
+func (r *UserRepository) DeleteUser(ctx context.Context, userID int) error {
+	ctx, cancel := r.db.withTimeout(ctx)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	return err
+}
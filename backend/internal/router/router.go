@@ -0,0 +1,126 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware оборачивает обработчик
+type Middleware func(http.Handler) http.Handler
+
+// ErrorHandler отвечает вместо ServeMux, когда маршрут не найден (404)
+// или метод не поддерживается (405). Заголовок Allow к этому моменту уже выставлен.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, status int)
+
+// Router регистрирует маршруты с методом и параметрами пути в http.ServeMux (Go 1.22+).
+// Если путь существует, но метод не подходит, ServeMux отвечает 405 с заголовком Allow.
+// Группы разделяют один ServeMux и добавляют к маршрутам свой префикс и цепочку middleware.
+type Router struct {
+	mux         *http.ServeMux
+	prefix      string
+	middlewares []Middleware
+	onError     ErrorHandler
+}
+
+func New() *Router {
+	return &Router{mux: http.NewServeMux()}
+}
+
+// Use добавляет middleware ко всем маршрутам, которые будут зарегистрированы в группе после вызова
+func (r *Router) Use(middlewares ...Middleware) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// SetErrorHandler задает ответ на 404 и 405 вместо текстовых ответов ServeMux.
+// Вызывается на корневом роутере до создания групп.
+func (r *Router) SetErrorHandler(h ErrorHandler) {
+	r.onError = h
+}
+
+// Group создает группу маршрутов с общим префиксом. Группа наследует middleware родителя.
+func (r *Router) Group(prefix string, middlewares ...Middleware) *Router {
+	chain := make([]Middleware, 0, len(r.middlewares)+len(middlewares))
+	chain = append(chain, r.middlewares...)
+	chain = append(chain, middlewares...)
+
+	return &Router{
+		mux:         r.mux,
+		prefix:      r.prefix + strings.TrimSuffix(prefix, "/"),
+		middlewares: chain,
+		onError:     r.onError,
+	}
+}
+
+// Handle регистрирует обработчик для метода и пути, например Handle("GET", "/products/{id}", h).
+// Пустой method означает любой метод.
+func (r *Router) Handle(method, path string, handler http.Handler) {
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+
+	pattern := r.prefix + path
+	if method != "" {
+		pattern = method + " " + pattern
+	}
+	r.mux.Handle(pattern, handler)
+}
+
+func (r *Router) HandleFunc(method, path string, handler http.HandlerFunc) {
+	r.Handle(method, path, handler)
+}
+
+func (r *Router) Get(path string, handler http.HandlerFunc) {
+	r.Handle(http.MethodGet, path, handler)
+}
+
+func (r *Router) Post(path string, handler http.HandlerFunc) {
+	r.Handle(http.MethodPost, path, handler)
+}
+
+func (r *Router) Put(path string, handler http.HandlerFunc) {
+	r.Handle(http.MethodPut, path, handler)
+}
+
+func (r *Router) Delete(path string, handler http.HandlerFunc) {
+	r.Handle(http.MethodDelete, path, handler)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.onError != nil {
+		// ServeMux не дает подменить ответы 404 и 405, поэтому выполняем его
+		// служебный обработчик вхолостую и берем из него только статус и Allow
+		if h, pattern := r.mux.Handler(req); pattern == "" {
+			rec := &statusRecorder{header: http.Header{}}
+			h.ServeHTTP(rec, req)
+			if rec.status == http.StatusNotFound || rec.status == http.StatusMethodNotAllowed {
+				if allow := rec.header.Get("Allow"); allow != "" {
+					w.Header().Set("Allow", allow)
+				}
+				r.onError(w, req, rec.status)
+				return
+			}
+		}
+	}
+	r.mux.ServeHTTP(w, req)
+}
+
+// statusRecorder запоминает статус и заголовки ответа, отбрасывая тело
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header { return s.header }
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return len(b), nil
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+}
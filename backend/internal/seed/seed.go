@@ -0,0 +1,194 @@
+package seed
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Демо-данные для разработки и тестов. Фикстуры лежат в JSON-файлах, повторный запуск
+// обновляет категории и товары по имени. Существующих пользователей не трогает:
+// пароль и роль, измененные после загрузки, не сбрасываются.
+
+type Category struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type Product struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Category    string  `json:"category"`
+	ImageURL    string  `json:"image_url"`
+	Material    string  `json:"material"`
+	InStock     *bool   `json:"in_stock"`
+}
+
+type User struct {
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Phone      string `json:"phone"`
+	Newsletter bool   `json:"newsletter"`
+	Role       string `json:"role"`
+}
+
+// Fixtures набор демо-данных
+type Fixtures struct {
+	Categories []Category
+	Products   []Product
+	Users      []User
+}
+
+// resetTables очищаются командой reset. Справочники из миграций (feedback_themes)
+// и schema_migrations не трогаем.
+var resetTables = []string{
+	"feedback_replies",
+	"feedback_moderation_log",
+	"feedback_rejections",
+	"feedbacks",
+	"cart_items",
+	"sessions",
+	"products",
+	"categories",
+	"users",
+}
+
+// LoadDir читает categories.json, products.json и users.json; отсутствующий файл пропускается
+func LoadDir(dir string) (*Fixtures, error) {
+	fixtures := &Fixtures{}
+	files := map[string]interface{}{
+		"categories.json": &fixtures.Categories,
+		"products.json":   &fixtures.Products,
+		"users.json":      &fixtures.Users,
+	}
+
+	for name, target := range files {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read seed file %s: %v", name, err)
+		}
+		if err := json.Unmarshal(content, target); err != nil {
+			return nil, fmt.Errorf("invalid seed file %s: %v", name, err)
+		}
+	}
+
+	return fixtures, nil
+}
+
+// Seeder загружает демо-данные в базу
+type Seeder struct {
+	db       *sql.DB
+	fixtures *Fixtures
+}
+
+func New(db *sql.DB, fixtures *Fixtures) *Seeder {
+	return &Seeder{db: db, fixtures: fixtures}
+}
+
+// Run загружает фикстуры в одной транзакции. Повторный запуск безопасен.
+func (s *Seeder) Run(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.seedCategories(ctx, tx); err != nil {
+		return err
+	}
+	if err := s.seedProducts(ctx, tx); err != nil {
+		return err
+	}
+	if err := s.seedUsers(ctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Демо-данные загружены",
+		"categories", len(s.fixtures.Categories),
+		"products", len(s.fixtures.Products),
+		"users", len(s.fixtures.Users))
+	return nil
+}
+
+// Reset очищает данные приложения и загружает фикстуры заново
+func (s *Seeder) Reset(ctx context.Context) error {
+	query := "TRUNCATE " + strings.Join(resetTables, ", ") + " RESTART IDENTITY CASCADE"
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to reset data: %v", err)
+	}
+	slog.InfoContext(ctx, "Данные приложения очищены")
+	return s.Run(ctx)
+}
+
+func (s *Seeder) seedCategories(ctx context.Context, tx *sql.Tx) error {
+	query := `INSERT INTO categories (name, description) VALUES ($1, $2)
+              ON CONFLICT (name) DO UPDATE SET description = EXCLUDED.description`
+	for _, c := range s.fixtures.Categories {
+		if _, err := tx.ExecContext(ctx, query, c.Name, c.Description); err != nil {
+			return fmt.Errorf("failed to seed category %s: %v", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Seeder) seedProducts(ctx context.Context, tx *sql.Tx) error {
+	query := `INSERT INTO products (name, description, price, category_id, image_url, material, in_stock)
+              VALUES ($1, $2, $3, (SELECT id FROM categories WHERE name = $4), $5, NULLIF($6, ''), $7)
+              ON CONFLICT (name) DO UPDATE SET
+                  description = EXCLUDED.description,
+                  price = EXCLUDED.price,
+                  category_id = EXCLUDED.category_id,
+                  image_url = EXCLUDED.image_url,
+                  material = EXCLUDED.material,
+                  in_stock = EXCLUDED.in_stock`
+	for _, p := range s.fixtures.Products {
+		inStock := true
+		if p.InStock != nil {
+			inStock = *p.InStock
+		}
+		_, err := tx.ExecContext(ctx, query, p.Name, p.Description, p.Price, p.Category, p.ImageURL, p.Material, inStock)
+		if err != nil {
+			return fmt.Errorf("failed to seed product %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Seeder) seedUsers(ctx context.Context, tx *sql.Tx) error {
+	query := `INSERT INTO users (email, password_hash, first_name, last_name, phone, newsletter, role)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              ON CONFLICT (email) DO NOTHING`
+	for _, u := range s.fixtures.Users {
+		hash, err := utils.HashPassword(u.Password)
+		if err != nil {
+			return err
+		}
+		role := u.Role
+		if role == "" {
+			role = models.RoleCustomer
+		}
+		_, err = tx.ExecContext(ctx, query, u.Email, hash, u.FirstName, u.LastName, u.Phone, u.Newsletter, role)
+		if err != nil {
+			return fmt.Errorf("failed to seed user %s: %v", u.Email, err)
+		}
+	}
+	return nil
+}
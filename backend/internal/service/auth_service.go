@@ -1,113 +1,268 @@
 package service
 
 import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
 	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/phone"
 	"beladonna/backend/internal/repository"
 	"beladonna/backend/internal/utils"
+	"context"
 	"errors"
-	"log"
+	"sync"
+)
+
+var (
+	ErrTermsNotAccepted = apperr.Validation("terms_not_accepted", "Необходимо согласие с условиями использования").
+				WithField("agreeTerms", "Необходимо согласие с условиями использования")
+	ErrEmailTaken = apperr.Conflict("email_taken", "Пользователь с таким email уже существует").
+			WithField("email", "Пользователь с таким email уже существует")
+	ErrInvalidCredentials = apperr.Unauthorized("invalid_credentials", "Неверный email или пароль")
+	ErrUserNotFound       = apperr.NotFound("user_not_found", "Пользователь не найден")
 )
 
 type AuthService struct {
-	userRepo *repository.UserRepository
+	userRepo   repository.UserStore
+	tx         repository.UnitOfWork
+	guard      *LoginGuard
+	twoFactor  *TwoFactorService
+	consents   *ConsentService
+	newsletter *NewsletterService
+	phones     *PhoneService
 }
 
-func NewAuthService(userRepo *repository.UserRepository) *AuthService {
-	return &AuthService{userRepo: userRepo}
+func NewAuthService(userRepo repository.UserStore, tx repository.UnitOfWork, guard *LoginGuard, twoFactor *TwoFactorService, consents *ConsentService, newsletter *NewsletterService, phones *PhoneService) *AuthService {
+	return &AuthService{userRepo: userRepo, tx: tx, guard: guard, twoFactor: twoFactor, consents: consents, newsletter: newsletter, phones: phones}
 }
 
-func (s *AuthService) Register(req models.RegisterRequest) (*models.AuthResponse, error) {
-	log.Printf("Начало регистрации для email: %s", req.Email)
+// dummyHash хеш, с которым сверяется пароль, если пользователь не найден:
+// так ответ для неизвестного email занимает столько же времени, сколько для неверного пароля
+var dummyHash = sync.OnceValue(func() string {
+	hash, err := utils.HashPassword("dummy password for timing")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// Register создает пользователя и записывает его согласие с действующими версиями
+// документов; meta сохраняется в журнале согласий. Подписка на рассылку, отмеченная
+// при регистрации, начнет действовать после подтверждения по ссылке из письма.
+func (s *AuthService) Register(ctx context.Context, req models.RegisterRequest, meta ClientMeta) (*models.AuthResponse, error) {
+	logger := logging.FromContext(ctx).With("email", req.Email)
+	logger.Debug("Начало регистрации")
 
 	// Проверяем согласие с условиями
 	if !req.AgreeTerms {
-		log.Printf("Пользователь %s не согласился с условиями", req.Email)
-		return nil, errors.New("необходимо согласие с условиями использования")
+		logger.Info("Регистрация без согласия с условиями")
+		return nil, ErrTermsNotAccepted
 	}
 
-	// Проверяем существование пользователя
-	log.Printf("Проверка существования пользователя: %s", req.Email)
-	exists, err := s.userRepo.UserExists(req.Email)
-	if err != nil {
-		log.Printf("Ошибка проверки пользователя: %v", err)
-		return nil, err
-	}
-	if exists {
-		log.Printf("Пользователь %s уже существует", req.Email)
-		return nil, errors.New("пользователь с таким email уже существует")
-	}
-
-	// Хешируем пароль
-	log.Printf("Хеширование пароля для: %s", req.Email)
+	// Хешируем пароль до транзакции, чтобы не держать ее открытой
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		log.Printf("Ошибка хеширования пароля: %v", err)
 		return nil, err
 	}
 
-	// Создаем пользователя
 	user := &models.User{
 		Email:        req.Email,
 		PasswordHash: hashedPassword,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
-		Phone:        req.Phone,
-		Newsletter:   req.Newsletter,
+	}
+	if req.Phone != "" {
+		if user.Phone, err = phone.Normalize(req.Phone); err != nil {
+			return nil, ErrInvalidPhone
+		}
 	}
 
-	log.Printf("Создание пользователя в БД: %s %s", user.FirstName, user.LastName)
-	err = s.userRepo.CreateUser(user)
+	// Проверка и вставка в одной транзакции; параллельную регистрацию
+	// с тем же email остановит уникальный индекс
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		exists, err := s.userRepo.UserExists(ctx, req.Email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrEmailTaken
+		}
+		if err := s.userRepo.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return s.consents.AcceptCurrent(ctx, user.ID, meta)
+	})
+	if repository.IsDuplicate(err) {
+		err = ErrEmailTaken.Wrap(err)
+	}
+	if errors.Is(err, ErrEmailTaken) {
+		logger.Info("Пользователь уже существует")
+		return nil, err
+	}
 	if err != nil {
-		log.Printf("Ошибка создания пользователя в БД: %v", err)
 		return nil, err
 	}
 
-	log.Printf("Пользователь успешно создан: ID=%d", user.ID)
+	logger.Info("Пользователь зарегистрирован", "user_id", user.ID)
+	if req.Newsletter {
+		// Аккаунт уже создан, поэтому ошибка подписки не отменяет регистрацию
+		if err := s.newsletter.SubscribeUser(ctx, user, models.SubscriptionSourceRegistration, meta); err != nil {
+			logger.Error("Ошибка подписки на рассылку при регистрации", "user_id", user.ID, "error", err)
+		}
+	}
 	return &models.AuthResponse{
 		Success: true,
 		Message: "Регистрация успешна",
 		UserID:  user.ID,
+		Email:   user.Email,
 		Name:    user.FirstName + " " + user.LastName,
 	}, nil
 }
 
-func (s *AuthService) Login(req models.LoginRequest) (*models.AuthResponse, error) {
-	log.Printf("Попытка входа для email: %s", req.Email)
+// Login проверяет email и пароль. ip — адрес клиента для защиты от подбора пароля.
+// Если у пользователя подключена 2FA, вход не завершается: ответ содержит challenge
+// для LoginTwoFactor, а счетчик неудач сбрасывается только после верного кода.
+func (s *AuthService) Login(ctx context.Context, req models.LoginRequest, ip string) (*models.AuthResponse, error) {
+	logger := logging.FromContext(ctx).With("email", req.Email)
+
+	if err := s.guard.Check(ctx, req.Email, ip); err != nil {
+		logger.Info("Вход отклонен защитой от подбора пароля", "ip", ip, "error", err)
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := dummyHash()
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !utils.CheckPasswordHash(req.Password, hash) || user == nil {
+		if user == nil {
+			logger.Info("Вход отклонен: пользователь не найден")
+		} else {
+			logger.Info("Вход отклонен: неверный пароль", "user_id", user.ID)
+		}
+		if err := s.guard.Fail(ctx, req.Email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.firstFactorPassed(ctx, user)
+}
+
+// RequestSmsCode отправляет код для входа на подтвержденный номер телефона
+func (s *AuthService) RequestSmsCode(ctx context.Context, req models.SmsCodeRequest, ip string) error {
+	return s.phones.RequestLoginCode(ctx, req.Phone, ip)
+}
 
-	user, err := s.userRepo.GetUserByEmail(req.Email)
+// LoginSms вход по номеру телефона и коду из SMS. Код заменяет пароль, поэтому
+// при подключенной 2FA, как и после пароля, нужен второй шаг LoginTwoFactor.
+func (s *AuthService) LoginSms(ctx context.Context, req models.SmsLoginRequest) (*models.AuthResponse, error) {
+	user, err := s.phones.LoginWithCode(ctx, req.Phone, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Вход по коду из SMS", "user_id", user.ID)
+	return s.firstFactorPassed(ctx, user)
+}
+
+// LoginExternal вход пользователя, которого подтвердил внешний провайдер OpenID Connect.
+// Провайдер заменяет пароль, поэтому при подключенной 2FA нужен второй шаг LoginTwoFactor.
+func (s *AuthService) LoginExternal(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	return s.firstFactorPassed(ctx, user)
+}
+
+// firstFactorPassed завершает вход или, если у пользователя подключена 2FA,
+// возвращает challenge для второго шага
+func (s *AuthService) firstFactorPassed(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	enabled, err := s.twoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		logging.FromContext(ctx).Info("Первый фактор пройден, ожидается код второго фактора", "user_id", user.ID)
+		return &models.AuthResponse{
+			Success:           true,
+			Message:           "Введите код из приложения-аутентификатора",
+			TwoFactorRequired: true,
+			Challenge:         s.twoFactor.NewChallenge(user.ID),
+		}, nil
+	}
+
+	return s.completeLogin(ctx, user, false), nil
+}
+
+// LoginTwoFactor второй шаг входа: проверяет challenge и код из приложения или код восстановления
+func (s *AuthService) LoginTwoFactor(ctx context.Context, req models.TwoFactorLoginRequest, ip string) (*models.AuthResponse, error) {
+	userID, err := s.twoFactor.ParseChallenge(req.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		log.Printf("Ошибка получения пользователя: %v", err)
 		return nil, err
 	}
 	if user == nil {
-		log.Printf("Пользователь не найден: %s", req.Email)
-		return nil, errors.New("неверный email или пароль")
+		return nil, ErrTwoFactorChallenge
 	}
+	logger := logging.FromContext(ctx).With("email", user.Email)
 
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		log.Printf("Неверный пароль для: %s", req.Email)
-		return nil, errors.New("неверный email или пароль")
+	if err := s.guard.Check(ctx, user.Email, ip); err != nil {
+		logger.Info("Вход отклонен защитой от подбора пароля", "ip", ip, "error", err)
+		return nil, err
 	}
 
-	log.Printf("Успешный вход: %s", req.Email)
+	err = s.twoFactor.Verify(ctx, user.ID, req.Code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		logger.Info("Вход отклонен: неверный код второго фактора", "user_id", user.ID)
+		if err := s.guard.Fail(ctx, user.Email, ip, user); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, true), nil
+}
+
+// completeLogin сбрасывает счетчик неудач и формирует ответ об успешном входе.
+// Если второй фактор не пройден, а для роли он обязателен, клиенту предлагается его подключить.
+func (s *AuthService) completeLogin(ctx context.Context, user *models.User, twoFactor bool) *models.AuthResponse {
+	logger := logging.FromContext(ctx).With("email", user.Email)
+	if err := s.guard.Succeed(ctx, user.Email); err != nil {
+		logger.Error("Ошибка сброса счетчика неудачных входов", "error", err)
+	}
+
+	logger.Info("Успешный вход", "user_id", user.ID)
 	return &models.AuthResponse{
-		Success: true,
-		Message: "Вход выполнен успешно",
-		UserID:  user.ID,
-		Name:    user.FirstName + " " + user.LastName,
-	}, nil
+		Success:                true,
+		Message:                "Вход выполнен успешно",
+		UserID:                 user.ID,
+		Email:                  user.Email,
+		Name:                   user.FirstName + " " + user.LastName,
+		TwoFactorSetupRequired: !twoFactor && s.twoFactor.Policy().Required(user.Role),
+	}
 }
 
-// GetUserByID получает пользователя по ID
-func (s *AuthService) GetUserByID(userID int) (*models.User, error) {
-	log.Printf("Получение пользователя по ID: %d", userID)
+// UnlockAccount снимает блокировку входа по токену из письма
+func (s *AuthService) UnlockAccount(ctx context.Context, token string) error {
+	return s.guard.Unlock(ctx, token)
+}
 
-	user, err := s.userRepo.GetUserByID(userID)
+// GetUserByID получает пользователя по ID
+func (s *AuthService) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
-		log.Printf("Ошибка получения пользователя по ID %d: %v", userID, err)
 		return nil, err
 	}
-
-	log.Printf("Пользователь найден: ID=%d, Email=%s", user.ID, user.Email)
+	if user == nil {
+		logging.FromContext(ctx).Debug("Пользователь не найден", "user_id", userID)
+		return nil, ErrUserNotFound
+	}
 	return user, nil
 }
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+)
+
+func registerRequest(email string) models.RegisterRequest {
+	return models.RegisterRequest{
+		Email:      email,
+		Password:   "secret123",
+		FirstName:  "Анна",
+		LastName:   "Иванова",
+		AgreeTerms: true,
+	}
+}
+
+// newTestAuthService сервис входа с защитой от подбора и 2FA поверх хранилищ в памяти
+func newTestAuthService(users repository.UserStore) *AuthService {
+	tx := memory.NewUnitOfWork()
+	twoFactor := NewTwoFactorService(memory.NewTwoFactorStore(), users, tx, DefaultTwoFactorPolicy(), "test-secret")
+	return NewAuthService(users, tx, newTestLoginGuard(nil), twoFactor, NewConsentService(memory.NewConsentStore(nil), tx), nil, nil)
+}
+
+func TestAuthServiceRegister(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserStore()
+	auth := newTestAuthService(users)
+
+	resp, err := auth.Register(ctx, registerRequest("anna@example.com"), ClientMeta{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if resp.UserID == 0 || resp.Name != "Анна Иванова" {
+		t.Errorf("Register = %+v, want user id and full name", resp)
+	}
+
+	user, err := users.GetUserByEmail(ctx, "anna@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("user not stored: %v", err)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "secret123" {
+		t.Errorf("password stored as %q, want bcrypt hash", user.PasswordHash)
+	}
+	if user.Role != models.RoleCustomer {
+		t.Errorf("role = %q, want %q", user.Role, models.RoleCustomer)
+	}
+
+	if _, err := auth.Register(ctx, registerRequest("anna@example.com"), ClientMeta{}); !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("second Register error = %v, want ErrEmailTaken", err)
+	}
+
+	req := registerRequest("boris@example.com")
+	req.AgreeTerms = false
+	if _, err := auth.Register(ctx, req, ClientMeta{}); !errors.Is(err, ErrTermsNotAccepted) {
+		t.Errorf("Register without terms error = %v, want ErrTermsNotAccepted", err)
+	}
+	if exists, _ := users.UserExists(ctx, "boris@example.com"); exists {
+		t.Error("user created without accepted terms")
+	}
+}
+
+func TestAuthServiceLogin(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuthService(memory.NewUserStore())
+	registered, err := auth.Register(ctx, registerRequest("anna@example.com"), ClientMeta{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{"valid credentials", "anna@example.com", "secret123", nil},
+		{"wrong password", "anna@example.com", "secret124", ErrInvalidCredentials},
+		{"unknown email", "nobody@example.com", "secret123", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := auth.Login(ctx, models.LoginRequest{Email: tt.email, Password: tt.password}, testIP)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && resp.UserID != registered.UserID {
+				t.Errorf("Login user id = %d, want %d", resp.UserID, registered.UserID)
+			}
+		})
+	}
+}
+
+func TestAuthServiceGetUserByID(t *testing.T) {
+	ctx := context.Background()
+	auth := newTestAuthService(memory.NewUserStore())
+	registered, err := auth.Register(ctx, registerRequest("anna@example.com"), ClientMeta{})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	user, err := auth.GetUserByID(ctx, registered.UserID)
+	if err != nil {
+		t.Fatalf("GetUserByID: %v", err)
+	}
+	if user.Email != "anna@example.com" || user.PasswordHash != "" {
+		t.Errorf("GetUserByID = %+v, want stored user without password hash", user)
+	}
+
+	if _, err := auth.GetUserByID(ctx, registered.UserID+1); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("GetUserByID(unknown) error = %v, want ErrUserNotFound", err)
+	}
+}
+
+// racingUserStore имитирует параллельную регистрацию: проверка email еще не видит
+// чужую запись, а вставка натыкается на уникальный индекс
+type racingUserStore struct {
+	*memory.UserStore
+}
+
+func (racingUserStore) UserExists(ctx context.Context, email string) (bool, error) {
+	return false, nil
+}
+
+func TestAuthServiceRegisterDuplicateRace(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserStore()
+	if err := users.CreateUser(ctx, &models.User{Email: "anna@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	auth := newTestAuthService(racingUserStore{users})
+
+	_, err := auth.Register(ctx, registerRequest("anna@example.com"), ClientMeta{})
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("Register error = %v, want ErrEmailTaken", err)
+	}
+}
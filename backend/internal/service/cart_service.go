@@ -1,56 +1,74 @@
 package service
 
 import (
+	"beladonna/backend/internal/apperr"
 	"beladonna/backend/internal/models"
 	"beladonna/backend/internal/repository"
+	"context"
 	"fmt"
 )
 
+var (
+	ErrInvalidQuantity = apperr.Validation("invalid_quantity", "Недопустимое количество товара").
+				WithField("quantity", fmt.Sprintf("Допустимо значение от 1 до %d", models.MaxCartQuantity))
+	// Чужая позиция корзины неотличима от несуществующей, чтобы не раскрывать чужие ID
+	ErrCartItemNotFound = apperr.NotFound("cart_item_not_found", "Товар в корзине не найден")
+)
+
 type CartService struct {
-	cartRepo *repository.CartRepository
+	cartRepo repository.CartStore
+	tx       repository.UnitOfWork
 }
 
-func NewCartService(cartRepo *repository.CartRepository) *CartService {
-	return &CartService{cartRepo: cartRepo}
+func NewCartService(cartRepo repository.CartStore, tx repository.UnitOfWork) *CartService {
+	return &CartService{cartRepo: cartRepo, tx: tx}
 }
 
-func (s *CartService) GetCartItems(userID int) ([]models.CartItem, error) {
-	return s.cartRepo.GetCartItems(userID)
+func (s *CartService) GetCartItems(ctx context.Context, userID int) ([]models.CartItem, error) {
+	return s.cartRepo.GetCartItems(ctx, userID)
 }
 
-func (s *CartService) AddToCart(userID, productID, quantity int) error {
-	if quantity <= 0 {
-		return fmt.Errorf("quantity must be positive")
+func (s *CartService) AddToCart(ctx context.Context, userID, productID, quantity int) error {
+	if quantity <= 0 || quantity > models.MaxCartQuantity {
+		return ErrInvalidQuantity
 	}
-	return s.cartRepo.AddToCart(userID, productID, quantity)
+	return s.cartRepo.AddToCart(ctx, userID, productID, quantity, models.MaxCartQuantity)
 }
 
-func (s *CartService) UpdateCartItem(userID, itemID, quantity int) error {
-	// Проверяем, что товар принадлежит пользователю
-	item, err := s.cartRepo.GetCartItemByID(itemID)
-	if err != nil {
-		return err
-	}
-	if item.UserID != userID {
-		return fmt.Errorf("unauthorized")
+func (s *CartService) UpdateCartItem(ctx context.Context, userID, itemID, quantity int) error {
+	if quantity <= 0 || quantity > models.MaxCartQuantity {
+		return ErrInvalidQuantity
 	}
 
-	return s.cartRepo.UpdateCartItem(itemID, quantity)
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.checkOwner(ctx, userID, itemID); err != nil {
+			return err
+		}
+		return s.cartRepo.UpdateCartItem(ctx, itemID, quantity)
+	})
+}
+
+func (s *CartService) RemoveFromCart(ctx context.Context, userID, itemID int) error {
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.checkOwner(ctx, userID, itemID); err != nil {
+			return err
+		}
+		return s.cartRepo.RemoveFromCart(ctx, itemID)
+	})
+}
+
+func (s *CartService) ClearCart(ctx context.Context, userID int) error {
+	return s.cartRepo.ClearUserCart(ctx, userID)
 }
 
-func (s *CartService) RemoveFromCart(userID, itemID int) error {
-	// Проверяем, что товар принадлежит пользователю
-	item, err := s.cartRepo.GetCartItemByID(itemID)
+// checkOwner проверяет, что позиция корзины принадлежит пользователю
+func (s *CartService) checkOwner(ctx context.Context, userID, itemID int) error {
+	item, err := s.cartRepo.GetCartItemByID(ctx, itemID)
 	if err != nil {
 		return err
 	}
-	if item.UserID != userID {
-		return fmt.Errorf("unauthorized")
+	if item == nil || item.UserID != userID {
+		return ErrCartItemNotFound
 	}
-
-	return s.cartRepo.RemoveFromCart(itemID)
-}
-
-func (s *CartService) ClearCart(userID int) error {
-	return s.cartRepo.ClearUserCart(userID)
+	return nil
 }
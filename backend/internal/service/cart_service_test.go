@@ -0,0 +1,103 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+)
+
+const (
+	owner    = 1
+	stranger = 2
+)
+
+func newCartService(t *testing.T) (*CartService, *memory.CartStore, models.Product) {
+	t.Helper()
+	products := memory.NewProductStore()
+	product := products.AddProduct(models.Product{Name: "Штора блэкаут", Price: 2500, InStock: true})
+	cart := memory.NewCartStore(products)
+	return NewCartService(cart, memory.NewUnitOfWork()), cart, product
+}
+
+// cartItem добавляет товар в корзину владельца и возвращает созданную позицию
+func cartItem(t *testing.T, s *CartService, productID, quantity int) models.CartItem {
+	t.Helper()
+	ctx := context.Background()
+	if err := s.AddToCart(ctx, owner, productID, quantity); err != nil {
+		t.Fatalf("AddToCart: %v", err)
+	}
+	items, err := s.GetCartItems(ctx, owner)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("GetCartItems = %v, %v; want one item", items, err)
+	}
+	return items[0]
+}
+
+func TestCartServiceAddToCart(t *testing.T) {
+	ctx := context.Background()
+	s, _, product := newCartService(t)
+
+	item := cartItem(t, s, product.ID, 2)
+	if item.Quantity != 2 || item.ProductName != product.Name || item.Price != product.Price {
+		t.Errorf("item = %+v, want quantity 2 with product data", item)
+	}
+
+	// Повторное добавление увеличивает количество, но не выше MaxCartQuantity
+	if err := s.AddToCart(ctx, owner, product.ID, 3); err != nil {
+		t.Fatalf("AddToCart: %v", err)
+	}
+	if err := s.AddToCart(ctx, owner, product.ID, models.MaxCartQuantity); err != nil {
+		t.Fatalf("AddToCart: %v", err)
+	}
+	items, _ := s.GetCartItems(ctx, owner)
+	if len(items) != 1 || items[0].Quantity != models.MaxCartQuantity {
+		t.Errorf("items = %+v, want one item with quantity %d", items, models.MaxCartQuantity)
+	}
+
+	for _, quantity := range []int{0, -1, models.MaxCartQuantity + 1} {
+		if err := s.AddToCart(ctx, owner, product.ID, quantity); !errors.Is(err, ErrInvalidQuantity) {
+			t.Errorf("AddToCart(quantity %d) error = %v, want ErrInvalidQuantity", quantity, err)
+		}
+	}
+
+	if err := s.AddToCart(ctx, owner, product.ID+100, 1); !errors.Is(err, memory.ErrForeignKeyViolation) {
+		t.Errorf("AddToCart(unknown product) error = %v, want foreign key violation", err)
+	}
+}
+
+func TestCartServiceOwnership(t *testing.T) {
+	ctx := context.Background()
+	s, cart, product := newCartService(t)
+	item := cartItem(t, s, product.ID, 2)
+
+	if err := s.UpdateCartItem(ctx, stranger, item.ID, 5); !errors.Is(err, ErrCartItemNotFound) {
+		t.Errorf("UpdateCartItem by stranger error = %v, want ErrCartItemNotFound", err)
+	}
+	if err := s.RemoveFromCart(ctx, stranger, item.ID); !errors.Is(err, ErrCartItemNotFound) {
+		t.Errorf("RemoveFromCart by stranger error = %v, want ErrCartItemNotFound", err)
+	}
+	if err := s.UpdateCartItem(ctx, owner, item.ID+1, 5); !errors.Is(err, ErrCartItemNotFound) {
+		t.Errorf("UpdateCartItem(unknown item) error = %v, want ErrCartItemNotFound", err)
+	}
+
+	stored, _ := cart.GetCartItemByID(ctx, item.ID)
+	if stored == nil || stored.Quantity != 2 {
+		t.Fatalf("stranger changed the item: %+v", stored)
+	}
+
+	if err := s.UpdateCartItem(ctx, owner, item.ID, 5); err != nil {
+		t.Fatalf("UpdateCartItem by owner: %v", err)
+	}
+	if stored, _ := cart.GetCartItemByID(ctx, item.ID); stored.Quantity != 5 {
+		t.Errorf("quantity = %d, want 5", stored.Quantity)
+	}
+
+	if err := s.RemoveFromCart(ctx, owner, item.ID); err != nil {
+		t.Fatalf("RemoveFromCart by owner: %v", err)
+	}
+	if items, _ := s.GetCartItems(ctx, owner); len(items) != 0 {
+		t.Errorf("cart = %+v, want empty", items)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrPolicyNotFound = apperr.NotFound("policy_not_found", "Документ не найден")
+	ErrPolicyOutdated = apperr.Conflict("policy_outdated", "Документ обновился, ознакомьтесь с новой версией")
+)
+
+// ConsentService версии юридических документов и журнал согласий с ними.
+// Пользователь соглашается с конкретной версией; после публикации новой версии
+// Status возвращает ее в Pending, и сайт просит согласиться повторно.
+type ConsentService struct {
+	store repository.ConsentStore
+	tx    repository.UnitOfWork
+}
+
+func NewConsentService(store repository.ConsentStore, tx repository.UnitOfWork) *ConsentService {
+	return &ConsentService{store: store, tx: tx}
+}
+
+// Publish публикует новую версию документа, если его содержимое или адрес изменились,
+// иначе возвращает действующую версию
+func (s *ConsentService) Publish(ctx context.Context, kind, url string, content []byte) (*models.PolicyDocument, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	var doc *models.PolicyDocument
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		latest, err := s.store.GetLatestPolicyDocument(ctx, kind)
+		if err != nil {
+			return err
+		}
+		if latest != nil && latest.SHA256 == hash && latest.URL == url {
+			doc = latest
+			return nil
+		}
+
+		doc = &models.PolicyDocument{Kind: kind, Version: 1, URL: url, SHA256: hash}
+		if latest != nil {
+			doc.Version = latest.Version + 1
+		}
+		if err := s.store.CreatePolicyDocument(ctx, doc); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("Опубликована новая версия документа", "kind", kind, "version", doc.Version, "sha256", hash)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// PublishFile сверяет файл документа с последней версией; вызывается при старте сервера
+func (s *ConsentService) PublishFile(ctx context.Context, kind, path, url string) (*models.PolicyDocument, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("чтение документа %s: %w", kind, err)
+	}
+	return s.Publish(ctx, kind, url, content)
+}
+
+// Current действующие версии обязательных документов; неопубликованные пропускаются
+func (s *ConsentService) Current(ctx context.Context) ([]models.PolicyDocument, error) {
+	docs := []models.PolicyDocument{}
+	for _, kind := range models.RequiredPolicies {
+		doc, err := s.store.GetLatestPolicyDocument(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			docs = append(docs, *doc)
+		}
+	}
+	return docs, nil
+}
+
+// AcceptCurrent записывает согласие со всеми действующими версиями. Вызывается
+// при регистрации в той же транзакции, что и создание пользователя.
+func (s *ConsentService) AcceptCurrent(ctx context.Context, userID int, meta ClientMeta) error {
+	docs, err := s.Current(ctx)
+	if err != nil {
+		return err
+	}
+	for _, doc := range docs {
+		if err := s.record(ctx, userID, doc, meta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status действующие документы, принятые версии и документы, ожидающие согласия
+func (s *ConsentService) Status(ctx context.Context, userID int) (*models.ConsentStatus, error) {
+	docs, err := s.Current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	consents, err := s.store.GetUserConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accepted := make(map[int]bool, len(consents))
+	for _, c := range consents {
+		accepted[c.DocumentID] = true
+	}
+	status := &models.ConsentStatus{Documents: docs, Pending: []models.PolicyDocument{}, Accepted: consents}
+	for _, doc := range docs {
+		if !accepted[doc.ID] {
+			status.Pending = append(status.Pending, doc)
+		}
+	}
+	return status, nil
+}
+
+// Accept записывает согласие с документами. Принять можно только действующую версию:
+// если пока пользователь читал документ вышла новая, возвращается ErrPolicyOutdated.
+func (s *ConsentService) Accept(ctx context.Context, userID int, req models.ConsentAcceptRequest, meta ClientMeta) (*models.ConsentStatus, error) {
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		for _, id := range req.DocumentIDs {
+			doc, err := s.store.GetPolicyDocumentByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if doc == nil {
+				return ErrPolicyNotFound
+			}
+			latest, err := s.store.GetLatestPolicyDocument(ctx, doc.Kind)
+			if err != nil {
+				return err
+			}
+			if latest.ID != doc.ID {
+				return ErrPolicyOutdated
+			}
+			if err := s.record(ctx, userID, *doc, meta); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Status(ctx, userID)
+}
+
+// RecordCookieConsent сохраняет выбор в баннере cookie. userID равен 0 для гостя.
+// Возвращает запись с consent_id, который браузер присылает при следующих решениях;
+// чужие или поддельные идентификаторы заменяются новыми.
+func (s *ConsentService) RecordCookieConsent(ctx context.Context, req models.CookieConsentRequest, userID int, meta ClientMeta) (*models.CookieConsent, error) {
+	consent := &models.CookieConsent{
+		ConsentID: req.ConsentID,
+		Status:    req.Status,
+		IP:        meta.IP,
+		UserAgent: meta.userAgent(),
+	}
+	if !validConsentID(consent.ConsentID) {
+		consent.ConsentID = newSecretToken()
+	}
+	if userID != 0 {
+		consent.UserID = &userID
+	}
+	// Баннер ссылается на политику конфиденциальности
+	doc, err := s.store.GetLatestPolicyDocument(ctx, models.PolicyPrivacy)
+	if err != nil {
+		return nil, err
+	}
+	if doc != nil {
+		consent.DocumentID = &doc.ID
+	}
+
+	if err := s.store.CreateCookieConsent(ctx, consent); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Сохранен выбор cookie", "status", consent.Status, "user_id", userID)
+	return consent, nil
+}
+
+func (s *ConsentService) record(ctx context.Context, userID int, doc models.PolicyDocument, meta ClientMeta) error {
+	consent := &models.Consent{
+		UserID:     userID,
+		DocumentID: doc.ID,
+		IP:         meta.IP,
+		UserAgent:  meta.userAgent(),
+	}
+	if err := s.store.CreateConsent(ctx, consent); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Согласие с документом", "user_id", userID, "kind", doc.Kind, "version", doc.Version)
+	return nil
+}
+
+// validConsentID проверяет, что идентификатор похож на выданный newSecretToken
+func validConsentID(id string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil && len(b) == 32
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"beladonna/backend/internal/utils"
+	"context"
+	"errors"
+	"testing"
+)
+
+// newTestConsentService сервис согласий поверх хранилищ в памяти с одним пользователем
+func newTestConsentService(t *testing.T) (*ConsentService, *memory.ConsentStore, *models.User) {
+	t.Helper()
+	users := memory.NewUserStore()
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Email: "anna@example.com", PasswordHash: hash, FirstName: "Анна"}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	store := memory.NewConsentStore(users)
+	return NewConsentService(store, memory.NewUnitOfWork()), store, user
+}
+
+func TestConsentServicePublishBumpsVersion(t *testing.T) {
+	ctx := context.Background()
+	s, _, _ := newTestConsentService(t)
+
+	v1, err := s.Publish(ctx, models.PolicyPrivacy, "/privacy", []byte("Политика, редакция 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1.Version != 1 {
+		t.Fatalf("first version = %d, want 1", v1.Version)
+	}
+
+	steps := []struct {
+		name    string
+		url     string
+		content string
+		want    int
+	}{
+		{"same content keeps version", "/privacy", "Политика, редакция 1", 1},
+		{"new content bumps version", "/privacy", "Политика, редакция 2", 2},
+		{"new url bumps version", "/legal/privacy", "Политика, редакция 2", 3},
+		{"republish keeps latest", "/legal/privacy", "Политика, редакция 2", 3},
+	}
+	for _, step := range steps {
+		doc, err := s.Publish(ctx, models.PolicyPrivacy, step.url, []byte(step.content))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if doc.Version != step.want {
+			t.Errorf("%s: version = %d, want %d", step.name, doc.Version, step.want)
+		}
+	}
+
+	current, err := s.Current(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) != 1 || current[0].Version != 3 {
+		t.Errorf("current = %+v, want version 3 only", current)
+	}
+}
+
+// Пользователь открыл версию 1, пока он читал, вышла версия 2: согласие со старой
+// версией отклоняется и не записывается, документ остается в Pending
+func TestConsentServiceAcceptOutdatedVersion(t *testing.T) {
+	ctx := context.Background()
+	s, _, user := newTestConsentService(t)
+	meta := ClientMeta{IP: testIP, UserAgent: "test"}
+
+	v1, err := s.Publish(ctx, models.PolicyPrivacy, "/privacy", []byte("Политика, редакция 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := s.Publish(ctx, models.PolicyPrivacy, "/privacy", []byte("Политика, редакция 2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.Accept(ctx, user.ID, models.ConsentAcceptRequest{DocumentIDs: []int{v1.ID}}, meta)
+	if !errors.Is(err, ErrPolicyOutdated) {
+		t.Fatalf("accept outdated: err = %v, want ErrPolicyOutdated", err)
+	}
+	status, err := s.Status(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Accepted) != 0 || len(status.Pending) != 1 || status.Pending[0].ID != v2.ID {
+		t.Fatalf("status after outdated accept = %+v, want v2 pending and nothing accepted", status)
+	}
+
+	if _, err := s.Accept(ctx, user.ID, models.ConsentAcceptRequest{DocumentIDs: []int{999}}, meta); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("accept unknown: err = %v, want ErrPolicyNotFound", err)
+	}
+
+	status, err = s.Accept(ctx, user.ID, models.ConsentAcceptRequest{DocumentIDs: []int{v2.ID}}, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Pending) != 0 || len(status.Accepted) != 1 || status.Accepted[0].DocumentID != v2.ID {
+		t.Errorf("status after accept = %+v, want v2 accepted", status)
+	}
+}
+
+func TestConsentServiceCookieConsentID(t *testing.T) {
+	ctx := context.Background()
+	s, store, user := newTestConsentService(t)
+	meta := ClientMeta{IP: testIP}
+
+	doc, err := s.Publish(ctx, models.PolicyPrivacy, "/privacy", []byte("Политика"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.RecordCookieConsent(ctx, models.CookieConsentRequest{Status: models.CookieConsentAccepted}, 0, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !validConsentID(first.ConsentID) {
+		t.Fatalf("issued consent_id %q is not valid", first.ConsentID)
+	}
+	if first.UserID != nil || first.DocumentID == nil || *first.DocumentID != doc.ID {
+		t.Errorf("guest consent = %+v, want no user and document %d", first, doc.ID)
+	}
+
+	// Выданный ранее идентификатор сохраняется
+	again, err := s.RecordCookieConsent(ctx, models.CookieConsentRequest{ConsentID: first.ConsentID, Status: models.CookieConsentRejected}, user.ID, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ConsentID != first.ConsentID {
+		t.Errorf("consent_id = %q, want the issued %q kept", again.ConsentID, first.ConsentID)
+	}
+	if again.UserID == nil || *again.UserID != user.ID {
+		t.Errorf("user_id = %v, want %d", again.UserID, user.ID)
+	}
+
+	forged := []struct {
+		name string
+		id   string
+	}{
+		{"guessable", "12345"},
+		{"not base64", "consent id with spaces!"},
+		{"short token", "c2hvcnQ"},
+		{"padded base64", first.ConsentID + "="},
+	}
+	for _, tc := range forged {
+		t.Run(tc.name, func(t *testing.T) {
+			consent, err := s.RecordCookieConsent(ctx, models.CookieConsentRequest{ConsentID: tc.id, Status: models.CookieConsentAccepted}, 0, meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if consent.ConsentID == tc.id || !validConsentID(consent.ConsentID) {
+				t.Errorf("consent_id = %q, want a newly issued one instead of %q", consent.ConsentID, tc.id)
+			}
+		})
+	}
+
+	for _, c := range store.CookieConsents() {
+		if !validConsentID(c.ConsentID) {
+			t.Errorf("stored consent_id %q is not valid", c.ConsentID)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FeedbackGuardConfig пороги антиспам-проверок формы отзыва
+type FeedbackGuardConfig struct {
+	UserLimit       int
+	UserWindow      time.Duration
+	IPLimit         int
+	IPWindow        time.Duration
+	MinFillTime     time.Duration
+	MaxFormAge      time.Duration
+	DuplicateWindow time.Duration
+	MaxLinks        int
+	BannedWords     []string
+}
+
+func DefaultFeedbackGuardConfig() FeedbackGuardConfig {
+	return FeedbackGuardConfig{
+		UserLimit:       3,
+		UserWindow:      10 * time.Minute,
+		IPLimit:         10,
+		IPWindow:        time.Hour,
+		MinFillTime:     3 * time.Second,
+		MaxFormAge:      24 * time.Hour,
+		DuplicateWindow: 24 * time.Hour,
+		MaxLinks:        2,
+	}
+}
+
+// SpamRejection ошибка, означающая, что отзыв отклонен антиспам-проверкой
+type SpamRejection struct {
+	Reason string
+}
+
+func (e *SpamRejection) Error() string {
+	return fmt.Sprintf("feedback rejected: %s", e.Reason)
+}
+
+// SubmissionMeta сведения об отправителе, которые не приходят в теле запроса
+type SubmissionMeta struct {
+	UserID int
+	IP     string
+}
+
+// FeedbackGuard проверяет отзыв перед сохранением и журналирует отказы
+type FeedbackGuard struct {
+	cfg          FeedbackGuardConfig
+	secret       []byte
+	feedbackRepo repository.FeedbackStore
+	userLimiter  *antispam.RateLimiter
+	ipLimiter    *antispam.RateLimiter
+	filters      []antispam.ContentFilter
+	now          func() time.Time
+}
+
+// NewFeedbackGuard создает проверку; secret — ключ подписи меток времени формы
+func NewFeedbackGuard(cfg FeedbackGuardConfig, secret string, feedbackRepo repository.FeedbackStore) *FeedbackGuard {
+	return &FeedbackGuard{
+		cfg:          cfg,
+		secret:       []byte(secret),
+		feedbackRepo: feedbackRepo,
+		userLimiter:  antispam.NewRateLimiter(cfg.UserLimit, cfg.UserWindow),
+		ipLimiter:    antispam.NewRateLimiter(cfg.IPLimit, cfg.IPWindow),
+		filters: []antispam.ContentFilter{
+			antispam.NewBannedWordsFilter(cfg.BannedWords),
+			&antispam.LinkFilter{MaxLinks: cfg.MaxLinks},
+		},
+		now: time.Now,
+	}
+}
+
+// AddFilter подключает дополнительный фильтр содержимого
+func (g *FeedbackGuard) AddFilter(filter antispam.ContentFilter) {
+	g.filters = append(g.filters, filter)
+}
+
+// Check выполняет проверки от дешевых к дорогим. При отказе возвращает *SpamRejection
+func (g *FeedbackGuard) Check(ctx context.Context, submission models.FeedbackSubmission, meta SubmissionMeta) error {
+	if submission.Website != "" {
+		return g.reject(ctx, submission, meta, antispam.ReasonHoneypot)
+	}
+
+	// Время заполнения считает сервер по подписанной метке, выданной при открытии формы
+	openedAt, ok := g.formOpenedAt(submission.FormToken)
+	if !ok {
+		return g.reject(ctx, submission, meta, antispam.ReasonFormToken)
+	}
+	elapsed := g.now().Sub(openedAt)
+	if elapsed > g.cfg.MaxFormAge {
+		return g.reject(ctx, submission, meta, antispam.ReasonFormToken)
+	}
+	if elapsed < g.cfg.MinFillTime {
+		return g.reject(ctx, submission, meta, antispam.ReasonTooFast)
+	}
+
+	if !g.ipLimiter.Allow("ip:" + meta.IP) {
+		return g.reject(ctx, submission, meta, antispam.ReasonRateLimitIP)
+	}
+	if !g.userLimiter.Allow("user:" + strconv.Itoa(meta.UserID)) {
+		return g.reject(ctx, submission, meta, antispam.ReasonRateLimitUser)
+	}
+
+	text := submission.Theme + "\n" + submission.Message
+	for _, filter := range g.filters {
+		if reason := filter.Check(text); reason != "" {
+			return g.reject(ctx, submission, meta, reason)
+		}
+	}
+
+	duplicate, err := g.feedbackRepo.HasRecentDuplicate(ctx, submission.Email, submission.Message, time.Now().Add(-g.cfg.DuplicateWindow))
+	if err != nil {
+		return err
+	}
+	if duplicate {
+		return g.reject(ctx, submission, meta, antispam.ReasonDuplicate)
+	}
+
+	return nil
+}
+
+// IssueFormToken выдает метку открытия формы: <unix-мс>.<hmac>. Подделать или
+// сдвинуть время в ней клиент не может.
+func (g *FeedbackGuard) IssueFormToken() string {
+	issued := strconv.FormatInt(g.now().UnixMilli(), 10)
+	return issued + "." + g.sign(issued)
+}
+
+// formOpenedAt проверяет подпись метки и возвращает время открытия формы
+func (g *FeedbackGuard) formOpenedAt(token string) (time.Time, bool) {
+	issued, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(g.sign(issued))) {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(issued, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+func (g *FeedbackGuard) sign(issued string) string {
+	h := hmac.New(sha256.New, g.secret)
+	h.Write([]byte("feedback-form:" + issued))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Cleanup освобождает память ограничителей частоты
+func (g *FeedbackGuard) Cleanup() {
+	g.userLimiter.Cleanup()
+	g.ipLimiter.Cleanup()
+}
+
+// StartJanitor периодически вызывает Cleanup. Возвращает функцию остановки,
+// которая дожидается завершения фоновой горутины.
+func (g *FeedbackGuard) StartJanitor(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				g.Cleanup()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (g *FeedbackGuard) reject(ctx context.Context, submission models.FeedbackSubmission, meta SubmissionMeta, reason string) error {
+	logger := logging.FromContext(ctx)
+	logger.Info("Отзыв отклонен антиспамом", "reason", reason, "user_id", meta.UserID, "ip", meta.IP)
+
+	rejection := &models.FeedbackRejection{
+		UserID:  meta.UserID,
+		IP:      meta.IP,
+		Email:   submission.Email,
+		Reason:  reason,
+		Message: submission.Message,
+	}
+	if err := g.feedbackRepo.LogRejection(ctx, rejection); err != nil {
+		logger.Error("Ошибка записи отказа в журнал", "error", err)
+	}
+
+	return &SpamRejection{Reason: reason}
+}
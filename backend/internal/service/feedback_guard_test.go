@@ -0,0 +1,62 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFeedbackGuardFormToken(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultFeedbackGuardConfig()
+	cfg.IPLimit, cfg.UserLimit = 100, 100
+	guard := NewFeedbackGuard(cfg, "test-secret", memory.NewFeedbackStore(memory.NewUserStore(), memory.NewFeedbackThemeStore()))
+	clock := &fakeClock{t: time.Now()}
+	guard.now = clock.now
+	meta := SubmissionMeta{UserID: 1, IP: testIP}
+
+	reason := func(token, message string) string {
+		err := guard.Check(ctx, models.FeedbackSubmission{Email: "anna@example.com", Message: message, FormToken: token}, meta)
+		var rejection *SpamRejection
+		if errors.As(err, &rejection) {
+			return rejection.Reason
+		}
+		if err != nil {
+			t.Fatalf("Check: %v", err)
+		}
+		return ""
+	}
+
+	token := guard.IssueFormToken()
+	if got := reason(token, "сразу"); got != antispam.ReasonTooFast {
+		t.Errorf("fresh token: reason = %q, want %q", got, antispam.ReasonTooFast)
+	}
+
+	clock.advance(cfg.MinFillTime)
+	if got := reason(token, "вовремя"); got != "" {
+		t.Errorf("token after min fill time: reason = %q, want accepted", got)
+	}
+
+	// Клиент не может сдвинуть время в метке или подписать свою
+	forged := NewFeedbackGuard(cfg, "other-secret", nil)
+	forged.now = func() time.Time { return clock.t.Add(-time.Hour) }
+	for name, bad := range map[string]string{
+		"missing":      "",
+		"no signature": "1700000000000",
+		"other secret": forged.IssueFormToken(),
+		"shifted time": "1" + token,
+	} {
+		if got := reason(bad, "подделка "+name); got != antispam.ReasonFormToken {
+			t.Errorf("%s: reason = %q, want %q", name, got, antispam.ReasonFormToken)
+		}
+	}
+
+	clock.advance(cfg.MaxFormAge)
+	if got := reason(token, "устаревшая форма"); got != antispam.ReasonFormToken {
+		t.Errorf("expired token: reason = %q, want %q", got, antispam.ReasonFormToken)
+	}
+}
@@ -1,22 +1,308 @@
 package service
 
 import (
+    "beladonna/backend/internal/apperr"
+    "beladonna/backend/internal/logging"
+    "beladonna/backend/internal/mailer"
     "beladonna/backend/internal/models"
     "beladonna/backend/internal/repository"
+    "context"
+    "fmt"
+    "strings"
+    "time"
+)
+
+var (
+    ErrFeedbackNotFound     = apperr.NotFound("feedback_not_found", "Отзыв не найден")
+    ErrRejectReasonRequired = apperr.Validation("reject_reason_required", "Укажите причину отклонения").
+        WithField("reason", "Укажите причину отклонения")
+    ErrReplyMessageRequired = apperr.Validation("reply_message_required", "Введите текст ответа").
+        WithField("message", "Введите текст ответа")
+    ErrInvalidFeedbackStatus = apperr.Validation("invalid_feedback_status", "Некорректный статус отзыва").
+        WithField("status", "Допустимые статусы: open, answered, closed")
 )
 
 type FeedbackService struct {
-    feedbackRepo *repository.FeedbackRepository
+    feedbackRepo repository.FeedbackStore
+    userRepo     repository.UserStore
+    themes       *FeedbackThemeService
+    guard        *FeedbackGuard
+    tx           repository.UnitOfWork
+    mailer       mailer.Mailer
+}
+
+func NewFeedbackService(feedbackRepo repository.FeedbackStore, userRepo repository.UserStore, themes *FeedbackThemeService, guard *FeedbackGuard, tx repository.UnitOfWork, mailer mailer.Mailer) *FeedbackService {
+    return &FeedbackService{feedbackRepo: feedbackRepo, userRepo: userRepo, themes: themes, guard: guard, tx: tx, mailer: mailer}
+}
+
+// FormToken подписанная метка открытия формы отзыва; по ней антиспам считает время заполнения
+func (s *FeedbackService) FormToken() string {
+    return s.guard.IssueFormToken()
+}
+
+// SubmitFeedback привязывает отзыв к пользователю, проверяет его антиспамом
+// и ставит в очередь модерации. Имя и email берутся из профиля, если не переданы явно.
+func (s *FeedbackService) SubmitFeedback(ctx context.Context, submission models.FeedbackSubmission, meta SubmissionMeta) (*models.Feedback, error) {
+    user, err := s.userRepo.GetUserByID(ctx, meta.UserID)
+    if err != nil {
+        return nil, err
+    }
+    if user == nil {
+        return nil, ErrUserNotFound
+    }
+
+    submission.Name = strings.TrimSpace(submission.Name)
+    if submission.Name == "" {
+        submission.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+    }
+    submission.Email = strings.TrimSpace(submission.Email)
+    if submission.Email == "" {
+        submission.Email = user.Email
+    }
+
+    submission.Theme = strings.TrimSpace(submission.Theme)
+    theme, err := s.themes.ResolveTheme(ctx, submission.Theme)
+    if err != nil {
+        return nil, err
+    }
+
+    if err := s.guard.Check(ctx, submission, meta); err != nil {
+        return nil, err
+    }
+
+    feedback := &models.Feedback{
+        UserID:  user.ID,
+        Name:    submission.Name,
+        Email:   submission.Email,
+        Theme:   submission.Theme,
+        Message: submission.Message,
+    }
+    if err := s.CreateFeedback(ctx, feedback); err != nil {
+        return nil, err
+    }
+
+    s.themes.NotifyNewFeedback(ctx, theme, feedback)
+    return feedback, nil
+}
+
+// CreateFeedback ставит отзыв в очередь модерации
+func (s *FeedbackService) CreateFeedback(ctx context.Context, feedback *models.Feedback) error {
+    return s.feedbackRepo.CreateFeedback(ctx, feedback)
+}
+
+// GetVisibleFeedbacks возвращает опубликованные отзывы вместе с публичными ответами.
+// Непустой theme ограничивает выборку темой.
+func (s *FeedbackService) GetVisibleFeedbacks(ctx context.Context, theme string) ([]models.Feedback, error) {
+    feedbacks, err := s.feedbackRepo.GetVisibleFeedbacks(ctx, theme)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.attachReplies(ctx, feedbacks, true); err != nil {
+        return nil, err
+    }
+    return feedbacks, nil
+}
+
+// GetUserFeedbacks возвращает отзывы пользователя со статусами модерации и всеми ответами
+func (s *FeedbackService) GetUserFeedbacks(ctx context.Context, userID int) ([]models.Feedback, error) {
+    feedbacks, err := s.feedbackRepo.GetFeedbacksByUser(ctx, userID)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.attachReplies(ctx, feedbacks, false); err != nil {
+        return nil, err
+    }
+    return feedbacks, nil
+}
+
+// ReplyToFeedback сохраняет ответ сотрудника. Приватный ответ отправляется автору отзыва на email;
+// если письмо не ушло, ответ остается сохраненным, а EmailedAt пустым.
+func (s *FeedbackService) ReplyToFeedback(ctx context.Context, authorID int, req models.FeedbackReplyRequest) (*models.FeedbackReply, error) {
+    message := strings.TrimSpace(req.Message)
+    if message == "" {
+        return nil, ErrReplyMessageRequired
+    }
+
+    feedback, err := s.getFeedback(ctx, req.FeedbackID)
+    if err != nil {
+        return nil, err
+    }
+
+    reply := &models.FeedbackReply{
+        FeedbackID: feedback.ID,
+        AuthorID:   authorID,
+        Message:    message,
+        IsPublic:   req.IsPublic,
+    }
+    if err := s.feedbackRepo.CreateReply(ctx, reply); err != nil {
+        return nil, err
+    }
+
+    if !reply.IsPublic {
+        if err := s.mailer.Send(replyEmail(feedback, reply)); err != nil {
+            logging.FromContext(ctx).Error("Ошибка отправки ответа на отзыв", "feedback_id", feedback.ID, "error", err)
+            return reply, nil
+        }
+        if err := s.feedbackRepo.MarkReplyEmailed(ctx, reply.ID); err != nil {
+            logging.FromContext(ctx).Error("Ошибка отметки отправки ответа", "reply_id", reply.ID, "error", err)
+        }
+        now := time.Now()
+        reply.EmailedAt = &now
+    }
+
+    return reply, nil
+}
+
+// SetFeedbackStatus меняет статус переписки (open, answered, closed)
+func (s *FeedbackService) SetFeedbackStatus(ctx context.Context, feedbackID int, status string) error {
+    switch status {
+    case models.FeedbackOpen, models.FeedbackAnswered, models.FeedbackClosed:
+    default:
+        return ErrInvalidFeedbackStatus
+    }
+    return s.tx.Do(ctx, func(ctx context.Context) error {
+        if _, err := s.getFeedback(ctx, feedbackID); err != nil {
+            return err
+        }
+        return s.feedbackRepo.SetStatus(ctx, feedbackID, status)
+    })
+}
+
+// GetFeedbackThread возвращает отзыв со всеми ответами, включая приватные
+func (s *FeedbackService) GetFeedbackThread(ctx context.Context, feedbackID int) (*models.Feedback, error) {
+    feedback, err := s.getFeedback(ctx, feedbackID)
+    if err != nil {
+        return nil, err
+    }
+    thread := []models.Feedback{*feedback}
+    if err := s.attachReplies(ctx, thread, false); err != nil {
+        return nil, err
+    }
+    return &thread[0], nil
+}
+
+func (s *FeedbackService) attachReplies(ctx context.Context, feedbacks []models.Feedback, publicOnly bool) error {
+    ids := make([]int, len(feedbacks))
+    for i := range feedbacks {
+        ids[i] = feedbacks[i].ID
+    }
+
+    replies, err := s.feedbackRepo.GetReplies(ctx, ids, publicOnly)
+    if err != nil {
+        return err
+    }
+    for i := range feedbacks {
+        feedbacks[i].Replies = replies[feedbacks[i].ID]
+    }
+    return nil
+}
+
+func replyEmail(feedback *models.Feedback, reply *models.FeedbackReply) mailer.Message {
+    subject := "Ответ на ваш отзыв"
+    if feedback.Theme != "" {
+        subject += ": " + feedback.Theme
+    }
+
+    body := fmt.Sprintf("Здравствуйте, %s!\n\n%s\n\nВаше сообщение:\n> %s\n\nС уважением,\nкоманда Belladonna",
+        feedback.Name, reply.Message, strings.ReplaceAll(feedback.Message, "\n", "\n> "))
+
+    return mailer.Message{To: feedback.Email, Subject: subject, Body: body}
 }
 
-func NewFeedbackService(feedbackRepo *repository.FeedbackRepository) *FeedbackService {
-    return &FeedbackService{feedbackRepo: feedbackRepo}
+// GetPendingFeedbacks возвращает очередь отзывов, ожидающих модерации.
+// Непустой theme ограничивает выборку темой.
+func (s *FeedbackService) GetPendingFeedbacks(ctx context.Context, theme string) ([]models.Feedback, error) {
+    return s.feedbackRepo.GetFeedbacksByStatus(ctx, models.ModerationPending, theme)
 }
 
-func (s *FeedbackService) CreateFeedback(feedback *models.Feedback) error {
-    return s.feedbackRepo.CreateFeedback(feedback)
+// ApproveFeedback публикует отзыв
+func (s *FeedbackService) ApproveFeedback(ctx context.Context, moderatorID, feedbackID int) error {
+    return s.tx.Do(ctx, func(ctx context.Context) error {
+        if _, err := s.getFeedback(ctx, feedbackID); err != nil {
+            return err
+        }
+        return s.feedbackRepo.SetModerationStatus(ctx, feedbackID, moderatorID, models.ModerationApproved, models.ModerationActionApprove, "")
+    })
 }
 
-func (s *FeedbackService) GetVisibleFeedbacks() ([]models.Feedback, error) {
-    return s.feedbackRepo.GetVisibleFeedbacks()
-}
\ No newline at end of file
+// RejectFeedback отклоняет отзыв с указанием причины
+func (s *FeedbackService) RejectFeedback(ctx context.Context, moderatorID, feedbackID int, reason string) error {
+    reason = strings.TrimSpace(reason)
+    if reason == "" {
+        return ErrRejectReasonRequired
+    }
+    return s.tx.Do(ctx, func(ctx context.Context) error {
+        if _, err := s.getFeedback(ctx, feedbackID); err != nil {
+            return err
+        }
+        return s.feedbackRepo.SetModerationStatus(ctx, feedbackID, moderatorID, models.ModerationRejected, models.ModerationActionReject, reason)
+    })
+}
+
+// EditFeedback правит текст отзыва; пустые поля запроса оставляют прежние значения
+func (s *FeedbackService) EditFeedback(ctx context.Context, moderatorID int, req models.FeedbackEditRequest) (*models.Feedback, error) {
+    var feedback *models.Feedback
+    err := s.tx.Do(ctx, func(ctx context.Context) error {
+        var err error
+        feedback, err = s.editFeedback(ctx, moderatorID, req)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    return feedback, nil
+}
+
+// editFeedback читает отзыв и сохраняет правки; вызывается в транзакции
+func (s *FeedbackService) editFeedback(ctx context.Context, moderatorID int, req models.FeedbackEditRequest) (*models.Feedback, error) {
+    feedback, err := s.getFeedback(ctx, req.ID)
+    if err != nil {
+        return nil, err
+    }
+
+    var changed []string
+    if name := strings.TrimSpace(req.Name); name != "" && name != feedback.Name {
+        feedback.Name = name
+        changed = append(changed, "name")
+    }
+    if theme := strings.TrimSpace(req.Theme); theme != "" && theme != feedback.Theme {
+        if _, err := s.themes.ResolveTheme(ctx, theme); err != nil {
+            return nil, err
+        }
+        feedback.Theme = theme
+        changed = append(changed, "theme")
+    }
+    if message := strings.TrimSpace(req.Message); message != "" && message != feedback.Message {
+        feedback.Message = message
+        changed = append(changed, "message")
+    }
+
+    if len(changed) == 0 {
+        return feedback, nil
+    }
+
+    note := "changed: " + strings.Join(changed, ", ")
+    if err := s.feedbackRepo.UpdateFeedbackContent(ctx, feedback, moderatorID, note); err != nil {
+        return nil, err
+    }
+    return feedback, nil
+}
+
+// GetModerationHistory возвращает журнал решений по отзыву
+func (s *FeedbackService) GetModerationHistory(ctx context.Context, feedbackID int) ([]models.FeedbackModerationEntry, error) {
+    if _, err := s.getFeedback(ctx, feedbackID); err != nil {
+        return nil, err
+    }
+    return s.feedbackRepo.GetModerationHistory(ctx, feedbackID)
+}
+
+func (s *FeedbackService) getFeedback(ctx context.Context, id int) (*models.Feedback, error) {
+    feedback, err := s.feedbackRepo.GetFeedbackByID(ctx, id)
+    if err != nil {
+        return nil, err
+    }
+    if feedback == nil {
+        return nil, ErrFeedbackNotFound
+    }
+    return feedback, nil
+}
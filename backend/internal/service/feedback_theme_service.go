@@ -0,0 +1,166 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/validation"
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrThemeNotFound = apperr.NotFound("theme_not_found", "Тема отзыва не найдена")
+	ErrUnknownTheme  = apperr.Validation("unknown_theme", "Выберите тему из списка").
+				WithField("theme", "Выберите тему из списка")
+	ErrThemeCodeTaken = apperr.Conflict("theme_code_taken", "Тема с таким кодом уже существует").
+				WithField("code", "Тема с таким кодом уже существует")
+	ErrInvalidThemeCode = apperr.Validation("invalid_theme_code", "Некорректный код темы").
+				WithField("code", "Код темы: 2-50 строчных латинских букв, цифр, '-' или '_'")
+	ErrThemeTitleMissing = apperr.Validation("theme_title_required", "Укажите название темы").
+				WithField("title", "Укажите название темы")
+	ErrInvalidNotifyRole = apperr.Validation("invalid_notify_role", "Некорректная роль для уведомлений").
+				WithField("notify_role", "Допустимые роли: manager, admin")
+	ErrInvalidNotifyEmail = apperr.Validation("invalid_notify_email", "Некорректный email для уведомлений").
+				WithField("notify_email", "Введите корректный email")
+)
+
+var themeCodePattern = regexp.MustCompile(`^[a-z0-9_-]{2,50}$`)
+
+// FeedbackThemeService управляет списком тем отзывов и маршрутизацией уведомлений по ним
+type FeedbackThemeService struct {
+	themeRepo repository.FeedbackThemeStore
+	userRepo  repository.UserStore
+	tx        repository.UnitOfWork
+	mailer    mailer.Mailer
+	mail      sync.WaitGroup
+}
+
+func NewFeedbackThemeService(themeRepo repository.FeedbackThemeStore, userRepo repository.UserStore, tx repository.UnitOfWork, mailer mailer.Mailer) *FeedbackThemeService {
+	return &FeedbackThemeService{themeRepo: themeRepo, userRepo: userRepo, tx: tx, mailer: mailer}
+}
+
+// GetThemes возвращает темы; если activeOnly, только доступные в форме
+func (s *FeedbackThemeService) GetThemes(ctx context.Context, activeOnly bool) ([]models.FeedbackTheme, error) {
+	return s.themeRepo.GetThemes(ctx, activeOnly)
+}
+
+// ResolveTheme проверяет, что код соответствует активной теме. Пустой код допустим.
+func (s *FeedbackThemeService) ResolveTheme(ctx context.Context, code string) (*models.FeedbackTheme, error) {
+	if code == "" {
+		return nil, nil
+	}
+	theme, err := s.themeRepo.GetThemeByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if theme == nil || !theme.IsActive {
+		return nil, ErrUnknownTheme
+	}
+	return theme, nil
+}
+
+func (s *FeedbackThemeService) CreateTheme(ctx context.Context, theme *models.FeedbackTheme) error {
+	theme.Code = strings.TrimSpace(theme.Code)
+	if !themeCodePattern.MatchString(theme.Code) {
+		return ErrInvalidThemeCode
+	}
+	if err := validateTheme(theme); err != nil {
+		return err
+	}
+	err := s.themeRepo.CreateTheme(ctx, theme)
+	if repository.IsDuplicate(err) {
+		return ErrThemeCodeTaken.Wrap(err)
+	}
+	return err
+}
+
+// UpdateTheme меняет название, правило маршрутизации, порядок и активность темы. Код темы неизменен.
+func (s *FeedbackThemeService) UpdateTheme(ctx context.Context, theme *models.FeedbackTheme) (*models.FeedbackTheme, error) {
+	if err := validateTheme(theme); err != nil {
+		return nil, err
+	}
+
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		existing, err := s.themeRepo.GetThemeByID(ctx, theme.ID)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrThemeNotFound
+		}
+		theme.Code = existing.Code
+		theme.CreatedAt = existing.CreatedAt
+		return s.themeRepo.UpdateTheme(ctx, theme)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return theme, nil
+}
+
+// NotifyNewFeedback отправляет уведомление о новом отзыве по правилу его темы:
+// на почтовый ящик темы и всем сотрудникам с указанной ролью. Получатели определяются
+// в запросе, а письма уходят в фоне, чтобы отправка отзыва не ждала почтовый сервер.
+func (s *FeedbackThemeService) NotifyNewFeedback(ctx context.Context, theme *models.FeedbackTheme, feedback *models.Feedback) {
+	if theme == nil {
+		return
+	}
+
+	logger := logging.FromContext(ctx).With("feedback_id", feedback.ID)
+	recipients := map[string]bool{}
+	if theme.NotifyEmail != "" {
+		recipients[theme.NotifyEmail] = true
+	}
+	if theme.NotifyRole != "" {
+		staff, err := s.userRepo.GetUsersByRole(ctx, theme.NotifyRole)
+		if err != nil {
+			logger.Error("Ошибка получения сотрудников для уведомления", "role", theme.NotifyRole, "error", err)
+		}
+		for _, user := range staff {
+			recipients[user.Email] = true
+		}
+	}
+
+	subject := fmt.Sprintf("Новый отзыв на модерации: %s", theme.Title)
+	body := fmt.Sprintf("Тема: %s\nАвтор: %s\nОтзыв №%d\n\n%s", theme.Title, feedback.Name, feedback.ID, feedback.Message)
+	if len(recipients) == 0 {
+		return
+	}
+	s.mail.Add(1)
+	go func() {
+		defer s.mail.Done()
+		for email := range recipients {
+			if err := s.mailer.Send(mailer.Message{To: email, Subject: subject, Body: body}); err != nil {
+				logger.Error("Ошибка уведомления о новом отзыве", "email", email, "error", err)
+			}
+		}
+	}()
+}
+
+// Wait дожидается отправки уведомлений о новых отзывах
+func (s *FeedbackThemeService) Wait() {
+	s.mail.Wait()
+}
+
+func validateTheme(theme *models.FeedbackTheme) error {
+	theme.Title = strings.TrimSpace(theme.Title)
+	theme.NotifyEmail = strings.TrimSpace(theme.NotifyEmail)
+	if theme.Title == "" {
+		return ErrThemeTitleMissing
+	}
+	if theme.NotifyEmail != "" && (len(theme.NotifyEmail) > validation.MaxEmailLen || !validation.IsEmail(theme.NotifyEmail)) {
+		return ErrInvalidNotifyEmail
+	}
+	switch theme.NotifyRole {
+	case "", models.RoleManager, models.RoleAdmin:
+	default:
+		return ErrInvalidNotifyRole
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"testing"
+	"time"
+)
+
+// blockingMailer не отпускает Send, пока тест не закроет release
+type blockingMailer struct {
+	recordingMailer
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(msg mailer.Message) error {
+	<-m.release
+	return m.recordingMailer.Send(msg)
+}
+
+func TestFeedbackThemeNotifyInBackground(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserStore()
+	manager := &models.User{Email: "manager@example.com"}
+	if err := users.CreateUser(ctx, manager); err != nil {
+		t.Fatal(err)
+	}
+	users.SetRole(manager.ID, models.RoleManager)
+	mail := &blockingMailer{release: make(chan struct{})}
+	themes := NewFeedbackThemeService(memory.NewFeedbackThemeStore(), users, memory.NewUnitOfWork(), mail)
+
+	theme := &models.FeedbackTheme{Title: "Доставка", NotifyEmail: "delivery@example.com", NotifyRole: models.RoleManager}
+	returned := make(chan struct{})
+	go func() {
+		themes.NotifyNewFeedback(ctx, theme, &models.Feedback{ID: 1, Name: "Анна", Message: "Где заказ?"})
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("NotifyNewFeedback waits for the mail server")
+	}
+
+	close(mail.release)
+	themes.Wait()
+	sent := map[string]bool{}
+	for _, msg := range mail.messages() {
+		sent[msg.To] = true
+	}
+	if len(sent) != 2 || !sent["delivery@example.com"] || !sent["manager@example.com"] {
+		t.Errorf("notified %v, want theme mailbox and manager", sent)
+	}
+}
+
+func TestFeedbackThemeNotifyEmailValidation(t *testing.T) {
+	themes := NewFeedbackThemeService(memory.NewFeedbackThemeStore(), memory.NewUserStore(), memory.NewUnitOfWork(), &recordingMailer{})
+
+	for _, email := range []string{"not-an-email", "Support <support@example.com>", "a@b"} {
+		err := themes.CreateTheme(context.Background(), &models.FeedbackTheme{Code: "support", Title: "Поддержка", NotifyEmail: email})
+		if apperr.From(err).Code != "invalid_notify_email" {
+			t.Errorf("notify_email %q: err = %v, want invalid_notify_email", email, err)
+		}
+	}
+
+	theme := &models.FeedbackTheme{Code: "support", Title: "Поддержка", NotifyEmail: " support@example.com "}
+	if err := themes.CreateTheme(context.Background(), theme); err != nil {
+		t.Fatalf("valid notify_email: %v", err)
+	}
+	if theme.NotifyEmail != "support@example.com" {
+		t.Errorf("notify_email = %q, want trimmed", theme.NotifyEmail)
+	}
+}
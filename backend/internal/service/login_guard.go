@@ -0,0 +1,264 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLoginThrottled = apperr.TooManyRequests("login_throttled", "Слишком много попыток входа, повторите позже")
+	ErrAccountLocked  = apperr.TooManyRequests("account_locked",
+		"Вход временно заблокирован из-за неудачных попыток. Если аккаунт существует, на его email отправлена ссылка для разблокировки")
+	ErrInvalidUnlockToken = apperr.Validation("invalid_unlock_token", "Ссылка для разблокировки недействительна или устарела")
+)
+
+// LoginGuardConfig пороги защиты входа от подбора пароля.
+// После BackoffAfter неудач подряд каждая следующая попытка возможна не раньше,
+// чем через BaseDelay, 2*BaseDelay, 4*BaseDelay... (не больше MaxDelay).
+// После MaxFailures неудач вход блокируется на LockoutDuration.
+type LoginGuardConfig struct {
+	BackoffAfter    int
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	MaxFailures     int
+	FailureWindow   time.Duration
+	LockoutDuration time.Duration
+	UnlockTokenTTL  time.Duration
+	IPLimit         int
+	IPWindow        time.Duration
+}
+
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		BackoffAfter:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		MaxFailures:     10,
+		FailureWindow:   time.Hour,
+		LockoutDuration: 30 * time.Minute,
+		UnlockTokenTTL:  24 * time.Hour,
+		IPLimit:         50,
+		IPWindow:        15 * time.Minute,
+	}
+}
+
+// LoginGuard считает неудачные входы по email и по IP, задерживает повторные попытки
+// и блокирует вход после серии неудач. Счетчики по email ведутся и для незарегистрированных
+// адресов, чтобы ответ не выдавал, есть ли такой пользователь.
+type LoginGuard struct {
+	cfg       LoginGuardConfig
+	attempts  repository.LoginAttemptStore
+	tx        repository.UnitOfWork
+	mailer    mailer.Mailer
+	baseURL   string
+	ipLimiter *antispam.RateLimiter
+	now       func() time.Time
+	mail      sync.WaitGroup
+}
+
+// NewLoginGuard создает защиту входа; baseURL — адрес сайта для ссылки разблокировки
+func NewLoginGuard(cfg LoginGuardConfig, attempts repository.LoginAttemptStore, tx repository.UnitOfWork, mailer mailer.Mailer, baseURL string) *LoginGuard {
+	return &LoginGuard{
+		cfg:       cfg,
+		attempts:  attempts,
+		tx:        tx,
+		mailer:    mailer,
+		baseURL:   baseURL,
+		ipLimiter: antispam.NewRateLimiter(cfg.IPLimit, cfg.IPWindow),
+		now:       time.Now,
+	}
+}
+
+// Check разрешает или откладывает попытку входа до проверки пароля
+func (g *LoginGuard) Check(ctx context.Context, email, ip string) error {
+	if g.ipLimiter.Exceeded("ip:" + ip) {
+		logging.FromContext(ctx).Warn("Вход отклонен: превышен лимит неудач с IP", "ip", ip)
+		return ErrLoginThrottled.WithRetryAfter(g.cfg.IPWindow)
+	}
+
+	throttle, err := g.attempts.GetThrottle(ctx, email)
+	if err != nil || throttle == nil {
+		return err
+	}
+
+	now := g.now()
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return ErrAccountLocked.WithRetryAfter(throttle.LockedUntil.Sub(now))
+	}
+	if delay := g.delay(throttle.Failures); delay > 0 && throttle.LastFailureAt != nil {
+		if next := throttle.LastFailureAt.Add(delay); now.Before(next) {
+			return ErrLoginThrottled.WithRetryAfter(next.Sub(now))
+		}
+	}
+	return nil
+}
+
+// Fail учитывает неудачную попытку. user — владелец email или nil.
+// Возвращает ErrAccountLocked, если попытка исчерпала лимит и вход заблокирован.
+func (g *LoginGuard) Fail(ctx context.Context, email, ip string, user *models.User) error {
+	g.ipLimiter.Allow("ip:" + ip)
+
+	now := g.now()
+	throttle, err := g.attempts.RecordFailure(ctx, email, now, now.Add(-g.cfg.FailureWindow))
+	if err != nil {
+		return err
+	}
+	if throttle.Failures < g.cfg.MaxFailures {
+		return nil
+	}
+
+	lockout := &models.AccountLockout{
+		Email:       email,
+		IP:          ip,
+		Failures:    throttle.Failures,
+		LockedUntil: now.Add(g.cfg.LockoutDuration),
+	}
+	token := ""
+	if user != nil {
+		lockout.UserID = user.ID
+		token = newSecretToken()
+		lockout.UnlockTokenHash = hashSecretToken(token)
+	}
+
+	err = g.tx.Do(ctx, func(ctx context.Context) error {
+		if err := g.attempts.LockUntil(ctx, email, lockout.LockedUntil); err != nil {
+			return err
+		}
+		return g.attempts.CreateLockout(ctx, lockout)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Warn("Вход заблокирован после неудачных попыток",
+		"email", email, "ip", ip, "failures", throttle.Failures, "locked_until", lockout.LockedUntil)
+	if user != nil {
+		// Письмо уходит в фоне: время ответа не должно зависеть от того, есть ли пользователь
+		g.mail.Add(1)
+		go func() {
+			defer g.mail.Done()
+			g.notify(user, lockout, token)
+		}()
+	}
+	return ErrAccountLocked.WithRetryAfter(g.cfg.LockoutDuration)
+}
+
+// Succeed сбрасывает счетчик после успешного входа
+func (g *LoginGuard) Succeed(ctx context.Context, email string) error {
+	return g.attempts.ResetThrottle(ctx, email)
+}
+
+// Unlock снимает блокировку по одноразовому токену из письма
+func (g *LoginGuard) Unlock(ctx context.Context, token string) error {
+	return g.tx.Do(ctx, func(ctx context.Context) error {
+		lockout, err := g.attempts.GetLockoutByTokenHash(ctx, hashSecretToken(token))
+		if err != nil {
+			return err
+		}
+		now := g.now()
+		if lockout == nil || lockout.UnlockedAt != nil || now.After(lockout.CreatedAt.Add(g.cfg.UnlockTokenTTL)) {
+			return ErrInvalidUnlockToken
+		}
+
+		if err := g.attempts.MarkUnlocked(ctx, lockout.ID, now); err != nil {
+			return err
+		}
+		if err := g.attempts.ResetThrottle(ctx, lockout.Email); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("Вход разблокирован по ссылке из письма", "email", lockout.Email, "lockout_id", lockout.ID)
+		return nil
+	})
+}
+
+// Wait дожидается отправки писем о блокировке
+func (g *LoginGuard) Wait() {
+	g.mail.Wait()
+}
+
+// Cleanup освобождает память ограничителя по IP и удаляет устаревшие счетчики
+func (g *LoginGuard) Cleanup(ctx context.Context) error {
+	g.ipLimiter.Cleanup()
+	_, err := g.attempts.DeleteStaleThrottles(ctx, g.now().Add(-g.cfg.FailureWindow))
+	return err
+}
+
+// StartJanitor периодически вызывает Cleanup. Возвращает функцию остановки,
+// которая дожидается завершения фоновой горутины.
+func (g *LoginGuard) StartJanitor(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := g.Cleanup(context.Background()); err != nil {
+					slog.Warn("Ошибка очистки счетчиков входа", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// delay пауза перед следующей попыткой после failures неудач подряд
+func (g *LoginGuard) delay(failures int) time.Duration {
+	if failures < g.cfg.BackoffAfter {
+		return 0
+	}
+	delay := g.cfg.BaseDelay
+	for i := g.cfg.BackoffAfter; i < failures && delay < g.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, g.cfg.MaxDelay)
+}
+
+func (g *LoginGuard) notify(user *models.User, lockout *models.AccountLockout, token string) {
+	link := g.baseURL + "/pages/login.html?unlock=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Здравствуйте, %s!\n\n"+
+		"После %d неудачных попыток входа вход в ваш аккаунт заблокирован до %s (UTC).\n"+
+		"Если это были вы, снять блокировку можно по ссылке (действует %s):\n%s\n\n"+
+		"Если вы не пытались войти, рекомендуем сменить пароль.\n\nС уважением,\nкоманда Belladonna",
+		user.FirstName, lockout.Failures, lockout.LockedUntil.UTC().Format("02.01.2006 15:04"),
+		g.cfg.UnlockTokenTTL, link)
+
+	msg := mailer.Message{To: user.Email, Subject: "Вход в аккаунт заблокирован", Body: body}
+	if err := g.mailer.Send(msg); err != nil {
+		slog.Error("Ошибка отправки письма о блокировке входа", "user_id", user.ID, "error", err)
+	}
+}
+
+// newSecretToken случайный токен для ссылок из писем и cookie сессии
+func newSecretToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashSecretToken в базе хранится только SHA-256 токена
+func hashSecretToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testIP = "203.0.113.7"
+
+// recordingMailer запоминает отправленные письма
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *recordingMailer) messages() []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]mailer.Message(nil), m.sent...)
+}
+
+// newTestLoginGuard защита входа с настройками по умолчанию; mail может быть nil
+func newTestLoginGuard(mail mailer.Mailer) *LoginGuard {
+	if mail == nil {
+		mail = &recordingMailer{}
+	}
+	return NewLoginGuard(DefaultLoginGuardConfig(), memory.NewLoginAttemptStore(), memory.NewUnitOfWork(), mail, "https://belladonna.test")
+}
+
+// fakeClock время, которое двигает тест
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLoginGuardBackoff(t *testing.T) {
+	ctx := context.Background()
+	guard := newTestLoginGuard(nil)
+	clock := &fakeClock{t: time.Now()}
+	guard.now = clock.now
+	cfg := guard.cfg
+
+	for i := 0; i < cfg.BackoffAfter; i++ {
+		if err := guard.Check(ctx, "anna@example.com", testIP); err != nil {
+			t.Fatalf("Check before failure %d: %v", i+1, err)
+		}
+		if err := guard.Fail(ctx, "anna@example.com", testIP, nil); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+
+	err := guard.Check(ctx, "anna@example.com", testIP)
+	if !errors.Is(err, ErrLoginThrottled) {
+		t.Fatalf("Check after %d failures = %v, want ErrLoginThrottled", cfg.BackoffAfter, err)
+	}
+	var e *apperr.Error
+	if !errors.As(err, &e) || e.RetryAfter != cfg.BaseDelay {
+		t.Errorf("RetryAfter = %v, want %v", e.RetryAfter, cfg.BaseDelay)
+	}
+	if err := guard.Check(ctx, "boris@example.com", testIP); err != nil {
+		t.Errorf("other email throttled: %v", err)
+	}
+
+	clock.advance(cfg.BaseDelay)
+	if err := guard.Check(ctx, "anna@example.com", testIP); err != nil {
+		t.Fatalf("Check after BaseDelay: %v", err)
+	}
+
+	// Следующая неудача удваивает паузу
+	guard.Fail(ctx, "anna@example.com", testIP, nil)
+	clock.advance(cfg.BaseDelay)
+	if err := guard.Check(ctx, "anna@example.com", testIP); !errors.Is(err, ErrLoginThrottled) {
+		t.Errorf("Check after doubled delay not elapsed = %v, want ErrLoginThrottled", err)
+	}
+	clock.advance(cfg.BaseDelay)
+	if err := guard.Check(ctx, "anna@example.com", testIP); err != nil {
+		t.Errorf("Check after doubled delay: %v", err)
+	}
+
+	if err := guard.Succeed(ctx, "anna@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	guard.Fail(ctx, "anna@example.com", testIP, nil)
+	if err := guard.Check(ctx, "anna@example.com", testIP); err != nil {
+		t.Errorf("Check after success and one failure: %v", err)
+	}
+}
+
+func TestLoginGuardLockoutAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	mail := &recordingMailer{}
+	guard := newTestLoginGuard(mail)
+	clock := &fakeClock{t: time.Now()}
+	guard.now = clock.now
+	cfg := guard.cfg
+	attempts := guard.attempts.(*memory.LoginAttemptStore)
+	user := &models.User{ID: 7, Email: "anna@example.com", FirstName: "Анна"}
+
+	var err error
+	for i := 0; i < cfg.MaxFailures; i++ {
+		err = guard.Fail(ctx, user.Email, testIP, user)
+		clock.advance(cfg.MaxDelay)
+	}
+	if !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Fail #%d = %v, want ErrAccountLocked", cfg.MaxFailures, err)
+	}
+	if err := guard.Check(ctx, user.Email, testIP); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Check while locked = %v, want ErrAccountLocked", err)
+	}
+
+	lockouts := attempts.Lockouts()
+	if len(lockouts) != 1 || lockouts[0].UserID != user.ID || lockouts[0].Failures != cfg.MaxFailures {
+		t.Fatalf("lockouts = %+v, want one lockout of user %d", lockouts, user.ID)
+	}
+
+	guard.Wait()
+	sent := mail.messages()
+	if len(sent) != 1 || sent[0].To != user.Email {
+		t.Fatalf("sent = %+v, want one email to %s", sent, user.Email)
+	}
+	_, link, ok := strings.Cut(sent[0].Body, "?unlock=")
+	if !ok {
+		t.Fatalf("no unlock link in %q", sent[0].Body)
+	}
+	token, err := url.QueryUnescape(strings.Fields(link)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(lockouts[0].UnlockTokenHash, token) {
+		t.Error("unlock token stored in plain text")
+	}
+
+	if err := guard.Unlock(ctx, "forged"); !errors.Is(err, ErrInvalidUnlockToken) {
+		t.Errorf("Unlock(forged) = %v, want ErrInvalidUnlockToken", err)
+	}
+	if err := guard.Unlock(ctx, token); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := guard.Check(ctx, user.Email, testIP); err != nil {
+		t.Errorf("Check after unlock: %v", err)
+	}
+	if err := guard.Unlock(ctx, token); !errors.Is(err, ErrInvalidUnlockToken) {
+		t.Errorf("second Unlock = %v, want ErrInvalidUnlockToken", err)
+	}
+}
+
+func TestLoginGuardLockoutUnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	mail := &recordingMailer{}
+	guard := newTestLoginGuard(mail)
+	clock := &fakeClock{t: time.Now()}
+	guard.now = clock.now
+
+	var err error
+	for i := 0; i < guard.cfg.MaxFailures; i++ {
+		err = guard.Fail(ctx, "nobody@example.com", testIP, nil)
+		clock.advance(guard.cfg.MaxDelay)
+	}
+	if !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Fail = %v, want ErrAccountLocked as for an existing user", err)
+	}
+	guard.Wait()
+	if sent := mail.messages(); len(sent) != 0 {
+		t.Errorf("sent = %+v, want no email for unknown address", sent)
+	}
+
+	clock.advance(guard.cfg.LockoutDuration)
+	if err := guard.Check(ctx, "nobody@example.com", testIP); err != nil {
+		t.Errorf("Check after lockout expired: %v", err)
+	}
+}
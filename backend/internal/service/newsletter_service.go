@@ -0,0 +1,544 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidNewsletterToken = apperr.Validation("invalid_newsletter_token", "Ссылка недействительна или устарела")
+	ErrCampaignNotFound       = apperr.NotFound("campaign_not_found", "Кампания не найдена")
+	ErrCampaignNotDraft       = apperr.Conflict("campaign_not_draft", "Кампания уже отправлена, изменить ее нельзя")
+)
+
+// NewsletterOptions сроки подтверждения подписки и размер партий рассылки
+type NewsletterOptions struct {
+	// ConfirmTTL сколько действует ссылка подтверждения подписки
+	ConfirmTTL time.Duration
+	// ResendInterval не чаще этого письмо подтверждения отправляется повторно на тот же адрес
+	ResendInterval time.Duration
+	// BatchSize сколько писем кампании отправляется за один проход
+	BatchSize int
+}
+
+func DefaultNewsletterOptions() NewsletterOptions {
+	return NewsletterOptions{
+		ConfirmTTL:     48 * time.Hour,
+		ResendInterval: 10 * time.Minute,
+		BatchSize:      50,
+	}
+}
+
+// NewsletterService подписка на рассылку с подтверждением по email (double opt-in),
+// отписка по подписанной ссылке и рассылка кампаний партиями.
+// Флаг users.newsletter отражает действующую подписку пользователя и меняется только здесь.
+type NewsletterService struct {
+	store    repository.NewsletterStore
+	users    repository.UserStore
+	products repository.ProductStore
+	tx       repository.UnitOfWork
+	mailer   mailer.Mailer
+	opts     NewsletterOptions
+	key      []byte
+	baseURL  string
+	now      func() time.Time
+}
+
+// NewNewsletterService создает сервис; signingKey подписывает ссылки отписки,
+// baseURL — адрес сайта для ссылок в письмах
+func NewNewsletterService(store repository.NewsletterStore, users repository.UserStore, products repository.ProductStore, tx repository.UnitOfWork, mailer mailer.Mailer, opts NewsletterOptions, signingKey, baseURL string) *NewsletterService {
+	return &NewsletterService{
+		store:    store,
+		users:    users,
+		products: products,
+		tx:       tx,
+		mailer:   mailer,
+		opts:     opts,
+		key:      []byte(signingKey),
+		baseURL:  baseURL,
+		now:      time.Now,
+	}
+}
+
+// Subscribe подписывает адрес из формы на сайте. Ответ не зависит от того,
+// подписан ли адрес, чтобы по нему нельзя было проверить чужой email.
+func (s *NewsletterService) Subscribe(ctx context.Context, req models.NewsletterSubscribeRequest, meta ClientMeta) error {
+	return s.subscribe(ctx, req.Email, nil, models.SubscriptionSourceForm, meta)
+}
+
+// SubscribeUser подписывает пользователя; письма будут приходить на email аккаунта
+func (s *NewsletterService) SubscribeUser(ctx context.Context, user *models.User, source string, meta ClientMeta) error {
+	return s.subscribe(ctx, user.Email, &user.ID, source, meta)
+}
+
+// SetUserSubscription включает или отключает подписку пользователя из профиля
+func (s *NewsletterService) SetUserSubscription(ctx context.Context, user *models.User, subscribed bool, meta ClientMeta) error {
+	if subscribed {
+		return s.SubscribeUser(ctx, user, models.SubscriptionSourceProfile, meta)
+	}
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		sub, err := s.store.GetSubscriptionByUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		if sub != nil {
+			if err := s.unsubscribe(ctx, sub); err != nil {
+				return err
+			}
+		}
+		return s.users.SetNewsletter(ctx, user.ID, false)
+	})
+}
+
+// UserSubscription подписка пользователя или nil
+func (s *NewsletterService) UserSubscription(ctx context.Context, userID int) (*models.NewsletterSubscription, error) {
+	return s.store.GetSubscriptionByUser(ctx, userID)
+}
+
+// subscribe создает подписку в статусе pending и отправляет ссылку подтверждения.
+// Действующая подписка не меняется; повторное письмо уходит не чаще ResendInterval.
+func (s *NewsletterService) subscribe(ctx context.Context, email string, userID *int, source string, meta ClientMeta) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	logger := logging.FromContext(ctx)
+
+	token := ""
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var sub *models.NewsletterSubscription
+		var err error
+		if userID != nil {
+			if sub, err = s.store.GetSubscriptionByUser(ctx, *userID); err != nil {
+				return err
+			}
+		}
+		if sub == nil {
+			if sub, err = s.store.GetSubscriptionByEmail(ctx, email); err != nil {
+				return err
+			}
+		}
+
+		now := s.now()
+		if sub != nil && sub.Status == models.SubscriptionActive {
+			if userID == nil || sub.UserID != nil {
+				return nil
+			}
+			// Адрес уже подтвержден гостем — привязываем подписку к аккаунту
+			sub.UserID = userID
+			if err := s.store.UpdateSubscription(ctx, sub); err != nil {
+				return err
+			}
+			return s.users.SetNewsletter(ctx, *userID, true)
+		}
+		if sub != nil && sub.Status == models.SubscriptionPending && sub.ConfirmSentAt != nil &&
+			now.Sub(*sub.ConfirmSentAt) < s.opts.ResendInterval {
+			logger.Info("Письмо подтверждения подписки отправлялось недавно", "subscription_id", sub.ID)
+			return nil
+		}
+
+		token = newSecretToken()
+		if sub == nil {
+			sub = &models.NewsletterSubscription{Email: email, Source: source}
+		}
+		if sub.UserID == nil {
+			sub.UserID = userID
+		}
+		sub.Status = models.SubscriptionPending
+		sub.ConfirmTokenHash = hashSecretToken(token)
+		sub.ConfirmSentAt = &now
+		sub.IP = meta.IP
+		if sub.ID == 0 {
+			return s.store.CreateSubscription(ctx, sub)
+		}
+		return s.store.UpdateSubscription(ctx, sub)
+	})
+	if err != nil || token == "" {
+		return err
+	}
+
+	link := s.baseURL + "/index.html?newsletter_confirm=" + url.QueryEscape(token)
+	err = s.mailer.Send(mailer.Message{
+		To:      email,
+		Subject: "Подтвердите подписку на рассылку",
+		Body: fmt.Sprintf("Здравствуйте!\n\n"+
+			"Чтобы получать новости и акции салона Belladonna, подтвердите подписку по ссылке (действует %s):\n%s\n\n"+
+			"Если вы не подписывались, просто проигнорируйте это письмо.\n\nС уважением,\nкоманда Belladonna",
+			s.opts.ConfirmTTL, link),
+	})
+	if err != nil {
+		return fmt.Errorf("отправка письма подтверждения подписки: %w", err)
+	}
+	logger.Info("Запрошена подписка на рассылку", "source", source)
+	return nil
+}
+
+// Confirm подтверждает подписку по токену из письма. Если адрес принадлежит
+// зарегистрированному пользователю, подписка привязывается к его аккаунту.
+func (s *NewsletterService) Confirm(ctx context.Context, token string) error {
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		sub, err := s.store.GetSubscriptionByTokenHash(ctx, hashSecretToken(token))
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		if sub == nil || sub.Status != models.SubscriptionPending || sub.ConfirmSentAt == nil ||
+			now.After(sub.ConfirmSentAt.Add(s.opts.ConfirmTTL)) {
+			return ErrInvalidNewsletterToken
+		}
+
+		if sub.UserID == nil {
+			user, err := s.users.GetUserByEmail(ctx, sub.Email)
+			if err != nil {
+				return err
+			}
+			if user != nil {
+				if other, err := s.store.GetSubscriptionByUser(ctx, user.ID); err != nil {
+					return err
+				} else if other == nil {
+					sub.UserID = &user.ID
+				}
+			}
+		}
+		sub.Status = models.SubscriptionActive
+		sub.ConfirmTokenHash = ""
+		sub.ConfirmedAt = &now
+		sub.UnsubscribedAt = nil
+		if err := s.store.UpdateSubscription(ctx, sub); err != nil {
+			return err
+		}
+		if sub.UserID != nil {
+			if err := s.users.SetNewsletter(ctx, *sub.UserID, true); err != nil {
+				return err
+			}
+		}
+		logging.FromContext(ctx).Info("Подписка на рассылку подтверждена", "subscription_id", sub.ID)
+		return nil
+	})
+}
+
+// Unsubscribe отписывает по подписанному токену из письма рассылки. Повторная отписка не ошибка.
+func (s *NewsletterService) Unsubscribe(ctx context.Context, token string) error {
+	id, ok := s.parseUnsubscribeToken(token)
+	if !ok {
+		return ErrInvalidNewsletterToken
+	}
+	return s.tx.Do(ctx, func(ctx context.Context) error {
+		sub, err := s.store.GetSubscriptionByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if sub == nil {
+			return ErrInvalidNewsletterToken
+		}
+		if sub.Status == models.SubscriptionUnsubscribed {
+			return nil
+		}
+		if err := s.unsubscribe(ctx, sub); err != nil {
+			return err
+		}
+		if sub.UserID != nil {
+			return s.users.SetNewsletter(ctx, *sub.UserID, false)
+		}
+		return nil
+	})
+}
+
+func (s *NewsletterService) unsubscribe(ctx context.Context, sub *models.NewsletterSubscription) error {
+	now := s.now()
+	sub.Status = models.SubscriptionUnsubscribed
+	sub.ConfirmTokenHash = ""
+	sub.UnsubscribedAt = &now
+	if err := s.store.UpdateSubscription(ctx, sub); err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Отписка от рассылки", "subscription_id", sub.ID)
+	return nil
+}
+
+// unsubscribeToken подписанный токен отписки. Формат: <subscription_id>.<hmac>.
+// Срока у токена нет: ссылка из старого письма должна работать всегда.
+func (s *NewsletterService) unsubscribeToken(subscriptionID int) string {
+	id := strconv.Itoa(subscriptionID)
+	return id + "." + s.sign(id)
+}
+
+func (s *NewsletterService) parseUnsubscribeToken(token string) (int, bool) {
+	id, mac, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.sign(id))) {
+		return 0, false
+	}
+	subscriptionID, err := strconv.Atoi(id)
+	return subscriptionID, err == nil
+}
+
+func (s *NewsletterService) sign(payload string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte("newsletter-unsubscribe:" + payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// GetCampaigns все кампании со статистикой отправки
+func (s *NewsletterService) GetCampaigns(ctx context.Context) ([]models.NewsletterCampaign, error) {
+	campaigns, err := s.store.GetCampaigns(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range campaigns {
+		if campaigns[i].Status == models.CampaignDraft {
+			continue
+		}
+		if campaigns[i].Stats, err = s.store.CountDeliveries(ctx, campaigns[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return campaigns, nil
+}
+
+// CreateCampaign создает черновик кампании
+func (s *NewsletterService) CreateCampaign(ctx context.Context, req models.NewsletterCampaignRequest, authorID int) (*models.NewsletterCampaign, error) {
+	if err := s.checkProducts(ctx, req.ProductIDs); err != nil {
+		return nil, err
+	}
+	campaign := &models.NewsletterCampaign{
+		Subject:    req.Subject,
+		Intro:      req.Intro,
+		ProductIDs: req.ProductIDs,
+		Status:     models.CampaignDraft,
+		CreatedBy:  &authorID,
+	}
+	if err := s.store.CreateCampaign(ctx, campaign); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Создана кампания рассылки", "campaign_id", campaign.ID, "author_id", authorID)
+	return campaign, nil
+}
+
+// UpdateCampaign меняет черновик; отправленную кампанию изменить нельзя
+func (s *NewsletterService) UpdateCampaign(ctx context.Context, id int, req models.NewsletterCampaignRequest) (*models.NewsletterCampaign, error) {
+	if err := s.checkProducts(ctx, req.ProductIDs); err != nil {
+		return nil, err
+	}
+	var campaign *models.NewsletterCampaign
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if campaign, err = s.draft(ctx, id); err != nil {
+			return err
+		}
+		campaign.Subject = req.Subject
+		campaign.Intro = req.Intro
+		campaign.ProductIDs = req.ProductIDs
+		return s.store.UpdateCampaign(ctx, campaign)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// Preview письмо кампании в том виде, в каком его получит подписчик
+func (s *NewsletterService) Preview(ctx context.Context, id int) (*mailer.Message, error) {
+	campaign, err := s.store.GetCampaignByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	products, err := s.campaignProducts(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	msg := s.campaignMessage(campaign, products, models.NewsletterDelivery{Email: "subscriber@example.com"})
+	return &msg, nil
+}
+
+// Launch ставит письма кампании в очередь всем активным подписчикам.
+// Письма отправляет StartSender партиями по BatchSize.
+func (s *NewsletterService) Launch(ctx context.Context, id int) (*models.NewsletterCampaign, error) {
+	var campaign *models.NewsletterCampaign
+	var queued int64
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if campaign, err = s.draft(ctx, id); err != nil {
+			return err
+		}
+		if queued, err = s.store.EnqueueDeliveries(ctx, id); err != nil {
+			return err
+		}
+		campaign.Status = models.CampaignSending
+		return s.store.UpdateCampaign(ctx, campaign)
+	})
+	if err != nil {
+		return nil, err
+	}
+	campaign.Stats = map[string]int{models.DeliveryPending: int(queued)}
+	logging.FromContext(ctx).Info("Кампания поставлена в очередь", "campaign_id", id, "recipients", queued)
+	return campaign, nil
+}
+
+// SendPending отправляет очередную партию писем каждой запущенной кампании.
+// Кампания отмечается отправленной, когда в ее очереди не осталось писем.
+func (s *NewsletterService) SendPending(ctx context.Context) error {
+	campaigns, err := s.store.GetCampaigns(ctx, models.CampaignSending)
+	if err != nil {
+		return err
+	}
+	for i := range campaigns {
+		if err := s.sendBatch(ctx, &campaigns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *NewsletterService) sendBatch(ctx context.Context, campaign *models.NewsletterCampaign) error {
+	logger := logging.FromContext(ctx).With("campaign_id", campaign.ID)
+	deliveries, err := s.store.GetPendingDeliveries(ctx, campaign.ID, s.opts.BatchSize)
+	if err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		now := s.now()
+		campaign.Status = models.CampaignSent
+		campaign.SentAt = &now
+		if err := s.store.UpdateCampaign(ctx, campaign); err != nil {
+			return err
+		}
+		logger.Info("Кампания отправлена")
+		return nil
+	}
+
+	products, err := s.campaignProducts(ctx, campaign)
+	if err != nil {
+		return err
+	}
+	for _, d := range deliveries {
+		status, errText := models.DeliverySent, ""
+		// Подписчик мог отписаться, пока кампания ждала очереди
+		if d.SubscriptionStatus != models.SubscriptionActive {
+			status = models.DeliverySkipped
+		} else if err := s.mailer.Send(s.campaignMessage(campaign, products, d)); err != nil {
+			logger.Warn("Ошибка отправки письма рассылки", "delivery_id", d.ID, "error", err)
+			status, errText = models.DeliveryFailed, err.Error()
+		}
+		if err := s.store.MarkDelivery(ctx, d.ID, status, errText, s.now()); err != nil {
+			return err
+		}
+	}
+	logger.Info("Отправлена партия писем рассылки", "count", len(deliveries))
+	return nil
+}
+
+// StartSender периодически отправляет партии писем запущенных кампаний; возвращает функцию остановки
+func (s *NewsletterService) StartSender(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.SendPending(context.Background()); err != nil {
+					slog.Warn("Ошибка отправки рассылки", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// campaignMessage письмо кампании подписчику: вступление, блоки товаров и ссылка отписки.
+// Заголовки List-Unsubscribe позволяют отписаться кнопкой в почтовом клиенте (RFC 8058).
+func (s *NewsletterService) campaignMessage(campaign *models.NewsletterCampaign, products []models.Product, d models.NewsletterDelivery) mailer.Message {
+	token := s.unsubscribeToken(d.SubscriptionID)
+	unsubscribe := s.baseURL + "/index.html?newsletter_unsubscribe=" + url.QueryEscape(token)
+	oneClick := s.baseURL + "/api/newsletter/unsubscribe/one-click?token=" + url.QueryEscape(token)
+
+	var body strings.Builder
+	body.WriteString(campaign.Intro)
+	body.WriteString("\n")
+	for _, p := range products {
+		fmt.Fprintf(&body, "\n— %s\n  %.0f руб.", p.Name, p.Price)
+		if !p.InStock {
+			body.WriteString(" (под заказ)")
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&body, "\n  %s", p.Description)
+		}
+		fmt.Fprintf(&body, "\n  %s/pages/catalog.html\n", s.baseURL)
+	}
+	fmt.Fprintf(&body, "\nС уважением,\nкоманда Belladonna\n\n"+
+		"Вы получили это письмо, потому что подписаны на рассылку Belladonna.\n"+
+		"Отписаться: %s\n", unsubscribe)
+
+	return mailer.Message{
+		To:      d.Email,
+		Subject: campaign.Subject,
+		Body:    body.String(),
+		Headers: map[string]string{
+			"List-Unsubscribe":      "<" + oneClick + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		},
+	}
+}
+
+// campaignProducts товары кампании в заданном порядке; снятые с каталога пропускаются
+func (s *NewsletterService) campaignProducts(ctx context.Context, campaign *models.NewsletterCampaign) ([]models.Product, error) {
+	products := make([]models.Product, 0, len(campaign.ProductIDs))
+	for _, id := range campaign.ProductIDs {
+		product, err := s.products.GetProductByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if product != nil {
+			products = append(products, *product)
+		}
+	}
+	return products, nil
+}
+
+func (s *NewsletterService) checkProducts(ctx context.Context, ids []int) error {
+	for _, id := range ids {
+		product, err := s.products.GetProductByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return ErrProductNotFound.WithField("product_ids", fmt.Sprintf("Товар %d не найден", id))
+		}
+	}
+	return nil
+}
+
+// draft кампания-черновик с блокировкой изменений после запуска
+func (s *NewsletterService) draft(ctx context.Context, id int) (*models.NewsletterCampaign, error) {
+	campaign, err := s.store.GetCampaignByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, ErrCampaignNotFound
+	}
+	if campaign.Status != models.CampaignDraft {
+		return nil, ErrCampaignNotDraft
+	}
+	return campaign, nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestNewsletter сервис рассылки на хранилищах в памяти с управляемыми часами
+func newTestNewsletter(t *testing.T) (*NewsletterService, *recordingMailer, *fakeClock) {
+	t.Helper()
+	users := memory.NewUserStore()
+	mail := &recordingMailer{}
+	clock := &fakeClock{t: time.Now()}
+	s := NewNewsletterService(memory.NewNewsletterStore(users), users, memory.NewProductStore(), memory.NewUnitOfWork(),
+		mail, DefaultNewsletterOptions(), "test-secret", "https://belladonna.test")
+	s.now = clock.now
+	return s, mail, clock
+}
+
+// confirmToken токен из последнего письма подтверждения на адрес email
+func confirmToken(t *testing.T, mail *recordingMailer, email string) string {
+	t.Helper()
+	sent := mail.messages()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if sent[i].To != email {
+			continue
+		}
+		_, link, ok := strings.Cut(sent[i].Body, "?newsletter_confirm=")
+		if !ok {
+			break
+		}
+		token, err := url.QueryUnescape(strings.Fields(link)[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	t.Fatalf("no confirmation email to %s in %+v", email, sent)
+	return ""
+}
+
+func subscribe(t *testing.T, s *NewsletterService, email string) {
+	t.Helper()
+	if err := s.Subscribe(context.Background(), models.NewsletterSubscribeRequest{Email: email}, ClientMeta{IP: testIP}); err != nil {
+		t.Fatalf("Subscribe(%s): %v", email, err)
+	}
+}
+
+func TestNewsletterConfirmToken(t *testing.T) {
+	ctx := context.Background()
+	s, mail, clock := newTestNewsletter(t)
+
+	subscribe(t, s, "anna@example.com")
+	token := confirmToken(t, mail, "anna@example.com")
+	if err := s.Confirm(ctx, "forged"); !errors.Is(err, ErrInvalidNewsletterToken) {
+		t.Errorf("Confirm(forged) = %v, want ErrInvalidNewsletterToken", err)
+	}
+	if err := s.Confirm(ctx, token); err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	sub, _ := s.store.GetSubscriptionByEmail(ctx, "anna@example.com")
+	if sub == nil || sub.Status != models.SubscriptionActive {
+		t.Fatalf("subscription = %+v, want active", sub)
+	}
+	if err := s.Confirm(ctx, token); !errors.Is(err, ErrInvalidNewsletterToken) {
+		t.Errorf("second Confirm = %v, want ErrInvalidNewsletterToken", err)
+	}
+
+	// Ссылка перестает действовать через ConfirmTTL
+	subscribe(t, s, "ivan@example.com")
+	expired := confirmToken(t, mail, "ivan@example.com")
+	clock.advance(s.opts.ConfirmTTL + time.Second)
+	if err := s.Confirm(ctx, expired); !errors.Is(err, ErrInvalidNewsletterToken) {
+		t.Errorf("Confirm after TTL = %v, want ErrInvalidNewsletterToken", err)
+	}
+	sub, _ = s.store.GetSubscriptionByEmail(ctx, "ivan@example.com")
+	if sub == nil || sub.Status != models.SubscriptionPending {
+		t.Errorf("subscription after expired confirm = %+v, want pending", sub)
+	}
+
+	// Повторная подписка выдает новую ссылку, старая остается недействительной
+	subscribe(t, s, "ivan@example.com")
+	fresh := confirmToken(t, mail, "ivan@example.com")
+	if fresh == expired {
+		t.Fatal("resubscribe reused the confirmation token")
+	}
+	if err := s.Confirm(ctx, expired); !errors.Is(err, ErrInvalidNewsletterToken) {
+		t.Errorf("Confirm(old token) = %v, want ErrInvalidNewsletterToken", err)
+	}
+	if err := s.Confirm(ctx, fresh); err != nil {
+		t.Errorf("Confirm(new token): %v", err)
+	}
+}
+
+func TestNewsletterUnsubscribeSignature(t *testing.T) {
+	ctx := context.Background()
+	s, mail, _ := newTestNewsletter(t)
+
+	subscribe(t, s, "anna@example.com")
+	if err := s.Confirm(ctx, confirmToken(t, mail, "anna@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	sub, _ := s.store.GetSubscriptionByEmail(ctx, "anna@example.com")
+	token := s.unsubscribeToken(sub.ID)
+
+	other, _, _ := newTestNewsletter(t)
+	other.key = []byte("other-secret")
+	id, mac, _ := strings.Cut(token, ".")
+	for name, bad := range map[string]string{
+		"empty":         "",
+		"id only":       id,
+		"other id":      "999." + mac,
+		"other secret":  other.unsubscribeToken(sub.ID),
+		"truncated mac": id + "." + mac[:len(mac)-1],
+	} {
+		if err := s.Unsubscribe(ctx, bad); !errors.Is(err, ErrInvalidNewsletterToken) {
+			t.Errorf("Unsubscribe(%s) = %v, want ErrInvalidNewsletterToken", name, err)
+		}
+	}
+	if sub, _ = s.store.GetSubscriptionByID(ctx, sub.ID); sub.Status != models.SubscriptionActive {
+		t.Fatalf("status after rejected tokens = %s, want active", sub.Status)
+	}
+
+	if err := s.Unsubscribe(ctx, token); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if err := s.Unsubscribe(ctx, token); err != nil {
+		t.Errorf("second Unsubscribe = %v, want nil", err)
+	}
+	if sub, _ = s.store.GetSubscriptionByID(ctx, sub.ID); sub.Status != models.SubscriptionUnsubscribed {
+		t.Errorf("status = %s, want unsubscribed", sub.Status)
+	}
+}
+
+func TestNewsletterCampaignBatches(t *testing.T) {
+	ctx := context.Background()
+	s, mail, _ := newTestNewsletter(t)
+	s.opts.BatchSize = 2
+
+	active := []string{"a@example.com", "b@example.com", "c@example.com"}
+	for _, email := range active {
+		subscribe(t, s, email)
+		if err := s.Confirm(ctx, confirmToken(t, mail, email)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	subscribe(t, s, "pending@example.com")
+	subscribe(t, s, "gone@example.com")
+	if err := s.Confirm(ctx, confirmToken(t, mail, "gone@example.com")); err != nil {
+		t.Fatal(err)
+	}
+	gone, _ := s.store.GetSubscriptionByEmail(ctx, "gone@example.com")
+	if err := s.Unsubscribe(ctx, s.unsubscribeToken(gone.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	campaign, err := s.CreateCampaign(ctx, models.NewsletterCampaignRequest{Subject: "Осенняя коллекция", Intro: "Новинки сезона"}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if campaign, err = s.Launch(ctx, campaign.ID); err != nil {
+		t.Fatal(err)
+	}
+	if queued := campaign.Stats[models.DeliveryPending]; queued != len(active) {
+		t.Fatalf("queued %d deliveries, want %d active subscribers", queued, len(active))
+	}
+
+	// Подписчик отписывается, пока кампания ждет своей партии
+	late, _ := s.store.GetSubscriptionByEmail(ctx, "c@example.com")
+	if err := s.Unsubscribe(ctx, s.unsubscribeToken(late.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	before := len(mail.messages())
+	if err := s.SendPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if sent := len(mail.messages()) - before; sent != s.opts.BatchSize {
+		t.Errorf("first batch sent %d emails, want %d", sent, s.opts.BatchSize)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.SendPending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := map[string]bool{}
+	for _, msg := range mail.messages()[before:] {
+		got[msg.To] = true
+		if msg.Headers["List-Unsubscribe"] == "" {
+			t.Errorf("campaign email to %s has no List-Unsubscribe header", msg.To)
+		}
+	}
+	if len(got) != 2 || !got["a@example.com"] || !got["b@example.com"] {
+		t.Errorf("campaign sent to %v, want only a@ and b@", got)
+	}
+
+	stats, _ := s.store.CountDeliveries(ctx, campaign.ID)
+	if stats[models.DeliverySent] != 2 || stats[models.DeliverySkipped] != 1 {
+		t.Errorf("delivery stats = %v, want 2 sent and 1 skipped", stats)
+	}
+	if campaign, _ = s.store.GetCampaignByID(ctx, campaign.ID); campaign.Status != models.CampaignSent {
+		t.Errorf("campaign status = %s, want sent", campaign.Status)
+	}
+}
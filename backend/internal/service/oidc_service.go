@@ -0,0 +1,391 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/oidc"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/utils"
+	"beladonna/backend/internal/validation"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownProvider = apperr.NotFound("oidc_provider_unknown", "Вход через этот сервис недоступен")
+	ErrOIDCThrottled   = apperr.TooManyRequests("oidc_throttled", "Слишком много попыток входа, повторите позже")
+	ErrOIDCState       = apperr.Unauthorized("oidc_state", "Сеанс входа устарел, попробуйте еще раз")
+	ErrOIDCDenied      = apperr.Unauthorized("oidc_denied", "Вход через внешний сервис отменен")
+	ErrOIDCFailed      = apperr.Unauthorized("oidc_failed", "Не удалось войти через внешний сервис, попробуйте позже")
+	ErrIdentityEmail   = apperr.Validation("oidc_email_required", "Сервис не передал подтвержденный email, войдите другим способом")
+	// Аккаунт по совпадению email не привязывается автоматически: владелец адреса у провайдера
+	// не обязательно владелец аккаунта магазина
+	ErrIdentityEmailTaken = apperr.Conflict("oidc_email_taken", "Аккаунт с этим email уже есть: войдите по паролю и привяжите сервис в профиле")
+	ErrIdentityLinked     = apperr.Conflict("oidc_identity_linked", "Этот аккаунт сервиса уже привязан к другому пользователю")
+	ErrProviderLinked     = apperr.Conflict("oidc_provider_linked", "К вашему аккаунту уже привязан аккаунт этого сервиса")
+	ErrIdentityNotFound   = apperr.NotFound("identity_not_found", "Привязка не найдена")
+)
+
+const (
+	// oidcStateTTL сколько ждать возврата с провайдера
+	oidcStateTTL = 10 * time.Minute
+	// oidcStartLimit попыток входа через провайдеров с одного IP за oidcStartWindow
+	oidcStartLimit  = 30
+	oidcStartWindow = 10 * time.Minute
+)
+
+// OIDCService вход через внешних провайдеров OpenID Connect и привязка их аккаунтов.
+// Незавершенный вход хранится в базе: state, nonce и code_verifier PKCE. State дополнительно
+// кладется в cookie браузера, чтобы чужой ответ провайдера нельзя было подсунуть в сессию.
+type OIDCService struct {
+	providers map[string]oidc.Provider
+	order     []oidc.Provider
+	store     repository.IdentityStore
+	users     repository.UserStore
+	tx        repository.UnitOfWork
+	baseURL   string
+	limiter   *antispam.RateLimiter
+	now       func() time.Time
+}
+
+// OIDCCallback параметры возврата с провайдера и state из cookie браузера
+type OIDCCallback struct {
+	Provider    string
+	State       string
+	Code        string
+	Error       string
+	CookieState string
+}
+
+// OIDCResult итог возврата с провайдера: вход пользователя User или,
+// если Linked, привязка аккаунта к уже вошедшему пользователю
+type OIDCResult struct {
+	User   *models.User
+	Linked bool
+}
+
+// NewOIDCService создает сервис; baseURL — адрес сайта, из него строится redirect_uri
+func NewOIDCService(providers []oidc.Provider, store repository.IdentityStore, users repository.UserStore, tx repository.UnitOfWork, baseURL string) *OIDCService {
+	s := &OIDCService{
+		providers: make(map[string]oidc.Provider, len(providers)),
+		order:     providers,
+		store:     store,
+		users:     users,
+		tx:        tx,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		limiter:   antispam.NewRateLimiter(oidcStartLimit, oidcStartWindow),
+		now:       time.Now,
+	}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+	}
+	return s
+}
+
+// Providers провайдеры для кнопок на странице входа
+func (s *OIDCService) Providers() []models.AuthProvider {
+	providers := make([]models.AuthProvider, len(s.order))
+	for i, p := range s.order {
+		providers[i] = models.AuthProvider{Name: p.Name(), DisplayName: p.DisplayName()}
+	}
+	return providers
+}
+
+// RedirectURI адрес возврата с провайдера; его нужно зарегистрировать у провайдера
+func (s *OIDCService) RedirectURI(provider string) string {
+	return s.baseURL + "/api/auth/oidc/" + provider + "/callback"
+}
+
+// Start начинает вход через провайдера и возвращает адрес его страницы входа и state
+// для cookie. linkUserID не 0, если вошедший пользователь привязывает аккаунт.
+func (s *OIDCService) Start(ctx context.Context, name string, linkUserID int, ip string) (string, string, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	logger := logging.FromContext(ctx).With("provider", name)
+	if !s.limiter.Allow(ip) {
+		logger.Warn("Вход через провайдера отклонен: превышен лимит попыток с IP", "ip", ip)
+		return "", "", ErrOIDCThrottled.WithRetryAfter(oidcStartWindow)
+	}
+
+	state := &models.OIDCLoginState{
+		Provider:     name,
+		Nonce:        newSecretToken(),
+		CodeVerifier: oidc.NewCodeVerifier(),
+		UserID:       linkUserID,
+		ExpiresAt:    s.now().Add(oidcStateTTL),
+	}
+	rawState := newSecretToken()
+	state.StateHash = hashSecretToken(rawState)
+
+	authURL, err := provider.AuthCodeURL(ctx, oidc.AuthRequest{
+		State:         rawState,
+		Nonce:         state.Nonce,
+		CodeChallenge: oidc.CodeChallenge(state.CodeVerifier),
+		RedirectURI:   s.RedirectURI(name),
+	})
+	if err != nil {
+		logger.Warn("Провайдер недоступен", "error", err)
+		return "", "", ErrOIDCFailed.Wrap(err)
+	}
+	if err := s.store.CreateOIDCState(ctx, state); err != nil {
+		return "", "", err
+	}
+	logger.Debug("Начат вход через провайдера", "link_user_id", linkUserID)
+	return authURL, rawState, nil
+}
+
+// Callback завершает вход: сверяет state с cookie и базой, обменивает код на ID-токен,
+// сверяет nonce и находит, создает или привязывает пользователя
+func (s *OIDCService) Callback(ctx context.Context, cb OIDCCallback) (*OIDCResult, error) {
+	provider, ok := s.providers[cb.Provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	logger := logging.FromContext(ctx).With("provider", cb.Provider)
+
+	if cb.State == "" || subtle.ConstantTimeCompare([]byte(cb.State), []byte(cb.CookieState)) != 1 {
+		logger.Info("Возврат с провайдера отклонен: state не совпадает с cookie")
+		return nil, ErrOIDCState
+	}
+	state, err := s.store.TakeOIDCState(ctx, hashSecretToken(cb.State))
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.Provider != cb.Provider || !s.now().Before(state.ExpiresAt) {
+		logger.Info("Возврат с провайдера отклонен: state не найден или истек")
+		return nil, ErrOIDCState
+	}
+
+	if cb.Error != "" {
+		logger.Info("Провайдер вернул ошибку", "oidc_error", cb.Error)
+		return nil, ErrOIDCDenied
+	}
+	if cb.Code == "" {
+		return nil, ErrOIDCFailed
+	}
+
+	token, err := provider.Exchange(ctx, cb.Code, state.CodeVerifier, s.RedirectURI(cb.Provider))
+	if err != nil {
+		logger.Warn("Ошибка обмена кода провайдера", "error", err)
+		return nil, ErrOIDCFailed.Wrap(err)
+	}
+	if subtle.ConstantTimeCompare([]byte(token.Nonce), []byte(state.Nonce)) != 1 {
+		logger.Warn("ID-токен отклонен: nonce не совпадает")
+		return nil, ErrOIDCFailed
+	}
+
+	if state.UserID != 0 {
+		if err := s.link(ctx, state.UserID, cb.Provider, token); err != nil {
+			return nil, err
+		}
+		user, err := s.users.GetUserByID(ctx, state.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+		return &OIDCResult{User: user, Linked: true}, nil
+	}
+
+	user, err := s.login(ctx, cb.Provider, token)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCResult{User: user}, nil
+}
+
+// login находит пользователя по привязке или регистрирует нового по подтвержденному email.
+// Согласие с документами при этом не записывается: пользователь их не видел, поэтому
+// у нового аккаунта все документы в Pending и сайт запросит согласие через /api/consents.
+func (s *OIDCService) login(ctx context.Context, provider string, token *oidc.IDToken) (*models.User, error) {
+	logger := logging.FromContext(ctx).With("provider", provider)
+
+	identity, err := s.store.GetIdentity(ctx, provider, token.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if identity != nil {
+		user, err := s.users.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+		if err := s.store.TouchIdentity(ctx, identity.ID, token.Email, s.now()); err != nil {
+			logger.Error("Ошибка отметки входа через провайдера", "identity_id", identity.ID, "error", err)
+		}
+		logger.Info("Вход через провайдера", "user_id", user.ID)
+		return user, nil
+	}
+
+	email := strings.TrimSpace(token.Email)
+	if email == "" || !token.EmailVerified || len(email) > validation.MaxEmailLen {
+		logger.Info("Регистрация через провайдера отклонена: нет подтвержденного email")
+		return nil, ErrIdentityEmail
+	}
+
+	// Пароль аккаунта случайный и никому не известен: входить можно через провайдера
+	hash, err := utils.HashPassword(newSecretToken())
+	if err != nil {
+		return nil, err
+	}
+	first, last := token.GivenName, token.FamilyName
+	if first == "" && last == "" {
+		first, last, _ = strings.Cut(strings.TrimSpace(token.Name), " ")
+	}
+	user := &models.User{
+		Email:        email,
+		PasswordHash: hash,
+		FirstName:    clip(first, validation.MaxNameLen),
+		LastName:     clip(strings.TrimSpace(last), validation.MaxNameLen),
+	}
+
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		exists, err := s.users.UserExists(ctx, email)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrIdentityEmailTaken
+		}
+		if err := s.users.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		now := s.now()
+		return s.store.CreateIdentity(ctx, &models.UserIdentity{
+			UserID:      user.ID,
+			Provider:    provider,
+			Subject:     token.Subject,
+			Email:       email,
+			LastLoginAt: &now,
+		})
+	})
+	if repository.IsDuplicate(err) {
+		err = ErrIdentityEmailTaken.Wrap(err)
+	}
+	if errors.Is(err, ErrIdentityEmailTaken) {
+		logger.Info("Регистрация через провайдера отклонена: email уже занят", "email", email)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Пользователь зарегистрирован через провайдера", "user_id", user.ID, "email", email)
+	return user, nil
+}
+
+// link привязывает аккаунт провайдера к вошедшему пользователю
+func (s *OIDCService) link(ctx context.Context, userID int, provider string, token *oidc.IDToken) error {
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		existing, err := s.store.GetIdentity(ctx, provider, token.Subject)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if existing.UserID != userID {
+				return ErrIdentityLinked
+			}
+			return nil
+		}
+
+		identities, err := s.store.GetUserIdentities(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, i := range identities {
+			if i.Provider == provider {
+				return ErrProviderLinked
+			}
+		}
+		return s.store.CreateIdentity(ctx, &models.UserIdentity{
+			UserID:   userID,
+			Provider: provider,
+			Subject:  token.Subject,
+			Email:    token.Email,
+		})
+	})
+	if repository.IsDuplicate(err) {
+		err = ErrIdentityLinked.Wrap(err)
+	}
+
+	logger := logging.FromContext(ctx).With("provider", provider, "user_id", userID)
+	if err != nil {
+		logger.Info("Аккаунт провайдера не привязан", "error", err)
+		return err
+	}
+	logger.Info("Аккаунт провайдера привязан")
+	return nil
+}
+
+// Identities привязанные аккаунты пользователя
+func (s *OIDCService) Identities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	return s.store.GetUserIdentities(ctx, userID)
+}
+
+// Unlink отвязывает аккаунт провайдера. Нужен текущий пароль: так пользователь,
+// зарегистрированный через провайдера и не знающий пароля, не потеряет доступ к аккаунту.
+func (s *OIDCService) Unlink(ctx context.Context, userID, identityID int, req models.IdentityUnlinkRequest) error {
+	if _, err := verifyPassword(ctx, s.users, userID, req.CurrentPassword); err != nil {
+		return err
+	}
+	deleted, err := s.store.DeleteIdentity(ctx, userID, identityID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotFound
+	}
+	logging.FromContext(ctx).Info("Аккаунт провайдера отвязан", "user_id", userID, "identity_id", identityID)
+	return nil
+}
+
+// Cleanup удаляет незавершенные входы с истекшим сроком
+func (s *OIDCService) Cleanup(ctx context.Context) error {
+	_, err := s.store.DeleteExpiredOIDCStates(ctx, s.now())
+	s.limiter.Cleanup()
+	return err
+}
+
+// StartJanitor периодически удаляет незавершенные входы; возвращает функцию остановки
+func (s *OIDCService) StartJanitor(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Cleanup(context.Background()); err != nil {
+					slog.Warn("Ошибка очистки незавершенных входов через провайдеров", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// clip обрезает строку до n символов
+func clip(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
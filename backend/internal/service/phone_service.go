@@ -0,0 +1,355 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/phone"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/sms"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrSmsThrottled   = apperr.TooManyRequests("sms_throttled", "Слишком много запросов кода, повторите позже")
+	ErrInvalidSmsCode = apperr.Validation("invalid_sms_code", "Неверный или устаревший код").
+				WithField("code", "Неверный или устаревший код")
+	ErrInvalidPhone = apperr.Validation("invalid_phone", "Некорректный номер телефона").
+			WithField("phone", "Некорректный номер телефона")
+	ErrPhoneRequired = apperr.Validation("phone_required", "Укажите номер телефона в профиле").
+				WithField("phone", "Укажите номер телефона в профиле")
+	ErrSmsUnavailable       = apperr.New(apperr.KindUnavailable, "sms_unavailable", "Отправка SMS временно недоступна")
+	ErrPhoneAlreadyVerified = apperr.Validation("phone_verified", "Номер телефона уже подтвержден")
+	ErrPhoneTaken           = apperr.Conflict("phone_taken", "Этот номер подтвержден в другом аккаунте").
+				WithField("phone", "Этот номер подтвержден в другом аккаунте")
+)
+
+// PhoneOptions коды из SMS и лимиты их отправки. На один номер уходит не больше
+// Limit кодов за LimitWindow и не чаще одного за ResendInterval; с одного IP
+// запрашивается не больше IPLimit кодов за IPWindow.
+type PhoneOptions struct {
+	CodeTTL        time.Duration
+	CodeDigits     int
+	MaxAttempts    int
+	ResendInterval time.Duration
+	Limit          int
+	LimitWindow    time.Duration
+	IPLimit        int
+	IPWindow       time.Duration
+}
+
+func DefaultPhoneOptions() PhoneOptions {
+	return PhoneOptions{
+		CodeTTL:        5 * time.Minute,
+		CodeDigits:     6,
+		MaxAttempts:    5,
+		ResendInterval: time.Minute,
+		Limit:          5,
+		LimitWindow:    time.Hour,
+		IPLimit:        20,
+		IPWindow:       time.Hour,
+	}
+}
+
+// PhoneService подтверждение номера телефона и вход по одноразовому коду из SMS.
+// Войти по номеру можно, только если он подтвержден: подтвержденный номер
+// принадлежит одному пользователю.
+type PhoneService struct {
+	store     repository.SmsCodeStore
+	users     repository.UserStore
+	sender    sms.Sender
+	opts      PhoneOptions
+	key       []byte
+	ipLimiter *antispam.RateLimiter
+	now       func() time.Time
+}
+
+// NewPhoneService создает сервис; signingKey — ключ HMAC для хранения кодов
+func NewPhoneService(store repository.SmsCodeStore, users repository.UserStore, sender sms.Sender, opts PhoneOptions, signingKey string) *PhoneService {
+	return &PhoneService{
+		store:     store,
+		users:     users,
+		sender:    sender,
+		opts:      opts,
+		key:       []byte(signingKey),
+		ipLimiter: antispam.NewRateLimiter(opts.IPLimit, opts.IPWindow),
+		now:       time.Now,
+	}
+}
+
+// RequestLoginCode отправляет код для входа, если номер подтвержден у какого-то пользователя.
+// Для неизвестного номера код тоже записывается, но не отправляется: ответ и лимиты
+// одинаковы, и по ним нельзя узнать, зарегистрирован ли номер.
+func (s *PhoneService) RequestLoginCode(ctx context.Context, rawPhone, ip string) error {
+	number, err := phone.Normalize(rawPhone)
+	if err != nil {
+		return ErrInvalidPhone
+	}
+	if err := s.checkLimits(ctx, number, models.SmsCodeLogin, ip); err != nil {
+		return err
+	}
+
+	user, err := s.users.GetUserByVerifiedPhone(ctx, number)
+	if err != nil {
+		return err
+	}
+	userID := 0
+	if user != nil {
+		userID = user.ID
+	}
+	code, err := s.issue(ctx, number, models.SmsCodeLogin, userID, ip)
+	if err != nil {
+		return err
+	}
+
+	logger := logging.FromContext(ctx).With("phone", number)
+	if user == nil {
+		logger.Info("Код для входа не отправлен: номер не подтвержден ни у кого")
+		return nil
+	}
+	logger.Info("Отправлен код для входа по SMS", "user_id", user.ID)
+	return s.send(number, fmt.Sprintf("Belladonna: код для входа %s. Никому его не сообщайте.", code))
+}
+
+// LoginWithCode проверяет код для входа и возвращает владельца номера
+func (s *PhoneService) LoginWithCode(ctx context.Context, rawPhone, code string) (*models.User, error) {
+	number, err := phone.Normalize(rawPhone)
+	if err != nil {
+		return nil, ErrInvalidPhone
+	}
+	issued, err := s.verify(ctx, number, models.SmsCodeLogin, code)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetUserByVerifiedPhone(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	// Номер могли сменить или подтвердить в другом аккаунте, пока шло SMS
+	if user == nil || user.ID != issued.UserID {
+		logging.FromContext(ctx).Info("Вход по SMS отклонен: владелец номера изменился", "phone", number)
+		return nil, ErrInvalidSmsCode
+	}
+	return user, nil
+}
+
+// RequestVerification отправляет код подтверждения на номер из профиля пользователя
+func (s *PhoneService) RequestVerification(ctx context.Context, userID int, ip string) error {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if user.Phone == "" {
+		return ErrPhoneRequired
+	}
+	if user.PhoneVerifiedAt != nil {
+		return ErrPhoneAlreadyVerified
+	}
+	owner, err := s.users.GetUserByVerifiedPhone(ctx, user.Phone)
+	if err != nil {
+		return err
+	}
+	if owner != nil {
+		return ErrPhoneTaken
+	}
+	if err := s.checkLimits(ctx, user.Phone, models.SmsCodeVerify, ip); err != nil {
+		return err
+	}
+
+	code, err := s.issue(ctx, user.Phone, models.SmsCodeVerify, user.ID, ip)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Отправлен код подтверждения телефона", "user_id", user.ID, "phone", user.Phone)
+	return s.send(user.Phone, fmt.Sprintf("Belladonna: код подтверждения номера %s.", code))
+}
+
+// ConfirmVerification подтверждает номер из профиля кодом из SMS
+func (s *PhoneService) ConfirmVerification(ctx context.Context, userID int, code string) (*models.User, error) {
+	user, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Phone == "" {
+		return nil, ErrPhoneRequired
+	}
+	if user.PhoneVerifiedAt != nil {
+		return nil, ErrPhoneAlreadyVerified
+	}
+
+	issued, err := s.verify(ctx, user.Phone, models.SmsCodeVerify, code)
+	if err != nil {
+		return nil, err
+	}
+	if issued.UserID != user.ID {
+		return nil, ErrInvalidSmsCode
+	}
+	ok, err := s.users.SetPhoneVerified(ctx, user.ID, user.Phone, s.now())
+	if repository.IsDuplicate(err) {
+		return nil, ErrPhoneTaken.Wrap(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidSmsCode
+	}
+
+	logging.FromContext(ctx).Info("Телефон подтвержден", "user_id", user.ID, "phone", user.Phone)
+	return s.users.GetUserByID(ctx, user.ID)
+}
+
+// checkLimits не дает отправлять коды на номер чаще, чем разрешено опциями
+func (s *PhoneService) checkLimits(ctx context.Context, number, purpose, ip string) error {
+	logger := logging.FromContext(ctx).With("phone", number)
+	if !s.ipLimiter.Allow("ip:" + ip) {
+		logger.Warn("Код не отправлен: превышен лимит запросов с IP", "ip", ip)
+		return ErrSmsThrottled.WithRetryAfter(s.opts.IPWindow)
+	}
+
+	now := s.now()
+	last, err := s.store.GetLatestSmsCode(ctx, number, purpose)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		if next := last.CreatedAt.Add(s.opts.ResendInterval); now.Before(next) {
+			return ErrSmsThrottled.WithRetryAfter(next.Sub(now))
+		}
+	}
+	sent, err := s.store.CountSmsCodes(ctx, number, now.Add(-s.opts.LimitWindow))
+	if err != nil {
+		return err
+	}
+	if sent >= s.opts.Limit {
+		logger.Warn("Код не отправлен: превышен лимит SMS на номер", "sent", sent)
+		return ErrSmsThrottled.WithRetryAfter(s.opts.LimitWindow)
+	}
+	return nil
+}
+
+// issue записывает новый код и возвращает его; предыдущие коды с тем же назначением
+// перестают действовать, потому что проверяется только последний
+func (s *PhoneService) issue(ctx context.Context, number, purpose string, userID int, ip string) (string, error) {
+	code, err := randomDigits(s.opts.CodeDigits)
+	if err != nil {
+		return "", err
+	}
+	err = s.store.CreateSmsCode(ctx, &models.SmsCode{
+		Phone:     number,
+		Purpose:   purpose,
+		UserID:    userID,
+		CodeHash:  s.hashCode(number, code),
+		IP:        ip,
+		ExpiresAt: s.now().Add(s.opts.CodeTTL),
+	})
+	return code, err
+}
+
+// verify сверяет code с последним кодом на номер и гасит его. Попытка учитывается
+// до сравнения, поэтому параллельные запросы не обходят MaxAttempts.
+func (s *PhoneService) verify(ctx context.Context, number, purpose, code string) (*models.SmsCode, error) {
+	issued, err := s.store.GetLatestSmsCode(ctx, number, purpose)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if issued == nil || issued.UsedAt != nil || !now.Before(issued.ExpiresAt) || issued.Attempts >= s.opts.MaxAttempts {
+		return nil, ErrInvalidSmsCode
+	}
+
+	attempts, err := s.store.AddSmsCodeAttempt(ctx, issued.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > s.opts.MaxAttempts || !hmac.Equal([]byte(s.hashCode(number, code)), []byte(issued.CodeHash)) {
+		logging.FromContext(ctx).Info("Неверный код из SMS", "phone", number, "purpose", purpose, "attempts", attempts)
+		return nil, ErrInvalidSmsCode
+	}
+
+	used, err := s.store.MarkSmsCodeUsed(ctx, issued.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidSmsCode
+	}
+	return issued, nil
+}
+
+func (s *PhoneService) send(number, text string) error {
+	err := s.sender.Send(sms.Message{To: number, Text: text})
+	if errors.Is(err, sms.ErrDisabled) {
+		return ErrSmsUnavailable.Wrap(err)
+	}
+	if err != nil {
+		return fmt.Errorf("отправка SMS: %w", err)
+	}
+	return nil
+}
+
+// hashCode HMAC кода, привязанный к номеру: по базе код не подобрать без ключа
+func (s *PhoneService) hashCode(number, code string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte("sms-code:" + number + ":" + code))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Cleanup удаляет коды, которые уже не действуют и не участвуют в лимитах
+func (s *PhoneService) Cleanup(ctx context.Context) error {
+	keep := max(s.opts.CodeTTL, s.opts.LimitWindow)
+	_, err := s.store.DeleteSmsCodesBefore(ctx, s.now().Add(-keep))
+	s.ipLimiter.Cleanup()
+	return err
+}
+
+// StartJanitor периодически удаляет старые коды; возвращает функцию остановки
+func (s *PhoneService) StartJanitor(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Cleanup(context.Background()); err != nil {
+					slog.Warn("Ошибка очистки кодов из SMS", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// randomDigits случайный код из n цифр с равномерным распределением
+func randomDigits(n int) (string, error) {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	v, err := rand.Int(rand.Reader, limit)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
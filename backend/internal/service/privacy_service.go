@@ -0,0 +1,162 @@
+package service
+
+import (
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PrivacyService выгрузка и удаление персональных данных по запросу пользователя (152-ФЗ, GDPR).
+// Заказов магазин пока не хранит: покупки оформляются по телефону, поэтому в выгрузке их нет.
+type PrivacyService struct {
+	userRepo     repository.UserStore
+	cartRepo     repository.CartStore
+	feedbackRepo repository.FeedbackStore
+	attempts     repository.LoginAttemptStore
+	deletions    repository.AccountDeletionStore
+	consents     repository.ConsentStore
+	newsletter   repository.NewsletterStore
+	identities   repository.IdentityStore
+	twoFactor    *TwoFactorService
+	sessions     *SessionService
+	tx           repository.UnitOfWork
+	mailer       mailer.Mailer
+	now          func() time.Time
+}
+
+func NewPrivacyService(userRepo repository.UserStore, cartRepo repository.CartStore, feedbackRepo repository.FeedbackStore, attempts repository.LoginAttemptStore, deletions repository.AccountDeletionStore, consents repository.ConsentStore, newsletter repository.NewsletterStore, identities repository.IdentityStore, twoFactor *TwoFactorService, sessions *SessionService, tx repository.UnitOfWork, mailer mailer.Mailer) *PrivacyService {
+	return &PrivacyService{
+		userRepo:     userRepo,
+		cartRepo:     cartRepo,
+		feedbackRepo: feedbackRepo,
+		attempts:     attempts,
+		deletions:    deletions,
+		consents:     consents,
+		newsletter:   newsletter,
+		identities:   identities,
+		twoFactor:    twoFactor,
+		sessions:     sessions,
+		tx:           tx,
+		mailer:       mailer,
+		now:          time.Now,
+	}
+}
+
+// Export собирает профиль, подписку на рассылку, корзину, отзывы пользователя
+// вместе с ответами на них и журнал согласий
+func (s *PrivacyService) Export(ctx context.Context, userID int) (*models.DataExport, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	export := &models.DataExport{GeneratedAt: s.now().UTC(), Profile: user}
+	if export.TwoFactorEnabled, err = s.twoFactor.Enabled(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Cart, err = s.cartRepo.GetCartItems(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Feedbacks, err = s.feedbackRepo.GetFeedbacksByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Consents, err = s.consents.GetUserConsents(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Newsletter, err = s.newsletter.GetSubscriptionByUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if export.Identities, err = s.identities.GetUserIdentities(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, len(export.Feedbacks))
+	for i, f := range export.Feedbacks {
+		ids[i] = f.ID
+	}
+	replies, err := s.feedbackRepo.GetReplies(ctx, ids, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range export.Feedbacks {
+		export.Feedbacks[i].Replies = replies[export.Feedbacks[i].ID]
+	}
+
+	logging.FromContext(ctx).Info("Выгрузка персональных данных", "user_id", userID)
+	return export, nil
+}
+
+// DeleteAccount удаляет аккаунт после проверки пароля. Отзывы остаются опубликованными,
+// но обезличиваются; остальные данные пользователя удаляются. В журнал удалений
+// записывается хеш email, роль и число обезличенных отзывов.
+func (s *PrivacyService) DeleteAccount(ctx context.Context, userID int, req models.AccountDeleteRequest, ip string) error {
+	user, err := verifyPassword(ctx, s.userRepo, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	deletion := &models.AccountDeletion{
+		UserID:    user.ID,
+		EmailHash: hashEmail(user.Email),
+		Role:      user.Role,
+		IP:        ip,
+	}
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		anonymized, err := s.feedbackRepo.AnonymizeUserFeedbacks(ctx, user.ID, user.Email, models.DeletedUserName)
+		if err != nil {
+			return err
+		}
+		deletion.FeedbacksAnonymized = int(anonymized)
+
+		if err := s.feedbackRepo.DeleteUserRejections(ctx, user.ID, user.Email); err != nil {
+			return err
+		}
+		if err := s.attempts.DeleteUserLockouts(ctx, user.ID, user.Email); err != nil {
+			return err
+		}
+		if err := s.attempts.ResetThrottle(ctx, user.Email); err != nil {
+			return err
+		}
+		if err := s.cartRepo.ClearUserCart(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := s.sessions.EndOthers(ctx, user.ID, ""); err != nil {
+			return err
+		}
+		if err := s.userRepo.DeleteUser(ctx, user.ID); err != nil {
+			return err
+		}
+		return s.deletions.CreateAccountDeletion(ctx, deletion)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Аккаунт удален",
+		"user_id", user.ID, "deletion_id", deletion.ID, "feedbacks_anonymized", deletion.FeedbacksAnonymized)
+
+	body := fmt.Sprintf("Здравствуйте, %s!\n\n"+
+		"Ваш аккаунт в Belladonna удален. Отзывы сохранены без имени и email, остальные данные удалены.\n"+
+		"Номер обращения: %d.\n\nС уважением,\nкоманда Belladonna", user.FirstName, deletion.ID)
+	if err := s.mailer.Send(mailer.Message{To: user.Email, Subject: "Аккаунт удален", Body: body}); err != nil {
+		logging.FromContext(ctx).Error("Ошибка отправки письма об удалении аккаунта", "deletion_id", deletion.ID, "error", err)
+	}
+	return nil
+}
+
+// hashEmail SHA-256 адреса в нижнем регистре: по журналу можно проверить, удален ли аккаунт
+// с этим email, но сам адрес из журнала не восстановить
+func hashEmail(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"beladonna/backend/internal/utils"
+	"context"
+	"errors"
+	"testing"
+)
+
+type privacyFixture struct {
+	s         *PrivacyService
+	users     *memory.UserStore
+	carts     *memory.CartStore
+	products  *memory.ProductStore
+	feedbacks *memory.FeedbackStore
+	deletions *memory.AccountDeletionStore
+	mail      *recordingMailer
+}
+
+// newTestPrivacyService сервис приватности поверх хранилищ в памяти
+func newTestPrivacyService(t *testing.T) *privacyFixture {
+	t.Helper()
+	users := memory.NewUserStore()
+	products := memory.NewProductStore()
+	tx := memory.NewUnitOfWork()
+	f := &privacyFixture{
+		users:     users,
+		carts:     memory.NewCartStore(products),
+		products:  products,
+		feedbacks: memory.NewFeedbackStore(users, memory.NewFeedbackThemeStore()),
+		deletions: memory.NewAccountDeletionStore(),
+		mail:      &recordingMailer{},
+	}
+	twoFactor := NewTwoFactorService(memory.NewTwoFactorStore(), users, tx, DefaultTwoFactorPolicy(), "test-secret")
+	sessions := NewSessionService(memory.NewSessionStore(users), DefaultSessionOptions())
+	f.s = NewPrivacyService(users, f.carts, f.feedbacks, memory.NewLoginAttemptStore(), f.deletions,
+		memory.NewConsentStore(users), memory.NewNewsletterStore(users), memory.NewIdentityStore(users),
+		twoFactor, sessions, tx, f.mail)
+	return f
+}
+
+// user регистрирует пользователя с паролем secret123
+func (f *privacyFixture) user(t *testing.T, email, firstName string) *models.User {
+	t.Helper()
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Email: email, PasswordHash: hash, FirstName: firstName}
+	if err := f.users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func (f *privacyFixture) feedback(t *testing.T, userID int, name, email, message string) int {
+	t.Helper()
+	feedback := &models.Feedback{UserID: userID, Name: name, Email: email, Theme: "general", Message: message}
+	if err := f.feedbacks.CreateFeedback(context.Background(), feedback); err != nil {
+		t.Fatal(err)
+	}
+	return feedback.ID
+}
+
+func TestPrivacyServiceExport(t *testing.T) {
+	ctx := context.Background()
+	f := newTestPrivacyService(t)
+	anna := f.user(t, "anna@example.com", "Анна")
+	boris := f.user(t, "boris@example.com", "Борис")
+
+	own := f.feedback(t, anna.ID, "Анна", anna.Email, "Спасибо за платье")
+	f.feedback(t, boris.ID, "Борис", boris.Email, "Где мой заказ?")
+	if err := f.feedbacks.CreateReply(ctx, &models.FeedbackReply{FeedbackID: own, Message: "Рады помочь"}); err != nil {
+		t.Fatal(err)
+	}
+	product := f.products.AddProduct(models.Product{Name: "Платье", Price: 4990, InStock: true})
+	if err := f.carts.AddToCart(ctx, anna.ID, product.ID, 2, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.carts.AddToCart(ctx, boris.ID, product.ID, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	export, err := f.s.Export(ctx, anna.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if export.Profile.Email != anna.Email {
+		t.Errorf("profile email = %q, want %q", export.Profile.Email, anna.Email)
+	}
+	if len(export.Feedbacks) != 1 || export.Feedbacks[0].ID != own {
+		t.Fatalf("feedbacks = %+v, want only feedback %d", export.Feedbacks, own)
+	}
+	if replies := export.Feedbacks[0].Replies; len(replies) != 1 || replies[0].Message != "Рады помочь" {
+		t.Errorf("replies = %+v, want the staff reply", replies)
+	}
+	if len(export.Cart) != 1 || export.Cart[0].Quantity != 2 {
+		t.Errorf("cart = %+v, want only Анна's item", export.Cart)
+	}
+
+	if _, err := f.s.Export(ctx, 999); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("unknown user: err = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestPrivacyServiceDeleteAccountWrongPassword(t *testing.T) {
+	ctx := context.Background()
+	f := newTestPrivacyService(t)
+	anna := f.user(t, "anna@example.com", "Анна")
+	id := f.feedback(t, anna.ID, "Анна", anna.Email, "Спасибо за платье")
+
+	err := f.s.DeleteAccount(ctx, anna.ID, models.AccountDeleteRequest{CurrentPassword: "wrong"}, testIP)
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("err = %v, want ErrWrongPassword", err)
+	}
+	if user, _ := f.users.GetUserByID(ctx, anna.ID); user == nil {
+		t.Error("user deleted despite wrong password")
+	}
+	if fb, _ := f.feedbacks.GetFeedbackByID(ctx, id); fb.Name != "Анна" || fb.UserID != anna.ID {
+		t.Errorf("feedback = %+v, want it untouched", fb)
+	}
+	if len(f.deletions.Deletions()) != 0 {
+		t.Error("deletion recorded despite wrong password")
+	}
+}
+
+// Обезличиваются только отзывы удаляемого пользователя и гостевые отзывы с его email;
+// чужие отзывы, в том числе оставленные другим пользователем с тем же email в форме, не трогаются
+func TestPrivacyServiceDeleteAccountAnonymizesOnlyOwnFeedbacks(t *testing.T) {
+	ctx := context.Background()
+	f := newTestPrivacyService(t)
+	anna := f.user(t, "anna@example.com", "Анна")
+	boris := f.user(t, "boris@example.com", "Борис")
+
+	annaOwn := f.feedback(t, anna.ID, "Анна", anna.Email, "Спасибо за платье")
+	annaGuest := f.feedback(t, 0, "Анна", anna.Email, "Отзыв до регистрации")
+	borisOwn := f.feedback(t, boris.ID, "Борис", boris.Email, "Где мой заказ?")
+	borisWithAnnaEmail := f.feedback(t, boris.ID, "Борис", anna.Email, "Подарок для Анны")
+	otherGuest := f.feedback(t, 0, "Гость", "guest@example.com", "Есть ли размер S?")
+
+	err := f.s.DeleteAccount(ctx, anna.ID, models.AccountDeleteRequest{CurrentPassword: "secret123"}, testIP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int{annaOwn, annaGuest} {
+		fb, err := f.feedbacks.GetFeedbackByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fb.UserID != 0 || fb.Email != "" || fb.Name != models.DeletedUserName {
+			t.Errorf("feedback %d = %+v, want anonymized", id, fb)
+		}
+	}
+
+	untouched := []struct {
+		id     int
+		userID int
+		name   string
+		email  string
+	}{
+		{borisOwn, boris.ID, "Борис", boris.Email},
+		{borisWithAnnaEmail, boris.ID, "Борис", anna.Email},
+		{otherGuest, 0, "Гость", "guest@example.com"},
+	}
+	for _, want := range untouched {
+		fb, err := f.feedbacks.GetFeedbackByID(ctx, want.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fb.UserID != want.userID || fb.Name != want.name || fb.Email != want.email {
+			t.Errorf("feedback %d = %+v, want user %d %q <%s> untouched", want.id, fb, want.userID, want.name, want.email)
+		}
+	}
+
+	if user, _ := f.users.GetUserByID(ctx, anna.ID); user != nil {
+		t.Error("user not deleted")
+	}
+	if user, _ := f.users.GetUserByID(ctx, boris.ID); user == nil {
+		t.Error("another user deleted")
+	}
+
+	deletions := f.deletions.Deletions()
+	if len(deletions) != 1 {
+		t.Fatalf("deletions = %+v, want one", deletions)
+	}
+	d := deletions[0]
+	if d.UserID != anna.ID || d.FeedbacksAnonymized != 2 {
+		t.Errorf("deletion = %+v, want user %d with 2 anonymized feedbacks", d, anna.ID)
+	}
+	if d.EmailHash != hashEmail(" ANNA@example.com ") {
+		t.Errorf("email hash = %q, want the SHA-256 of the normalized email", d.EmailHash)
+	}
+	if sent := f.mail.messages(); len(sent) != 1 || sent[0].To != anna.Email {
+		t.Errorf("mail = %+v, want a notice to %s", sent, anna.Email)
+	}
+}
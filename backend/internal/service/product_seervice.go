@@ -1,26 +1,37 @@
 package service
 
 import (
+	"beladonna/backend/internal/apperr"
 	"beladonna/backend/internal/models"
 	"beladonna/backend/internal/repository"
+	"context"
 )
 
+var ErrProductNotFound = apperr.NotFound("product_not_found", "Товар не найден")
+
 type ProductService struct {
-	productRepo *repository.ProductRepository
+	productRepo repository.ProductStore
 }
 
-func NewProductService(productRepo *repository.ProductRepository) *ProductService {
+func NewProductService(productRepo repository.ProductStore) *ProductService {
 	return &ProductService{productRepo: productRepo}
 }
 
-func (s *ProductService) GetProducts(filters models.ProductFilters) ([]models.Product, error) {
-	return s.productRepo.GetProducts(filters)
+func (s *ProductService) GetProducts(ctx context.Context, filters models.ProductFilters) ([]models.Product, error) {
+	return s.productRepo.GetProducts(ctx, filters)
 }
 
-func (s *ProductService) GetProductByID(id int) (*models.Product, error) {
-	return s.productRepo.GetProductByID(id)
+func (s *ProductService) GetProductByID(ctx context.Context, id int) (*models.Product, error) {
+	product, err := s.productRepo.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, ErrProductNotFound
+	}
+	return product, nil
 }
 
-func (s *ProductService) GetCategories() ([]models.Category, error) {
-	return s.productRepo.GetCategories()
+func (s *ProductService) GetCategories(ctx context.Context) ([]models.Category, error) {
+	return s.productRepo.GetCategories(ctx)
 }
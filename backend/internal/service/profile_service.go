@@ -0,0 +1,243 @@
+package service
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/phone"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/utils"
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+var (
+	ErrWrongPassword = apperr.Validation("wrong_password", "Неверный текущий пароль").
+				WithField("currentPassword", "Неверный текущий пароль")
+	ErrEmailUnchanged = apperr.Validation("email_unchanged", "Новый email совпадает с текущим").
+				WithField("newEmail", "Новый email совпадает с текущим")
+	ErrNewEmailTaken = apperr.Conflict("email_taken", "Пользователь с таким email уже существует").
+				WithField("newEmail", "Пользователь с таким email уже существует")
+	ErrInvalidEmailToken = apperr.Validation("invalid_email_token", "Ссылка для подтверждения email недействительна или устарела")
+)
+
+// emailChangeTTL сколько действует ссылка подтверждения нового email
+const emailChangeTTL = 24 * time.Hour
+
+// ProfileService данные профиля, смена email с подтверждением и смена пароля
+type ProfileService struct {
+	userRepo     repository.UserStore
+	emailChanges repository.EmailChangeStore
+	sessions     *SessionService
+	newsletter   *NewsletterService
+	tx           repository.UnitOfWork
+	mailer       mailer.Mailer
+	baseURL      string
+	now          func() time.Time
+}
+
+// NewProfileService создает сервис; baseURL — адрес сайта для ссылки подтверждения email
+func NewProfileService(userRepo repository.UserStore, emailChanges repository.EmailChangeStore, sessions *SessionService, newsletter *NewsletterService, tx repository.UnitOfWork, mailer mailer.Mailer, baseURL string) *ProfileService {
+	return &ProfileService{
+		userRepo:     userRepo,
+		emailChanges: emailChanges,
+		sessions:     sessions,
+		newsletter:   newsletter,
+		tx:           tx,
+		mailer:       mailer,
+		baseURL:      baseURL,
+		now:          time.Now,
+	}
+}
+
+// GetProfile данные пользователя из базы
+func (s *ProfileService) GetProfile(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateProfile сохраняет имя, фамилию и телефон в E.164; новый номер нужно подтвердить
+// заново. Включение рассылки отправляет письмо подтверждения: флаг newsletter станет
+// true после перехода по ссылке.
+func (s *ProfileService) UpdateProfile(ctx context.Context, userID int, req models.ProfileUpdateRequest, meta ClientMeta) (*models.User, error) {
+	number := ""
+	if req.Phone != "" {
+		var err error
+		if number, err = phone.Normalize(req.Phone); err != nil {
+			return nil, ErrInvalidPhone
+		}
+	}
+
+	var user *models.User
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = s.GetProfile(ctx, userID); err != nil {
+			return err
+		}
+		if user.Phone != number {
+			user.PhoneVerifiedAt = nil
+		}
+		user.FirstName = req.FirstName
+		user.LastName = req.LastName
+		user.Phone = number
+		return s.userRepo.UpdateProfile(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("Профиль обновлен", "user_id", userID)
+
+	if req.Newsletter != user.Newsletter {
+		if err := s.newsletter.SetUserSubscription(ctx, user, req.Newsletter, meta); err != nil {
+			return nil, err
+		}
+		return s.GetProfile(ctx, userID)
+	}
+	return user, nil
+}
+
+// RequestEmailChange проверяет пароль и отправляет на новый адрес ссылку подтверждения.
+// Email меняется только после перехода по ссылке; прежний адрес получает уведомление.
+func (s *ProfileService) RequestEmailChange(ctx context.Context, userID int, req models.EmailChangeRequest) error {
+	user, err := verifyPassword(ctx, s.userRepo, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+	if req.NewEmail == user.Email {
+		return ErrEmailUnchanged
+	}
+	taken, err := s.userRepo.UserExists(ctx, req.NewEmail)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrNewEmailTaken
+	}
+
+	token := newSecretToken()
+	change := &models.EmailChange{
+		UserID:    userID,
+		NewEmail:  req.NewEmail,
+		TokenHash: hashSecretToken(token),
+		ExpiresAt: s.now().Add(emailChangeTTL),
+	}
+	if err := s.emailChanges.CreateEmailChange(ctx, change); err != nil {
+		return err
+	}
+
+	link := s.baseURL + "/pages/login.html?confirm_email=" + url.QueryEscape(token)
+	err = s.mailer.Send(mailer.Message{
+		To:      req.NewEmail,
+		Subject: "Подтвердите новый email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\n"+
+			"Чтобы использовать этот адрес для входа в Belladonna, перейдите по ссылке (действует %s):\n%s\n\n"+
+			"Если вы не меняли email, просто проигнорируйте это письмо.\n\nС уважением,\nкоманда Belladonna",
+			user.FirstName, emailChangeTTL, link),
+	})
+	if err != nil {
+		return fmt.Errorf("отправка письма для подтверждения email: %w", err)
+	}
+	s.notify(ctx, user, "Запрошена смена email",
+		fmt.Sprintf("Для вашего аккаунта запрошена смена email на %s.\n"+
+			"Адрес изменится после подтверждения по ссылке, отправленной на новый email.\n"+
+			"Если это были не вы, смените пароль.", req.NewEmail))
+
+	logging.FromContext(ctx).Info("Запрошена смена email", "user_id", userID, "change_id", change.ID)
+	return nil
+}
+
+// ConfirmEmailChange применяет новый email по токену из письма
+func (s *ProfileService) ConfirmEmailChange(ctx context.Context, token string) error {
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		change, err := s.emailChanges.GetEmailChangeByTokenHash(ctx, hashSecretToken(token))
+		if err != nil {
+			return err
+		}
+		now := s.now()
+		if change == nil || change.ConfirmedAt != nil || now.After(change.ExpiresAt) {
+			return ErrInvalidEmailToken
+		}
+
+		if err := s.userRepo.UpdateEmail(ctx, change.UserID, change.NewEmail); err != nil {
+			return err
+		}
+		if err := s.emailChanges.MarkEmailChangeConfirmed(ctx, change.ID, now); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("Email изменен", "user_id", change.UserID, "change_id", change.ID)
+		return nil
+	})
+	// Адрес могли занять, пока письмо шло
+	if repository.IsDuplicate(err) {
+		return ErrNewEmailTaken.Wrap(err)
+	}
+	return err
+}
+
+// ChangePassword меняет пароль после проверки текущего и завершает все сессии
+// пользователя, кроме сессии с токеном keepToken
+func (s *ProfileService) ChangePassword(ctx context.Context, userID int, req models.PasswordChangeRequest, keepToken string) error {
+	user, err := verifyPassword(ctx, s.userRepo, userID, req.CurrentPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		return err
+	}
+	if err := s.sessions.EndOthers(ctx, userID, keepToken); err != nil {
+		return err
+	}
+
+	s.notify(ctx, user, "Пароль изменен",
+		"Пароль от вашего аккаунта изменен, вход на других устройствах завершен.\n"+
+			"Если это были не вы, восстановите доступ и свяжитесь с нами.")
+	logging.FromContext(ctx).Info("Пароль изменен", "user_id", userID)
+	return nil
+}
+
+// verifyPassword возвращает пользователя, если password совпадает с его паролем.
+// Им подтверждаются смена email и пароля и удаление аккаунта.
+func verifyPassword(ctx context.Context, users repository.UserStore, userID int, password string) (*models.User, error) {
+	profile, err := users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrUserNotFound
+	}
+	// GetUserByID не читает хеш пароля
+	user, err := users.GetUserByEmail(ctx, profile.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !utils.CheckPasswordHash(password, user.PasswordHash) {
+		logging.FromContext(ctx).Info("Неверный текущий пароль", "user_id", userID)
+		return nil, ErrWrongPassword
+	}
+	return user, nil
+}
+
+// notify уведомляет пользователя об изменении аккаунта; ошибка отправки только пишется в журнал
+func (s *ProfileService) notify(ctx context.Context, user *models.User, subject, text string) {
+	body := fmt.Sprintf("Здравствуйте, %s!\n\n%s\n\nС уважением,\nкоманда Belladonna", user.FirstName, text)
+	if err := s.mailer.Send(mailer.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		logging.FromContext(ctx).Error("Ошибка отправки уведомления", "user_id", user.ID, "subject", subject, "error", err)
+	}
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"beladonna/backend/internal/utils"
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestProfileService сервис профиля поверх хранилищ в памяти с пользователем Анной (пароль secret123)
+func newTestProfileService(t *testing.T) (*ProfileService, *memory.UserStore, *recordingMailer, *models.User) {
+	t.Helper()
+	users := memory.NewUserStore()
+	hash, err := utils.HashPassword("secret123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{Email: "anna@example.com", PasswordHash: hash, FirstName: "Анна", LastName: "Иванова"}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := memory.NewUnitOfWork()
+	mail := &recordingMailer{}
+	sessions := NewSessionService(memory.NewSessionStore(users), DefaultSessionOptions())
+	newsletter := NewNewsletterService(memory.NewNewsletterStore(users), users, memory.NewProductStore(), tx,
+		mail, DefaultNewsletterOptions(), "test-secret", "https://belladonna.test")
+	s := NewProfileService(users, memory.NewEmailChangeStore(), sessions, newsletter, tx, mail, "https://belladonna.test")
+	return s, users, mail, user
+}
+
+// emailChangeToken токен из последнего письма со ссылкой подтверждения email
+func emailChangeToken(t *testing.T, mail *recordingMailer) string {
+	t.Helper()
+	sent := mail.messages()
+	for i := len(sent) - 1; i >= 0; i-- {
+		if _, link, ok := strings.Cut(sent[i].Body, "?confirm_email="); ok {
+			token, err := url.QueryUnescape(strings.Fields(link)[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}
+	}
+	t.Fatalf("no email confirmation link in %+v", sent)
+	return ""
+}
+
+func TestProfileServiceUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	s, users, _, user := newTestProfileService(t)
+
+	req := models.ProfileUpdateRequest{FirstName: "Анна", LastName: "Петрова", Phone: "8 (999) 123-45-67"}
+	updated, err := s.UpdateProfile(ctx, user.ID, req, ClientMeta{})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	if updated.LastName != "Петрова" || updated.Phone != "+79991234567" {
+		t.Errorf("UpdateProfile = %+v, want new last name and phone in E.164", updated)
+	}
+
+	// Тот же номер в другой записи остается подтвержденным, новый — нет
+	if ok, err := users.SetPhoneVerified(ctx, user.ID, "+79991234567", time.Now()); err != nil || !ok {
+		t.Fatalf("SetPhoneVerified = %v, %v", ok, err)
+	}
+	req.Phone = "+7 999 123 45 67"
+	if updated, _ = s.UpdateProfile(ctx, user.ID, req, ClientMeta{}); updated.PhoneVerifiedAt == nil {
+		t.Error("unchanged phone lost its verification")
+	}
+	req.Phone = "+79997654321"
+	if updated, _ = s.UpdateProfile(ctx, user.ID, req, ClientMeta{}); updated.PhoneVerifiedAt != nil {
+		t.Error("new phone is marked verified")
+	}
+
+	req.Phone = "12345"
+	if _, err := s.UpdateProfile(ctx, user.ID, req, ClientMeta{}); !errors.Is(err, ErrInvalidPhone) {
+		t.Errorf("UpdateProfile(invalid phone) error = %v, want ErrInvalidPhone", err)
+	}
+	if _, err := s.UpdateProfile(ctx, user.ID+1, models.ProfileUpdateRequest{FirstName: "Х", LastName: "Х"}, ClientMeta{}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("UpdateProfile(unknown user) error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestProfileServiceEmailChange(t *testing.T) {
+	ctx := context.Background()
+	s, users, mail, user := newTestProfileService(t)
+	clock := &fakeClock{t: time.Now()}
+	s.now = clock.now
+	if err := users.CreateUser(ctx, &models.User{Email: "boris@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		newEmail string
+		password string
+		wantErr  error
+	}{
+		{"wrong password", "anna.new@example.com", "secret124", ErrWrongPassword},
+		{"same email", "anna@example.com", "secret123", ErrEmailUnchanged},
+		{"taken email", "boris@example.com", "secret123", ErrNewEmailTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.RequestEmailChange(ctx, user.ID, models.EmailChangeRequest{NewEmail: tt.newEmail, CurrentPassword: tt.password})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RequestEmailChange error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if len(mail.messages()) != 0 {
+		t.Fatalf("rejected requests sent %d emails", len(mail.messages()))
+	}
+
+	if err := s.RequestEmailChange(ctx, user.ID, models.EmailChangeRequest{NewEmail: "anna.new@example.com", CurrentPassword: "secret123"}); err != nil {
+		t.Fatalf("RequestEmailChange: %v", err)
+	}
+	sent := mail.messages()
+	if len(sent) != 2 || sent[0].To != "anna.new@example.com" || sent[1].To != "anna@example.com" {
+		t.Fatalf("sent = %+v, want link to new address and notice to old", sent)
+	}
+	if stored, _ := users.GetUserByID(ctx, user.ID); stored.Email != "anna@example.com" {
+		t.Errorf("email changed to %s before confirmation", stored.Email)
+	}
+
+	token := emailChangeToken(t, mail)
+	if err := s.ConfirmEmailChange(ctx, "forged"); !errors.Is(err, ErrInvalidEmailToken) {
+		t.Errorf("ConfirmEmailChange(forged) error = %v, want ErrInvalidEmailToken", err)
+	}
+	if err := s.ConfirmEmailChange(ctx, token); err != nil {
+		t.Fatalf("ConfirmEmailChange: %v", err)
+	}
+	if stored, _ := users.GetUserByID(ctx, user.ID); stored.Email != "anna.new@example.com" {
+		t.Errorf("email = %s, want anna.new@example.com", stored.Email)
+	}
+	if err := s.ConfirmEmailChange(ctx, token); !errors.Is(err, ErrInvalidEmailToken) {
+		t.Errorf("second ConfirmEmailChange error = %v, want ErrInvalidEmailToken", err)
+	}
+
+	// Ссылка устаревает через emailChangeTTL
+	if err := s.RequestEmailChange(ctx, user.ID, models.EmailChangeRequest{NewEmail: "anna.late@example.com", CurrentPassword: "secret123"}); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(emailChangeTTL + time.Second)
+	if err := s.ConfirmEmailChange(ctx, emailChangeToken(t, mail)); !errors.Is(err, ErrInvalidEmailToken) {
+		t.Errorf("ConfirmEmailChange after TTL error = %v, want ErrInvalidEmailToken", err)
+	}
+
+	// Адрес заняли, пока письмо шло
+	clock.advance(-emailChangeTTL)
+	if err := s.RequestEmailChange(ctx, user.ID, models.EmailChangeRequest{NewEmail: "vera@example.com", CurrentPassword: "secret123"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.CreateUser(ctx, &models.User{Email: "vera@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ConfirmEmailChange(ctx, emailChangeToken(t, mail)); !errors.Is(err, ErrNewEmailTaken) {
+		t.Errorf("ConfirmEmailChange(taken meanwhile) error = %v, want ErrNewEmailTaken", err)
+	}
+}
+
+func TestProfileServiceChangePassword(t *testing.T) {
+	ctx := context.Background()
+	s, users, mail, user := newTestProfileService(t)
+
+	current, _, err := s.sessions.Start(ctx, user.ID, false, false, ClientMeta{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, _ := s.sessions.Start(ctx, user.ID, false, false, ClientMeta{})
+
+	req := models.PasswordChangeRequest{CurrentPassword: "secret124", NewPassword: "newsecret456"}
+	if err := s.ChangePassword(ctx, user.ID, req, current); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangePassword(wrong password) error = %v, want ErrWrongPassword", err)
+	}
+	if session, _ := s.sessions.Resolve(ctx, other); session == nil {
+		t.Fatal("failed password change ended other sessions")
+	}
+
+	req.CurrentPassword = "secret123"
+	if err := s.ChangePassword(ctx, user.ID, req, current); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	stored, _ := users.GetUserByEmail(ctx, "anna@example.com")
+	if !utils.CheckPasswordHash("newsecret456", stored.PasswordHash) {
+		t.Error("new password does not match stored hash")
+	}
+	if session, _ := s.sessions.Resolve(ctx, current); session == nil {
+		t.Error("current session ended")
+	}
+	if session, _ := s.sessions.Resolve(ctx, other); session != nil {
+		t.Error("other session still active")
+	}
+	if sent := mail.messages(); len(sent) != 1 || sent[0].To != "anna@example.com" {
+		t.Errorf("sent = %+v, want notice to anna@example.com", sent)
+	}
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// SessionOptions сроки жизни сессий
+type SessionOptions struct {
+	Lifetime           time.Duration
+	RememberMeLifetime time.Duration
+}
+
+func DefaultSessionOptions() SessionOptions {
+	return SessionOptions{
+		Lifetime:           7 * 24 * time.Hour,
+		RememberMeLifetime: 30 * 24 * time.Hour,
+	}
+}
+
+// maxUserAgentLen длина колонок user_agent в сессиях и журнале согласий
+const maxUserAgentLen = 255
+
+// ClientMeta откуда пришел запрос; сохраняется в сессиях и журнале согласий
+type ClientMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// userAgent User-Agent, обрезанный до длины колонки
+func (m ClientMeta) userAgent() string {
+	if len(m.UserAgent) > maxUserAgentLen {
+		return strings.ToValidUTF8(m.UserAgent[:maxUserAgentLen], "")
+	}
+	return m.UserAgent
+}
+
+// SessionService серверные сессии. Клиент получает случайный токен, в базе хранится
+// только его хеш, поэтому сессию можно завершить на сервере, например при смене пароля.
+type SessionService struct {
+	store repository.SessionStore
+	opts  SessionOptions
+	now   func() time.Time
+}
+
+func NewSessionService(store repository.SessionStore, opts SessionOptions) *SessionService {
+	return &SessionService{store: store, opts: opts, now: time.Now}
+}
+
+// Start открывает сессию и возвращает токен для cookie и срок ее действия.
+// twoFactor — при входе пройдена проверка второго фактора.
+func (s *SessionService) Start(ctx context.Context, userID int, twoFactor, remember bool, meta ClientMeta) (string, time.Time, error) {
+	lifetime := s.opts.Lifetime
+	if remember {
+		lifetime = s.opts.RememberMeLifetime
+	}
+
+	token := newSecretToken()
+	session := &models.Session{
+		UserID:    userID,
+		TokenHash: hashSecretToken(token),
+		TwoFactor: twoFactor,
+		IP:        meta.IP,
+		UserAgent: meta.userAgent(),
+		ExpiresAt: s.now().Add(lifetime),
+	}
+	if err := s.store.CreateSession(ctx, session); err != nil {
+		return "", time.Time{}, err
+	}
+
+	logging.FromContext(ctx).Debug("Сессия создана", "user_id", userID, "remember", remember, "two_factor", twoFactor)
+	return token, session.ExpiresAt, nil
+}
+
+// Resolve возвращает действующую сессию по токену или nil, если она истекла или завершена
+func (s *SessionService) Resolve(ctx context.Context, token string) (*models.Session, error) {
+	if token == "" {
+		return nil, nil
+	}
+	session, err := s.store.GetSessionByTokenHash(ctx, hashSecretToken(token))
+	if err != nil || session == nil {
+		return nil, err
+	}
+	if !session.ExpiresAt.After(s.now()) {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// End завершает сессию с токеном token
+func (s *SessionService) End(ctx context.Context, token string) error {
+	if token == "" {
+		return nil
+	}
+	return s.store.DeleteSession(ctx, hashSecretToken(token))
+}
+
+// EndOthers завершает все сессии пользователя, кроме сессии с токеном keepToken
+func (s *SessionService) EndOthers(ctx context.Context, userID int, keepToken string) error {
+	keep := ""
+	if keepToken != "" {
+		keep = hashSecretToken(keepToken)
+	}
+	n, err := s.store.DeleteUserSessions(ctx, userID, keep)
+	if err != nil {
+		return err
+	}
+	logging.FromContext(ctx).Info("Сессии пользователя завершены", "user_id", userID, "count", n)
+	return nil
+}
+
+// Cleanup удаляет истекшие сессии
+func (s *SessionService) Cleanup(ctx context.Context) error {
+	_, err := s.store.DeleteExpiredSessions(ctx, s.now())
+	return err
+}
+
+// StartJanitor периодически удаляет истекшие сессии; возвращает функцию остановки
+func (s *SessionService) StartJanitor(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Cleanup(context.Background()); err != nil {
+					slog.Warn("Ошибка очистки истекших сессий", "error", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"beladonna/backend/internal/antispam"
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/totp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTwoFactorEnabled     = apperr.Conflict("two_factor_enabled", "Двухфакторная аутентификация уже подключена")
+	ErrTwoFactorNotEnabled  = apperr.Conflict("two_factor_not_enabled", "Двухфакторная аутентификация не подключена")
+	ErrTwoFactorNotPending  = apperr.Conflict("two_factor_not_pending", "Сначала получите ключ для приложения-аутентификатора")
+	ErrTwoFactorMandatory   = apperr.Forbidden("two_factor_mandatory", "Для вашей роли двухфакторная аутентификация обязательна")
+	ErrInvalidTwoFactorCode = apperr.Validation("invalid_two_factor_code", "Неверный код подтверждения").
+				WithField("code", "Неверный или уже использованный код")
+	ErrTwoFactorChallenge = apperr.Unauthorized("two_factor_challenge_invalid", "Время на ввод кода истекло, войдите заново")
+	ErrTwoFactorThrottled = apperr.TooManyRequests("two_factor_throttled", "Слишком много неверных кодов, повторите позже")
+)
+
+const (
+	// RecoveryCodeCount сколько кодов восстановления выдается за раз
+	RecoveryCodeCount = 10
+	// twoFactorChallengeTTL сколько действует challenge между вводом пароля и кода
+	twoFactorChallengeTTL = 5 * time.Minute
+	twoFactorIssuer       = "Belladonna"
+	// Неверных кодов на пользователя за окно, после которых проверка временно отключается
+	twoFactorFailureLimit  = 5
+	twoFactorFailureWindow = 15 * time.Minute
+)
+
+// TwoFactorPolicy роли, для которых двухфакторная аутентификация обязательна
+type TwoFactorPolicy struct {
+	RequiredRoles []string
+}
+
+func DefaultTwoFactorPolicy() TwoFactorPolicy {
+	return TwoFactorPolicy{RequiredRoles: []string{models.RoleAdmin, models.RoleManager}}
+}
+
+// Required сообщает, обязательна ли 2FA для роли
+func (p TwoFactorPolicy) Required(role string) bool {
+	return slices.Contains(p.RequiredRoles, role)
+}
+
+// TwoFactorService подключение TOTP, коды восстановления и проверка второго фактора при входе
+type TwoFactorService struct {
+	store    repository.TwoFactorStore
+	userRepo repository.UserStore
+	tx       repository.UnitOfWork
+	policy   TwoFactorPolicy
+	key      []byte
+	failures *antispam.RateLimiter
+	now      func() time.Time
+}
+
+// NewTwoFactorService создает сервис; signingKey подписывает challenge второго шага входа
+func NewTwoFactorService(store repository.TwoFactorStore, userRepo repository.UserStore, tx repository.UnitOfWork, policy TwoFactorPolicy, signingKey string) *TwoFactorService {
+	return &TwoFactorService{
+		store:    store,
+		userRepo: userRepo,
+		tx:       tx,
+		policy:   policy,
+		key:      []byte(signingKey),
+		failures: antispam.NewRateLimiter(twoFactorFailureLimit, twoFactorFailureWindow),
+		now:      time.Now,
+	}
+}
+
+// Policy роли с обязательной 2FA
+func (s *TwoFactorService) Policy() TwoFactorPolicy {
+	return s.policy
+}
+
+// Enabled сообщает, подключена ли у пользователя 2FA
+func (s *TwoFactorService) Enabled(ctx context.Context, userID int) (bool, error) {
+	key, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return key.Enabled(), nil
+}
+
+// Status состояние 2FA для личного кабинета
+func (s *TwoFactorService) Status(ctx context.Context, userID int) (*models.TwoFactorStatus, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.TwoFactorStatus{Enabled: key.Enabled(), Required: s.policy.Required(user.Role)}
+	if status.Enabled {
+		if status.RecoveryCodesLeft, err = s.store.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Setup выдает новый ключ для приложения-аутентификатора. Подключение вступает в силу
+// после Confirm; до этого вход по-прежнему выполняется только по паролю.
+func (s *TwoFactorService) Setup(ctx context.Context, userID int) (*models.TwoFactorSetup, error) {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := totp.GenerateSecret()
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		key, err := s.store.GetTwoFactor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if key.Enabled() {
+			return ErrTwoFactorEnabled
+		}
+		return s.store.SavePendingSecret(ctx, userID, secret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorSetup{
+		Secret: secret,
+		URI:    totp.ProvisioningURI(twoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm включает 2FA, если код из приложения совпал с выданным ключом,
+// и возвращает коды восстановления. Коды показываются один раз.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		key, err := s.store.GetTwoFactor(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if key == nil {
+			return ErrTwoFactorNotPending
+		}
+		if key.Enabled() {
+			return ErrTwoFactorEnabled
+		}
+
+		now := s.now()
+		step, ok := totp.Validate(key.Secret, normalizeCode(code), now, key.LastUsedStep)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		if err := s.store.EnableTwoFactor(ctx, userID, now, step); err != nil {
+			return err
+		}
+		return s.store.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Двухфакторная аутентификация подключена", "user_id", userID)
+	return codes, nil
+}
+
+// Disable отключает 2FA после проверки текущего кода. Для ролей из политики отключение запрещено.
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, code string) error {
+	user, err := s.user(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if s.policy.Required(user.Role) {
+		return ErrTwoFactorMandatory
+	}
+
+	err = s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.Verify(ctx, userID, code); err != nil {
+			return err
+		}
+		return s.store.DeleteTwoFactor(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Двухфакторная аутентификация отключена", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет коды восстановления новыми после проверки текущего кода
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	codes, hashes := newRecoveryCodes()
+	err := s.tx.Do(ctx, func(ctx context.Context) error {
+		if err := s.Verify(ctx, userID, code); err != nil {
+			return err
+		}
+		return s.store.ReplaceRecoveryCodes(ctx, userID, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify принимает код из приложения или неиспользованный код восстановления.
+// Каждый код проходит только один раз. После twoFactorFailureLimit неверных кодов
+// проверка отклоняется до конца окна, чтобы код нельзя было подобрать.
+func (s *TwoFactorService) Verify(ctx context.Context, userID int, code string) error {
+	limiterKey := "user:" + strconv.Itoa(userID)
+	if s.failures.Exceeded(limiterKey) {
+		return ErrTwoFactorThrottled.WithRetryAfter(twoFactorFailureWindow)
+	}
+	err := s.verify(ctx, userID, code)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		s.failures.Allow(limiterKey)
+	}
+	return err
+}
+
+func (s *TwoFactorService) verify(ctx context.Context, userID int, code string) error {
+	key, err := s.store.GetTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !key.Enabled() {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	now := s.now()
+	if len(code) == totp.Digits {
+		step, ok := totp.Validate(key.Secret, code, now, key.LastUsedStep)
+		if ok {
+			ok, err = s.store.UseStep(ctx, userID, step)
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	}
+
+	used, err := s.store.UseRecoveryCode(ctx, userID, hashRecoveryCode(code), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	logging.FromContext(ctx).Info("Вход по коду восстановления", "user_id", userID)
+	return nil
+}
+
+// NewChallenge подписанный токен, подтверждающий, что пароль пользователя уже проверен.
+// Формат: <user_id>.<истекает, unix>.<hmac>
+func (s *TwoFactorService) NewChallenge(userID int) string {
+	payload := strconv.Itoa(userID) + "." + strconv.FormatInt(s.now().Add(twoFactorChallengeTTL).Unix(), 10)
+	return payload + "." + s.sign(payload)
+}
+
+// ParseChallenge проверяет подпись и срок challenge и возвращает ID пользователя
+func (s *TwoFactorService) ParseChallenge(challenge string) (int, error) {
+	i := strings.LastIndexByte(challenge, '.')
+	if i < 0 || !hmac.Equal([]byte(challenge[i+1:]), []byte(s.sign(challenge[:i]))) {
+		return 0, ErrTwoFactorChallenge
+	}
+	id, expires, _ := strings.Cut(challenge[:i], ".")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, ErrTwoFactorChallenge
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || s.now().Unix() > unix {
+		return 0, ErrTwoFactorChallenge
+	}
+	return userID, nil
+}
+
+func (s *TwoFactorService) sign(payload string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte("2fa:" + payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+func (s *TwoFactorService) user(ctx context.Context, userID int) (*models.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// newRecoveryCodes коды вида xxxxx-xxxxx (50 бит случайности) и их хеши для хранения
+func newRecoveryCodes() (codes, hashes []string) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range RecoveryCodeCount {
+		b := make([]byte, 7)
+		rand.Read(b)
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, hashes
+}
+
+// normalizeCode убирает пробелы и дефисы, которые пользователи вводят вместе с кодом
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte("recovery:" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/repository/memory"
+	"beladonna/backend/internal/totp"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// enrolledUser пользователь с подтвержденной 2FA; возвращает сервис, ключ и коды восстановления
+func enrolledUser(t *testing.T, role string) (*TwoFactorService, *fakeClock, int, string, []string) {
+	t.Helper()
+	ctx := context.Background()
+	users := memory.NewUserStore()
+	user := &models.User{Email: "anna@example.com"}
+	if err := users.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.SetRole(user.ID, role); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewTwoFactorService(memory.NewTwoFactorStore(), users, memory.NewUnitOfWork(), DefaultTwoFactorPolicy(), "test-secret")
+	clock := &fakeClock{t: time.Now()}
+	s.now = clock.now
+
+	setup, err := s.Setup(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	if enabled, _ := s.Enabled(ctx, user.ID); enabled {
+		t.Fatal("2FA enabled before confirmation")
+	}
+	code, _ := totp.Code(setup.Secret, clock.now())
+	codes, err := s.Confirm(ctx, user.ID, code)
+	if err != nil {
+		t.Fatalf("Confirm: %v", err)
+	}
+	return s, clock, user.ID, setup.Secret, codes
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	ctx := context.Background()
+	s, clock, userID, secret, codes := enrolledUser(t, models.RoleCustomer)
+
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("recovery codes = %d, want %d", len(codes), RecoveryCodeCount)
+	}
+	if _, err := s.Setup(ctx, userID); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Errorf("Setup after enabling = %v, want ErrTwoFactorEnabled", err)
+	}
+
+	// Код, которым подтверждено подключение, повторно не принимается
+	code, _ := totp.Code(secret, clock.now())
+	if err := s.Verify(ctx, userID, code); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Verify(replayed code) = %v, want ErrInvalidTwoFactorCode", err)
+	}
+
+	clock.advance(totp.Period)
+	code, _ = totp.Code(secret, clock.now())
+	if err := s.Verify(ctx, userID, code[:3]+" "+code[3:]); err != nil {
+		t.Errorf("Verify(next code with space) = %v", err)
+	}
+
+	if err := s.Verify(ctx, userID, codes[0]); err != nil {
+		t.Errorf("Verify(recovery code) = %v", err)
+	}
+	if err := s.Verify(ctx, userID, codes[0]); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Verify(used recovery code) = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	status, err := s.Status(ctx, userID)
+	if err != nil || !status.Enabled || status.Required || status.RecoveryCodesLeft != RecoveryCodeCount-1 {
+		t.Errorf("Status = %+v, %v; want enabled with %d codes left", status, err, RecoveryCodeCount-1)
+	}
+}
+
+func TestTwoFactorVerifyThrottled(t *testing.T) {
+	ctx := context.Background()
+	s, clock, userID, secret, _ := enrolledUser(t, models.RoleCustomer)
+
+	for range twoFactorFailureLimit {
+		s.Verify(ctx, userID, "000000")
+	}
+	clock.advance(totp.Period)
+	code, _ := totp.Code(secret, clock.now())
+	if err := s.Verify(ctx, userID, code); !errors.Is(err, ErrTwoFactorThrottled) {
+		t.Errorf("Verify after %d wrong codes = %v, want ErrTwoFactorThrottled", twoFactorFailureLimit, err)
+	}
+}
+
+func TestTwoFactorDisable(t *testing.T) {
+	ctx := context.Background()
+
+	s, _, managerID, _, codes := enrolledUser(t, models.RoleManager)
+	if err := s.Disable(ctx, managerID, codes[0]); !errors.Is(err, ErrTwoFactorMandatory) {
+		t.Errorf("Disable for manager = %v, want ErrTwoFactorMandatory", err)
+	}
+
+	s, _, customerID, _, codes := enrolledUser(t, models.RoleCustomer)
+	if err := s.Disable(ctx, customerID, "wrong1"); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Errorf("Disable with wrong code = %v, want ErrInvalidTwoFactorCode", err)
+	}
+	if err := s.Disable(ctx, customerID, codes[0]); err != nil {
+		t.Fatalf("Disable: %v", err)
+	}
+	if enabled, _ := s.Enabled(ctx, customerID); enabled {
+		t.Error("2FA still enabled")
+	}
+}
+
+func TestTwoFactorChallenge(t *testing.T) {
+	s, clock, userID, _, _ := enrolledUser(t, models.RoleCustomer)
+
+	challenge := s.NewChallenge(userID)
+	if id, err := s.ParseChallenge(challenge); err != nil || id != userID {
+		t.Errorf("ParseChallenge = %d, %v; want %d", id, err, userID)
+	}
+	if _, err := s.ParseChallenge("1" + challenge); !errors.Is(err, ErrTwoFactorChallenge) {
+		t.Errorf("ParseChallenge(tampered) = %v, want ErrTwoFactorChallenge", err)
+	}
+	clock.advance(twoFactorChallengeTTL + time.Second)
+	if _, err := s.ParseChallenge(challenge); !errors.Is(err, ErrTwoFactorChallenge) {
+		t.Errorf("ParseChallenge(expired) = %v, want ErrTwoFactorChallenge", err)
+	}
+}
@@ -0,0 +1,83 @@
+// Package sms отправка SMS. Договора с SMS-шлюзом пока нет, поэтому есть только
+// заглушки для разработки и тестов: сообщения пишутся в лог или в файл.
+// В prod отправка выключена (DisabledSender).
+package sms
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message SMS на номер To в формате E.164
+type Message struct {
+	To   string
+	Text string
+}
+
+// ErrDisabled отправка SMS выключена: шлюз не подключен
+var ErrDisabled = errors.New("sms: sending is disabled")
+
+// Sender отправляет SMS. Реализации: DisabledSender, LogSender и FileSender.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// DisabledSender отклоняет все сообщения с ErrDisabled
+type DisabledSender struct{}
+
+func (DisabledSender) Send(msg Message) error {
+	return ErrDisabled
+}
+
+// LogSender не отправляет SMS, а отмечает их в логе. Текст не пишется: в нем код входа,
+// а лог читают не только разработчики. Увидеть коды можно с FileSender.
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Send(msg Message) error {
+	slog.Info("SMS не отправлено (LogSender)", "phone", msg.To, "length", len([]rune(msg.Text)))
+	return nil
+}
+
+// FileSender дописывает SMS строками в файл Path: время, номер и текст.
+// Файл с кодами только для разработки, права на него 0600.
+type FileSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{Path: path}
+}
+
+func (s *FileSender) Send(msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return fmt.Errorf("sms: %w", err)
+	}
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("sms: %w", err)
+	}
+	text := strings.ReplaceAll(msg.Text, "\n", " ")
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), msg.To, text)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("sms: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package sms
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogSenderOmitsText(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	if err := NewLogSender().Send(Message{To: "+79991234567", Text: "Belladonna: код для входа 482913."}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "482913") {
+		t.Errorf("log contains the code: %s", buf.String())
+	}
+}
+
+func TestDisabledSender(t *testing.T) {
+	if err := (DisabledSender{}).Send(Message{To: "+79991234567", Text: "код"}); !errors.Is(err, ErrDisabled) {
+		t.Errorf("Send = %v, want ErrDisabled", err)
+	}
+}
@@ -0,0 +1,76 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// Контрольные значения RFC 6238, приложение B (SHA1, 8 цифр)
+func TestHOTPRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		if got := hotp(key, Step(time.Unix(tt.unix, 0)), 8); got != tt.want {
+			t.Errorf("T=%d: code = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "081804" {
+		t.Fatalf("Code = %s, want 081804 (last 6 digits of the RFC vector)", code)
+	}
+
+	step, ok := Validate(secret, code, now, 0)
+	if !ok || step != Step(now) {
+		t.Fatalf("Validate = %d, %v; want current step", step, ok)
+	}
+	if _, ok := Validate(secret, code, now.Add(Period), 0); !ok {
+		t.Error("code from the previous step rejected")
+	}
+	if _, ok := Validate(secret, code, now.Add(3*Period), 0); ok {
+		t.Error("code accepted three steps later")
+	}
+	if _, ok := Validate(secret, code, now, step); ok {
+		t.Error("code accepted twice")
+	}
+	if _, ok := Validate(secret, "000000", now, 0); ok {
+		t.Error("wrong code accepted")
+	}
+	if _, ok := Validate("not base32!", code, now, 0); ok {
+		t.Error("invalid secret accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret := GenerateSecret()
+	u, err := url.Parse(ProvisioningURI("Belladonna", "anna@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Belladonna:anna@example.com" {
+		t.Errorf("URI = %s, want otpauth://totp/Belladonna:anna@example.com", u)
+	}
+	if q := u.Query(); q.Get("secret") != secret || q.Get("issuer") != "Belladonna" {
+		t.Errorf("query = %v, want secret and issuer", q)
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{"no proxy headers", "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"spoofed header from client", "203.0.113.7:5000", "1.2.3.4", "5.6.7.8", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:443", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed entry before proxy", "10.0.0.2:443", "1.2.3.4, 198.51.100.1", "", "198.51.100.1"},
+		{"chain of trusted proxies", "192.168.1.5:80", "198.51.100.1, 10.1.1.1", "", "198.51.100.1"},
+		{"garbage in chain", "10.0.0.2:443", "not-an-ip", "", "10.0.0.2"},
+		{"real ip from trusted proxy", "10.0.0.2:443", "", "198.51.100.9", "198.51.100.9"},
+		{"untrusted single address", "192.168.1.6:80", "198.51.100.1", "", "192.168.1.6"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := proxies.Resolve(r); got != tt.want {
+			t.Errorf("%s: Resolve = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ParseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("invalid network accepted")
+	}
+}
+
+func TestClientIPWithoutMiddleware(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "203.0.113.7:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	if got := ClientIP(r); got != "203.0.113.7" {
+		t.Errorf("ClientIP = %q, want connection address", got)
+	}
+}
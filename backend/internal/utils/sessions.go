@@ -1,80 +1,25 @@
 package utils
 
-import (
-	"encoding/base64"
-	"encoding/json"
-	"fmt"
-	"net/http"
-	"time"
-)
+import "context"
 
-// SessionData структура для хранения данных сессии
+// SessionData данные текущей сессии, которые middleware авторизации кладет в контекст запроса.
+// TwoFactor — при входе пройдена проверка второго фактора.
 type SessionData struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	TwoFactor bool   `json:"two_factor,omitempty"`
 }
 
-// CreateSession создает сессию через куки с base64 кодированием
-func CreateSession(w http.ResponseWriter, userID int, email, name string) {
-	sessionData := SessionData{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-	}
+type sessionContextKey struct{}
 
-	// Конвертируем в JSON
-	jsonData, err := json.Marshal(sessionData)
-	if err != nil {
-		fmt.Printf("Ошибка создания сессии: %v\n", err)
-		return
-	}
-
-	// Кодируем в base64 чтобы избежать проблем с символами в куках
-	encodedData := base64.URLEncoding.EncodeToString(jsonData)
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user_session",
-		Value:    encodedData,
-		Path:     "/",
-		Expires:  time.Now().Add(7 * 24 * time.Hour),
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-	})
-
-	fmt.Printf("Сессия создана: UserID=%d, Email=%s\n", userID, email)
-}
-
-// GetUserFromSession получает пользователя из сессии
-func GetUserFromSession(r *http.Request) (*SessionData, error) {
-	cookie, err := r.Cookie("user_session")
-	if err != nil {
-		return nil, fmt.Errorf("no session")
-	}
-
-	// Декодируем из base64
-	decodedData, err := base64.URLEncoding.DecodeString(cookie.Value)
-	if err != nil {
-		return nil, fmt.Errorf("invalid base64 encoding: %v", err)
-	}
-
-	var sessionData SessionData
-	err = json.Unmarshal(decodedData, &sessionData)
-	if err != nil {
-		return nil, fmt.Errorf("invalid session data: %v", err)
-	}
-
-	return &sessionData, nil
+// WithSession возвращает контекст с данными сессии
+func WithSession(ctx context.Context, sessionData *SessionData) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sessionData)
 }
 
-// ClearSession удаляет сессию
-func ClearSession(w http.ResponseWriter) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "user_session",
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Now().Add(-1 * time.Hour),
-		HttpOnly: true,
-	})
+// SessionFromContext возвращает данные сессии, положенные в контекст middleware авторизации
+func SessionFromContext(ctx context.Context) (*SessionData, bool) {
+	sessionData, ok := ctx.Value(sessionContextKey{}).(*SessionData)
+	return sessionData, ok && sessionData != nil
 }
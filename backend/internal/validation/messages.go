@@ -0,0 +1,61 @@
+package validation
+
+import "strings"
+
+const (
+	LangRU = "ru"
+	LangEN = "en"
+)
+
+// DefaultLang язык сообщений, если клиент не указал поддерживаемый
+const DefaultLang = LangRU
+
+var messages = map[string]map[string]string{
+	LangRU: {
+		"validation_failed": "Проверьте правильность заполнения полей",
+		"required":          "Обязательное поле",
+		"too_long":          "Не более %d символов",
+		"too_short":         "Не менее %d символов",
+		"email":             "Введите корректный email адрес",
+		"phone":             "Введите номер телефона, например +7 999 123-45-67",
+		"password_too_long": "Пароль не должен превышать %d байт",
+		"password_weak":     "Пароль должен содержать буквы и цифры",
+		"out_of_range":      "Допустимо значение от %d до %d",
+		"must_accept":       "Необходимо согласие",
+	},
+	LangEN: {
+		"validation_failed": "Please check the highlighted fields",
+		"required":          "This field is required",
+		"too_long":          "Must be at most %d characters",
+		"too_short":         "Must be at least %d characters",
+		"email":             "Enter a valid email address",
+		"phone":             "Enter a phone number like +7 999 123-45-67",
+		"password_too_long": "Password must not exceed %d bytes",
+		"password_weak":     "Password must contain letters and digits",
+		"out_of_range":      "Must be between %d and %d",
+		"must_accept":       "Consent is required",
+	},
+}
+
+func message(lang, key string) string {
+	if msg, ok := messages[lang][key]; ok {
+		return msg
+	}
+	if msg, ok := messages[DefaultLang][key]; ok {
+		return msg
+	}
+	return key
+}
+
+// LanguageFromHeader выбирает язык сообщений по заголовку Accept-Language.
+// Веса q не учитываются: берется первый поддерживаемый язык в порядке перечисления.
+func LanguageFromHeader(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := messages[base]; ok {
+			return base
+		}
+	}
+	return DefaultLang
+}
@@ -0,0 +1,149 @@
+// Package validation проверяет входящие запросы и собирает локализованные ошибки по полям.
+// Результат — ошибка apperr класса Validation, поэтому обработчики отдают ее как обычно.
+package validation
+
+import (
+	"beladonna/backend/internal/apperr"
+	"beladonna/backend/internal/phone"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ограничения, совпадающие с размерами колонок в схеме БД
+const (
+	MaxEmailLen    = 255
+	MaxNameLen     = 100
+	MaxPhoneLen    = 20
+	MinPasswordLen = 8
+	// bcrypt учитывает только первые 72 байта пароля
+	MaxPasswordBytes = 72
+)
+
+// MaxPhoneInputLen длина номера в запросе: до нормализации он может быть записан
+// с пробелами и скобками, в базу попадает E.164 не длиннее MaxPhoneLen
+const MaxPhoneInputLen = 32
+
+// Validatable запрос, который умеет проверить себя
+type Validatable interface {
+	Validate(v *Validator)
+}
+
+// Validate проверяет запрос и возвращает nil или ошибку с сообщениями на языке lang
+func Validate(lang string, target Validatable) error {
+	v := New(lang)
+	target.Validate(v)
+	return v.Err()
+}
+
+// Validator накапливает ошибки по полям. Для каждого поля сохраняется только первая ошибка,
+// поэтому проверки одного поля пишутся от общих к частным.
+type Validator struct {
+	lang   string
+	fields map[string]string
+}
+
+func New(lang string) *Validator {
+	return &Validator{lang: lang, fields: make(map[string]string)}
+}
+
+// Valid сообщает, что ошибок нет
+func (v *Validator) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// HasError сообщает, есть ли уже ошибка у поля
+func (v *Validator) HasError(field string) bool {
+	_, ok := v.fields[field]
+	return ok
+}
+
+// Err возвращает ошибку валидации или nil
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	err := apperr.Validation("validation_failed", message(v.lang, "validation_failed"))
+	for field, msg := range v.fields {
+		err = err.WithField(field, msg)
+	}
+	return err
+}
+
+// Check добавляет ошибку key для поля, если условие ok не выполнено
+func (v *Validator) Check(ok bool, field, key string, args ...any) bool {
+	if ok || v.HasError(field) {
+		return ok
+	}
+	msg := message(v.lang, key)
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	v.fields[field] = msg
+	return false
+}
+
+func (v *Validator) Required(field, value string) bool {
+	return v.Check(strings.TrimSpace(value) != "", field, "required")
+}
+
+func (v *Validator) MaxLen(field, value string, max int) bool {
+	return v.Check(utf8.RuneCountInString(value) <= max, field, "too_long", max)
+}
+
+func (v *Validator) MinLen(field, value string, min int) bool {
+	return v.Check(utf8.RuneCountInString(value) >= min, field, "too_short", min)
+}
+
+// Email проверяет адрес вида user@domain.tld без отображаемого имени
+func (v *Validator) Email(field, value string) bool {
+	return v.Check(IsEmail(value), field, "email")
+}
+
+// Phone проверяет, что номер приводится к E.164: +7 999 123-45-67, 8 (999) 123-45-67 и т.п.
+func (v *Validator) Phone(field, value string) bool {
+	return v.Check(len(value) <= MaxPhoneInputLen && phone.Valid(value), field, "phone")
+}
+
+// Password проверяет политику паролей: длина и наличие букв и цифр
+func (v *Validator) Password(field, value string) bool {
+	if !v.Check(utf8.RuneCountInString(value) >= MinPasswordLen, field, "too_short", MinPasswordLen) {
+		return false
+	}
+	if !v.Check(len(value) <= MaxPasswordBytes, field, "password_too_long", MaxPasswordBytes) {
+		return false
+	}
+	var hasLetter, hasDigit bool
+	for _, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return v.Check(hasLetter && hasDigit, field, "password_weak")
+}
+
+func (v *Validator) IntRange(field string, value, min, max int) bool {
+	return v.Check(value >= min && value <= max, field, "out_of_range", min, max)
+}
+
+func (v *Validator) Accepted(field string, value bool) bool {
+	return v.Check(value, field, "must_accept")
+}
+
+// IsEmail проверка адреса для Validator.Email; нужна и там, где запрос не проходит через Validator
+func IsEmail(value string) bool {
+	if value == "" || strings.ContainsAny(value, " <>") {
+		return false
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return false
+	}
+	at := strings.LastIndex(value, "@")
+	return strings.Contains(value[at+1:], ".")
+}
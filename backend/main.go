@@ -2,108 +2,356 @@ package main
 
 import (
 	"beladonna/backend/config"
+	"beladonna/backend/internal/antispam"
 	"beladonna/backend/internal/handlers"
+	"beladonna/backend/internal/logging"
+	"beladonna/backend/internal/mailer"
+	"beladonna/backend/internal/migrator"
+	"beladonna/backend/internal/models"
+	"beladonna/backend/internal/oidc"
 	"beladonna/backend/internal/repository"
+	"beladonna/backend/internal/router"
+	"beladonna/backend/internal/seed"
 	"beladonna/backend/internal/service"
+	"beladonna/backend/internal/sms"
+	"beladonna/backend/internal/utils"
+	"context"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	cfg, err := config.NewConfig()
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatal("Config error:", err)
+		log.Fatal("Config error: ", err)
 	}
-	defer cfg.DB.Close()
 
-	// Выполнить миграции
-	err = config.RunMigrations(cfg.DB, "backend/migrations/001_init.sql")
+	slog.SetDefault(logging.New(logging.Options{Level: cfg.Log.Level, Format: cfg.Log.Format}))
+	slog.Info("Конфигурация загружена", "config", cfg.String())
+
+	if err := cfg.OpenDB(); err != nil {
+		fatal("Ошибка подключения к БД", err)
+	}
+	defer func() {
+		cfg.DB.Close()
+		slog.Info("Соединения с БД закрыты")
+	}()
+
+	migrations, err := migrator.NewFromDir(cfg.DB, cfg.MigrationsDir)
 	if err != nil {
-		log.Printf("Migration warning: %v", err)
+		fatal("Ошибка загрузки миграций", err)
+	}
+
+	// go run ./backend migrate <status|up|down|redo>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(context.Background(), migrations, os.Args[2:]); err != nil {
+			fatal("Ошибка команды migrate", err)
+		}
+		return
+	}
+
+	// При старте сервера применяем ожидающие миграции
+	if _, err := migrations.Up(context.Background()); err != nil {
+		fatal("Ошибка применения миграций", err)
+	}
+
+	// go run ./backend seed [run|reset]. Сервер демо-данные не загружает:
+	// в них есть учетные записи сотрудников с известными паролями
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		fixtures, err := seed.LoadDir(cfg.SeedsDir)
+		if err != nil {
+			fatal("Ошибка загрузки демо-данных", err)
+		}
+		if err := runSeedCommand(context.Background(), seed.New(cfg.DB, fixtures), cfg.Env, os.Args[2:]); err != nil {
+			fatal("Ошибка команды seed", err)
+		}
+		return
+	}
+
+	db := repository.NewDB(cfg.DB, cfg.Database.QueryTimeout)
+
+	var mail mailer.Mailer = mailer.NewLogMailer()
+	switch cfg.Mail.Driver {
+	case "smtp":
+		mail = mailer.NewSMTPMailer(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, string(cfg.Mail.Password), cfg.Mail.From)
+	case "outbox":
+		mail = mailer.NewOutboxMailer(cfg.Mail.OutboxDir, cfg.Mail.From)
 	}
 
 	// === ДОБАВЛЕНО: Инициализация репозиториев и сервисов для корзины и продуктов ===
-	userRepo := repository.NewUserRepository(cfg.DB)
-	productRepo := repository.NewProductRepository(cfg.DB) // ДОБАВЛЕНО
-	cartRepo := repository.NewCartRepository(cfg.DB)       // ДОБАВЛЕНО
+	userRepo := repository.NewUserRepository(db)
+	productRepo := repository.NewProductRepository(db) // ДОБАВЛЕНО
+	cartRepo := repository.NewCartRepository(db)       // ДОБАВЛЕНО
+
+	// Защита входа от подбора пароля
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
+	loginGuard := service.NewLoginGuard(service.DefaultLoginGuardConfig(), loginAttemptRepo, db, mail, cfg.BaseURL)
+	stopLoginJanitor := loginGuard.StartJanitor(10 * time.Minute)
+
+	// Двухфакторная аутентификация; для admin и manager обязательна
+	twoFactorPolicy := service.DefaultTwoFactorPolicy()
+	twoFactorService := service.NewTwoFactorService(repository.NewTwoFactorRepository(db), userRepo, db, twoFactorPolicy, string(cfg.AppSecret))
+
+	// Серверные сессии: в cookie только токен, сессию можно завершить на сервере
+	sessionService := service.NewSessionService(repository.NewSessionRepository(db), service.SessionOptions{
+		Lifetime:           cfg.Session.Lifetime,
+		RememberMeLifetime: cfg.Session.RememberMeLifetime,
+	})
+	stopSessionJanitor := sessionService.StartJanitor(time.Hour)
+	sessions := handlers.NewSessions(sessionService, cfg.Session.CookieSecure)
+
+	// Версии политики конфиденциальности и журнал согласий. Файл отдается
+	// статикой, поэтому его адрес на сайте совпадает с путем от корня.
+	consentRepo := repository.NewConsentRepository(db)
+	consentService := service.NewConsentService(consentRepo, db)
+	policyURL := "/" + filepath.ToSlash(filepath.Clean(cfg.PrivacyPolicyFile))
+	if _, err := consentService.PublishFile(context.Background(), models.PolicyPrivacy, cfg.PrivacyPolicyFile, policyURL); err != nil {
+		slog.Warn("Версия политики конфиденциальности не проверена", "error", err)
+	}
+	consentHandler := handlers.NewConsentHandler(consentService, sessions)
+
+	// Рассылка: подписка с подтверждением по email, кампании уходят в фоне партиями
+	newsletterRepo := repository.NewNewsletterRepository(db)
+	newsletterService := service.NewNewsletterService(newsletterRepo, userRepo, productRepo, db, mail,
+		service.DefaultNewsletterOptions(), string(cfg.AppSecret), cfg.BaseURL)
+	stopNewsletterSender := newsletterService.StartSender(time.Minute)
+	newsletterHandler := handlers.NewNewsletterHandler(newsletterService)
+
+	// Подтверждение телефона и вход по коду из SMS; SMS-шлюза пока нет: в prod отправка
+	// выключена, при разработке коды пишутся в файл
+	var smsSender sms.Sender = sms.DisabledSender{}
+	switch cfg.SMS.Driver {
+	case "log":
+		smsSender = sms.NewLogSender()
+	case "file":
+		smsSender = sms.NewFileSender(cfg.SMS.File)
+	}
+	phoneService := service.NewPhoneService(repository.NewSmsCodeRepository(db), userRepo, smsSender,
+		service.DefaultPhoneOptions(), string(cfg.AppSecret))
+	stopPhoneJanitor := phoneService.StartJanitor(time.Hour)
+	phoneHandler := handlers.NewPhoneHandler(phoneService)
 
 	// === ДОБАВЛЕНО: Инициализация сервисов для корзины и продуктов ===
-	authService := service.NewAuthService(userRepo)
+	authService := service.NewAuthService(userRepo, db, loginGuard, twoFactorService, consentService, newsletterService, phoneService)
 	productService := service.NewProductService(productRepo) // ДОБАВЛЕНО
-	cartService := service.NewCartService(cartRepo)          // ДОБАВЛЕНО
+	cartService := service.NewCartService(cartRepo, db)      // ДОБАВЛЕНО
+	profileService := service.NewProfileService(userRepo, repository.NewEmailChangeRepository(db), sessionService, newsletterService, db, mail, cfg.BaseURL)
 
 	// === ДОБАВЛЕНО: Инициализация обработчиков для корзины и продуктов ===
-	authHandler := handlers.NewAuthHandler(authService)
+	authHandler := handlers.NewAuthHandler(authService, sessions)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, sessions)
+	profileHandler := handlers.NewProfileHandler(profileService, sessions)
 	productHandler := handlers.NewProductHandler(productService) // ДОБАВЛЕНО
 	cartHandler := handlers.NewCartHandler(cartService)          // ДОБАВЛЕНО
 
-	feedbackRepo := repository.NewFeedbackRepository(cfg.DB)
-	feedbackService := service.NewFeedbackService(feedbackRepo)
+	feedbackRepo := repository.NewFeedbackRepository(db)
+
+	// Вход через провайдеров OpenID Connect и привязанные к аккаунтам провайдеры
+	identityRepo := repository.NewIdentityRepository(db)
+	var providers []oidc.Provider
+	for _, p := range cfg.OIDC {
+		providers = append(providers, oidc.NewClient(oidc.Config{
+			Name:         p.Name,
+			DisplayName:  p.DisplayName,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: string(p.ClientSecret),
+			Scopes:       p.Scopes,
+		}, nil))
+	}
+	oidcService := service.NewOIDCService(providers, identityRepo, userRepo, db, cfg.BaseURL)
+	stopOIDCJanitor := oidcService.StartJanitor(time.Hour)
+	oidcHandler := handlers.NewOIDCHandler(oidcService, authService, sessions)
+	for _, p := range providers {
+		slog.Info("Провайдер входа подключен", "provider", p.Name(), "redirect_uri", oidcService.RedirectURI(p.Name()))
+	}
+
+	// Выгрузка и удаление персональных данных
+	privacyService := service.NewPrivacyService(userRepo, cartRepo, feedbackRepo, loginAttemptRepo, repository.NewAccountDeletionRepository(db),
+		consentRepo, newsletterRepo, identityRepo, twoFactorService, sessionService, db, mail)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService, sessions)
+
+	// Антиспам для формы отзыва
+	guardConfig := service.DefaultFeedbackGuardConfig()
+	bannedWords, err := antispam.LoadWordList(cfg.BannedWordsFile)
+	if err != nil {
+		slog.Warn("Список запрещенных слов не загружен", "error", err)
+	}
+	guardConfig.BannedWords = bannedWords
+	feedbackGuard := service.NewFeedbackGuard(guardConfig, string(cfg.AppSecret), feedbackRepo)
+	stopGuardJanitor := feedbackGuard.StartJanitor(10 * time.Minute)
+
+	themeRepo := repository.NewFeedbackThemeRepository(db)
+	themeService := service.NewFeedbackThemeService(themeRepo, userRepo, db, mail)
+	themeHandler := handlers.NewFeedbackThemeHandler(themeService)
+
+	feedbackService := service.NewFeedbackService(feedbackRepo, userRepo, themeService, feedbackGuard, db, mail)
 	feedbackHandler := handlers.NewFeedbackHandler(feedbackService)
 
-	// Настройка CORS для разработки
-	corsMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Authorization")
+	roleMiddleware := handlers.NewRoleMiddleware(authService, twoFactorPolicy)
+	proxies, err := utils.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		fatal("Некорректный список доверенных прокси", err)
+	}
+	csrf := handlers.NewCSRF(string(cfg.AppSecret), cfg.CORSOrigins, cfg.Session.CookieSecure)
 
-			if r.Method == "OPTIONS" {
-				return
-			}
+	r := router.New()
+	r.SetErrorHandler(handlers.RouteError)
 
-			next(w, r)
-		}
+	// Статические файлы: только страницы и ресурсы фронтенда. Корень репозитория
+	// целиком не раздается, иначе наружу попали бы конфигурация, исходники и письма outbox
+	static := http.FileServer(http.Dir("./"))
+	r.Handle(http.MethodGet, "/{$}", static)
+	for _, path := range []string{"/index.html", "/js/", "/styles/", "/images/", "/pages/", "/privacy/"} {
+		r.Handle(http.MethodGet, path, static)
 	}
 
-	// Статические файлы - НЕ ИЗМЕНЯЛОСЬ
-	fs := http.FileServer(http.Dir("./"))
-	http.Handle("/", fs)
-
-	// API routes - существующие маршруты (НЕ ИЗМЕНЯЛИСЬ)
-	http.HandleFunc("/api/register", corsMiddleware(authHandler.Register))
-	http.HandleFunc("/api/login", corsMiddleware(authHandler.Login))
-	http.HandleFunc("/api/logout", authHandler.Logout)
-	http.HandleFunc("/api/profile", authHandler.Profile)
-
-	// === ДОБАВЛЕНО: Маршруты для каталога товаров ===
-	http.HandleFunc("/api/products", corsMiddleware(productHandler.GetProducts))
-	http.HandleFunc("/api/product", corsMiddleware(productHandler.GetProduct))
-	http.HandleFunc("/api/categories", corsMiddleware(productHandler.GetCategories))
-
-	http.HandleFunc("/api/feedback", feedbackHandler.CreateFeedback)
-	http.HandleFunc("/api/feedbacks", feedbackHandler.GetFeedbacks)
-
-	// === ДОБАВЛЕНО: Маршруты для корзины ===
-	http.HandleFunc("/api/cart", func(w http.ResponseWriter, r *http.Request) {
-		// Применяем CORS middleware
-		corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-			switch r.Method {
-			case http.MethodGet:
-				cartHandler.GetCart(w, r)
-			case http.MethodPost:
-				cartHandler.AddToCart(w, r)
-			case http.MethodPut:
-				cartHandler.UpdateCart(w, r)
-			case http.MethodDelete:
-				cartHandler.RemoveFromCart(w, r)
-			default:
-				http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-			}
-		})(w, r)
+	// Все изменяющие запросы к API проверяются на CSRF
+	api := r.Group("/api", csrf.Protect)
+	// Неизвестные GET-запросы к API не должны уходить в файловый сервер
+	api.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		handlers.RouteError(w, r, http.StatusNotFound)
 	})
 
-	// Существующий health check - НЕ ИЗМЕНЯЛОСЬ
-	http.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
+	// Публичные маршруты
+	api.Get("/csrf-token", csrf.Token)
+	api.Post("/register", authHandler.Register)
+	api.Post("/login", authHandler.Login)
+	api.Post("/login/2fa", authHandler.LoginTwoFactor)
+	api.Post("/login/sms/code", authHandler.RequestSmsCode)
+	api.Post("/login/sms", authHandler.LoginSms)
+	api.Post("/logout", authHandler.Logout)
+	api.Get("/auth/providers", oidcHandler.Providers)
+	api.Get("/auth/oidc/{provider}", oidcHandler.Start)
+	api.Get("/auth/oidc/{provider}/callback", oidcHandler.Callback)
+	api.Post("/account/unlock", authHandler.Unlock)
+	api.Post("/profile/email/confirm", profileHandler.ConfirmEmailChange)
+	api.Post("/cookie-consent", consentHandler.Cookie)
+	api.Post("/newsletter/subscribe", newsletterHandler.Subscribe)
+	api.Post("/newsletter/confirm", newsletterHandler.Confirm)
+	api.Post("/newsletter/unsubscribe", newsletterHandler.Unsubscribe)
+	// Отписка кнопкой почтового клиента приходит без CSRF-токена
+	r.Post("/api/newsletter/unsubscribe/one-click", newsletterHandler.OneClickUnsubscribe)
+
+	api.Get("/products", productHandler.GetProducts)
+	api.Get("/products/{id}", productHandler.GetProduct)
+	api.Get("/categories", productHandler.GetCategories)
+
+	api.Get("/feedbacks", feedbackHandler.GetFeedbacks)
+	api.Get("/feedbacks/form-token", feedbackHandler.GetFormToken)
+	api.Get("/feedback-themes", themeHandler.GetThemes)
+
+	api.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status": "ok", "database": "connected"}`))
 	})
 
-	log.Println("Server starting on http://localhost:8080")
-	log.Println("✅ Аутентификация: /api/register, /api/login, /api/logout, /api/profile")
-	log.Println("✅ Каталог товаров: /api/products, /api/product, /api/categories") // ДОБАВЛЕНО
-	log.Println("✅ Корзина: /api/cart (GET, POST, PUT, DELETE)")                   // ДОБАВЛЕНО
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	// Маршруты для авторизованных пользователей
+	authed := api.Group("", sessions.RequireAuth)
+	authed.Get("/profile", profileHandler.Get)
+	authed.Get("/user", profileHandler.Get)
+	authed.Put("/profile", profileHandler.Update)
+	authed.Delete("/profile", privacyHandler.Delete)
+	authed.Get("/profile/export", privacyHandler.Export)
+	authed.Post("/profile/email", profileHandler.RequestEmailChange)
+	authed.Post("/profile/password", profileHandler.ChangePassword)
+	authed.Post("/profile/phone/code", phoneHandler.RequestVerification)
+	authed.Post("/profile/phone/confirm", phoneHandler.ConfirmVerification)
+	authed.Get("/consents", consentHandler.Status)
+	authed.Post("/consents", consentHandler.Accept)
+
+	authed.Get("/account/2fa", twoFactorHandler.Status)
+	authed.Post("/account/2fa/setup", twoFactorHandler.Setup)
+	authed.Post("/account/2fa/confirm", twoFactorHandler.Confirm)
+	authed.Post("/account/2fa/disable", twoFactorHandler.Disable)
+	authed.Post("/account/2fa/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+	authed.Get("/account/identities", oidcHandler.Identities)
+	authed.Delete("/account/identities/{id}", oidcHandler.Unlink)
+
+	authed.Get("/cart", cartHandler.GetCart)
+	authed.Post("/cart/items", cartHandler.AddToCart)
+	authed.Put("/cart/items/{id}", cartHandler.UpdateCart)
+	authed.Delete("/cart/items/{id}", cartHandler.RemoveFromCart)
+
+	authed.Post("/feedbacks", feedbackHandler.CreateFeedback)
+	authed.Get("/feedbacks/my", feedbackHandler.GetMyFeedbacks)
+
+	// Модерация отзывов (manager, admin)
+	moderation := api.Group("/moderation", sessions.RequireAuth, roleMiddleware.Require(models.RoleManager, models.RoleAdmin))
+	moderation.Get("/feedbacks", feedbackHandler.GetPendingFeedbacks)
+	moderation.Get("/feedbacks/{id}", feedbackHandler.GetFeedbackThread)
+	moderation.Put("/feedbacks/{id}", feedbackHandler.EditFeedback)
+	moderation.Post("/feedbacks/{id}/approve", feedbackHandler.ApproveFeedback)
+	moderation.Post("/feedbacks/{id}/reject", feedbackHandler.RejectFeedback)
+	moderation.Get("/feedbacks/{id}/history", feedbackHandler.GetModerationHistory)
+	moderation.Post("/feedbacks/{id}/replies", feedbackHandler.ReplyToFeedback)
+	moderation.Put("/feedbacks/{id}/status", feedbackHandler.SetFeedbackStatus)
+
+	// Кампании рассылки (manager, admin)
+	campaigns := api.Group("/newsletter/campaigns", sessions.RequireAuth, roleMiddleware.Require(models.RoleManager, models.RoleAdmin))
+	campaigns.Get("", newsletterHandler.GetCampaigns)
+	campaigns.Post("", newsletterHandler.CreateCampaign)
+	campaigns.Put("/{id}", newsletterHandler.UpdateCampaign)
+	campaigns.Get("/{id}/preview", newsletterHandler.PreviewCampaign)
+	campaigns.Post("/{id}/send", newsletterHandler.SendCampaign)
+
+	// Управление темами отзывов (admin)
+	admin := api.Group("/admin", sessions.RequireAuth, roleMiddleware.Require(models.RoleAdmin))
+	admin.Get("/feedback-themes", themeHandler.GetAllThemes)
+	admin.Post("/feedback-themes", themeHandler.CreateTheme)
+	admin.Put("/feedback-themes/{id}", themeHandler.UpdateTheme)
+
+	slog.Info("Маршруты зарегистрированы",
+		"auth", "POST /api/register, /api/login, /api/login/2fa, /api/login/sms[/code], /api/logout, /api/account/unlock; GET /api/csrf-token",
+		"profile", "GET /api/profile, /api/user, /api/profile/export; PUT, DELETE /api/profile; POST /api/profile/email, /api/profile/email/confirm, /api/profile/password, /api/profile/phone/code, /api/profile/phone/confirm",
+		"consents", "GET, POST /api/consents; POST /api/cookie-consent",
+		"oidc", "GET /api/auth/providers, /api/auth/oidc/{provider}[/callback], /api/account/identities; DELETE /api/account/identities/{id}",
+		"2fa", "GET /api/account/2fa; POST /api/account/2fa/setup, /confirm, /disable, /recovery-codes",
+		"catalog", "GET /api/products, /api/products/{id}, /api/categories",
+		"cart", "GET /api/cart; POST /api/cart/items; PUT, DELETE /api/cart/items/{id}",
+		"moderation", "/api/moderation/feedbacks[/{id}[/approve|/reject|/history|/replies|/status]]",
+		"newsletter", "POST /api/newsletter/subscribe, /confirm, /unsubscribe[/one-click]; /api/newsletter/campaigns[/{id}[/preview|/send]]",
+	)
+
+	srv := &http.Server{
+		Addr:              cfg.ListenAddr,
+		Handler:           handlers.RealIP(proxies)(handlers.RequestLogger(handlers.CORS(cfg.CORSOrigins)(r))),
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Порядок остановки: HTTP-сервер дожидается запросов, затем фоновые задачи,
+	// затем пул соединений с БД (defer cfg.DB.Close выше)
+	if err := runServer(ctx, srv, cfg.Server.ShutdownTimeout); err != nil {
+		slog.Error("Ошибка HTTP-сервера", "error", err)
+	}
+	stopGuardJanitor()
+	stopLoginJanitor()
+	stopSessionJanitor()
+	stopNewsletterSender()
+	stopPhoneJanitor()
+	stopOIDCJanitor()
+	loginGuard.Wait()
+	themeService.Wait()
+	slog.Info("Фоновые задачи остановлены")
+}
+
+// fatal пишет ошибку в журнал и завершает процесс, как log.Fatal
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"beladonna/backend/internal/migrator"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+const migrateUsage = `Использование: migrate <команда>
+  status      показать примененные и ожидающие миграции
+  up          применить все ожидающие миграции
+  down [N]    откатить последние N миграций (по умолчанию 1)
+  redo        откатить и заново применить последнюю миграцию`
+
+// runMigrateCommand выполняет подкоманду migrate из командной строки
+func runMigrateCommand(ctx context.Context, m *migrator.Migrator, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("не указана команда\n%s", migrateUsage)
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(statuses)
+		return nil
+
+	case "up":
+		count, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Применено миграций: %d\n", count)
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("неверное число шагов: %s", args[1])
+			}
+			steps = n
+		}
+		count, err := m.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Откачено миграций: %d\n", count)
+		return nil
+
+	case "redo":
+		return m.Redo(ctx)
+
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", args[0], migrateUsage)
+	}
+}
+
+func printMigrationStatus(statuses []migrator.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state := "pending"
+		appliedAt := ""
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if s.ChecksumMismatch {
+			state += " (checksum mismatch)"
+		}
+		if s.Missing {
+			state += " (file missing)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
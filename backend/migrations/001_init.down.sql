@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS feedbacks;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_feedbacks_moderation_status;
+DROP TABLE IF EXISTS feedback_moderation_log;
+
+ALTER TABLE feedbacks ALTER COLUMN is_visible SET DEFAULT true;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS moderated_at;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS moderated_by;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS rejection_reason;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS moderation_status;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
@@ -0,0 +1,26 @@
+-- Роли пользователей (модерация отзывов доступна manager и admin).
+-- Назначение модератора: UPDATE users SET role = 'manager' WHERE email = '...';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'customer';
+
+-- Статус модерации отзывов
+ALTER TABLE feedbacks ADD COLUMN IF NOT EXISTS moderation_status VARCHAR(20) NOT NULL DEFAULT 'pending';
+ALTER TABLE feedbacks ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
+ALTER TABLE feedbacks ADD COLUMN IF NOT EXISTS moderated_by INTEGER REFERENCES users(id);
+ALTER TABLE feedbacks ADD COLUMN IF NOT EXISTS moderated_at TIMESTAMP;
+ALTER TABLE feedbacks ALTER COLUMN is_visible SET DEFAULT false;
+
+-- Отзывы, опубликованные до появления модерации, считаем одобренными
+UPDATE feedbacks SET moderation_status = 'approved'
+WHERE is_visible = true AND moderation_status = 'pending';
+
+-- Журнал решений модераторов
+CREATE TABLE IF NOT EXISTS feedback_moderation_log (
+    id SERIAL PRIMARY KEY,
+    feedback_id INTEGER NOT NULL REFERENCES feedbacks(id) ON DELETE CASCADE,
+    moderator_id INTEGER REFERENCES users(id),
+    action VARCHAR(20) NOT NULL,
+    reason TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedbacks_moderation_status ON feedbacks(moderation_status);
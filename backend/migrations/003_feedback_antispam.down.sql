@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_feedbacks_email_created;
+DROP TABLE IF EXISTS feedback_rejections;
@@ -0,0 +1,15 @@
+-- Отклоненные антиспамом отправки формы отзыва (для настройки порогов)
+CREATE TABLE IF NOT EXISTS feedback_rejections (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER,
+    ip VARCHAR(45),
+    email VARCHAR(150),
+    reason VARCHAR(50) NOT NULL,
+    message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_rejections_reason ON feedback_rejections(reason, created_at);
+
+-- Поиск дубликатов по email и тексту
+CREATE INDEX IF NOT EXISTS idx_feedbacks_email_created ON feedbacks(email, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS feedback_replies;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS status;
@@ -0,0 +1,15 @@
+-- Статус переписки по отзыву: open, answered, closed
+ALTER TABLE feedbacks ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'open';
+
+-- Ответы сотрудников на отзывы
+CREATE TABLE IF NOT EXISTS feedback_replies (
+    id SERIAL PRIMARY KEY,
+    feedback_id INTEGER NOT NULL REFERENCES feedbacks(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id),
+    message TEXT NOT NULL,
+    is_public BOOLEAN NOT NULL DEFAULT true,
+    emailed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_replies_feedback ON feedback_replies(feedback_id);
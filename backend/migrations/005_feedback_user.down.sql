@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_feedbacks_user;
+ALTER TABLE feedbacks DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_feedbacks_theme;
+DROP TABLE IF EXISTS feedback_themes;
@@ -0,0 +1,22 @@
+-- Управляемый список тем отзывов. notify_email и notify_role задают правило маршрутизации:
+-- кому отправлять уведомление о новом отзыве с этой темой.
+CREATE TABLE IF NOT EXISTS feedback_themes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    title VARCHAR(200) NOT NULL,
+    notify_email VARCHAR(255),
+    notify_role VARCHAR(20),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    sort_order INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO feedback_themes (code, title, notify_role, sort_order) VALUES
+('delivery', 'Доставка', 'manager', 10),
+('quality', 'Качество изделий', 'manager', 20),
+('measurement', 'Замер', 'manager', 30),
+('installation', 'Монтаж', 'manager', 40),
+('website', 'Работа сайта', 'admin', 50)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_feedbacks_theme ON feedbacks(theme);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_account_lockouts_email;
+DROP TABLE IF EXISTS account_lockouts;
+DROP TABLE IF EXISTS login_throttle;
@@ -0,0 +1,23 @@
+-- Счетчики неудачных входов по email. Ведутся и для несуществующих адресов,
+-- чтобы по реакции на вход нельзя было узнать, зарегистрирован ли email.
+CREATE TABLE IF NOT EXISTS login_throttle (
+    email VARCHAR(255) PRIMARY KEY,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ
+);
+
+-- Журнал блокировок входа и одноразовые токены разблокировки (хранится SHA-256 токена)
+CREATE TABLE IF NOT EXISTS account_lockouts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    email VARCHAR(255) NOT NULL,
+    ip VARCHAR(45),
+    failures INTEGER NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    unlock_token_hash VARCHAR(64) UNIQUE,
+    unlocked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_lockouts_email ON account_lockouts(email, created_at);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_changes;
+DROP INDEX IF EXISTS idx_user_sessions_expires;
+DROP INDEX IF EXISTS idx_user_sessions_user;
+DROP TABLE IF EXISTS user_sessions;
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_account_deletions_email_hash;
+DROP TABLE IF EXISTS account_deletions;
+
+ALTER TABLE feedback_replies DROP CONSTRAINT IF EXISTS feedback_replies_author_id_fkey,
+    ADD CONSTRAINT feedback_replies_author_id_fkey FOREIGN KEY (author_id) REFERENCES users(id);
+ALTER TABLE feedback_moderation_log DROP CONSTRAINT IF EXISTS feedback_moderation_log_moderator_id_fkey,
+    ADD CONSTRAINT feedback_moderation_log_moderator_id_fkey FOREIGN KEY (moderator_id) REFERENCES users(id);
+ALTER TABLE feedbacks DROP CONSTRAINT IF EXISTS feedbacks_moderated_by_fkey,
+    ADD CONSTRAINT feedbacks_moderated_by_fkey FOREIGN KEY (moderated_by) REFERENCES users(id);
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_user_id_fkey,
+    ADD CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_user_id_fkey,
+    ADD CONSTRAINT cart_items_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);
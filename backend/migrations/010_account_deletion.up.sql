@@ -0,0 +1,27 @@
+-- Удаление аккаунта: ссылки на пользователя не должны мешать удалению строки users.
+-- Корзина и старые сессии удаляются вместе с пользователем, в журналах модерации
+-- и ответах на отзывы ссылка на сотрудника обнуляется.
+ALTER TABLE cart_items DROP CONSTRAINT IF EXISTS cart_items_user_id_fkey,
+    ADD CONSTRAINT cart_items_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE sessions DROP CONSTRAINT IF EXISTS sessions_user_id_fkey,
+    ADD CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE feedbacks DROP CONSTRAINT IF EXISTS feedbacks_moderated_by_fkey,
+    ADD CONSTRAINT feedbacks_moderated_by_fkey FOREIGN KEY (moderated_by) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE feedback_moderation_log DROP CONSTRAINT IF EXISTS feedback_moderation_log_moderator_id_fkey,
+    ADD CONSTRAINT feedback_moderation_log_moderator_id_fkey FOREIGN KEY (moderator_id) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE feedback_replies DROP CONSTRAINT IF EXISTS feedback_replies_author_id_fkey,
+    ADD CONSTRAINT feedback_replies_author_id_fkey FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL;
+
+-- Журнал удаленных аккаунтов. Email хранится только в виде SHA-256,
+-- чтобы подтвердить факт удаления, не сохраняя персональные данные.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    email_hash VARCHAR(64) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    feedbacks_anonymized INTEGER NOT NULL DEFAULT 0,
+    ip VARCHAR(45),
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_email_hash ON account_deletions(email_hash);
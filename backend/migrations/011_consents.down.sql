@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_cookie_consents_consent_id;
+DROP TABLE IF EXISTS cookie_consents;
+DROP TABLE IF EXISTS user_consents;
+DROP TABLE IF EXISTS policy_documents;
@@ -0,0 +1,37 @@
+-- Версии юридических документов. Новая версия публикуется, когда меняется файл;
+-- по хешу можно доказать, какой именно текст принял пользователь.
+CREATE TABLE IF NOT EXISTS policy_documents (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(30) NOT NULL,
+    version INTEGER NOT NULL,
+    url VARCHAR(255) NOT NULL,
+    sha256 VARCHAR(64) NOT NULL,
+    published_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, version)
+);
+
+-- Журнал согласий пользователей с конкретными версиями документов
+CREATE TABLE IF NOT EXISTS user_consents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id INTEGER NOT NULL REFERENCES policy_documents(id),
+    ip VARCHAR(45),
+    user_agent VARCHAR(255),
+    accepted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, document_id)
+);
+
+-- Выбор в баннере cookie. consent_id хранится в браузере и связывает
+-- повторные решения одного посетителя, в том числе до входа.
+CREATE TABLE IF NOT EXISTS cookie_consents (
+    id SERIAL PRIMARY KEY,
+    consent_id VARCHAR(64) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL,
+    document_id INTEGER REFERENCES policy_documents(id),
+    ip VARCHAR(45),
+    user_agent VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cookie_consents_consent_id ON cookie_consents(consent_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_newsletter_deliveries_pending;
+DROP TABLE IF EXISTS newsletter_deliveries;
+DROP TABLE IF EXISTS newsletter_campaigns;
+DROP TABLE IF EXISTS newsletter_subscriptions;
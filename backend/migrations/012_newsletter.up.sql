@@ -0,0 +1,39 @@
+-- Подписки на рассылку: зарегистрированные пользователи и гости.
+-- Подписка действует только после подтверждения по ссылке из письма (double opt-in).
+CREATE TABLE IF NOT EXISTS newsletter_subscriptions (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    source VARCHAR(20) NOT NULL,
+    confirm_token_hash VARCHAR(64) UNIQUE,
+    confirm_sent_at TIMESTAMPTZ,
+    ip VARCHAR(45),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMPTZ,
+    unsubscribed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS newsletter_campaigns (
+    id SERIAL PRIMARY KEY,
+    subject VARCHAR(200) NOT NULL,
+    intro TEXT NOT NULL,
+    product_ids INTEGER[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ
+);
+
+-- Очередь писем кампании: отправляются партиями, повторный запуск не дублирует письма
+CREATE TABLE IF NOT EXISTS newsletter_deliveries (
+    id SERIAL PRIMARY KEY,
+    campaign_id INTEGER NOT NULL REFERENCES newsletter_campaigns(id) ON DELETE CASCADE,
+    subscription_id INTEGER NOT NULL REFERENCES newsletter_subscriptions(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT,
+    sent_at TIMESTAMPTZ,
+    UNIQUE (campaign_id, subscription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_newsletter_deliveries_pending ON newsletter_deliveries(campaign_id) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_sms_codes_phone;
+DROP TABLE IF EXISTS sms_codes;
+DROP INDEX IF EXISTS idx_users_phone_verified;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_verified_at;
@@ -0,0 +1,29 @@
+-- Телефоны хранятся в E.164. Старые записи в российском формате приводятся к нему,
+-- остальные остаются как есть: пользователь исправит номер при следующем сохранении профиля.
+UPDATE users
+SET phone = '+7' || right(regexp_replace(phone, '[^0-9]', '', 'g'), 10)
+WHERE regexp_replace(phone, '[^0-9]', '', 'g') ~ '^[78][0-9]{10}$';
+
+-- Номер подтверждается кодом из SMS. Подтвержденный номер принадлежит одному пользователю
+-- и годится для входа; неподтвержденный может быть указан у нескольких.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_verified ON users(phone) WHERE phone_verified_at IS NOT NULL;
+
+-- Одноразовые коды из SMS: вход и подтверждение номера. Хранится HMAC кода.
+-- Коды без пользователя выдаются на неизвестные номера, чтобы ответ и лимиты
+-- не выдавали, зарегистрирован ли номер; такие коды не отправляются.
+CREATE TABLE IF NOT EXISTS sms_codes (
+    id SERIAL PRIMARY KEY,
+    phone VARCHAR(20) NOT NULL,
+    purpose VARCHAR(20) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    ip VARCHAR(45),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sms_codes_phone ON sms_codes(phone, created_at);
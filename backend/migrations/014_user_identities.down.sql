@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_oidc_login_states_expires;
+DROP TABLE IF EXISTS oidc_login_states;
+DROP TABLE IF EXISTS user_identities;
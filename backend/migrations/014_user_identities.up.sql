@@ -0,0 +1,30 @@
+-- Внешние аккаунты OpenID Connect, привязанные к пользователям. Аккаунт провайдера
+-- определяется парой (provider, subject); к пользователю привязывается не больше одного
+-- аккаунта каждого провайдера. Email — адрес из ID-токена на момент привязки, для справки.
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Незавершенные входы через провайдера: state из cookie, nonce и code_verifier PKCE.
+-- Хранится SHA-256 от state; строка удаляется при первом возврате с провайдера.
+-- user_id задан, когда уже вошедший пользователь привязывает новый аккаунт.
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    id SERIAL PRIMARY KEY,
+    state_hash VARCHAR(64) NOT NULL UNIQUE,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires ON oidc_login_states(expires_at);
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"beladonna/backend/internal/seed"
+	"context"
+	"fmt"
+)
+
+const seedUsage = `Использование: seed [команда]
+  run         загрузить демо-данные (по умолчанию)
+  reset       очистить данные приложения и загрузить демо-данные заново
+
+Обе команды работают только в окружениях dev и test.`
+
+// runSeedCommand выполняет подкоманду seed из командной строки. Демо-данные содержат
+// учетные записи администратора и менеджера с известными паролями, поэтому в prod
+// команда не выполняется.
+func runSeedCommand(ctx context.Context, seeder *seed.Seeder, env string, args []string) error {
+	command := "run"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	if env != "dev" && env != "test" {
+		return fmt.Errorf("seed разрешен только в окружениях dev и test, текущее: %q", env)
+	}
+
+	switch command {
+	case "run":
+		return seeder.Run(ctx)
+	case "reset":
+		return seeder.Reset(ctx)
+	default:
+		return fmt.Errorf("неизвестная команда %q\n%s", command, seedUsage)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// runServer обслуживает запросы до отмены ctx, затем останавливает сервер:
+// перестает принимать соединения и ждет незавершенные запросы не дольше shutdownTimeout
+func runServer(ctx context.Context, srv *http.Server, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("HTTP-сервер запущен", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Получен сигнал остановки, ожидание незавершенных запросов", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Не уложились в таймаут: закрываем оставшиеся соединения принудительно
+		srv.Close()
+		return err
+	}
+
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("HTTP-сервер остановлен")
+	return nil
+}
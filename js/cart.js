@@ -41,7 +41,7 @@ class CartManager {
     async addToCart(productId, quantity = 1) {
         try {
             console.log('Добавление товара в корзину:', productId, quantity);
-            const response = await fetch('/api/cart', {
+            const response = await apiFetch('/api/cart/items', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -68,13 +68,12 @@ class CartManager {
 
     async updateCartItem(itemId, quantity) {
         try {
-            const response = await fetch('/api/cart', {
+            const response = await apiFetch(`/api/cart/items/${itemId}`, {
                 method: 'PUT',
                 headers: {
                     'Content-Type': 'application/json',
                 },
                 body: JSON.stringify({
-                    item_id: itemId,
                     quantity: quantity
                 })
             });
@@ -89,14 +88,8 @@ class CartManager {
 
     async removeFromCart(itemId) {
         try {
-            const response = await fetch('/api/cart', {
-                method: 'DELETE',
-                headers: {
-                    'Content-Type': 'application/json',
-                },
-                body: JSON.stringify({
-                    item_id: itemId
-                })
+            const response = await apiFetch(`/api/cart/items/${itemId}`, {
+                method: 'DELETE'
             });
 
             if (response.ok) {
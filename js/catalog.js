@@ -64,7 +64,7 @@ class CatalogManager {
         console.log('Загрузка информации о товаре ID:', productId);
         
         // === ИСПРАВИЛ: правильный URL для получения товара ===
-        const response = await fetch(`/api/product?id=${productId}`);
+        const response = await fetch(`/api/products/${productId}`);
         
         if (!response.ok) {
             throw new Error(`HTTP error! status: ${response.status}`);
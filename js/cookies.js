@@ -2,6 +2,7 @@
 class CookieConsentManager {
     constructor() {
         this.cookieName = 'cookie_consent';
+        this.idCookieName = 'cookie_consent_id';
         this.consentDuration = 365; // дней
         this.init();
     }
@@ -61,11 +62,30 @@ class CookieConsentManager {
         expiryDate.setDate(expiryDate.getDate() + this.consentDuration);
         
         document.cookie = `${this.cookieName}=${status}; expires=${expiryDate.toUTCString()}; path=/; SameSite=Lax`;
+        this.saveConsent(status, expiryDate);
         
         // Вызываем событие для других частей приложения
         this.dispatchCookieEvent(status);
     }
 
+    // Сохраняем выбор на сервере; consent_id связывает повторные решения одного посетителя
+    async saveConsent(status, expiryDate) {
+        if (typeof apiFetch !== 'function') return;
+        try {
+            const response = await apiFetch('/api/cookie-consent', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ status: status, consent_id: this.getCookie(this.idCookieName) || '' })
+            });
+            const result = await response.json();
+            if (result.success && result.consent_id) {
+                document.cookie = `${this.idCookieName}=${result.consent_id}; expires=${expiryDate.toUTCString()}; path=/; SameSite=Lax`;
+            }
+        } catch (error) {
+            console.error('Не удалось сохранить согласие на cookie:', error);
+        }
+    }
+
     getCookie(name) {
         const value = `; ${document.cookie}`;
         const parts = value.split(`; ${name}=`);
@@ -0,0 +1,40 @@
+// js/csrf.js
+// Изменяющие запросы к API (POST, PUT, DELETE) должны нести CSRF-токен в заголовке X-CSRF-Token.
+// apiFetch получает токен с /api/csrf-token, добавляет его к запросу и один раз
+// запрашивает заново, если сервер ответил, что токен устарел.
+let csrfTokenPromise = null;
+
+function getCsrfToken(refresh = false) {
+    if (!csrfTokenPromise || refresh) {
+        csrfTokenPromise = fetch('/api/csrf-token', { credentials: 'same-origin' })
+            .then(response => response.json())
+            .then(data => data.csrf_token)
+            .catch(error => {
+                csrfTokenPromise = null;
+                throw error;
+            });
+    }
+    return csrfTokenPromise;
+}
+
+async function apiFetch(url, options = {}) {
+    const method = (options.method || 'GET').toUpperCase();
+    if (method === 'GET' || method === 'HEAD') {
+        return fetch(url, options);
+    }
+
+    const send = async (refresh) => {
+        const headers = new Headers(options.headers || {});
+        headers.set('X-CSRF-Token', await getCsrfToken(refresh));
+        return fetch(url, { ...options, headers, credentials: 'same-origin' });
+    };
+
+    let response = await send(false);
+    if (response.status === 403) {
+        const result = await response.clone().json().catch(() => ({}));
+        if (result.code === 'csrf_token_invalid') {
+            response = await send(true);
+        }
+    }
+    return response;
+}
@@ -1,18 +1,33 @@
+// Подписанная сервером метка открытия формы: по ней сервер отклоняет отзывы, отправленные слишком быстро
+let feedbackFormToken = '';
+
+// Запрашивает новую метку открытия формы
+async function loadFeedbackFormToken() {
+    try {
+        const response = await fetch('/api/feedbacks/form-token');
+        const result = await response.json();
+        feedbackFormToken = result.form_token || '';
+    } catch (error) {
+        console.error('Error loading form token:', error);
+    }
+}
+
 // Функция для отправки отзыва
 async function submitFeedback() {
     const name = document.getElementById('name').value;
     const email = document.getElementById('email').value;
     const theme = document.getElementById('theme').value;
     const text = document.getElementById('text').value;
+    const website = document.getElementById('website') ? document.getElementById('website').value : '';
 
-    // Валидация
-    if (!name || !email || !text) {
-        alert('Пожалуйста, заполните обязательные поля: Имя, Email и Сообщение');
+    // Валидация: имя и email можно не указывать, они возьмутся из профиля
+    if (!text) {
+        alert('Пожалуйста, заполните поле Сообщение');
         return;
     }
 
     try {
-        const response = await fetch('/api/feedback', {
+        const response = await apiFetch('/api/feedbacks', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -21,7 +36,9 @@ async function submitFeedback() {
                 name: name,
                 email: email,
                 theme: theme,
-                message: text
+                message: text,
+                website: website,
+                form_token: feedbackFormToken
             })
         });
 
@@ -35,16 +52,20 @@ async function submitFeedback() {
         const result = await response.json();
 
         if (response.ok) {
-            alert('Отзыв успешно отправлен! Спасибо за ваше мнение.');
+            alert('Спасибо за ваше мнение! Отзыв появится на сайте после проверки модератором.');
             // Очищаем форму
             document.getElementById('name').value = '';
             document.getElementById('email').value = '';
             document.getElementById('theme').value = '';
             document.getElementById('text').value = '';
+            loadFeedbackFormToken();
             
             // Обновляем список отзывов
             loadFeedbacks();
         } else {
+            if (result.code === 'feedback_form_expired') {
+                loadFeedbackFormToken();
+            }
             alert('Ошибка при отправке отзыва: ' + (result.message || 'Неизвестная ошибка'));
         }
     } catch (error) {
@@ -72,8 +93,17 @@ async function loadFeedbacks() {
                     <strong>${escapeHtml(feedback.name)}</strong>
                     <span class="feedback-date">${formatDate(feedback.created_at)}</span>
                 </div>
-                ${feedback.theme ? `<div class="feedback-theme">Тема: ${escapeHtml(feedback.theme)}</div>` : ''}
+                ${feedback.theme ? `<div class="feedback-theme">Тема: ${escapeHtml(feedback.theme_title || feedback.theme)}</div>` : ''}
                 <div class="feedback-message">${escapeHtml(feedback.message)}</div>
+                ${(feedback.replies || []).map(reply => `
+                    <div class="feedback-reply">
+                        <div class="feedback-header">
+                            <strong>Ответ магазина${reply.author_name ? ' (' + escapeHtml(reply.author_name) + ')' : ''}</strong>
+                            <span class="feedback-date">${formatDate(reply.created_at)}</span>
+                        </div>
+                        <div class="feedback-message">${escapeHtml(reply.message)}</div>
+                    </div>
+                `).join('')}
             </div>
         `).join('');
         
@@ -84,6 +114,30 @@ async function loadFeedbacks() {
     }
 }
 
+// Загрузка списка тем для формы отзыва
+async function loadFeedbackThemes() {
+    const select = document.getElementById('theme');
+    if (!select) {
+        return;
+    }
+
+    try {
+        const response = await fetch('/api/feedback-themes');
+        if (!response.ok) {
+            return;
+        }
+        const themes = await response.json();
+        (themes || []).forEach(theme => {
+            const option = document.createElement('option');
+            option.value = theme.code;
+            option.textContent = theme.title;
+            select.appendChild(option);
+        });
+    } catch (error) {
+        console.error('Error loading feedback themes:', error);
+    }
+}
+
 // Вспомогательные функции
 function escapeHtml(text) {
     const div = document.createElement('div');
@@ -102,5 +156,7 @@ function formatDate(dateString) {
 
 // Загружаем отзывы при загрузке страницы
 document.addEventListener('DOMContentLoaded', function() {
+    loadFeedbackFormToken();
+    loadFeedbackThemes();
     loadFeedbacks();
 });
\ No newline at end of file
@@ -1,7 +1,42 @@
 document.addEventListener('DOMContentLoaded', function() {
-    // Проверяем авторизацию при загрузке страницы
-    checkAuth();
+    const params = new URLSearchParams(window.location.search);
+
+    // Ссылка из письма о смене email: ?confirm_email=<токен>.
+    // Авторизованного пользователя не перенаправляем, пока он не увидит результат.
+    const confirmEmailToken = params.get('confirm_email');
+    if (confirmEmailToken) {
+        confirmEmail(confirmEmailToken);
+    } else {
+        // Проверяем авторизацию при загрузке страницы
+        checkAuth();
+    }
+
+    // Ссылка из письма о блокировке входа: ?unlock=<токен>
+    const unlockToken = params.get('unlock');
+    if (unlockToken) {
+        unlockAccount(unlockToken);
+    }
     
+    // challenge второго шага входа, если у пользователя подключена 2FA
+    let twoFactorChallenge = null;
+    let rememberMe = false;
+
+    // Возврат после входа через внешний сервис: ошибка в ?oidc_error,
+    // challenge второго шага во фрагменте #challenge=
+    const oidcError = params.get('oidc_error');
+    if (oidcError) {
+        showServerError(oidcErrorMessages[oidcError] || 'Не удалось войти через сервис, попробуйте позже');
+    }
+    const hash = new URLSearchParams(window.location.hash.slice(1));
+    if (hash.get('challenge')) {
+        twoFactorChallenge = hash.get('challenge');
+        history.replaceState(null, '', window.location.pathname);
+        document.getElementById('loginForm').style.display = 'none';
+        document.getElementById('twoFactorForm').style.display = 'block';
+        document.getElementById('twoFactorCode').focus();
+    }
+    loadOidcProviders();
+
     document.getElementById('loginForm').addEventListener('submit', async function(e) {
         e.preventDefault();
         
@@ -27,7 +62,7 @@ document.addEventListener('DOMContentLoaded', function() {
         submitBtn.disabled = true;
 
         try {
-            const response = await fetch('/api/login', {
+            const response = await apiFetch('/api/login', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -41,18 +76,15 @@ document.addEventListener('DOMContentLoaded', function() {
             const result = await response.json();
             console.log('Полный ответ:', result);
 
-            if (response.ok && result.success) {
-                showSuccess('Вход выполнен успешно! Перенаправляем на главную страницу...');
-                
-                // Сохраняем информацию о пользователе в localStorage (опционально)
-                localStorage.setItem('user', JSON.stringify({
-                    id: result.user_id,
-                    name: result.name
-                }));
-                
-                setTimeout(() => {
-                    window.location.href = '../index.html';
-                }, 1500);
+            if (response.ok && result.success && result.two_factor_required) {
+                // Пароль верный, нужен код второго фактора
+                twoFactorChallenge = result.challenge;
+                rememberMe = formData.rememberMe;
+                document.getElementById('loginForm').style.display = 'none';
+                document.getElementById('twoFactorForm').style.display = 'block';
+                document.getElementById('twoFactorCode').focus();
+            } else if (response.ok && result.success) {
+                completeLogin(result);
             } else {
                 handleServerError(result.message || result);
             }
@@ -66,6 +98,152 @@ document.addEventListener('DOMContentLoaded', function() {
         }
     });
 
+    document.getElementById('twoFactorForm').addEventListener('submit', async function(e) {
+        e.preventDefault();
+        clearErrors();
+        hideAlerts();
+
+        const submitBtn = this.querySelector('.submit-btn');
+        submitBtn.disabled = true;
+        try {
+            const response = await apiFetch('/api/login/2fa', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    challenge: twoFactorChallenge,
+                    code: document.getElementById('twoFactorCode').value,
+                    rememberMe: rememberMe
+                })
+            });
+            const result = await response.json();
+
+            if (response.ok && result.success) {
+                completeLogin(result);
+            } else if (result.code === 'two_factor_challenge_invalid') {
+                // Время на ввод кода истекло: возвращаемся к паролю
+                document.getElementById('twoFactorForm').style.display = 'none';
+                document.getElementById('loginForm').style.display = 'block';
+                showServerError(result.message);
+            } else {
+                document.getElementById('twoFactorCodeError').textContent = (result.fields && result.fields.code) || '';
+                showServerError(result.message || 'Неверный код');
+            }
+        } catch (error) {
+            console.error('Ошибка сети:', error);
+            showServerError('Ошибка соединения с сервером. Проверьте интернет-соединение.');
+        } finally {
+            submitBtn.disabled = false;
+        }
+    });
+
+    // Вход по SMS: сначала запрашиваем код, затем отправляем его вместе с номером
+    const smsLoginForm = document.getElementById('smsLoginForm');
+    let smsCodeSent = false;
+
+    document.getElementById('smsLoginLink').addEventListener('click', function() {
+        clearErrors();
+        hideAlerts();
+        document.getElementById('loginForm').style.display = 'none';
+        smsLoginForm.style.display = 'block';
+        document.getElementById('smsPhone').focus();
+    });
+
+    document.getElementById('passwordLoginLink').addEventListener('click', function() {
+        clearErrors();
+        hideAlerts();
+        smsLoginForm.style.display = 'none';
+        document.getElementById('loginForm').style.display = 'block';
+    });
+
+    smsLoginForm.addEventListener('submit', async function(e) {
+        e.preventDefault();
+        clearErrors();
+        hideAlerts();
+
+        const submitBtn = this.querySelector('.submit-btn');
+        const phone = document.getElementById('smsPhone').value;
+        submitBtn.disabled = true;
+        try {
+            const response = await apiFetch(smsCodeSent ? '/api/login/sms' : '/api/login/sms/code', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify(smsCodeSent
+                    ? { phone: phone, code: document.getElementById('smsCode').value }
+                    : { phone: phone })
+            });
+            const result = await response.json();
+
+            if (!response.ok || !result.success) {
+                const fields = result.fields || {};
+                document.getElementById('smsPhoneError').textContent = fields.phone || '';
+                document.getElementById('smsCodeError').textContent = fields.code || '';
+                showServerError(result.message || 'Не удалось войти');
+            } else if (!smsCodeSent) {
+                smsCodeSent = true;
+                document.getElementById('smsCodeGroup').style.display = 'block';
+                document.getElementById('smsCode').focus();
+                submitBtn.textContent = 'Войти';
+                showSuccess(result.message);
+            } else if (result.two_factor_required) {
+                twoFactorChallenge = result.challenge;
+                smsLoginForm.style.display = 'none';
+                document.getElementById('twoFactorForm').style.display = 'block';
+                document.getElementById('twoFactorCode').focus();
+            } else {
+                completeLogin(result);
+            }
+        } catch (error) {
+            console.error('Ошибка сети:', error);
+            showServerError('Ошибка соединения с сервером. Проверьте интернет-соединение.');
+        } finally {
+            submitBtn.disabled = false;
+        }
+    });
+
+    // Кнопки входа через внешние сервисы; вход идет переходом, а не fetch
+    async function loadOidcProviders() {
+        try {
+            const response = await fetch('/api/auth/providers');
+            const result = await response.json();
+            if (!response.ok || !result.providers || result.providers.length === 0) return;
+
+            const buttons = document.getElementById('oidcButtons');
+            result.providers.forEach(provider => {
+                const button = document.createElement('a');
+                button.className = 'link';
+                button.href = `/api/auth/oidc/${encodeURIComponent(provider.name)}`;
+                button.textContent = `Войти через ${provider.display_name}`;
+                buttons.appendChild(button);
+                buttons.appendChild(document.createElement('br'));
+            });
+            document.getElementById('oidcProviders').style.display = 'block';
+        } catch (error) {
+            console.error('Не удалось загрузить список сервисов входа:', error);
+        }
+    }
+
+    function completeLogin(result) {
+        if (result.two_factor_setup_required) {
+            showSuccess('Вход выполнен. Для вашей роли обязательна двухфакторная аутентификация: подключите ее в личном кабинете.');
+        } else {
+            showSuccess('Вход выполнен успешно! Перенаправляем на главную страницу...');
+        }
+
+        // Сохраняем информацию о пользователе в localStorage (опционально)
+        localStorage.setItem('user', JSON.stringify({
+            id: result.user_id,
+            name: result.name
+        }));
+
+        setTimeout(() => {
+            window.location.href = '../index.html';
+        }, result.two_factor_setup_required ? 4000 : 1500);
+    }
+
     // Функции для работы с ошибками
     function clearErrors() {
         const errorElements = document.querySelectorAll('.error-message');
@@ -130,6 +308,53 @@ document.addEventListener('DOMContentLoaded', function() {
         showServerError(friendlyMessage);
     }
 
+    // Снятие блокировки входа по токену из письма
+    async function unlockAccount(token) {
+        // Убираем токен из адресной строки, чтобы он не остался в истории
+        window.history.replaceState(null, '', window.location.pathname);
+        try {
+            const response = await apiFetch('/api/account/unlock', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ token: token })
+            });
+            const result = await response.json();
+            if (response.ok && result.success) {
+                showSuccess(result.message);
+            } else {
+                showServerError(result.message || 'Не удалось разблокировать вход');
+            }
+        } catch (error) {
+            console.error('Ошибка сети:', error);
+            showServerError('Ошибка соединения с сервером. Проверьте интернет-соединение.');
+        }
+    }
+
+    // Подтверждение нового email по токену из письма
+    async function confirmEmail(token) {
+        window.history.replaceState(null, '', window.location.pathname);
+        try {
+            const response = await apiFetch('/api/profile/email/confirm', {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ token: token })
+            });
+            const result = await response.json();
+            if (response.ok && result.success) {
+                showSuccess(result.message);
+            } else {
+                showServerError(result.message || 'Не удалось подтвердить email');
+            }
+        } catch (error) {
+            console.error('Ошибка сети:', error);
+            showServerError('Ошибка соединения с сервером. Проверьте интернет-соединение.');
+        }
+    }
+
     // Проверка авторизации
     async function checkAuth() {
         try {
@@ -162,4 +387,16 @@ function closeSuccess() {
     if (successAlert) {
         successAlert.style.display = 'none';
     }
-}
\ No newline at end of file
+}
+
+// Сообщения об ошибках входа через внешние сервисы по коду из параметра oidc_error
+const oidcErrorMessages = {
+    oidc_state: 'Сеанс входа устарел, попробуйте еще раз',
+    oidc_denied: 'Вход через сервис отменен',
+    oidc_failed: 'Не удалось войти через сервис, попробуйте позже',
+    oidc_throttled: 'Слишком много попыток входа, повторите позже',
+    oidc_provider_unknown: 'Вход через этот сервис недоступен',
+    oidc_email_required: 'Сервис не передал подтвержденный email, войдите другим способом',
+    oidc_email_taken: 'Аккаунт с этим email уже есть: войдите по паролю и привяжите сервис в личном кабинете',
+    unauthorized: 'Войдите, чтобы привязать сервис',
+};
@@ -0,0 +1,54 @@
+// Подписка на рассылку: форма в подвале и ссылки из писем
+// (?newsletter_confirm= — подтверждение, ?newsletter_unsubscribe= — отписка)
+
+async function postNewsletter(url, data) {
+    const response = await apiFetch(url, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(data)
+    });
+    const result = await response.json();
+    alert(result.message || (response.ok ? 'Готово' : 'Ошибка'));
+    return response.ok;
+}
+
+async function handleNewsletterLinks() {
+    const params = new URLSearchParams(window.location.search);
+    const confirmToken = params.get('newsletter_confirm');
+    const unsubscribeToken = params.get('newsletter_unsubscribe');
+    if (!confirmToken && !unsubscribeToken) return;
+
+    // Убираем токен из адреса, чтобы он не остался в истории
+    window.history.replaceState(null, '', window.location.pathname);
+    try {
+        if (confirmToken) {
+            await postNewsletter('/api/newsletter/confirm', { token: confirmToken });
+        } else if (confirm('Отписаться от рассылки Belladonna?')) {
+            await postNewsletter('/api/newsletter/unsubscribe', { token: unsubscribeToken });
+        }
+    } catch (error) {
+        console.error('Ошибка обработки ссылки рассылки:', error);
+        alert('Ошибка соединения с сервером');
+    }
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    handleNewsletterLinks();
+
+    const form = document.getElementById('newsletterForm');
+    if (!form) return;
+    form.addEventListener('submit', async function(e) {
+        e.preventDefault();
+        const input = document.getElementById('newsletterEmail');
+        try {
+            if (await postNewsletter('/api/newsletter/subscribe', { email: input.value.trim() })) {
+                form.reset();
+            }
+        } catch (error) {
+            console.error('Ошибка подписки на рассылку:', error);
+            alert('Ошибка соединения с сервером');
+        }
+    });
+});
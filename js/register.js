@@ -45,9 +45,9 @@ document.addEventListener('DOMContentLoaded', function() {
             return;
         }
         
-        if (password.length < 6) {
-            showError('passwordError', 'Пароль должен содержать минимум 6 символов');
-            showServerError('Пароль должен содержать минимум 6 символов.');
+        if (password.length < 8 || !/\p{L}/u.test(password) || !/\d/.test(password)) {
+            showError('passwordError', 'Пароль должен содержать минимум 8 символов, буквы и цифры');
+            showServerError('Пароль должен содержать минимум 8 символов, буквы и цифры.');
             return;
         }
         
@@ -78,7 +78,7 @@ document.addEventListener('DOMContentLoaded', function() {
         submitBtn.disabled = true;
 
         try {
-            const response = await fetch('/api/register', {
+            const response = await apiFetch('/api/register', {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
@@ -95,7 +95,12 @@ document.addEventListener('DOMContentLoaded', function() {
                     window.location.href = 'login.html';
                 }, 2000);
             } else {
-                // Обработка различных ошибок сервера
+                // Ошибки по полям подсвечиваем у соответствующих полей формы
+                if (result.fields) {
+                    for (const [field, message] of Object.entries(result.fields)) {
+                        showError(field + 'Error', message);
+                    }
+                }
                 handleServerError(result.message || result);
             }
         } catch (error) {
@@ -160,7 +165,7 @@ document.addEventListener('DOMContentLoaded', function() {
             'неверный email или пароль': 'Неверный email или пароль.',
             'все обязательные поля должны быть заполнены': 'Пожалуйста, заполните все обязательные поля.',
             'неверный формат данных': 'Ошибка в данных формы. Проверьте правильность ввода.',
-            'password must be at least': 'Пароль должен содержать минимум 6 символов.'
+            'password must be at least': 'Пароль должен содержать минимум 8 символов.'
         };
 
         // Ищем понятное сообщение или используем оригинальное
@@ -13,6 +13,7 @@ async function checkAuthAndUpdateUI() {
             if (result.success) {
                 // Пользователь авторизован - показываем личный кабинет
                 showUserPanel(result.user);
+                checkPolicyConsents();
                 return true;
             }
         }
@@ -57,7 +58,7 @@ function showLoginButton() {
 // Выход из системы
 async function logout() {
     try {
-        const response = await fetch('/api/logout', {
+        const response = await apiFetch('/api/logout', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
@@ -201,13 +202,12 @@ async function updateCartItem(itemId, newQuantity) {
             return;
         }
         
-        const response = await fetch('/api/cart', {
+        const response = await apiFetch(`/api/cart/items/${itemId}`, {
             method: 'PUT',
             headers: {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
-                item_id: itemId,
                 quantity: newQuantity
             })
         });
@@ -226,14 +226,8 @@ async function updateCartItem(itemId, newQuantity) {
 // Удаление товара из корзины
 async function removeFromCart(itemId) {
     try {
-        const response = await fetch('/api/cart', {
-            method: 'DELETE',
-            headers: {
-                'Content-Type': 'application/json',
-            },
-            body: JSON.stringify({
-                item_id: itemId
-            })
+        const response = await apiFetch(`/api/cart/items/${itemId}`, {
+            method: 'DELETE'
         });
         
         if (response.ok) {
@@ -249,6 +243,216 @@ async function removeFromCart(itemId) {
 }
 // === КОНЕЦ ЗАМЕНЫ ===
 
+// Двухфакторная аутентификация: подключение по коду из приложения-аутентификатора
+async function manageTwoFactor() {
+    try {
+        const statusResponse = await fetch('/api/account/2fa');
+        if (!statusResponse.ok) {
+            alert('Не удалось получить настройки защиты входа');
+            return;
+        }
+        const status = await statusResponse.json();
+
+        if (status.enabled) {
+            const action = prompt(
+                `Двухфакторная аутентификация подключена. Осталось кодов восстановления: ${status.recovery_codes_left}.\n` +
+                'Введите код из приложения, чтобы получить новые коды восстановления' +
+                (status.required ? '.' : ', или "отключить", чтобы отключить защиту.'));
+            if (!action) return;
+
+            if (!status.required && action.trim().toLowerCase() === 'отключить') {
+                const code = prompt('Введите код из приложения или код восстановления для отключения');
+                if (!code) return;
+                const result = await postTwoFactor('/api/account/2fa/disable', code);
+                if (result) alert(result.message);
+                return;
+            }
+
+            const result = await postTwoFactor('/api/account/2fa/recovery-codes', action);
+            if (result) showRecoveryCodes(result.recovery_codes);
+            return;
+        }
+
+        const setupResponse = await apiFetch('/api/account/2fa/setup', { method: 'POST' });
+        const setup = await setupResponse.json();
+        if (!setupResponse.ok) {
+            alert(setup.message || 'Не удалось начать подключение');
+            return;
+        }
+
+        const code = prompt(
+            'Добавьте ключ в приложение-аутентификатор (Google Authenticator, Яндекс Ключ и др.):\n\n' +
+            `${setup.secret}\n\nили откройте ссылку: ${setup.uri}\n\nЗатем введите 6-значный код из приложения:`);
+        if (!code) return;
+
+        const result = await postTwoFactor('/api/account/2fa/confirm', code);
+        if (result) showRecoveryCodes(result.recovery_codes);
+    } catch (error) {
+        console.error('Ошибка настройки двухфакторной аутентификации:', error);
+        alert('Ошибка соединения с сервером');
+    }
+}
+
+async function postTwoFactor(url, code) {
+    const response = await apiFetch(url, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ code: code })
+    });
+    const result = await response.json();
+    if (!response.ok) {
+        alert(result.message || 'Ошибка');
+        return null;
+    }
+    return result;
+}
+
+function showRecoveryCodes(codes) {
+    alert('Сохраните коды восстановления в надежном месте. Каждый код можно использовать один раз, ' +
+        'если нет доступа к приложению. Больше они показаны не будут:\n\n' + codes.join('\n'));
+}
+
+// Выгрузка персональных данных: браузер скачивает ZIP-архив с JSON-файлами
+function exportMyData() {
+    window.location.href = '/api/profile/export';
+}
+
+// Удаление аккаунта: отзывы останутся без имени, остальные данные будут удалены
+async function deleteAccount() {
+    if (!confirm('Удалить аккаунт? Корзина и данные профиля будут удалены, отзывы останутся без имени и email. ' +
+        'Отменить удаление нельзя.')) {
+        return;
+    }
+    const password = prompt('Для подтверждения введите текущий пароль');
+    if (!password) return;
+
+    try {
+        const response = await apiFetch('/api/profile', {
+            method: 'DELETE',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ currentPassword: password, confirm: true })
+        });
+        const result = await response.json();
+        alert(result.message || (response.ok ? 'Аккаунт удален' : 'Не удалось удалить аккаунт'));
+        if (response.ok) {
+            window.location.reload();
+        }
+    } catch (error) {
+        console.error('Ошибка удаления аккаунта:', error);
+        alert('Ошибка соединения с сервером');
+    }
+}
+
+// Сообщения об ошибках входа через внешние сервисы по коду из параметра oidc_error
+const oidcErrorMessages = {
+    oidc_state: 'Сеанс входа устарел, попробуйте еще раз',
+    oidc_denied: 'Вход через сервис отменен',
+    oidc_failed: 'Не удалось войти через сервис, попробуйте позже',
+    oidc_throttled: 'Слишком много попыток, повторите позже',
+    oidc_provider_unknown: 'Вход через этот сервис недоступен',
+    oidc_identity_linked: 'Этот аккаунт сервиса уже привязан к другому пользователю',
+    oidc_provider_linked: 'К вашему аккаунту уже привязан аккаунт этого сервиса',
+    unauthorized: 'Войдите, чтобы привязать сервис',
+};
+
+// Вход через внешние сервисы: список привязанных, привязка и отвязка
+async function manageIdentities() {
+    try {
+        const response = await fetch('/api/account/identities');
+        const result = await response.json();
+        if (!response.ok) {
+            alert(result.message || 'Не удалось получить список сервисов');
+            return;
+        }
+        if (result.providers.length === 0) {
+            alert('Вход через внешние сервисы не настроен');
+            return;
+        }
+
+        const linked = {};
+        result.identities.forEach(identity => { linked[identity.provider] = identity; });
+        const lines = result.providers.map((provider, i) => {
+            const identity = linked[provider.name];
+            const state = identity ? `привязан${identity.email ? ' (' + identity.email + ')' : ''}` : 'не привязан';
+            return `${i + 1}. ${provider.display_name}: ${state}`;
+        });
+        const choice = prompt(lines.join('\n') + '\n\nВведите номер сервиса, чтобы привязать или отвязать его');
+        const provider = result.providers[parseInt(choice, 10) - 1];
+        if (!provider) return;
+
+        const identity = linked[provider.name];
+        if (!identity) {
+            window.location.href = `/api/auth/oidc/${encodeURIComponent(provider.name)}?link=1`;
+            return;
+        }
+
+        const password = prompt(`Отвязать ${provider.display_name}? Для подтверждения введите текущий пароль`);
+        if (!password) return;
+        const unlinkResponse = await apiFetch(`/api/account/identities/${identity.id}`, {
+            method: 'DELETE',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ currentPassword: password })
+        });
+        const unlinkResult = await unlinkResponse.json();
+        alert(unlinkResult.message || (unlinkResponse.ok ? 'Аккаунт отвязан' : 'Не удалось отвязать аккаунт'));
+    } catch (error) {
+        console.error('Ошибка управления входом через сервисы:', error);
+        alert('Ошибка соединения с сервером');
+    }
+}
+
+// Итог привязки сервиса: возврат с провайдера на ?oidc_linked=1 или ?oidc_error=<код>
+function showOidcResult() {
+    const params = new URLSearchParams(window.location.search);
+    if (!params.has('oidc_linked') && !params.has('oidc_error')) return;
+
+    if (params.has('oidc_linked')) {
+        alert('Аккаунт сервиса привязан: теперь через него можно входить');
+    } else {
+        alert(oidcErrorMessages[params.get('oidc_error')] || 'Не удалось привязать сервис');
+    }
+    history.replaceState(null, '', window.location.pathname);
+}
+
+// Повторное согласие: если вышла новая редакция политики, показываем ее и просим принять
+async function checkPolicyConsents() {
+    try {
+        const response = await fetch('/api/consents');
+        if (!response.ok) return;
+        const status = await response.json();
+        if (!status.pending || status.pending.length === 0) return;
+
+        const doc = status.pending[0];
+        if (typeof openPdfModal === 'function') {
+            openPdfModal(doc.url, 'Политика конфиденциальности');
+        }
+        if (!confirm(`Мы обновили политику конфиденциальности (редакция ${doc.version}). ` +
+            'Нажмите «ОК», чтобы принять новую редакцию.')) {
+            return;
+        }
+
+        const acceptResponse = await apiFetch('/api/consents', {
+            method: 'POST',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({ document_ids: status.pending.map(d => d.id) })
+        });
+        if (!acceptResponse.ok) {
+            const result = await acceptResponse.json();
+            alert(result.message || 'Не удалось сохранить согласие');
+        }
+    } catch (error) {
+        console.error('Ошибка проверки согласий:', error);
+    }
+}
+
 // Закрытие корзины при клике вне ее области
 document.addEventListener('click', function(e) {
     const cartModal = document.getElementById('cartModal');
@@ -261,6 +465,7 @@ document.addEventListener('click', function(e) {
 document.addEventListener('DOMContentLoaded', function() {
     console.log('Загружен user-panel.js');
     checkAuthAndUpdateUI();
+    showOidcResult();
 });
 
 // js/user-panel.js